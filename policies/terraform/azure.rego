@@ -62,15 +62,26 @@ deny[msg] {
   msg := sprintf("Storage account %s must use TLS 1.2 minimum (current: %s)", [resource.address, tls_version])
 }
 
+# Flexible servers enforce TLS through the require_secure_transport server
+# parameter rather than a server attribute.
 deny[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_postgresql_flexible_server"
   resource.change.actions[_] in ["create", "update"]
-  ssl_mode := object.get(resource.change.after, "ssl_enforcement_enabled", false)
-  ssl_mode == false
+  not requires_secure_transport(resource)
   msg := sprintf("PostgreSQL server %s must have SSL enforcement enabled", [resource.address])
 }
 
+# The configuration's server_id is only known after apply, so match a
+# configuration planned in the same module as the server.
+requires_secure_transport(server) {
+  config := input.resource_changes[_]
+  config.type == "azurerm_postgresql_flexible_server_configuration"
+  object.get(config, "module_address", "") == object.get(server, "module_address", "")
+  config.change.after.name == "require_secure_transport"
+  lower(config.change.after.value) == "on"
+}
+
 # -----------------------------------------------------------------------------
 # SECURITY - ENCRYPTION
 # -----------------------------------------------------------------------------
@@ -145,16 +156,43 @@ warn[msg] {
   resource := input.resource_changes[_]
   resource.type == "azurerm_storage_account"
   resource.change.actions[_] == "create"
-  not has_private_endpoint(resource.address)
+  not has_private_endpoint(resource)
   msg := sprintf("Storage account %s should use private endpoints for secure access", [resource.address])
 }
 
-has_private_endpoint(resource_address) {
+has_private_endpoint(resource) {
   pe := input.resource_changes[_]
   pe.type == "azurerm_private_endpoint"
-  contains(pe.address, resource_address)
+  contains(pe.address, resource.address)
 }
 
+# The endpoint's target ID is only known after apply, so match the
+# configuration reference instead, e.g. azurerm_storage_account.main.id.
+# References are relative to their module, so only endpoints declared in the
+# resource's own module count.
+has_private_endpoint(resource) {
+  some path, value
+  walk(input.configuration, [path, value])
+  value.type == "azurerm_private_endpoint"
+  configuration_module_calls(path) == address_module_calls(object.get(resource, "module_address", ""))
+  connection := value.expressions.private_service_connection[_]
+  connection.private_connection_resource_id.references[_] == sprintf("%s.%s.id", [resource.type, resource.name])
+}
+
+# The module call names leading to a path in the configuration, e.g. ["ai"]
+# for root_module.module_calls.ai.module.resources[0].
+configuration_module_calls(path) := [name |
+  some i
+  path[i] == "module_calls"
+  name := path[i + 1]
+]
+
+# The module call names of a module address, without instance keys, e.g.
+# ["ai", "search"] for module.ai[0].module.search.
+address_module_calls(address) := [match[1] |
+  match := regex.find_all_string_submatch_n(`module\.([^.\[]+)`, address, -1)[_]
+]
+
 # -----------------------------------------------------------------------------
 # AKS SPECIFIC POLICIES
 # -----------------------------------------------------------------------------
@@ -90,26 +90,26 @@ provider "github" {
 }
 
 provider "kubernetes" {
-  host                   = module.aks.kube_config.host
-  client_certificate     = base64decode(module.aks.kube_config.client_certificate)
-  client_key             = base64decode(module.aks.kube_config.client_key)
-  cluster_ca_certificate = base64decode(module.aks.kube_config.cluster_ca_certificate)
+  host                   = module.aks.host
+  client_certificate     = module.aks.client_certificate
+  client_key             = module.aks.client_key
+  cluster_ca_certificate = module.aks.cluster_ca_certificate
 }
 
 provider "helm" {
   kubernetes {
-    host                   = module.aks.kube_config.host
-    client_certificate     = base64decode(module.aks.kube_config.client_certificate)
-    client_key             = base64decode(module.aks.kube_config.client_key)
-    cluster_ca_certificate = base64decode(module.aks.kube_config.cluster_ca_certificate)
+    host                   = module.aks.host
+    client_certificate     = module.aks.client_certificate
+    client_key             = module.aks.client_key
+    cluster_ca_certificate = module.aks.cluster_ca_certificate
   }
 }
 
 provider "kubectl" {
-  host                   = module.aks.kube_config.host
-  client_certificate     = base64decode(module.aks.kube_config.client_certificate)
-  client_key             = base64decode(module.aks.kube_config.client_key)
-  cluster_ca_certificate = base64decode(module.aks.kube_config.cluster_ca_certificate)
+  host                   = module.aks.host
+  client_certificate     = module.aks.client_certificate
+  client_key             = module.aks.client_key
+  cluster_ca_certificate = module.aks.cluster_ca_certificate
   load_config_file       = false
 }
 
@@ -163,6 +163,30 @@ locals {
   config = local.deployment_configs[var.deployment_mode]
 }
 
+# =============================================================================
+# MODULE: NAMING (region short codes)
+# =============================================================================
+
+# modules/naming owns the region code table. It takes shorter project and
+# environment names than the root module, which only affect the names it
+# outputs, not region_code.
+module "naming" {
+  source = "./modules/naming"
+
+  project_name = substr(replace(var.customer_name, "-", ""), 0, 10)
+  environment  = lookup({ dev = "dev", staging = "stg", prod = "prd" }, var.environment)
+  location     = var.location
+}
+
+module "naming_dr" {
+  source = "./modules/naming"
+  count  = var.enable_disaster_recovery ? 1 : 0
+
+  project_name = substr(replace(var.customer_name, "-", ""), 0, 10)
+  environment  = lookup({ dev = "dev", staging = "stg", prod = "prd" }, var.environment)
+  location     = var.dr_location
+}
+
 # =============================================================================
 # RESOURCE GROUP
 # =============================================================================
@@ -201,6 +225,14 @@ module "networking" {
   dns_zone_name   = var.domain_name
   create_dns_zone = true
 
+  # Purview private endpoints need their own privatelink zones
+  additional_private_dns_zones = var.enable_purview ? {
+    purview        = "privatelink.purview.azure.com"
+    purview_studio = "privatelink.purviewstudio.azure.com"
+    queue          = "privatelink.queue.core.windows.net"
+    servicebus     = "privatelink.servicebus.windows.net"
+  } : {}
+
   tags = local.common_tags
 }
 
@@ -320,8 +352,6 @@ module "aks" {
 
   admin_group_ids = [var.admin_group_id]
 
-  private_dns_zone_id = module.networking.private_dns_zone_ids.aks
-
   tags = local.common_tags
 
   depends_on = [module.networking]
@@ -440,7 +470,8 @@ module "ai_foundry" {
   }
 
   key_vault_id               = module.security.key_vault_id
-  log_analytics_workspace_id = module.observability[0].log_analytics_workspace_id
+  log_analytics_workspace_id = var.enable_observability ? module.observability[0].log_analytics_workspace_id : ""
+  enable_diagnostics         = var.enable_observability
 
   tags = local.common_tags
 
@@ -522,15 +553,12 @@ module "container_registry" {
   location            = var.location
   resource_group_name = azurerm_resource_group.main.name
 
-  sku                       = var.deployment_mode == "express" ? "Standard" : "Premium"
-  zone_redundancy_enabled   = var.deployment_mode == "enterprise"
-  admin_enabled             = false
-  public_network_access_enabled = var.deployment_mode == "express"
+  sku = var.deployment_mode == "express" ? "Standard" : "Premium"
 
   subnet_id           = module.networking.subnet_ids.private_endpoints
   private_dns_zone_id = module.networking.private_dns_zone_ids.acr
 
-  aks_kubelet_identity_id = module.aks.kubelet_identity.object_id
+  aks_kubelet_identity_object_id = module.aks.kubelet_identity
 
   tags = local.common_tags
 
@@ -545,10 +573,16 @@ module "external_secrets" {
   source = "./modules/external-secrets"
   count  = var.enable_external_secrets ? 1 : 0
 
-  namespace        = "external-secrets"
-  chart_version    = "0.9.9"
-  key_vault_name   = module.security.keyvault_name
-  tenant_id        = var.azure_tenant_id
+  customer_name       = var.customer_name
+  environment         = var.environment
+  location            = var.location
+  resource_group_name = azurerm_resource_group.main.name
+
+  namespace         = "external-secrets"
+  eso_chart_version = "0.9.9"
+  aks_cluster_name  = module.aks.cluster_name
+  key_vault_id      = module.security.key_vault_id
+  key_vault_uri     = module.security.key_vault_uri
 
   tags = local.common_tags
 
@@ -563,14 +597,32 @@ module "github_runners" {
   source = "./modules/github-runners"
   count  = var.enable_github_runners ? 1 : 0
 
-  namespace                   = "github-runners"
-  github_org                  = var.github_org
-  github_app_id               = var.github_app_id
-  github_app_installation_id  = var.github_app_installation_id
-  github_app_private_key      = var.github_app_private_key
-  runner_scale_set_name       = "arc-runners"
-  min_runners                 = 1
-  max_runners                 = var.deployment_mode == "enterprise" ? 10 : 5
+  customer_name = var.customer_name
+  environment   = var.environment
+
+  namespace                  = "github-runners"
+  github_org                 = var.github_org
+  github_app_id              = var.github_app_id
+  github_app_installation_id = var.github_app_installation_id
+  github_app_private_key     = var.github_app_private_key
+
+  runner_groups = {
+    "arc-runners" = {
+      min_runners   = 1
+      max_runners   = var.deployment_mode == "enterprise" ? 10 : 5
+      runner_group  = "default"
+      labels        = ["self-hosted", "linux", "x64"]
+      node_selector = {}
+      tolerations   = []
+      resources = {
+        cpu_request    = "500m"
+        cpu_limit      = "2000m"
+        memory_request = "1Gi"
+        memory_limit   = "4Gi"
+      }
+      container_mode = "dind"
+    }
+  }
 
   tags = local.common_tags
 
@@ -581,20 +633,34 @@ module "github_runners" {
 # MODULE: DEFENDER (H1 — Optional)
 # =============================================================================
 
+# Defender exports its alerts to the observability Log Analytics workspace.
+# Enabling it without observability fails the plan instead of dropping it.
+resource "terraform_data" "defender_requires_observability" {
+  count = var.enable_defender ? 1 : 0
+
+  lifecycle {
+    precondition {
+      condition     = var.enable_observability
+      error_message = "enable_defender requires enable_observability: Defender exports to the observability Log Analytics workspace."
+    }
+  }
+}
+
 module "defender" {
   source = "./modules/defender"
-  count  = var.enable_defender ? 1 : 0
+  count  = var.enable_defender && var.enable_observability ? 1 : 0
 
-  customer_name       = var.customer_name
-  environment         = var.environment
-  location            = var.location
-  resource_group_name = azurerm_resource_group.main.name
+  subscription_id = var.azure_subscription_id
+  customer_name   = var.customer_name
+  environment     = var.environment
+
+  # Defender plans per sizing profile: express maps to small, enterprise to
+  # large
+  sizing_profile = lookup({ express = "small", standard = "medium", enterprise = "large" }, var.deployment_mode)
 
-  enable_container_plan     = true
-  enable_servers_plan       = var.deployment_mode == "enterprise"
-  enable_storage_plan       = var.deployment_mode == "enterprise"
-  enable_key_vault_plan     = true
-  enable_dns_plan           = false
+  log_analytics_workspace_id = module.observability[0].log_analytics_workspace_id
+  security_contact_email     = var.security_contact_email
+  aks_cluster_ids            = [module.aks.cluster_id]
 
   tags = local.common_tags
 }
@@ -612,8 +678,17 @@ module "purview" {
   location            = var.location
   resource_group_name = azurerm_resource_group.main.name
 
-  subnet_id           = module.networking.subnet_ids.private_endpoints
-  admin_group_id      = var.admin_group_id
+  subnet_id      = module.networking.subnet_ids.private_endpoints
+  admin_group_id = var.admin_group_id
+
+  private_dns_zone_ids = {
+    purview        = module.networking.private_dns_zone_ids.purview
+    purview_studio = module.networking.private_dns_zone_ids.purview_studio
+    storage_blob   = module.networking.private_dns_zone_ids.blob
+    storage_queue  = module.networking.private_dns_zone_ids.queue
+    servicebus     = module.networking.private_dns_zone_ids.servicebus
+    eventhub       = module.networking.private_dns_zone_ids.servicebus
+  }
 
   tags = local.common_tags
 
@@ -628,11 +703,13 @@ module "cost_management" {
   source = "./modules/cost-management"
   count  = var.enable_cost_management ? 1 : 0
 
+  customer_name       = var.customer_name
+  environment         = var.environment
+  location            = var.location
   resource_group_name = azurerm_resource_group.main.name
-  subscription_id     = var.azure_subscription_id
-  budget_amount       = var.budget_amount
-  alert_thresholds    = [50, 75, 90, 100]
-  alert_emails        = var.alert_emails
+
+  monthly_budget        = var.budget_amount
+  alert_email_addresses = var.alert_emails
 
   tags = local.common_tags
 }
@@ -645,16 +722,16 @@ module "disaster_recovery" {
   source = "./modules/disaster-recovery"
   count  = var.enable_disaster_recovery ? 1 : 0
 
-  customer_name       = var.customer_name
-  environment         = var.environment
-  resource_group_name = azurerm_resource_group.main.name
+  customer_name               = var.customer_name
+  environment                 = var.environment
+  primary_resource_group_name = azurerm_resource_group.main.name
 
-  primary_location   = var.location
-  secondary_location = var.dr_location
+  primary_location     = var.location
+  primary_region_short = module.naming.region_code
+  dr_location          = var.dr_location
+  dr_region_short      = module.naming_dr[0].region_code
 
-  enable_aks_dr               = var.deployment_mode == "enterprise"
-  enable_database_replication = true
-  enable_storage_replication  = true
+  enable_site_recovery = var.deployment_mode == "enterprise"
 
   tags = local.common_tags
 }
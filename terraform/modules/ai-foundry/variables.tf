@@ -121,6 +121,12 @@ variable "log_analytics_workspace_id" {
   default     = ""
 }
 
+variable "enable_diagnostics" {
+  description = "Send diagnostics to log_analytics_workspace_id. Defaults to whether the ID is set; set it when the workspace is created in the same apply, since count cannot depend on its ID"
+  type        = bool
+  default     = null
+}
+
 variable "tags" {
   description = "Tags to apply to resources"
   type        = map(string)
@@ -131,6 +131,7 @@ resource "azurerm_postgresql_flexible_server_configuration" "configs" {
     "work_mem"                   = "32768"
     "maintenance_work_mem"       = "524288"
     "effective_cache_size"       = "1572864"
+    "require_secure_transport"   = "on"
   } : {}
 
   name      = each.key
@@ -471,7 +471,8 @@ output "monitor_workspace" {
 
 output "grafana" {
   description = "Azure Managed Grafana name (amg-)"
-  value       = "amg-${local.base_prefix}"
+  # Rules: 2-23 chars, alphanumeric and hyphens, end with alphanumeric
+  value = trim(substr("amg-${local.base_prefix}", 0, 23), "-")
 }
 
 # =============================================================================
@@ -11,8 +11,8 @@ variable "project_name" {
   description = "Project or workload name (e.g., 'openhorizons')"
   type        = string
   validation {
-    condition     = can(regex("^[a-z0-9]{2,10}$", var.project_name))
-    error_message = "Project name must be 2-10 lowercase alphanumeric characters."
+    condition     = can(regex("^[a-z][a-z0-9]{1,9}$", var.project_name))
+    error_message = "Project name must be 2-10 lowercase alphanumeric characters, starting with a letter."
   }
 }
 
@@ -28,16 +28,28 @@ variable "environment" {
 variable "location" {
   description = "Azure region"
   type        = string
+  validation {
+    condition     = can(regex("^[a-z][a-z0-9]*$", var.location))
+    error_message = "Location must be an Azure region name in lowercase letters and digits (e.g., 'eastus2')."
+  }
 }
 
 variable "instance" {
   description = "Instance number for multiple deployments (e.g., '001')"
   type        = string
   default     = "001"
+  validation {
+    condition     = can(regex("^[0-9]{3}$", var.instance))
+    error_message = "Instance must be three digits (e.g., '001')."
+  }
 }
 
 variable "org_code" {
   description = "Organization code (2-4 chars, e.g., 'ms', 'cont')"
   type        = string
   default     = ""
+  validation {
+    condition     = var.org_code == "" || can(regex("^[a-z0-9]{2,4}$", var.org_code))
+    error_message = "Organization code must be empty or 2-4 lowercase alphanumeric characters."
+  }
 }
@@ -29,7 +29,7 @@ locals {
   })
 
   # Private DNS zones for Azure services
-  private_dns_zones = {
+  private_dns_zones = merge({
     "postgres"          = "privatelink.postgres.database.azure.com"
     "redis"             = "privatelink.redis.cache.windows.net"
     "keyvault"          = "privatelink.vaultcore.azure.net"
@@ -38,7 +38,7 @@ locals {
     "openai"            = "privatelink.openai.azure.com"
     "cognitiveservices" = "privatelink.cognitiveservices.azure.com"
     "search"            = "privatelink.search.windows.net"
-  }
+  }, var.additional_private_dns_zones)
 }
 
 # =============================================================================
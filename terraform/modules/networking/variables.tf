@@ -69,6 +69,12 @@ variable "create_dns_zone" {
   default     = true
 }
 
+variable "additional_private_dns_zones" {
+  description = "Private DNS zones to create besides the built-in ones, keyed like private_dns_zone_ids"
+  type        = map(string)
+  default     = {}
+}
+
 variable "tags" {
   description = "Tags to apply to resources"
   type        = map(string)
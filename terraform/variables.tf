@@ -95,7 +95,7 @@ variable "tags" {
 # -----------------------------------------------------------------------------
 
 variable "enable_defender" {
-  description = "Enable Microsoft Defender for Cloud"
+  description = "Enable Microsoft Defender for Cloud (requires enable_observability for continuous export)"
   type        = bool
   default     = false
 }
@@ -230,6 +230,16 @@ variable "github_app_private_key" {
   sensitive   = true
 }
 
+# -----------------------------------------------------------------------------
+# Defender — Required when enable_defender = true
+# -----------------------------------------------------------------------------
+
+variable "security_contact_email" {
+  description = "Email address for Microsoft Defender for Cloud security alerts"
+  type        = string
+  default     = ""
+}
+
 # -----------------------------------------------------------------------------
 # Cost Management — Required when enable_cost_management = true
 # -----------------------------------------------------------------------------
@@ -0,0 +1,48 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - MODULE INTERFACE REPORT
+// =============================================================================
+//
+// Prints the changes to the variables and outputs of every module under
+// terraform/modules between two git refs, with the semantic version bump each
+// module needs. Breaking changes are a removed variable or output, a new
+// required variable, a removed default, a narrowed type and an output that
+// became sensitive.
+//
+// Run with: go run ./cmd/module-interface -from v4.0.0 [-to HEAD] [-check]
+//
+// Without -to the working tree is compared. With -check the command exits 1
+// when any module needs a major version bump.
+//
+// =============================================================================
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+func main() {
+	from := flag.String("from", helpers.UpgradeRef(), "git ref to compare from")
+	to := flag.String("to", "", "git ref to compare to (default: the working tree)")
+	check := flag.Bool("check", false, "exit 1 when a module needs a major version bump")
+	flag.Parse()
+
+	reports, err := helpers.CompareRefsE(*from, *to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "module-interface:", err)
+		os.Exit(2)
+	}
+	fmt.Print(helpers.FormatModuleReports(reports))
+
+	if *check {
+		for _, report := range reports {
+			if report.Bump == helpers.BumpMajor {
+				os.Exit(1)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - NSG RULE REPORT
+// =============================================================================
+//
+// Prints the risky network security rules of one or more plans: inbound
+// allows from any address or the Internet to SSH, RDP and other management
+// ports, allows of every protocol and port between any addresses, and denies
+// that an allow with a lower priority number makes unreachable.
+//
+// Run with: go run ./cmd/nsg-report [-json] [-check] plan.json...
+//
+// Each plan is the output of terraform show -json. With -json the findings
+// are printed as a JSON array. With -check the command exits 1 when any
+// finding is high severity.
+//
+// =============================================================================
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the findings as JSON")
+	check := flag.Bool("check", false, "exit 1 when any finding is high severity")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "nsg-report: no plan files given")
+		os.Exit(2)
+	}
+	var plans []*helpers.Plan
+	for _, path := range flag.Args() {
+		plan, err := helpers.LoadPlan(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "nsg-report:", err)
+			os.Exit(2)
+		}
+		plans = append(plans, plan)
+	}
+
+	findings := helpers.NSGFindings(plans...)
+	if *asJSON {
+		if findings == nil {
+			findings = []helpers.NSGFinding{}
+		}
+		out, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "nsg-report:", err)
+			os.Exit(2)
+		}
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(helpers.FormatNSGFindings(findings))
+	}
+
+	if *check {
+		for _, finding := range findings {
+			if finding.Severity == helpers.SeverityHigh {
+				os.Exit(1)
+			}
+		}
+	}
+}
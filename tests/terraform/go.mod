@@ -4,7 +4,13 @@ go 1.21
 
 require (
 	github.com/gruntwork-io/terratest v0.47.2
+	github.com/hashicorp/hcl/v2 v2.22.0
+	github.com/hashicorp/terraform-json v0.22.1
+	github.com/open-policy-agent/opa v0.68.0
 	github.com/stretchr/testify v1.9.0
+	github.com/zclconf/go-cty v1.15.0
+	github.com/zclconf/go-cty-yaml v1.0.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -27,8 +33,6 @@ require (
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/hashicorp/go-safetemp v1.0.0 // indirect
 	github.com/hashicorp/go-version v1.7.0 // indirect
-	github.com/hashicorp/hcl/v2 v2.22.0 // indirect
-	github.com/hashicorp/terraform-json v0.22.1 // indirect
 	github.com/jinzhu/copier v0.4.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/klauspost/compress v1.17.11 // indirect
@@ -43,12 +47,10 @@ require (
 	github.com/ulikunitz/xz v0.5.15 // indirect
 	github.com/urfave/cli/v2 v2.27.5 // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
-	github.com/zclconf/go-cty v1.15.0 // indirect
 	golang.org/x/crypto v0.45.0 // indirect
 	golang.org/x/net v0.38.0 // indirect
 	golang.org/x/oauth2 v0.27.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
 	google.golang.org/protobuf v1.35.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,448 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+// AzureReservedAddresses is how many addresses Azure keeps in every subnet
+// prefix: the network address, the default gateway, two for DNS and the
+// broadcast address.
+const AzureReservedAddresses = 5
+
+const (
+	// minSubnetBits is the longest IPv4 prefix Azure accepts for a subnet.
+	minSubnetBits = 29
+
+	// bastionSubnetName is the name Azure Bastion requires for its subnet.
+	bastionSubnetName = "AzureBastionSubnet"
+
+	// bastionSubnetBits is the longest prefix AzureBastionSubnet may have.
+	bastionSubnetBits = 26
+)
+
+// SubnetUsage is a planned subnet and the addresses the AKS node pools placed
+// in it need at their largest.
+type SubnetUsage struct {
+	Address  string
+	Name     string
+	VNet     string
+	Prefixes []netip.Prefix
+
+	// Usable counts the IPv4 addresses left once Azure reserves
+	// AzureReservedAddresses in each prefix. IPv6 prefixes are only checked
+	// for containment and overlap.
+	Usable int
+
+	// Required counts the addresses the node pools need: one per node at the
+	// pool's maximum size plus its upgrade surge, and max_pods per node where
+	// pods take addresses from the subnet.
+	Required int
+
+	// Pools describe each node pool's share of Required.
+	Pools []string
+}
+
+// Headroom is the number of usable addresses the node pools leave free.
+func (s SubnetUsage) Headroom() int {
+	return s.Usable - s.Required
+}
+
+// String describes the usage, e.g. "azurerm_subnet.aks_pods (snet-aks-pods,
+// 10.0.16.0/20): 4091 usable, 880 required, 3211 headroom".
+func (s SubnetUsage) String() string {
+	prefixes := make([]string, len(s.Prefixes))
+	for i, prefix := range s.Prefixes {
+		prefixes[i] = prefix.String()
+	}
+	return fmt.Sprintf("%s (%s, %s): %d usable, %d required, %d headroom",
+		s.Address, s.Name, strings.Join(prefixes, " "), s.Usable, s.Required, s.Headroom())
+}
+
+// AddressSpaceReport is the analysis of the virtual networks, subnets and
+// AKS node pools in one or more plans.
+type AddressSpaceReport struct {
+	// Subnets are sorted by address.
+	Subnets []SubnetUsage
+
+	// Problems are addresses Azure would reject or that cannot hold the
+	// node pools, sorted.
+	Problems []string
+
+	// Unchecked are node pools whose subnet or size the plans leave unknown,
+	// sorted.
+	Unchecked []string
+}
+
+// plannedNodePool is the part of an AKS node pool that takes subnet
+// addresses.
+type plannedNodePool struct {
+	address      string
+	name         string
+	subnetID     interface{}
+	podSubnetID  interface{}
+	nodes        int
+	maxPods      int
+	podsOnSubnet bool
+
+	// standalone pools are azurerm_kubernetes_cluster_node_pool resources.
+	standalone bool
+}
+
+// AnalyzeAddressSpace checks the address space of the plans, which are
+// analysed as one: every subnet prefix lies within its virtual network's
+// address space, no two subnets of a network overlap, every IPv4 subnet is
+// /29 or larger, AzureBastionSubnet is /26 or larger, and the subnets AKS
+// node pools use can hold them at max_count plus the upgrade surge, with
+// max_pods addresses per node in the pod subnet. Without a pod subnet the
+// pods take node subnet addresses, unless the cluster uses kubenet or CNI
+// overlay. A node pool finds its subnet by the name in its subnet ID, and
+// the network name too when a subnet of that name is planned in several.
+// Resources planned for deletion are skipped.
+func AnalyzeAddressSpace(plans ...*Plan) *AddressSpaceReport {
+	report := &AddressSpaceReport{}
+	vnets := map[string][]netip.Prefix{}
+	usage := map[string]*SubnetUsage{}
+	var subnets []*SubnetUsage
+	var pools []plannedNodePool
+	var clusterPlugins []string
+
+	for _, plan := range plans {
+		for _, address := range plan.Addresses() {
+			change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+			if change.Mode != "managed" {
+				continue
+			}
+			if action := change.Action(); action == ActionDelete || action == ActionRead {
+				continue
+			}
+
+			switch change.Type {
+			case "azurerm_virtual_network":
+				name, _ := change.After("name")
+				vnets[fmt.Sprint(name)] = parsePrefixes(report, address, change, "address_space")
+			case "azurerm_subnet":
+				name, _ := change.After("name")
+				vnet, _ := change.After("virtual_network_name")
+				subnet := &SubnetUsage{
+					Address:  address,
+					Name:     fmt.Sprint(name),
+					VNet:     fmt.Sprint(vnet),
+					Prefixes: parsePrefixes(report, address, change, "address_prefixes"),
+				}
+				subnets = append(subnets, subnet)
+				usage[address] = subnet
+			case "azurerm_kubernetes_cluster":
+				plugin := clusterPodPlacement(change)
+				clusterPlugins = append(clusterPlugins, plugin)
+				if pool, ok := readNodePool(report, address, change, "default_node_pool.0."); ok {
+					pool.podsOnSubnet = plugin == "azure"
+					pools = append(pools, pool)
+				}
+			case "azurerm_kubernetes_cluster_node_pool":
+				if pool, ok := readNodePool(report, address, change, ""); ok {
+					pool.standalone = true
+					pools = append(pools, pool)
+				}
+			}
+		}
+	}
+
+	// Node pools of their own find their cluster by an ID that is unknown
+	// until apply, so they share the network plugin of the only cluster.
+	nodePoolPlugin := "azure"
+	if len(clusterPlugins) == 1 {
+		nodePoolPlugin = clusterPlugins[0]
+	}
+	for i := range pools {
+		if pools[i].standalone {
+			pools[i].podsOnSubnet = nodePoolPlugin == "azure"
+		}
+	}
+
+	for _, subnet := range subnets {
+		subnet.Usable = usableAddresses(subnet.Prefixes)
+		checkSubnet(report, subnet, vnets)
+	}
+	checkOverlaps(report, subnets)
+	for _, pool := range pools {
+		placeNodePool(report, pool, subnets)
+	}
+	for _, subnet := range subnets {
+		if subnet.Required > subnet.Usable {
+			report.Problems = append(report.Problems, fmt.Sprintf(
+				"%s: node pools need %d addresses, but %s has %d usable after Azure reserves %d per prefix (%s)",
+				subnet.Address, subnet.Required, subnet.Name, subnet.Usable, AzureReservedAddresses, strings.Join(subnet.Pools, "; ")))
+		}
+	}
+
+	for _, address := range sortedStrings(usage) {
+		report.Subnets = append(report.Subnets, *usage[address])
+	}
+	sort.Strings(report.Problems)
+	sort.Strings(report.Unchecked)
+	return report
+}
+
+// parsePrefixes reads a list of CIDR blocks from a planned attribute.
+func parsePrefixes(report *AddressSpaceReport, address string, change *ResourceChange, attr string) []netip.Prefix {
+	value, _ := change.After(attr)
+	list, _ := value.([]interface{})
+	var prefixes []netip.Prefix
+	for _, item := range list {
+		text, _ := item.(string)
+		prefix, err := netip.ParsePrefix(text)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %s %q is not a CIDR block", address, attr, text))
+			continue
+		}
+		if prefix.Masked() != prefix {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %s %s has host bits set; Azure expects %s", address, attr, prefix, prefix.Masked()))
+			prefix = prefix.Masked()
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// usableAddresses counts the IPv4 addresses of the prefixes that Azure does
+// not reserve.
+func usableAddresses(prefixes []netip.Prefix) int {
+	usable := 0
+	for _, prefix := range prefixes {
+		if !prefix.Addr().Is4() {
+			continue
+		}
+		if size := 1 << (32 - prefix.Bits()); size > AzureReservedAddresses {
+			usable += size - AzureReservedAddresses
+		}
+	}
+	return usable
+}
+
+// checkSubnet checks the size of a subnet and that it lies within its
+// virtual network, when the network is planned too.
+func checkSubnet(report *AddressSpaceReport, subnet *SubnetUsage, vnets map[string][]netip.Prefix) {
+	space, planned := vnets[subnet.VNet]
+	for _, prefix := range subnet.Prefixes {
+		if prefix.Addr().Is4() && prefix.Bits() > minSubnetBits {
+			report.Problems = append(report.Problems, fmt.Sprintf(
+				"%s: %s is smaller than /%d, the smallest subnet Azure allows once it reserves %d addresses",
+				subnet.Address, prefix, minSubnetBits, AzureReservedAddresses))
+		}
+		if subnet.Name == bastionSubnetName && prefix.Bits() > bastionSubnetBits {
+			report.Problems = append(report.Problems, fmt.Sprintf(
+				"%s: %s is %s, but Azure Bastion needs /%d or larger", subnet.Address, bastionSubnetName, prefix, bastionSubnetBits))
+		}
+		if planned && !withinAny(prefix, space) {
+			report.Problems = append(report.Problems, fmt.Sprintf(
+				"%s: %s is outside the address space of %s (%s)", subnet.Address, prefix, subnet.VNet, formatPrefixes(space)))
+		}
+	}
+}
+
+// checkOverlaps reports every pair of subnets of the same network whose
+// prefixes overlap.
+func checkOverlaps(report *AddressSpaceReport, subnets []*SubnetUsage) {
+	for i, a := range subnets {
+		for _, b := range subnets[i+1:] {
+			if a.VNet != b.VNet {
+				continue
+			}
+			for _, pa := range a.Prefixes {
+				for _, pb := range b.Prefixes {
+					if pa.Overlaps(pb) {
+						first, second := a, b
+						if second.Address < first.Address {
+							first, second = second, first
+							pa, pb = pb, pa
+						}
+						report.Problems = append(report.Problems, fmt.Sprintf(
+							"%s: %s overlaps %s of %s in %s", first.Address, pa, pb, second.Address, a.VNet))
+					}
+				}
+			}
+		}
+	}
+}
+
+// withinAny reports whether prefix lies inside one of space.
+func withinAny(prefix netip.Prefix, space []netip.Prefix) bool {
+	for _, block := range space {
+		if block.Bits() <= prefix.Bits() && block.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPrefixes joins prefixes for messages.
+func formatPrefixes(prefixes []netip.Prefix) string {
+	parts := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		parts[i] = prefix.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// clusterPodPlacement returns "azure" when the pods of a cluster's node
+// pools without a pod subnet take node subnet addresses, and the network
+// plugin or mode that gives them other addresses otherwise.
+func clusterPodPlacement(change *ResourceChange) string {
+	mode, _ := change.After("network_profile.0.network_plugin_mode")
+	if mode == "overlay" {
+		return "overlay"
+	}
+	plugin, ok := change.After("network_profile.0.network_plugin")
+	if !ok {
+		return "azure"
+	}
+	return fmt.Sprint(plugin)
+}
+
+// readNodePool reads the attributes of a node pool under prefix. A pool whose
+// size is unknown is recorded as unchecked.
+func readNodePool(report *AddressSpaceReport, address string, change *ResourceChange, prefix string) (plannedNodePool, bool) {
+	name, _ := change.After(prefix + "name")
+	pool := plannedNodePool{address: address, name: fmt.Sprint(name)}
+	if change.IsUnknown(prefix+"vnet_subnet_id") || change.IsUnknown(prefix+"pod_subnet_id") {
+		report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s %s: subnet ID is not known until apply", address, pool.name))
+		return pool, false
+	}
+	pool.subnetID, _ = change.After(prefix + "vnet_subnet_id")
+	pool.podSubnetID, _ = change.After(prefix + "pod_subnet_id")
+
+	nodes, ok := planNumber(change, prefix+"max_count")
+	if !ok {
+		nodes, ok = planNumber(change, prefix+"node_count")
+	}
+	maxPods, podsOK := planNumber(change, prefix+"max_pods")
+	if !ok || !podsOK {
+		report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s %s: node count or max_pods is not known until apply", address, pool.name))
+		return pool, false
+	}
+
+	surge, _ := change.After(prefix + "upgrade_settings.0.max_surge")
+	pool.nodes = nodes + surgeNodes(nodes, surge)
+	pool.maxPods = maxPods
+	return pool, true
+}
+
+// planNumber reads a planned number that is set and known.
+func planNumber(change *ResourceChange, path string) (int, bool) {
+	value, ok := change.After(path)
+	number, isNumber := value.(float64)
+	if !ok || !isNumber {
+		return 0, false
+	}
+	return int(number), true
+}
+
+// surgeNodes is how many extra nodes an upgrade adds to a pool of the given
+// size: max_surge as a count or a percentage rounded up, and one node when it
+// is not set, as AKS does.
+func surgeNodes(nodes int, maxSurge interface{}) int {
+	text, _ := maxSurge.(string)
+	if percent, ok := strings.CutSuffix(text, "%"); ok {
+		if value, err := strconv.ParseFloat(percent, 64); err == nil {
+			return int(math.Ceil(float64(nodes) * value / 100))
+		}
+	}
+	if value, err := strconv.Atoi(text); err == nil {
+		return value
+	}
+	return 1
+}
+
+// placeNodePool adds a node pool's addresses to the subnets it uses.
+func placeNodePool(report *AddressSpaceReport, pool plannedNodePool, subnets []*SubnetUsage) {
+	label := pool.address + " " + pool.name
+	nodeSubnet := findSubnet(pool.subnetID, subnets)
+	if nodeSubnet == nil {
+		report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s: subnet %v is not in the plans", label, pool.subnetID))
+		return
+	}
+
+	if pool.podSubnetID == nil {
+		if pool.podsOnSubnet {
+			nodeSubnet.Required += pool.nodes * (1 + pool.maxPods)
+			nodeSubnet.Pools = append(nodeSubnet.Pools, fmt.Sprintf("%s: %d nodes × (1 + %d pods)", label, pool.nodes, pool.maxPods))
+			return
+		}
+		nodeSubnet.Required += pool.nodes
+		nodeSubnet.Pools = append(nodeSubnet.Pools, fmt.Sprintf("%s: %d nodes", label, pool.nodes))
+		return
+	}
+
+	podSubnet := findSubnet(pool.podSubnetID, subnets)
+	if podSubnet == nil {
+		report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s: pod subnet %v is not in the plans", label, pool.podSubnetID))
+		return
+	}
+	nodeSubnet.Required += pool.nodes
+	nodeSubnet.Pools = append(nodeSubnet.Pools, fmt.Sprintf("%s: %d nodes", label, pool.nodes))
+	podSubnet.Required += pool.nodes * pool.maxPods
+	podSubnet.Pools = append(podSubnet.Pools, fmt.Sprintf("%s: %d nodes × %d pods", label, pool.nodes, pool.maxPods))
+}
+
+// findSubnet returns the planned subnet an ARM subnet ID names: the subnet
+// with that name and network, or else the only subnet with that name.
+func findSubnet(id interface{}, subnets []*SubnetUsage) *SubnetUsage {
+	text, _ := id.(string)
+	parts := strings.Split(strings.Trim(text, "/"), "/")
+	var vnet, name string
+	for i := 0; i+1 < len(parts); i += 2 {
+		switch strings.ToLower(parts[i]) {
+		case "virtualnetworks":
+			vnet = parts[i+1]
+		case "subnets":
+			name = parts[i+1]
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	var named []*SubnetUsage
+	for _, subnet := range subnets {
+		if !strings.EqualFold(subnet.Name, name) {
+			continue
+		}
+		if strings.EqualFold(subnet.VNet, vnet) {
+			return subnet
+		}
+		named = append(named, subnet)
+	}
+	if len(named) == 1 {
+		return named[0]
+	}
+	return nil
+}
+
+// AssertAddressSpace analyses the plans with AnalyzeAddressSpace, logs the
+// headroom of every subnet and each unchecked node pool, and fails the test
+// listing every problem.
+func AssertAddressSpace(t testing.TestingT, plans ...*Plan) bool {
+	markHelper(t)
+
+	report := AnalyzeAddressSpace(plans...)
+	for _, subnet := range report.Subnets {
+		logger.Default.Logf(t, "subnet %s", subnet)
+	}
+	for _, message := range report.Unchecked {
+		logger.Default.Logf(t, "node pool not checked: %s", message)
+	}
+	if len(report.Problems) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("%d address space problem(s):\n  - %s",
+		len(report.Problems), strings.Join(report.Problems, "\n  - ")))
+}
@@ -0,0 +1,129 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - ADDRESS SPACE TESTS
+// =============================================================================
+//
+// Tests for the analysis of planned virtual networks, subnets and AKS node
+// pools. testdata/address_space/plan_network.json has one of each problem:
+// overlapping subnets, a subnet outside the network, a /30 subnet, a /27
+// AzureBastionSubnet and a pod subnet too small for its node pools.
+//
+// Run with: go test -v -run TestAddressSpace ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAddressSpaceProblems tests the problems found in a plan
+func TestAddressSpaceProblems(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "address_space", "plan_network.json"))
+	require.NoError(t, err)
+	report := AnalyzeAddressSpace(plan)
+
+	assert.Equal(t, []string{
+		"azurerm_subnet.aks_nodes: 10.0.0.0/24 overlaps 10.0.0.128/25 of azurerm_subnet.private_endpoints in vnet-terratest",
+		`azurerm_subnet.aks_pods: node pools need 4290 addresses, but snet-aks-pods has 4091 usable after Azure reserves 5 per prefix (azurerm_kubernetes_cluster.main system: 8 nodes × 110 pods; azurerm_kubernetes_cluster_node_pool.user["big"] big: 31 nodes × 110 pods)`,
+		"azurerm_subnet.bastion: AzureBastionSubnet is 10.0.5.0/27, but Azure Bastion needs /26 or larger",
+		"azurerm_subnet.outside: 10.1.0.0/24 is outside the address space of vnet-terratest (10.0.0.0/16)",
+		"azurerm_subnet.tiny: 10.0.6.0/30 is smaller than /29, the smallest subnet Azure allows once it reserves 5 addresses",
+	}, report.Problems)
+	assert.Equal(t, []string{
+		`azurerm_kubernetes_cluster_node_pool.user["pending"] pending: subnet ID is not known until apply`,
+	}, report.Unchecked)
+}
+
+// TestAddressSpaceHeadroom tests the usable, required and free addresses per subnet
+func TestAddressSpaceHeadroom(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "address_space", "plan_network.json"))
+	require.NoError(t, err)
+	report := AnalyzeAddressSpace(plan)
+
+	headroom := map[string]int{}
+	for _, subnet := range report.Subnets {
+		headroom[subnet.Name] = subnet.Headroom()
+	}
+	assert.Equal(t, map[string]int{
+		"AzureBastionSubnet":     27,
+		"snet-aks-nodes":         251 - 39,
+		"snet-aks-pods":          4091 - 4290,
+		"snet-outside":           251,
+		"snet-private-endpoints": 123,
+		"snet-tiny":              0,
+	}, headroom, "the deleted snet-old is skipped")
+
+	assert.Equal(t, "azurerm_subnet.aks_nodes (snet-aks-nodes, 10.0.0.0/24): 251 usable, 39 required, 212 headroom",
+		report.Subnets[0].String())
+}
+
+// TestAddressSpacePodPlacement tests where pods take addresses without a pod subnet
+func TestAddressSpacePodPlacement(t *testing.T) {
+	t.Parallel()
+
+	planFor := func(plugin, mode string) *Plan {
+		plan, err := ParsePlan([]byte(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "azurerm_subnet.nodes",
+      "mode": "managed",
+      "type": "azurerm_subnet",
+      "change": {
+        "actions": ["create"],
+        "after": {"name": "snet-nodes", "virtual_network_name": "vnet", "address_prefixes": ["10.0.0.0/24"]}
+      }
+    },
+    {
+      "address": "azurerm_kubernetes_cluster.main",
+      "mode": "managed",
+      "type": "azurerm_kubernetes_cluster",
+      "change": {
+        "actions": ["create"],
+        "after": {
+          "default_node_pool": [{"name": "system", "node_count": 3, "max_count": null, "max_pods": 30, "vnet_subnet_id": "` + SubnetID("snet-nodes") + `", "pod_subnet_id": null, "upgrade_settings": [{"max_surge": "1"}]}],
+          "network_profile": [{"network_plugin": "` + plugin + `", "network_plugin_mode": ` + mode + `}]
+        }
+      }
+    }
+  ]
+}`))
+		require.NoError(t, err)
+		return plan
+	}
+
+	testCases := []struct {
+		plugin, mode string
+		required     int
+	}{
+		{"azure", "null", 4 * 31},
+		{"azure", `"overlay"`, 4},
+		{"kubenet", "null", 4},
+	}
+	for _, tc := range testCases {
+		report := AnalyzeAddressSpace(planFor(tc.plugin, tc.mode))
+		if assert.Len(t, report.Subnets, 1) {
+			assert.Equal(t, tc.required, report.Subnets[0].Required, "%s %s", tc.plugin, tc.mode)
+		}
+		assert.Empty(t, report.Problems)
+	}
+}
+
+// TestAddressSpaceSurge tests the extra nodes an upgrade adds
+func TestAddressSpaceSurge(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 2, surgeNodes(6, "33%"))
+	assert.Equal(t, 10, surgeNodes(10, "100%"))
+	assert.Equal(t, 3, surgeNodes(10, "3"))
+	assert.Equal(t, 1, surgeNodes(10, nil))
+}
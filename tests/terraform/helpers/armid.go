@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fake identifiers used by fixtures. They are well-formed so that provider
+// validation accepts them, but never point at a real tenant or subscription.
+const (
+	FakeSubscriptionID = "00000000-0000-0000-0000-000000000000"
+	FakeTenantID       = "00000000-0000-0000-0000-000000000000"
+	FakeObjectID       = "00000000-0000-0000-0000-000000000001"
+	FakeResourceGroup  = "rg-terratest"
+	FakeVNetName       = "vnet-terratest"
+)
+
+// PrivateDNSZoneNames maps the keys used in the modules' private_dns_zone_ids
+// inputs to the Azure privatelink zone they must point at.
+var PrivateDNSZoneNames = map[string]string{
+	"postgres":          "privatelink.postgres.database.azure.com",
+	"redis":             "privatelink.redis.cache.windows.net",
+	"keyvault":          "privatelink.vaultcore.azure.net",
+	"acr":               "privatelink.azurecr.io",
+	"blob":              "privatelink.blob.core.windows.net",
+	"openai":            "privatelink.openai.azure.com",
+	"cognitiveservices": "privatelink.cognitiveservices.azure.com",
+	"search":            "privatelink.search.windows.net",
+	"purview":           "privatelink.purview.azure.com",
+	"purview_studio":    "privatelink.purviewstudio.azure.com",
+	"storage_blob":      "privatelink.blob.core.windows.net",
+	"storage_queue":     "privatelink.queue.core.windows.net",
+	"servicebus":        "privatelink.servicebus.windows.net",
+	"eventhub":          "privatelink.servicebus.windows.net",
+}
+
+// ResourceGroupID returns the ARM ID of a resource group in the fake
+// subscription.
+func ResourceGroupID(resourceGroup string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", FakeSubscriptionID, resourceGroup)
+}
+
+// ResourceID returns the ARM ID of a resource in FakeResourceGroup. The
+// segments alternate between resource type and name, e.g.
+// ResourceID("Microsoft.Network", "virtualNetworks", "vnet", "subnets", "snet").
+func ResourceID(provider string, segments ...string) string {
+	if len(segments) == 0 || len(segments)%2 != 0 {
+		panic(fmt.Sprintf("ResourceID needs type/name pairs, got %v", segments))
+	}
+	return fmt.Sprintf("%s/providers/%s/%s", ResourceGroupID(FakeResourceGroup), provider, strings.Join(segments, "/"))
+}
+
+// VirtualNetworkID returns the ARM ID of a virtual network.
+func VirtualNetworkID(vnet string) string {
+	return ResourceID("Microsoft.Network", "virtualNetworks", vnet)
+}
+
+// SubnetID returns the ARM ID of a subnet inside FakeVNetName.
+func SubnetID(subnet string) string {
+	return ResourceID("Microsoft.Network", "virtualNetworks", FakeVNetName, "subnets", subnet)
+}
+
+// KeyVaultID returns the ARM ID of a Key Vault.
+func KeyVaultID(name string) string {
+	return ResourceID("Microsoft.KeyVault", "vaults", name)
+}
+
+// KeyVaultURI returns the data plane URI of a Key Vault.
+func KeyVaultURI(name string) string {
+	return fmt.Sprintf("https://%s.vault.azure.net/", name)
+}
+
+// PrivateDNSZoneID returns the ARM ID of a private DNS zone, e.g.
+// PrivateDNSZoneID("privatelink.vaultcore.azure.net").
+func PrivateDNSZoneID(zone string) string {
+	return ResourceID("Microsoft.Network", "privateDnsZones", zone)
+}
+
+// PrivateDNSZoneIDs returns a private_dns_zone_ids map for the given keys,
+// resolving each key through PrivateDNSZoneNames.
+func PrivateDNSZoneIDs(keys ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		zone, ok := PrivateDNSZoneNames[key]
+		if !ok {
+			panic(fmt.Sprintf("unknown private DNS zone key %q", key))
+		}
+		out[key] = PrivateDNSZoneID(zone)
+	}
+	return out
+}
+
+// LogAnalyticsWorkspaceID returns the ARM ID of a Log Analytics workspace.
+func LogAnalyticsWorkspaceID(name string) string {
+	return ResourceID("Microsoft.OperationalInsights", "workspaces", name)
+}
+
+// KubernetesClusterID returns the ARM ID of an AKS cluster.
+func KubernetesClusterID(name string) string {
+	return ResourceID("Microsoft.ContainerService", "managedClusters", name)
+}
+
+// ContainerRegistryID returns the ARM ID of a container registry.
+func ContainerRegistryID(name string) string {
+	return ResourceID("Microsoft.ContainerRegistry", "registries", name)
+}
@@ -0,0 +1,218 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Horizon is a stage of the platform rollout and the root module calls it
+// adds.
+type Horizon struct {
+	Name  string
+	Calls []string
+}
+
+// Horizons follow the module table in terraform/README.md, by the names of
+// the module calls in terraform/main.tf. cost_management and
+// disaster_recovery belong to no horizon.
+var Horizons = []Horizon{
+	{Name: "H1", Calls: []string{"networking", "security", "aks", "databases", "container_registry", "defender"}},
+	{Name: "H2", Calls: []string{"observability", "argocd", "external_secrets", "github_runners"}},
+	{Name: "H3", Calls: []string{"ai_foundry", "purview"}},
+}
+
+// Composition is a root configuration reduced to some of its module calls,
+// wired to each other exactly as the root module wires them.
+type Composition struct {
+	// Calls are the module calls kept, sorted: those asked for and every call
+	// they reference, directly or through other calls.
+	Calls []string
+
+	// Vars are the variables the composition is planned with: the base
+	// variables, with every feature flag of a kept call on and every other
+	// feature flag off.
+	Vars Vars
+}
+
+// OfflineOptions composes the root module calls of the horizon and returns
+// options that plan the composition with OfflinePlanJSON, so module outputs
+// feed module inputs as in terraform/main.tf. The variables are Root's, and
+// the policy waivers of every composed module's fixture apply.
+func (h Horizon) OfflineOptions(t testing.TestingT) *terraform.Options {
+	config := LoadConfiguration(t, RootModule)
+	composition, err := config.ComposeE(Root().Vars, h.Calls...)
+	require.NoError(t, err)
+
+	fixture := &Fixture{Module: RootModule, Vars: composition.Vars}
+	for _, name := range composition.Calls {
+		module, err := ForModule(filepath.Base(config.Calls[name].Dir))
+		require.NoError(t, err)
+		for _, waiver := range module.PolicyWaivers {
+			if !containsString(fixture.PolicyWaivers, waiver) {
+				fixture.PolicyWaivers = append(fixture.PolicyWaivers, waiver)
+			}
+		}
+	}
+	require.NoError(t, fixture.CheckE())
+
+	dir, err := ComposeOfflineE(config.Dir, composition.Calls)
+	require.NoError(t, err)
+	return fixture.offlineOptions(t, dir, fixture.Vars.Clone())
+}
+
+// ComposeE returns the composition of the named module calls with vars,
+// which override the variable defaults. Feature flags are the bool variables
+// named enable_*; a flag belongs to a call when its count references it. It
+// fails when a kept call is still disabled, e.g. by a local that depends on
+// another variable.
+func (c *Configuration) ComposeE(vars Vars, names ...string) (*Composition, error) {
+	calls, err := c.DependenciesE(names...)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := LoadVariablesE(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := map[string]bool{}
+	for _, name := range calls {
+		if count := c.Calls[name].Count; count != nil {
+			for _, traversal := range count.Variables() {
+				if flag, ok := traversalAttr(traversal, 1); ok && traversal.RootName() == "var" {
+					flags[flag] = true
+				}
+			}
+		}
+	}
+
+	composed := vars.Clone()
+	if composed == nil {
+		composed = Vars{}
+	}
+	for _, name := range variables.Names() {
+		if strings.HasPrefix(name, "enable_") && variables[name].Type == cty.Bool {
+			composed[name] = flags[name]
+		}
+	}
+
+	enabled, err := c.EnabledCallsE(composed)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range calls {
+		if !enabled[name] {
+			return nil, fmt.Errorf("module.%s is disabled with the composition's variables", name)
+		}
+	}
+	return &Composition{Calls: calls, Vars: composed}, nil
+}
+
+// DependenciesE returns the named module calls and every call they
+// reference, directly or through other calls, sorted. References in both
+// branches of a conditional and in depends_on count.
+func (c *Configuration) DependenciesE(names ...string) ([]string, error) {
+	found := map[string]bool{}
+	pending := append([]string(nil), names...)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if found[name] {
+			continue
+		}
+		call, ok := c.Calls[name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no module call %q", c.Dir, name)
+		}
+		found[name] = true
+		for _, ref := range referencesIn(nil, "module."+name, call.block.Body) {
+			pending = append(pending, ref.module)
+		}
+	}
+	return sortedStrings(found), nil
+}
+
+// ComposeOfflineE copies the configuration in dir to a new workspace,
+// reduces the copy to the named module calls with WriteCompositionE and adds
+// the offline test file of PrepareOfflineE. It returns the path of the copy.
+func ComposeOfflineE(dir string, calls []string) (string, error) {
+	copyDir, err := WorkspaceE(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteCompositionE(copyDir, calls); err != nil {
+		os.RemoveAll(filepath.Dir(copyDir))
+		return "", err
+	}
+	if err := writeOfflineTestE(copyDir, offlineRunName, "plan"); err != nil {
+		os.RemoveAll(filepath.Dir(copyDir))
+		return "", err
+	}
+	return copyDir, nil
+}
+
+// WriteCompositionE rewrites the .tf files in dir, in place, down to the
+// named module calls. Other module blocks go, and so do outputs, moved
+// blocks and provider blocks: the composition is only planned, with every
+// provider mocked. It fails when a block it keeps still references a module
+// call it removed.
+func WriteCompositionE(dir string, calls []string) error {
+	keep := map[string]bool{}
+	for _, name := range calls {
+		keep[name] = true
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		body := file.Body()
+		for _, block := range body.Blocks() {
+			switch block.Type() {
+			case "output", "moved", "provider":
+				body.RemoveBlock(block)
+			case "module":
+				if !keep[block.Labels()[0]] {
+					body.RemoveBlock(block)
+				}
+			}
+		}
+		if err := os.WriteFile(path, file.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+
+	config, err := LoadConfigurationE(dir)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, ref := range config.moduleReferences(nil) {
+		if config.Calls[ref.module] == nil {
+			problems = append(problems, fmt.Sprintf("%s: %s references %s, which the composition leaves out",
+				ref.rng, ref.from, ref.text))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("composing %s:\n  %s", strings.Join(calls, ", "), strings.Join(dedupe(problems), "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - COMPOSITION TESTS
+// =============================================================================
+//
+// Tests for reducing a root configuration to some of its module calls and
+// the calls they depend on. testdata/wiring is a small root configuration
+// whose ai call reads the monitoring call; the real root module is composed
+// per horizon. Planning the compositions is left to the module tests.
+//
+// Run with: go test -v -run TestComposition ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompositionDependencies tests the calls each horizon of the root module depends on
+func TestCompositionDependencies(t *testing.T) {
+	t.Parallel()
+
+	config := LoadConfiguration(t, RootModule)
+	want := map[string][]string{
+		"H1": {"aks", "container_registry", "databases", "defender", "networking", "observability", "security"},
+		"H2": {"aks", "argocd", "external_secrets", "github_runners", "networking", "observability", "security"},
+		"H3": {"ai_foundry", "aks", "networking", "observability", "purview", "security"},
+	}
+	for _, horizon := range Horizons {
+		calls, err := config.DependenciesE(horizon.Calls...)
+		require.NoError(t, err)
+		assert.Equal(t, want[horizon.Name], calls, horizon.Name)
+	}
+
+	_, err := config.DependenciesE("backstage")
+	assert.ErrorContains(t, err, `has no module call "backstage"`)
+}
+
+// TestCompositionVars tests that only the feature flags of composed calls are on
+func TestCompositionVars(t *testing.T) {
+	t.Parallel()
+
+	config := loadTestConfiguration(t)
+
+	composition, err := config.ComposeE(Vars{"name": "contoso", "mode": "large"}, "ai")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ai", "monitoring"}, composition.Calls)
+	assert.Equal(t, Vars{"name": "contoso", "mode": "large", "enable_ai": true, "enable_monitoring": true}, composition.Vars)
+
+	composition, err = config.ComposeE(nil, "monitoring")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"monitoring"}, composition.Calls)
+	assert.Equal(t, Vars{"enable_ai": false, "enable_monitoring": true}, composition.Vars)
+
+	_, err = config.ComposeE(Vars{"mode": "small"}, "ai")
+	assert.ErrorContains(t, err, "module.ai is disabled with the composition's variables")
+
+	root := LoadConfiguration(t, RootModule)
+	composition, err = root.ComposeE(Root().Vars, "purview")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"networking", "purview"}, composition.Calls)
+	assert.Equal(t, true, composition.Vars["enable_purview"])
+	assert.Equal(t, false, composition.Vars["enable_databases"])
+	assert.Equal(t, false, composition.Vars["enable_observability"])
+}
+
+// TestComposeOffline tests writing a composition into a workspace copy
+func TestComposeOffline(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ComposeOfflineE(filepath.Join("testdata", "wiring"), []string{"monitoring"})
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(dir)) })
+
+	config, err := LoadConfigurationE(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"monitoring"}, sortedStrings(config.Calls))
+
+	src, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), `resource "random_pet" "suffix" {}`)
+	assert.NotContains(t, string(src), `module "registry"`)
+	assert.NotContains(t, string(src), "output ")
+
+	testFile, err := os.ReadFile(filepath.Join(dir, offlineTestFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(testFile), `mock_provider "random" {}`)
+	assert.Contains(t, string(testFile), `mock_provider "time" {}`)
+
+	_, err = ComposeOfflineE(filepath.Join("testdata", "wiring"), []string{"ai"})
+	assert.ErrorContains(t, err, "module.ai references module.monitoring[0].workspace_id, which the composition leaves out")
+}
+
+// TestCompositionHorizons tests that every horizon of the root module composes
+func TestCompositionHorizons(t *testing.T) {
+	t.Parallel()
+
+	config := LoadConfiguration(t, RootModule)
+	for _, horizon := range Horizons {
+		composition, err := config.ComposeE(Root().Vars, horizon.Calls...)
+		require.NoError(t, err, horizon.Name)
+
+		dir, err := ComposeOfflineE(config.Dir, composition.Calls)
+		require.NoError(t, err, horizon.Name)
+		t.Cleanup(func() { os.RemoveAll(filepath.Dir(dir)) })
+
+		composed, err := LoadConfigurationE(dir)
+		require.NoError(t, err, horizon.Name)
+		assert.Equal(t, composition.Calls, sortedStrings(composed.Calls), horizon.Name)
+
+		problems, err := composed.CheckModuleCallsE()
+		require.NoError(t, err, horizon.Name)
+		assert.Empty(t, problems, horizon.Name)
+	}
+}
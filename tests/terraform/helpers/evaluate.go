@@ -0,0 +1,542 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Evaluation is a module's input variables, locals and outputs evaluated in
+// process with HCL, without the terraform binary. Locals and outputs that
+// depend on resources, data sources or module calls cannot be known without
+// a plan and are listed in Skipped instead.
+type Evaluation struct {
+	// Dir is the evaluated module directory.
+	Dir string
+
+	// Variables are the bound input variables: the given vars converted to
+	// their declared types, and defaults for the rest.
+	Variables map[string]cty.Value
+
+	Locals  map[string]cty.Value
+	Outputs map[string]cty.Value
+
+	// Skipped maps "local.<name>" and "output.<name>" to the reason the value
+	// was not evaluated, e.g. "references azurerm_resource_group.main".
+	Skipped map[string]string
+
+	// Invalid maps each variable whose validation blocks reject its value to
+	// their error messages. Only EvaluateModuleUncheckedE evaluates such a
+	// module; EvaluateModuleE fails instead.
+	Invalid map[string][]string
+}
+
+// OutputValues returns the evaluated outputs decoded from JSON, in the same
+// shape as Plan.Outputs, so the same assertions work on both.
+func (e *Evaluation) OutputValues() map[string]interface{} {
+	values := map[string]interface{}{}
+	for name, value := range e.Outputs {
+		if decoded, err := ctyToGo(value); err == nil {
+			values[name] = decoded
+		}
+	}
+	return values
+}
+
+// Output returns an evaluated output decoded from JSON. This will fail the
+// test if the output was not evaluated.
+func (e *Evaluation) Output(t testing.TestingT, name string) interface{} {
+	value, ok := e.Outputs[name]
+	if !ok {
+		require.Failf(t, "output not evaluated", "output %q was not evaluated: %s", name, e.Skipped["output."+name])
+		return nil
+	}
+	decoded, err := ctyToGo(value)
+	require.NoError(t, err)
+	return decoded
+}
+
+// Local returns an evaluated local value decoded from JSON. This will fail
+// the test if the local was not evaluated.
+func (e *Evaluation) Local(t testing.TestingT, name string) interface{} {
+	value, ok := e.Locals[name]
+	if !ok {
+		require.Failf(t, "local not evaluated", "local %q was not evaluated: %s", name, e.Skipped["local."+name])
+		return nil
+	}
+	decoded, err := ctyToGo(value)
+	require.NoError(t, err)
+	return decoded
+}
+
+// Evaluate evaluates the fixture's module with its vars. This will fail the
+// test if the module cannot be parsed, the vars are rejected or an expression
+// fails to evaluate.
+func (f *Fixture) Evaluate(t testing.TestingT) *Evaluation {
+	evaluation, err := f.EvaluateE()
+	require.NoError(t, err)
+	return evaluation
+}
+
+// EvaluateE evaluates the fixture's module with its vars; see EvaluateModuleE.
+func (f *Fixture) EvaluateE() (*Evaluation, error) {
+	dir, err := ModuleDirE(f.Module)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateModuleE(dir, f.Vars)
+}
+
+// EvaluateUncheckedE evaluates the fixture's module with its vars even when
+// they fail validation; see EvaluateModuleUncheckedE.
+func (f *Fixture) EvaluateUncheckedE() (*Evaluation, error) {
+	dir, err := ModuleDirE(f.Module)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateModuleUncheckedE(dir, f.Vars)
+}
+
+// EvaluateModuleE parses every .tf file in a module directory, binds vars to
+// its input variables and evaluates the locals and outputs that do not depend
+// on resources, in dependency order. Like terraform, it rejects undeclared
+// vars, missing required variables, values that do not convert to the
+// declared type and values that fail a validation block.
+func EvaluateModuleE(dir string, vars Vars) (*Evaluation, error) {
+	evaluation, err := EvaluateModuleUncheckedE(dir, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(evaluation.Invalid) > 0 {
+		var problems []string
+		for _, name := range sortedStrings(evaluation.Invalid) {
+			for _, message := range evaluation.Invalid[name] {
+				problems = append(problems, fmt.Sprintf("invalid value for variable %q: %s", name, message))
+			}
+		}
+		return nil, fmt.Errorf("%s: binding variables:\n  %s", dir, strings.Join(problems, "\n  "))
+	}
+	return evaluation, nil
+}
+
+// EvaluateModuleUncheckedE is EvaluateModuleE for values that may fail a
+// validation block: the failures are listed in Invalid and the locals and
+// outputs are evaluated anyway, showing what the module would build from
+// the values its validation rejects. Undeclared, missing and mistyped vars
+// still fail.
+func EvaluateModuleUncheckedE(dir string, vars Vars) (*Evaluation, error) {
+	config, err := parseModuleConfigE(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &Evaluation{
+		Dir:     dir,
+		Locals:  map[string]cty.Value{},
+		Outputs: map[string]cty.Value{},
+		Skipped: map[string]string{},
+	}
+	evaluation.Variables, evaluation.Invalid, err = config.bindVariables(vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+
+	e := &evaluator{config: config, evaluation: evaluation, visiting: map[string]bool{}}
+	for _, name := range sortedStrings(config.locals) {
+		if err := e.evaluateLocal(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+	}
+	for _, name := range sortedStrings(config.outputs) {
+		if err := e.evaluateOutput(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+	}
+	return evaluation, nil
+}
+
+// moduleConfig is the part of a module the evaluator reads.
+type moduleConfig struct {
+	dir       string
+	variables map[string]*variableConfig
+	locals    map[string]*hcl.Attribute
+	outputs   map[string]hcl.Expression
+}
+
+// variableConfig is a variable block with what binding a value needs.
+type variableConfig struct {
+	name        string
+	typeExpr    hcl.Expression
+	defaultExpr hcl.Expression
+	validations []*variableValidation
+}
+
+// variableValidation is one validation block of a variable.
+type variableValidation struct {
+	condition    hcl.Expression
+	errorMessage hcl.Expression
+}
+
+// evaluationSchema selects the blocks the evaluator reads from a file.
+var evaluationSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "output", LabelNames: []string{"name"}},
+	},
+}
+
+var evaluationVariableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "type"}, {Name: "default"}},
+	Blocks:     []hcl.BlockHeaderSchema{{Type: "validation"}},
+}
+
+var evaluationValidationSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "condition", Required: true},
+		{Name: "error_message", Required: true},
+	},
+}
+
+var evaluationOutputSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "value", Required: true}},
+}
+
+// parseModuleConfigE reads the variables, locals and outputs of a module.
+func parseModuleConfigE(dir string) (*moduleConfig, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .tf files in %s", dir)
+	}
+
+	config := &moduleConfig{
+		dir:       dir,
+		variables: map[string]*variableConfig{},
+		locals:    map[string]*hcl.Attribute{},
+		outputs:   map[string]hcl.Expression{},
+	}
+	parser := hclparse.NewParser()
+	var diags hcl.Diagnostics
+	for _, file := range files {
+		parsed, parseDiags := parser.ParseHCLFile(file)
+		diags = append(diags, parseDiags...)
+		if parseDiags.HasErrors() {
+			continue
+		}
+
+		content, _, contentDiags := parsed.Body.PartialContent(evaluationSchema)
+		diags = append(diags, contentDiags...)
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "variable":
+				variable, varDiags := decodeVariableConfig(block)
+				diags = append(diags, varDiags...)
+				config.variables[variable.name] = variable
+			case "locals":
+				attrs, attrDiags := block.Body.JustAttributes()
+				diags = append(diags, attrDiags...)
+				for name, attr := range attrs {
+					config.locals[name] = attr
+				}
+			case "output":
+				attrs, _, attrDiags := block.Body.PartialContent(evaluationOutputSchema)
+				diags = append(diags, attrDiags...)
+				if attr, ok := attrs.Attributes["value"]; ok {
+					config.outputs[block.Labels[0]] = attr.Expr
+				}
+			}
+		}
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parsing %s: %s", dir, diags.Error())
+	}
+	return config, nil
+}
+
+// decodeVariableConfig reads the type, default and validation blocks of a
+// variable block.
+func decodeVariableConfig(block *hcl.Block) (*variableConfig, hcl.Diagnostics) {
+	variable := &variableConfig{name: block.Labels[0]}
+	content, _, diags := block.Body.PartialContent(evaluationVariableSchema)
+	if attr, ok := content.Attributes["type"]; ok {
+		variable.typeExpr = attr.Expr
+	}
+	if attr, ok := content.Attributes["default"]; ok {
+		variable.defaultExpr = attr.Expr
+	}
+	for _, validationBlock := range content.Blocks {
+		attrs, _, validationDiags := validationBlock.Body.PartialContent(evaluationValidationSchema)
+		diags = append(diags, validationDiags...)
+		if validationDiags.HasErrors() {
+			continue
+		}
+		variable.validations = append(variable.validations, &variableValidation{
+			condition:    attrs.Attributes["condition"].Expr,
+			errorMessage: attrs.Attributes["error_message"].Expr,
+		})
+	}
+	return variable, diags
+}
+
+// bindVariables converts vars to the declared variable types, fills in
+// defaults and runs the validation blocks, returning the messages of those
+// that fail by variable.
+func (c *moduleConfig) bindVariables(vars Vars) (map[string]cty.Value, map[string][]string, error) {
+	var problems []string
+	for _, name := range sortedKeys(vars) {
+		if _, ok := c.variables[name]; !ok {
+			problems = append(problems, fmt.Sprintf("variable %q is not declared", name))
+		}
+	}
+
+	values := map[string]cty.Value{}
+	for _, name := range sortedStrings(c.variables) {
+		value, err := c.variables[name].bind(vars)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		values[name] = value
+	}
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("binding variables:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(values)},
+		Functions: terraformFunctions,
+	}
+	invalid := map[string][]string{}
+	for _, name := range sortedStrings(c.variables) {
+		for _, validation := range c.variables[name].validations {
+			if message, failed := validation.check(ctx); failed {
+				invalid[name] = append(invalid[name], message)
+			}
+		}
+	}
+	return values, invalid, nil
+}
+
+// bind returns the variable's value from vars, or its default.
+func (v *variableConfig) bind(vars Vars) (cty.Value, error) {
+	ty, defaults := cty.DynamicPseudoType, (*typeexpr.Defaults)(nil)
+	if v.typeExpr != nil {
+		var diags hcl.Diagnostics
+		ty, defaults, diags = typeexpr.TypeConstraintWithDefaults(v.typeExpr)
+		if diags.HasErrors() {
+			return cty.NilVal, fmt.Errorf("variable %q: %s", v.name, diags.Error())
+		}
+	}
+
+	var value cty.Value
+	given, ok := vars[v.name]
+	switch {
+	case ok && given != nil:
+		var err error
+		if value, err = goToCty(given); err != nil {
+			return cty.NilVal, fmt.Errorf("variable %q: %w", v.name, err)
+		}
+	case v.defaultExpr != nil:
+		var diags hcl.Diagnostics
+		if value, diags = v.defaultExpr.Value(nil); diags.HasErrors() {
+			return cty.NilVal, fmt.Errorf("variable %q: %s", v.name, diags.Error())
+		}
+	case ok:
+		return cty.NilVal, fmt.Errorf("variable %q is required and cannot be null", v.name)
+	default:
+		return cty.NilVal, fmt.Errorf("variable %q is required", v.name)
+	}
+
+	if defaults != nil {
+		value = defaults.Apply(value)
+	}
+	converted, err := convert.Convert(value, ty)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("variable %q: %w", v.name, err)
+	}
+	return converted, nil
+}
+
+// check evaluates the validation condition and returns its error message
+// when the condition does not hold. A condition that cannot be evaluated
+// fails too, as it does in terraform.
+func (v *variableValidation) check(ctx *hcl.EvalContext) (string, bool) {
+	condition, diags := v.condition.Value(ctx)
+	if diags.HasErrors() {
+		return diags.Error(), true
+	}
+	if !condition.IsKnown() || condition.IsNull() {
+		return "", false
+	}
+	condition, err := convert.Convert(condition, cty.Bool)
+	if err != nil {
+		return err.Error(), true
+	}
+	if condition.True() {
+		return "", false
+	}
+
+	message, diags := v.errorMessage.Value(ctx)
+	if diags.HasErrors() || message.Type() != cty.String || !message.IsKnown() || message.IsNull() {
+		return "the validation condition failed", true
+	}
+	return message.AsString(), true
+}
+
+// evaluator evaluates locals and outputs on demand, so a local is evaluated
+// after the locals it references.
+type evaluator struct {
+	config     *moduleConfig
+	evaluation *Evaluation
+
+	// visiting holds the locals being evaluated, to detect cycles.
+	visiting map[string]bool
+}
+
+// evaluateLocal evaluates a local after the locals it references, or records
+// why it was skipped.
+func (e *evaluator) evaluateLocal(name string) error {
+	key := "local." + name
+	if _, ok := e.evaluation.Locals[name]; ok {
+		return nil
+	}
+	if _, ok := e.evaluation.Skipped[key]; ok {
+		return nil
+	}
+	if e.visiting[name] {
+		return fmt.Errorf("%s refers to itself through other locals", key)
+	}
+	e.visiting[name] = true
+	defer delete(e.visiting, name)
+
+	attr := e.config.locals[name]
+	value, reason, err := e.evaluate(attr.Expr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if reason != "" {
+		e.evaluation.Skipped[key] = reason
+		return nil
+	}
+	e.evaluation.Locals[name] = value
+	return nil
+}
+
+// evaluateOutput evaluates an output's value, or records why it was skipped.
+func (e *evaluator) evaluateOutput(name string) error {
+	key := "output." + name
+	value, reason, err := e.evaluate(e.config.outputs[name])
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if reason != "" {
+		e.evaluation.Skipped[key] = reason
+		return nil
+	}
+	e.evaluation.Outputs[name] = value
+	return nil
+}
+
+// evaluate returns the value of expr, or the reason it cannot be known
+// without a plan: a reference to something other than a variable, a local
+// or path and terraform values, a local that was skipped, or a function that
+// is only known at apply time.
+func (e *evaluator) evaluate(expr hcl.Expression) (cty.Value, string, error) {
+	for _, traversal := range expr.Variables() {
+		root := traversal.RootName()
+		switch root {
+		case "var", "path", "terraform":
+			continue
+		case "local":
+			name, ok := traversalAttr(traversal, 1)
+			if !ok || e.config.locals[name] == nil {
+				return cty.NilVal, "", fmt.Errorf("reference to undeclared local value at %s", traversal.SourceRange())
+			}
+			if err := e.evaluateLocal(name); err != nil {
+				return cty.NilVal, "", err
+			}
+			if _, ok := e.evaluation.Skipped["local."+name]; ok {
+				return cty.NilVal, "depends on local." + name, nil
+			}
+		default:
+			return cty.NilVal, "references " + traversalName(traversal), nil
+		}
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var":   cty.ObjectVal(e.evaluation.Variables),
+			"local": cty.ObjectVal(e.evaluation.Locals),
+			"path": cty.ObjectVal(map[string]cty.Value{
+				"module": cty.StringVal(e.config.dir),
+				"root":   cty.StringVal(e.config.dir),
+				"cwd":    cty.StringVal(e.config.dir),
+			}),
+			"terraform": cty.ObjectVal(map[string]cty.Value{
+				"workspace": cty.StringVal("default"),
+			}),
+		},
+		Functions: terraformFunctions,
+	}
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.NilVal, "", fmt.Errorf("%s", diags.Error())
+	}
+	if !value.IsWhollyKnown() {
+		return cty.NilVal, "value is not known until apply", nil
+	}
+	return value, "", nil
+}
+
+// traversalAttr returns the attribute name at step i of a traversal.
+func traversalAttr(traversal hcl.Traversal, i int) (string, bool) {
+	if len(traversal) <= i {
+		return "", false
+	}
+	if attr, ok := traversal[i].(hcl.TraverseAttr); ok {
+		return attr.Name, true
+	}
+	return "", false
+}
+
+// traversalName names the object a reference points at: two steps for a
+// resource or module call, three for a data source.
+func traversalName(traversal hcl.Traversal) string {
+	steps := 2
+	if traversal.RootName() == "data" {
+		steps = 3
+	}
+	parts := []string{traversal.RootName()}
+	for i := 1; i < steps; i++ {
+		name, ok := traversalAttr(traversal, i)
+		if !ok {
+			break
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ctyToGo decodes a known value into the types encoding/json produces.
+func ctyToGo(value cty.Value) (interface{}, error) {
+	encoded, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	err = json.Unmarshal(encoded, &decoded)
+	return decoded, err
+}
@@ -0,0 +1,194 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - IN-PROCESS EVALUATION TESTS
+// =============================================================================
+//
+// Tests for evaluating module locals and outputs with HCL instead of the
+// terraform binary. testdata/evaluate/module has locals that chain, locals
+// and outputs that need a plan, and variables with defaults and validation.
+//
+// Run with: go test -v -run TestEvaluate ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var evaluateModuleDir = filepath.Join("testdata", "evaluate", "module")
+
+// TestEvaluateModule tests evaluating locals and outputs in dependency order
+func TestEvaluateModule(t *testing.T) {
+	t.Parallel()
+
+	evaluation, err := EvaluateModuleE(evaluateModuleDir, Vars{"name": "contoso"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "contoso-brs", evaluation.Local(t, "prefix"))
+	assert.Equal(t, "\"name\": \"contoso-brs\"\n", evaluation.Local(t, "manifest"))
+	assert.Equal(t, map[string]interface{}{
+		"resource_group":  "rg-contoso-brs",
+		"storage_account": "stcontosobrs001",
+		"replicas":        float64(2),
+	}, evaluation.OutputValues())
+
+	evaluation, err = EvaluateModuleE(evaluateModuleDir, Vars{
+		"name":     "fabrikam",
+		"region":   "westeurope",
+		"settings": map[string]interface{}{"tier": "premium", "replicas": 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "rg-fabrikam-west", evaluation.Output(t, "resource_group"))
+	assert.Equal(t, float64(9), evaluation.Output(t, "replicas"))
+}
+
+// TestEvaluateSkipped tests that values needing a plan are skipped with a reason
+func TestEvaluateSkipped(t *testing.T) {
+	t.Parallel()
+
+	evaluation, err := EvaluateModuleE(evaluateModuleDir, Vars{"name": "contoso"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"local.created":   "value is not known until apply",
+		"local.group_id":  "references azurerm_resource_group.main",
+		"local.tags":      "depends on local.created",
+		"output.group_id": "depends on local.group_id",
+		"output.location": "references azurerm_resource_group.main",
+		"output.tags":     "depends on local.tags",
+	}, evaluation.Skipped)
+
+	rec := &recordingT{}
+	assert.Nil(t, evaluation.Output(rec, "location"))
+	assert.Contains(t, rec.output(), `output "location" was not evaluated: references azurerm_resource_group.main`)
+}
+
+// TestEvaluateVariables tests binding, defaults and validation of variables
+func TestEvaluateVariables(t *testing.T) {
+	t.Parallel()
+
+	evaluation, err := EvaluateModuleE(evaluateModuleDir, Vars{
+		"name":     "contoso",
+		"settings": map[string]interface{}{"tier": "premium"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, cty.StringVal("brazilsouth"), evaluation.Variables["region"])
+	assert.True(t, cty.ObjectVal(map[string]cty.Value{
+		"tier":     cty.StringVal("premium"),
+		"replicas": cty.NumberIntVal(2),
+	}).Equals(evaluation.Variables["settings"]).True())
+
+	testCases := []struct {
+		name string
+		vars Vars
+		want string
+	}{
+		{"missing", Vars{}, `variable "name" is required`},
+		{"null", Vars{"name": nil}, `variable "name" is required and cannot be null`},
+		{"undeclared", Vars{"name": "contoso", "zone": "1"}, `variable "zone" is not declared`},
+		{"wrong type", Vars{"name": "contoso", "settings": "basic"}, `variable "settings": object required`},
+		{"validation", Vars{"name": "Contoso"}, `invalid value for variable "name": Name must be lowercase letters.`},
+	}
+	for _, tc := range testCases {
+		_, err := EvaluateModuleE(evaluateModuleDir, tc.vars)
+		if assert.Error(t, err, tc.name) {
+			assert.Contains(t, err.Error(), tc.want, tc.name)
+		}
+	}
+}
+
+// TestEvaluateUnchecked tests that values failing validation are evaluated and listed as invalid
+func TestEvaluateUnchecked(t *testing.T) {
+	t.Parallel()
+
+	evaluation, err := EvaluateModuleUncheckedE(evaluateModuleDir, Vars{"name": "Contoso"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"Name must be lowercase letters."}}, evaluation.Invalid)
+	assert.Equal(t, "rg-Contoso-brs", evaluation.Output(t, "resource_group"))
+
+	evaluation, err = EvaluateModuleUncheckedE(evaluateModuleDir, Vars{"name": "contoso"})
+	require.NoError(t, err)
+	assert.Empty(t, evaluation.Invalid)
+
+	_, err = EvaluateModuleUncheckedE(evaluateModuleDir, Vars{"name": "contoso", "settings": "basic"})
+	assert.ErrorContains(t, err, `variable "settings": object required`)
+}
+
+// TestEvaluateCycle tests that locals referring to each other fail
+func TestEvaluateCycle(t *testing.T) {
+	t.Parallel()
+
+	_, err := EvaluateModuleE(filepath.Join("testdata", "evaluate", "cycle"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "local.a refers to itself through other locals")
+}
+
+// TestEvaluateFunctions tests the Terraform functions not taken from the cty stdlib
+func TestEvaluateFunctions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expr string
+		want cty.Value
+	}{
+		{`length("brazilsouth")`, cty.NumberIntVal(11)},
+		{`length(["a", "b"])`, cty.NumberIntVal(2)},
+		{`length({ a = 1 })`, cty.NumberIntVal(1)},
+		{`replace("a-b_c", "/[-_]/", "")`, cty.StringVal("abc")},
+		{`replace("a/b", "/", "-")`, cty.StringVal("a-b")},
+		{`one([])`, cty.NullVal(cty.DynamicPseudoType)},
+		{`one(["a"])`, cty.StringVal("a")},
+		{`one(toset(["a"]))`, cty.StringVal("a")},
+		{`sum([1, 2.5, "3"])`, cty.NumberFloatVal(6.5)},
+		{`alltrue([true, true])`, cty.True},
+		{`alltrue([])`, cty.True},
+		{`anytrue([false, true])`, cty.True},
+		{`anytrue([])`, cty.False},
+		{`base64decode(base64encode("contoso"))`, cty.StringVal("contoso")},
+		{`startswith("rg-contoso", "rg-")`, cty.True},
+		{`endswith("rg-contoso", "rg-")`, cty.False},
+		{`strcontains("rg-contoso", "cont")`, cty.True},
+		{`try(local.missing, "fallback")`, cty.StringVal("fallback")},
+		{`can(regex("^[a-z]+$", "Contoso"))`, cty.False},
+		{`timestamp()`, cty.UnknownVal(cty.String)},
+		{`yamldecode("a: 1")`, cty.ObjectVal(map[string]cty.Value{"a": cty.NumberIntVal(1)})},
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"local": cty.EmptyObjectVal},
+		Functions: terraformFunctions,
+	}
+	for _, tc := range testCases {
+		expr, diags := hclsyntax.ParseExpression([]byte(tc.expr), "test.tf", hcl.InitialPos)
+		require.False(t, diags.HasErrors(), diags.Error())
+		got, diags := expr.Value(ctx)
+		if assert.False(t, diags.HasErrors(), "%s: %s", tc.expr, diags.Error()) {
+			assert.True(t, tc.want.RawEquals(got), "%s: got %#v", tc.expr, got)
+		}
+	}
+
+	for _, bad := range []string{`one(["a", "b"])`, `sum([])`, `base64decode("!")`} {
+		expr, _ := hclsyntax.ParseExpression([]byte(bad), "test.tf", hcl.InitialPos)
+		_, diags := expr.Value(ctx)
+		assert.True(t, diags.HasErrors(), bad)
+	}
+}
+
+// TestEvaluatePlatformModules tests that every module evaluates with its fixture
+func TestEvaluatePlatformModules(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range append(Fixtures(), Root()) {
+		evaluation, err := fixture.EvaluateE()
+		if assert.NoError(t, err, fixture.Module) {
+			assert.NotEmpty(t, len(evaluation.Locals)+len(evaluation.Outputs)+len(evaluation.Skipped), fixture.Module)
+		}
+	}
+}
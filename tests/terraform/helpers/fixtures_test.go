@@ -0,0 +1,91 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - TEST HELPER TESTS
+// =============================================================================
+//
+// Tests for the fixture builders and fake ARM IDs. These run without
+// Terraform or Azure credentials.
+//
+// Run with: go test -v ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFixturesCoverEveryModule tests that each module directory has a builder
+func TestFixturesCoverEveryModule(t *testing.T) {
+	t.Parallel()
+
+	entries, err := os.ReadDir(filepath.Join(RepoRoot(t), modulesPath))
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		fixture, err := ForModule(entry.Name())
+		require.NoError(t, err)
+		assert.Equal(t, entry.Name(), fixture.Module)
+		assert.NotEmpty(t, fixture.Vars)
+	}
+}
+
+// TestFixtureOverridesDoNotLeak tests that overrides never touch the baseline
+func TestFixtureOverridesDoNotLeak(t *testing.T) {
+	t.Parallel()
+
+	base := Databases()
+	changed := base.Clone().
+		With("environment", "prod").
+		WithAttr("redis_config", "sku_name", "Premium").
+		Without("key_vault_id")
+
+	assert.Equal(t, "dev", base.Vars["environment"])
+	assert.Equal(t, "Standard", base.Vars["redis_config"].(map[string]interface{})["sku_name"])
+	assert.Contains(t, base.Vars, "key_vault_id")
+
+	redis := changed.Vars["redis_config"].(map[string]interface{})
+	assert.Equal(t, "Premium", redis["sku_name"])
+	assert.Equal(t, "C", redis["family"], "WithAttr must keep the other attributes")
+	assert.NotContains(t, changed.Vars, "key_vault_id")
+
+	options := base.Options(t)
+	options.Vars["environment"] = "prd"
+	assert.Equal(t, "dev", base.Vars["environment"], "Options must copy the vars")
+}
+
+// TestFakeARMIDs tests that generated resource IDs are well formed
+func TestFakeARMIDs(t *testing.T) {
+	t.Parallel()
+
+	armID := regexp.MustCompile(`^/subscriptions/[0-9a-f-]{36}/resourceGroups/[^/]+/providers/[A-Za-z.]+(/[A-Za-z]+/[^/]+)+$`)
+
+	ids := []string{
+		SubnetID("snet-aks"),
+		KeyVaultID("kv-terratest"),
+		PrivateDNSZoneID(PrivateDNSZoneNames["postgres"]),
+		LogAnalyticsWorkspaceID("log-terratest"),
+		KubernetesClusterID("aks-terratest-dev"),
+		ContainerRegistryID("crterratestdev"),
+	}
+	for _, id := range ids {
+		assert.Regexp(t, armID, id)
+	}
+
+	assert.Equal(t,
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-terratest/providers/Microsoft.Network/virtualNetworks/vnet-terratest/subnets/snet-aks",
+		SubnetID("snet-aks"))
+
+	zones := PrivateDNSZoneIDs("openai", "search")
+	assert.Len(t, zones, 2)
+	assert.Contains(t, zones["openai"], "/privateDnsZones/privatelink.openai.azure.com")
+}
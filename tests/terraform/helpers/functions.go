@@ -0,0 +1,276 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// terraformFunctions are the Terraform built-in functions EvaluateModuleE
+// supports. Most are the cty stdlib functions Terraform itself uses; the
+// rest reimplement Terraform's own functions the modules call. Filesystem,
+// hashing, crypto and UUID functions are left out, and calling them fails
+// the evaluation. timestamp is unknown, as it is during a plan.
+var terraformFunctions = map[string]function.Function{
+	"abs":             stdlib.AbsoluteFunc,
+	"alltrue":         allTrueFunc,
+	"anytrue":         anyTrueFunc,
+	"base64decode":    base64DecodeFunc,
+	"base64encode":    base64EncodeFunc,
+	"can":             tryfunc.CanFunc,
+	"ceil":            stdlib.CeilFunc,
+	"chomp":           stdlib.ChompFunc,
+	"chunklist":       stdlib.ChunklistFunc,
+	"coalesce":        stdlib.CoalesceFunc,
+	"coalescelist":    stdlib.CoalesceListFunc,
+	"compact":         stdlib.CompactFunc,
+	"concat":          stdlib.ConcatFunc,
+	"contains":        stdlib.ContainsFunc,
+	"csvdecode":       stdlib.CSVDecodeFunc,
+	"distinct":        stdlib.DistinctFunc,
+	"element":         stdlib.ElementFunc,
+	"endswith":        endsWithFunc,
+	"flatten":         stdlib.FlattenFunc,
+	"floor":           stdlib.FloorFunc,
+	"format":          stdlib.FormatFunc,
+	"formatdate":      stdlib.FormatDateFunc,
+	"formatlist":      stdlib.FormatListFunc,
+	"indent":          stdlib.IndentFunc,
+	"join":            stdlib.JoinFunc,
+	"jsondecode":      stdlib.JSONDecodeFunc,
+	"jsonencode":      stdlib.JSONEncodeFunc,
+	"keys":            stdlib.KeysFunc,
+	"length":          lengthFunc,
+	"log":             stdlib.LogFunc,
+	"lookup":          stdlib.LookupFunc,
+	"lower":           stdlib.LowerFunc,
+	"max":             stdlib.MaxFunc,
+	"merge":           stdlib.MergeFunc,
+	"min":             stdlib.MinFunc,
+	"one":             oneFunc,
+	"parseint":        stdlib.ParseIntFunc,
+	"pow":             stdlib.PowFunc,
+	"range":           stdlib.RangeFunc,
+	"regex":           stdlib.RegexFunc,
+	"regexall":        stdlib.RegexAllFunc,
+	"replace":         replaceFunc,
+	"reverse":         stdlib.ReverseListFunc,
+	"setintersection": stdlib.SetIntersectionFunc,
+	"setproduct":      stdlib.SetProductFunc,
+	"setsubtract":     stdlib.SetSubtractFunc,
+	"setunion":        stdlib.SetUnionFunc,
+	"signum":          stdlib.SignumFunc,
+	"slice":           stdlib.SliceFunc,
+	"sort":            stdlib.SortFunc,
+	"split":           stdlib.SplitFunc,
+	"startswith":      startsWithFunc,
+	"strcontains":     strContainsFunc,
+	"strrev":          stdlib.ReverseFunc,
+	"substr":          stdlib.SubstrFunc,
+	"sum":             sumFunc,
+	"timeadd":         stdlib.TimeAddFunc,
+	"timestamp":       timestampFunc,
+	"title":           stdlib.TitleFunc,
+	"tobool":          stdlib.MakeToFunc(cty.Bool),
+	"tolist":          stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType)),
+	"tomap":           stdlib.MakeToFunc(cty.Map(cty.DynamicPseudoType)),
+	"tonumber":        stdlib.MakeToFunc(cty.Number),
+	"toset":           stdlib.MakeToFunc(cty.Set(cty.DynamicPseudoType)),
+	"tostring":        stdlib.MakeToFunc(cty.String),
+	"trim":            stdlib.TrimFunc,
+	"trimprefix":      stdlib.TrimPrefixFunc,
+	"trimspace":       stdlib.TrimSpaceFunc,
+	"trimsuffix":      stdlib.TrimSuffixFunc,
+	"try":             tryfunc.TryFunc,
+	"upper":           stdlib.UpperFunc,
+	"values":          stdlib.ValuesFunc,
+	"yamldecode":      ctyyaml.YAMLDecodeFunc,
+	"yamlencode":      ctyyaml.YAMLEncodeFunc,
+	"zipmap":          stdlib.ZipmapFunc,
+}
+
+// lengthFunc counts the characters of a string, the attributes of an object
+// or the elements of a collection, where stdlib.LengthFunc only takes
+// collections and tuples.
+var lengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "value", Type: cty.DynamicPseudoType, AllowDynamicType: true, AllowUnknown: true},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		switch ty := args[0].Type(); {
+		case ty == cty.String:
+			return stdlib.Strlen(args[0])
+		case ty.IsObjectType():
+			return cty.NumberIntVal(int64(len(ty.AttributeTypes()))), nil
+		}
+		return stdlib.Length(args[0])
+	},
+})
+
+// replaceFunc treats a substring wrapped in slashes as a regular expression.
+var replaceFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+		{Name: "substr", Type: cty.String},
+		{Name: "replace", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		substr := args[1].AsString()
+		if len(substr) > 1 && strings.HasPrefix(substr, "/") && strings.HasSuffix(substr, "/") {
+			return stdlib.RegexReplace(args[0], cty.StringVal(substr[1:len(substr)-1]), args[2])
+		}
+		return stdlib.Replace(args[0], args[1], args[2])
+	},
+})
+
+// oneFunc returns the only element of a list, set or tuple, or null when it
+// is empty.
+var oneFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "list", Type: cty.DynamicPseudoType}},
+	Type: func(args []cty.Value) (cty.Type, error) {
+		ty := args[0].Type()
+		switch {
+		case ty.IsListType() || ty.IsSetType():
+			return ty.ElementType(), nil
+		case ty.IsTupleType() && len(ty.TupleElementTypes()) == 0:
+			return cty.DynamicPseudoType, nil
+		case ty.IsTupleType() && len(ty.TupleElementTypes()) == 1:
+			return ty.TupleElementTypes()[0], nil
+		}
+		return cty.NilType, fmt.Errorf("must be a list, set or tuple with at most one element")
+	},
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		switch length := args[0].LengthInt(); length {
+		case 0:
+			return cty.NullVal(retType), nil
+		case 1:
+			it := args[0].ElementIterator()
+			it.Next()
+			_, element := it.Element()
+			return element, nil
+		default:
+			return cty.NilVal, fmt.Errorf("must have at most one element, not %d", length)
+		}
+	},
+})
+
+// sumFunc adds the numbers of a list, set or tuple.
+var sumFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "list", Type: cty.DynamicPseudoType}},
+	Type:   function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		ty := args[0].Type()
+		if !ty.IsListType() && !ty.IsSetType() && !ty.IsTupleType() {
+			return cty.NilVal, fmt.Errorf("cannot sum a %s", ty.FriendlyName())
+		}
+		if args[0].LengthInt() == 0 {
+			return cty.NilVal, fmt.Errorf("cannot sum an empty list")
+		}
+		total := cty.Zero
+		for it := args[0].ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			if element.IsNull() {
+				return cty.NilVal, fmt.Errorf("cannot sum a null element")
+			}
+			number, err := convert.Convert(element, cty.Number)
+			if err != nil {
+				return cty.NilVal, fmt.Errorf("cannot sum %s: %w", element.Type().FriendlyName(), err)
+			}
+			total = total.Add(number)
+		}
+		return total, nil
+	},
+})
+
+// allTrueFunc is true when every element of a list is true.
+var allTrueFunc = boolListFunc(true)
+
+// anyTrueFunc is true when some element of a list is true.
+var anyTrueFunc = boolListFunc(false)
+
+// boolListFunc folds a list of bools: with all set it is true when every
+// element is true, otherwise when any is.
+func boolListFunc(all bool) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "list", Type: cty.List(cty.Bool)}},
+		Type:   function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			for it := args[0].ElementIterator(); it.Next(); {
+				_, element := it.Element()
+				isTrue := !element.IsNull() && element.True()
+				if isTrue != all {
+					return cty.BoolVal(!all), nil
+				}
+			}
+			return cty.BoolVal(all), nil
+		},
+	})
+}
+
+var base64EncodeFunc = stringFunc(func(s string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+})
+
+var base64DecodeFunc = stringFunc(func(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 data: %w", err)
+	}
+	if !utf8.Valid(decoded) {
+		return "", fmt.Errorf("the decoded result is not valid UTF-8")
+	}
+	return string(decoded), nil
+})
+
+// stringFunc wraps a string transformation as a function.
+func stringFunc(fn func(string) (string, error)) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "str", Type: cty.String}},
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			result, err := fn(args[0].AsString())
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(result), nil
+		},
+	})
+}
+
+var startsWithFunc = stringTestFunc("prefix", strings.HasPrefix)
+
+var endsWithFunc = stringTestFunc("suffix", strings.HasSuffix)
+
+var strContainsFunc = stringTestFunc("substr", strings.Contains)
+
+// stringTestFunc wraps a test of a string against another as a function.
+func stringTestFunc(param string, test func(s, other string) bool) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "str", Type: cty.String},
+			{Name: param, Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			return cty.BoolVal(test(args[0].AsString(), args[1].AsString())), nil
+		},
+	})
+}
+
+// timestampFunc is only known at apply time.
+var timestampFunc = function.New(&function.Spec{
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(_ []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.UnknownVal(cty.String), nil
+	},
+})
@@ -0,0 +1,884 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// kubernetesPoliciesPath holds the Gatekeeper ConstraintTemplates and
+// Constraints, relative to the repository root.
+const kubernetesPoliciesPath = "policies/kubernetes"
+
+// defaultNamespace is the namespace of a manifest that sets none itself and
+// whose kustomization sets none either, as kubectl apply would use.
+const defaultNamespace = "default"
+
+// Gatekeeper holds compiled ConstraintTemplates and the Constraints that use
+// them, and evaluates manifests the way the admission webhook would.
+type Gatekeeper struct {
+	templates   map[string]*constraintTemplate
+	constraints []*Constraint
+}
+
+// constraintTemplate is the compiled violation rule of one template.
+type constraintTemplate struct {
+	name   string
+	kind   string
+	schema map[string]interface{}
+	query  rego.PreparedEvalQuery
+}
+
+// Constraint is one Gatekeeper constraint, e.g. K8sRequiredLabels
+// require-standard-labels.
+type Constraint struct {
+	Kind              string
+	Name              string
+	EnforcementAction string
+	Match             ConstraintMatch
+	Parameters        map[string]interface{}
+}
+
+// ConstraintMatch is the spec.match of a constraint.
+type ConstraintMatch struct {
+	Kinds              []KindMatch    `yaml:"kinds"`
+	Namespaces         []string       `yaml:"namespaces"`
+	ExcludedNamespaces []string       `yaml:"excludedNamespaces"`
+	LabelSelector      *LabelSelector `yaml:"labelSelector"`
+	NamespaceSelector  *LabelSelector `yaml:"namespaceSelector"`
+}
+
+// KindMatch selects kinds in API groups; "*" matches any.
+type KindMatch struct {
+	APIGroups []string `yaml:"apiGroups"`
+	Kinds     []string `yaml:"kinds"`
+}
+
+// LabelSelector is a Kubernetes label selector.
+type LabelSelector struct {
+	MatchLabels      map[string]string          `yaml:"matchLabels"`
+	MatchExpressions []LabelSelectorRequirement `yaml:"matchExpressions"`
+}
+
+// LabelSelectorRequirement is one matchExpressions entry.
+type LabelSelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values"`
+}
+
+// Manifest is one Kubernetes object read from a YAML document in the repo.
+type Manifest struct {
+	// Path is the file relative to the directory it was found from, and
+	// Index the document's position in the file.
+	Path      string
+	Index     int
+	Namespace string
+	Object    map[string]interface{}
+}
+
+// ConstraintViolation is one violation reported by a constraint.
+type ConstraintViolation struct {
+	Constraint        string
+	EnforcementAction string
+	Manifest          *Manifest
+	Message           string
+
+	// MissingLabels holds details.missing_labels, for templates that report
+	// it.
+	MissingLabels []string
+}
+
+var (
+	gatekeeperMu    sync.Mutex
+	gatekeeperCache = map[string]*Gatekeeper{}
+)
+
+// KubernetesPolicies returns the constraints under policies/kubernetes. This
+// will fail the test if they cannot be loaded.
+func KubernetesPolicies(t testing.TestingT) *Gatekeeper {
+	gatekeeper, err := LoadGatekeeperE(filepath.Join(RepoRoot(t), kubernetesPoliciesPath))
+	require.NoError(t, err)
+	return gatekeeper
+}
+
+// LoadGatekeeperE reads the ConstraintTemplates and Constraints in every YAML
+// file under dir and compiles the templates' Rego. Every constraint must use
+// a template defined there. Results are cached per directory.
+func LoadGatekeeperE(dir string) (*Gatekeeper, error) {
+	gatekeeperMu.Lock()
+	defer gatekeeperMu.Unlock()
+
+	if gatekeeper, ok := gatekeeperCache[dir]; ok {
+		return gatekeeper, nil
+	}
+
+	docs, err := readPolicyDocuments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	gatekeeper := &Gatekeeper{templates: map[string]*constraintTemplate{}}
+	var constraintDocs []policyDocument
+	for _, doc := range docs {
+		if doc.header.Kind == "ConstraintTemplate" {
+			template, err := compileConstraintTemplate(doc)
+			if err != nil {
+				return nil, err
+			}
+			gatekeeper.templates[template.kind] = template
+		} else if strings.HasPrefix(doc.header.APIVersion, "constraints.gatekeeper.sh/") {
+			constraintDocs = append(constraintDocs, doc)
+		}
+	}
+
+	for _, doc := range constraintDocs {
+		template, ok := gatekeeper.templates[doc.header.Kind]
+		if !ok {
+			return nil, fmt.Errorf("%s: constraint %s uses %s, which no ConstraintTemplate in %s defines",
+				doc.path, doc.header.Metadata.Name, doc.header.Kind, dir)
+		}
+		constraint, err := parseConstraint(doc, template)
+		if err != nil {
+			return nil, err
+		}
+		gatekeeper.constraints = append(gatekeeper.constraints, constraint)
+	}
+
+	gatekeeperCache[dir] = gatekeeper
+	return gatekeeper, nil
+}
+
+// policyDocument is one decoded YAML document of a policy file.
+type policyDocument struct {
+	path   string
+	node   *yaml.Node
+	header struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+}
+
+// readPolicyDocuments decodes every document of every YAML file under dir.
+func readPolicyDocuments(dir string) ([]policyDocument, error) {
+	var docs []policyDocument
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !isYAMLFile(path) {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(src))
+		for {
+			doc := policyDocument{path: path, node: &yaml.Node{}}
+			if err := decoder.Decode(doc.node); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("parsing %s: %w", path, err)
+			}
+			if err := doc.node.Decode(&doc.header); err != nil {
+				return fmt.Errorf("parsing %s: %w", path, err)
+			}
+			docs = append(docs, doc)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no Gatekeeper policies in %s", dir)
+	}
+	return docs, nil
+}
+
+// compileConstraintTemplate compiles the Rego of the template's admission
+// target and prepares a query for its violation rule.
+func compileConstraintTemplate(doc policyDocument) (*constraintTemplate, error) {
+	var spec struct {
+		Spec struct {
+			CRD struct {
+				Spec struct {
+					Names struct {
+						Kind string `yaml:"kind"`
+					} `yaml:"names"`
+					Validation struct {
+						OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+					} `yaml:"validation"`
+				} `yaml:"spec"`
+			} `yaml:"crd"`
+			Targets []struct {
+				Target string `yaml:"target"`
+				Rego   string `yaml:"rego"`
+			} `yaml:"targets"`
+		} `yaml:"spec"`
+	}
+	if err := doc.node.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("%s: ConstraintTemplate %s: %w", doc.path, doc.header.Metadata.Name, err)
+	}
+
+	template := &constraintTemplate{
+		name:   doc.header.Metadata.Name,
+		kind:   spec.Spec.CRD.Spec.Names.Kind,
+		schema: spec.Spec.CRD.Spec.Validation.OpenAPIV3Schema,
+	}
+	if template.kind == "" {
+		return nil, fmt.Errorf("%s: ConstraintTemplate %s has no spec.crd.spec.names.kind", doc.path, template.name)
+	}
+
+	for _, target := range spec.Spec.Targets {
+		if target.Target != "admission.k8s.gatekeeper.sh" {
+			continue
+		}
+		filename := fmt.Sprintf("%s#%s", doc.path, template.name)
+		module, err := ast.ParseModule(filename, target.Rego)
+		if err != nil {
+			return nil, err
+		}
+		query, err := rego.New(
+			rego.Module(filename, target.Rego),
+			rego.Query(module.Package.Path.String()+".violation"),
+		).PrepareForEval(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("compiling ConstraintTemplate %s: %w", template.name, err)
+		}
+		template.query = query
+		return template, nil
+	}
+	return nil, fmt.Errorf("%s: ConstraintTemplate %s has no admission.k8s.gatekeeper.sh target", doc.path, template.name)
+}
+
+// parseConstraint reads a constraint, filling parameters the template's
+// schema gives defaults for, as the API server does for the constraint CRD.
+func parseConstraint(doc policyDocument, template *constraintTemplate) (*Constraint, error) {
+	var spec struct {
+		Spec struct {
+			EnforcementAction string                 `yaml:"enforcementAction"`
+			Match             ConstraintMatch        `yaml:"match"`
+			Parameters        map[string]interface{} `yaml:"parameters"`
+		} `yaml:"spec"`
+	}
+	if err := doc.node.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("%s: %s %s: %w", doc.path, doc.header.Kind, doc.header.Metadata.Name, err)
+	}
+
+	constraint := &Constraint{
+		Kind:              doc.header.Kind,
+		Name:              doc.header.Metadata.Name,
+		EnforcementAction: spec.Spec.EnforcementAction,
+		Match:             spec.Spec.Match,
+		Parameters:        spec.Spec.Parameters,
+	}
+	if constraint.EnforcementAction == "" {
+		constraint.EnforcementAction = "deny"
+	}
+	if constraint.Match.NamespaceSelector != nil {
+		return nil, fmt.Errorf("%s: %s uses match.namespaceSelector, which needs Namespace objects the harness does not have", doc.path, constraint)
+	}
+	if constraint.Parameters == nil {
+		constraint.Parameters = map[string]interface{}{}
+	}
+	properties, _ := template.schema["properties"].(map[string]interface{})
+	for name, property := range properties {
+		def, ok := property.(map[string]interface{})["default"]
+		if _, set := constraint.Parameters[name]; ok && !set {
+			constraint.Parameters[name] = def
+		}
+	}
+	return constraint, nil
+}
+
+// String identifies the constraint, e.g. "K8sRequiredLabels/require-standard-labels".
+func (c *Constraint) String() string {
+	return c.Kind + "/" + c.Name
+}
+
+// Constraints returns the loaded constraints in file order.
+func (g *Gatekeeper) Constraints() []*Constraint {
+	return g.constraints
+}
+
+// Matches applies the constraint's match block to the manifest: its kinds,
+// namespaces, excludedNamespaces and labelSelector.
+func (c *Constraint) Matches(manifest *Manifest) bool {
+	match := c.Match
+	if len(match.Kinds) > 0 && !matchesKinds(match.Kinds, manifest.Group(), manifest.Kind()) {
+		return false
+	}
+	if len(match.Namespaces) > 0 && !matchesNamespace(match.Namespaces, manifest.Namespace) {
+		return false
+	}
+	if matchesNamespace(match.ExcludedNamespaces, manifest.Namespace) {
+		return false
+	}
+	if match.LabelSelector != nil && !match.LabelSelector.Matches(manifest.Labels()) {
+		return false
+	}
+	return true
+}
+
+func matchesKinds(kinds []KindMatch, group, kind string) bool {
+	for _, k := range kinds {
+		if containsOrWildcard(k.APIGroups, group) && containsOrWildcard(k.Kinds, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrWildcard(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNamespace reports whether namespace is in patterns, where a
+// trailing "*" matches any suffix, e.g. "kube-*".
+func matchesNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(namespace, prefix) {
+			return true
+		}
+		if pattern == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether labels satisfy the selector.
+func (s *LabelSelector) Matches(labels map[string]string) bool {
+	for key, value := range s.MatchLabels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	for _, requirement := range s.MatchExpressions {
+		value, ok := labels[requirement.Key]
+		in := ok && containsString(requirement.Values, value)
+		switch requirement.Operator {
+		case "In":
+			if !in {
+				return false
+			}
+		case "NotIn":
+			if in {
+				return false
+			}
+		case "Exists":
+			if !ok {
+				return false
+			}
+		case "DoesNotExist":
+			if ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateE runs every constraint that matches the manifest and returns the
+// violations, sorted by constraint and message.
+func (g *Gatekeeper) EvaluateE(manifest *Manifest) ([]ConstraintViolation, error) {
+	var violations []ConstraintViolation
+	for _, constraint := range g.constraints {
+		if !constraint.Matches(manifest) {
+			continue
+		}
+		input := map[string]interface{}{
+			"review":     manifest.review(),
+			"parameters": constraint.Parameters,
+		}
+		results, err := g.templates[constraint.Kind].query.Eval(context.Background(), rego.EvalInput(input))
+		if err != nil {
+			return nil, fmt.Errorf("evaluating %s on %s: %w", constraint, manifest, err)
+		}
+		for _, result := range results {
+			for _, expression := range result.Expressions {
+				values, _ := expression.Value.([]interface{})
+				for _, value := range values {
+					violations = append(violations, newConstraintViolation(constraint, manifest, value))
+				}
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Constraint != violations[j].Constraint {
+			return violations[i].Constraint < violations[j].Constraint
+		}
+		return violations[i].Message < violations[j].Message
+	})
+	return violations, nil
+}
+
+// newConstraintViolation reads one {"msg": ..., "details": ...} result.
+func newConstraintViolation(constraint *Constraint, manifest *Manifest, value interface{}) ConstraintViolation {
+	violation := ConstraintViolation{
+		Constraint:        constraint.String(),
+		EnforcementAction: constraint.EnforcementAction,
+		Manifest:          manifest,
+	}
+	result, _ := value.(map[string]interface{})
+	violation.Message = fmt.Sprint(result["msg"])
+	details, _ := result["details"].(map[string]interface{})
+	missing, _ := details["missing_labels"].([]interface{})
+	for _, label := range missing {
+		violation.MissingLabels = append(violation.MissingLabels, fmt.Sprint(label))
+	}
+	sort.Strings(violation.MissingLabels)
+	return violation
+}
+
+// String formats the violation with the file and resource it was found in.
+func (v ConstraintViolation) String() string {
+	message := fmt.Sprintf("%s %s [%s]: %s", v.Manifest, v.Manifest.Resource(), v.Constraint, v.Message)
+	if len(v.MissingLabels) > 0 {
+		message += " (missing " + strings.Join(v.MissingLabels, ", ") + ")"
+	}
+	return message
+}
+
+// review builds the AdmissionReview request Gatekeeper passes as
+// input.review for a create of the manifest.
+func (m *Manifest) review() map[string]interface{} {
+	version := m.APIVersion()
+	if i := strings.Index(version, "/"); i >= 0 {
+		version = version[i+1:]
+	}
+	return map[string]interface{}{
+		"kind": map[string]interface{}{
+			"group":   m.Group(),
+			"version": version,
+			"kind":    m.Kind(),
+		},
+		"name":      m.Name(),
+		"namespace": m.Namespace,
+		"operation": "CREATE",
+		"object":    m.Object,
+	}
+}
+
+// String returns the manifest's file and, for multi-document files, the
+// document index, e.g. "deploy/app.yaml#1".
+func (m *Manifest) String() string {
+	if m.Index == 0 {
+		return m.Path
+	}
+	return fmt.Sprintf("%s#%d", m.Path, m.Index)
+}
+
+// Resource identifies the object, e.g. "Deployment/api".
+func (m *Manifest) Resource() string {
+	return m.Kind() + "/" + m.Name()
+}
+
+// APIVersion returns the object's apiVersion.
+func (m *Manifest) APIVersion() string {
+	version, _ := m.Object["apiVersion"].(string)
+	return version
+}
+
+// Group returns the API group of the object, "" for the core group.
+func (m *Manifest) Group() string {
+	if i := strings.Index(m.APIVersion(), "/"); i >= 0 {
+		return m.APIVersion()[:i]
+	}
+	return ""
+}
+
+// Kind returns the object's kind.
+func (m *Manifest) Kind() string {
+	kind, _ := m.Object["kind"].(string)
+	return kind
+}
+
+// Name returns metadata.name.
+func (m *Manifest) Name() string {
+	name, _ := m.metadata()["name"].(string)
+	return name
+}
+
+// Labels returns metadata.labels.
+func (m *Manifest) Labels() map[string]string {
+	labels := map[string]string{}
+	raw, _ := m.metadata()["labels"].(map[string]interface{})
+	for key, value := range raw {
+		labels[key] = fmt.Sprint(value)
+	}
+	return labels
+}
+
+func (m *Manifest) metadata() map[string]interface{} {
+	metadata, _ := m.Object["metadata"].(map[string]interface{})
+	return metadata
+}
+
+// templatePlaceholder matches a Backstage template expression such as
+// ${{ values.name }} or ${{ values.cpu | default("100m") }}.
+var templatePlaceholder = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+
+// templateDefault matches the argument of a default() filter.
+var templateDefault = regexp.MustCompile(`default\(\s*["']?([^"')]*)["']?\s*\)`)
+
+// templateTagLine matches a line holding only a template tag such as
+// {%- if values.enableIngress %}.
+var templateTagLine = regexp.MustCompile(`(?m)^[ \t]*\{%.*%\}[ \t]*\n?`)
+
+// placeholderValues are stand-ins for template parameters whose value a
+// constraint checks. Registry parameters hold the customer's ACR, written
+// ${ACR_NAME}.azurecr.io as in the constraints.
+var placeholderValues = map[string]string{
+	"registry":          "${ACR_NAME}.azurecr.io",
+	"containerregistry": "${ACR_NAME}.azurecr.io",
+}
+
+// RenderPlaceholders replaces each Backstage template expression with a
+// stand-in value so a golden-path skeleton parses as the manifest it will
+// become: the expression's default() if it has one, a placeholderValues
+// entry, or otherwise the last segment of the parameter it reads, e.g. "name"
+// for ${{ values.name }}. Lines holding only a {% %} tag are dropped, so
+// conditional content is kept.
+func RenderPlaceholders(src string) string {
+	src = templateTagLine.ReplaceAllString(src, "")
+	return templatePlaceholder.ReplaceAllStringFunc(src, func(expression string) string {
+		inner := templatePlaceholder.FindStringSubmatch(expression)[1]
+		if def := templateDefault.FindStringSubmatch(inner); def != nil {
+			return def[1]
+		}
+		parameter := strings.TrimSpace(strings.SplitN(inner, "|", 2)[0])
+		if i := strings.LastIndexAny(parameter, ".]"); i >= 0 {
+			parameter = parameter[i+1:]
+		}
+		parameter = strings.ToLower(strings.Trim(parameter, `'"[`))
+		if value, ok := placeholderValues[parameter]; ok {
+			return value
+		}
+		if parameter == "" {
+			return "placeholder"
+		}
+		return parameter
+	})
+}
+
+// manifestSkipDirs are never searched for manifests below the root. Go
+// testdata directories hold fixtures that break constraints on purpose.
+var manifestSkipDirs = map[string]bool{
+	".git":         true,
+	".terraform":   true,
+	"node_modules": true,
+	"testdata":     true,
+}
+
+// FindManifestsE returns every Kubernetes object in the YAML files under
+// root: each document with an apiVersion, a kind and metadata. Skeleton
+// placeholders are rendered first. A kustomization.yaml's namespace and
+// labels are applied to the manifests beside it, and the patches it lists
+// are skipped, since they are fragments of an object rather than objects. A
+// file that cannot be parsed is an error only if it looks like a manifest.
+func FindManifestsE(root string) ([]*Manifest, error) {
+	var paths []string
+	kustomizations := map[string]*kustomization{}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && manifestSkipDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isYAMLFile(path) {
+			return nil
+		}
+		if isKustomization(path) {
+			k, err := readKustomization(path)
+			if err != nil {
+				return err
+			}
+			kustomizations[filepath.Dir(path)] = k
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*Manifest
+	for _, path := range paths {
+		k := kustomizations[filepath.Dir(path)]
+		if k != nil && k.patches[filepath.Base(path)] {
+			continue
+		}
+		found, err := readManifests(root, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, manifest := range found {
+			if k != nil {
+				k.apply(manifest)
+			}
+			if manifest.Namespace == "" {
+				manifest.Namespace = defaultNamespace
+			}
+		}
+		manifests = append(manifests, found...)
+	}
+	return manifests, nil
+}
+
+func isYAMLFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func isKustomization(path string) bool {
+	name := filepath.Base(path)
+	return name == "kustomization.yaml" || name == "kustomization.yml"
+}
+
+// kustomization is what a kustomization.yaml changes about the manifests in
+// its own directory.
+type kustomization struct {
+	namespace string
+	labels    map[string]string
+	images    []kustomizeImage
+	patches   map[string]bool
+}
+
+// kustomizeImage is one entry of a kustomization's images transformer.
+type kustomizeImage struct {
+	Name    string `yaml:"name"`
+	NewName string `yaml:"newName"`
+	NewTag  string `yaml:"newTag"`
+	Digest  string `yaml:"digest"`
+}
+
+// readKustomization reads the namespace, commonLabels, labels, images and
+// patch files of a kustomization.
+func readKustomization(path string) (*kustomization, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec struct {
+		Namespace    string            `yaml:"namespace"`
+		CommonLabels map[string]string `yaml:"commonLabels"`
+		Labels       []struct {
+			Pairs map[string]string `yaml:"pairs"`
+		} `yaml:"labels"`
+		Images                []kustomizeImage `yaml:"images"`
+		PatchesStrategicMerge []string         `yaml:"patchesStrategicMerge"`
+		Patches               []struct {
+			Path string `yaml:"path"`
+		} `yaml:"patches"`
+	}
+	if err := yaml.Unmarshal([]byte(RenderPlaceholders(string(src))), &spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	k := &kustomization{
+		namespace: spec.Namespace,
+		labels:    map[string]string{},
+		images:    spec.Images,
+		patches:   map[string]bool{},
+	}
+	for key, value := range spec.CommonLabels {
+		k.labels[key] = value
+	}
+	for _, labels := range spec.Labels {
+		for key, value := range labels.Pairs {
+			k.labels[key] = value
+		}
+	}
+	for _, patch := range spec.PatchesStrategicMerge {
+		k.patches[filepath.Clean(patch)] = true
+	}
+	for _, patch := range spec.Patches {
+		if patch.Path != "" {
+			k.patches[filepath.Clean(patch.Path)] = true
+		}
+	}
+	return k, nil
+}
+
+// apply sets the kustomization's namespace, unless the manifest has one,
+// adds its labels and rewrites container images.
+func (k *kustomization) apply(manifest *Manifest) {
+	if manifest.Namespace == "" {
+		manifest.Namespace = k.namespace
+	}
+	if len(k.labels) > 0 {
+		metadata := manifest.metadata()
+		labels, _ := metadata["labels"].(map[string]interface{})
+		if labels == nil {
+			labels = map[string]interface{}{}
+			metadata["labels"] = labels
+		}
+		for key, value := range k.labels {
+			labels[key] = value
+		}
+	}
+	if len(k.images) > 0 {
+		walkContainers(manifest.Object, func(container map[string]interface{}) {
+			if image, ok := container["image"].(string); ok {
+				container["image"] = k.rewriteImage(image)
+			}
+		})
+	}
+}
+
+// rewriteImage applies the first images entry whose name matches the image
+// without its tag or digest.
+func (k *kustomization) rewriteImage(image string) string {
+	name, tag := image, ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, tag = name[:i], name[i:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i:]
+	}
+	for _, entry := range k.images {
+		if entry.Name != name {
+			continue
+		}
+		if entry.NewName != "" {
+			name = entry.NewName
+		}
+		if entry.NewTag != "" {
+			tag = ":" + entry.NewTag
+		}
+		if entry.Digest != "" {
+			tag = "@" + entry.Digest
+		}
+		return name + tag
+	}
+	return image
+}
+
+// walkContainers calls fn for every container and init container of a pod
+// spec anywhere in the object, e.g. a Deployment's or CronJob's template.
+func walkContainers(value interface{}, fn func(map[string]interface{})) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range typed {
+			if key == "containers" || key == "initContainers" {
+				list, _ := elem.([]interface{})
+				for _, container := range list {
+					if c, ok := container.(map[string]interface{}); ok {
+						fn(c)
+					}
+				}
+				continue
+			}
+			walkContainers(elem, fn)
+		}
+	case []interface{}:
+		for _, elem := range typed {
+			walkContainers(elem, fn)
+		}
+	}
+}
+
+// looksLikeManifest matches a top-level apiVersion key.
+var looksLikeManifest = regexp.MustCompile(`(?m)^apiVersion:`)
+
+// readManifests decodes the Kubernetes objects in one file.
+func readManifests(root, path string) ([]*Manifest, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*Manifest
+	decoder := yaml.NewDecoder(strings.NewReader(RenderPlaceholders(string(src))))
+	for index := 0; ; index++ {
+		var object map[string]interface{}
+		err := decoder.Decode(&object)
+		if errors.Is(err, io.EOF) {
+			return manifests, nil
+		}
+		if err != nil {
+			if !looksLikeManifest.Match(src) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("parsing %s: %w", rel, err)
+		}
+		manifest := &Manifest{Path: filepath.ToSlash(rel), Index: index, Object: object}
+		if manifest.APIVersion() == "" || manifest.Kind() == "" || manifest.metadata() == nil {
+			continue
+		}
+		manifest.Namespace, _ = manifest.metadata()["namespace"].(string)
+		manifests = append(manifests, manifest)
+	}
+}
+
+// AssertManifestsComply evaluates every manifest against the constraints.
+// Each violation of a deny constraint fails the test, listing the file, the
+// resource and, for required labels, the labels it is missing; violations of
+// warn and dryrun constraints are logged.
+func AssertManifestsComply(t testing.TestingT, gatekeeper *Gatekeeper, manifests []*Manifest) bool {
+	markHelper(t)
+
+	var denied []string
+	for _, manifest := range manifests {
+		violations, err := gatekeeper.EvaluateE(manifest)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		for _, violation := range violations {
+			if violation.EnforcementAction == "deny" {
+				denied = append(denied, violation.String())
+			} else {
+				logger.Default.Logf(t, "constraint violation (%s): %s", violation.EnforcementAction, violation)
+			}
+		}
+	}
+	if len(denied) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("%d constraint violation(s) in %s:\n  - %s",
+		len(denied), kubernetesPoliciesPath, strings.Join(denied, "\n  - ")))
+}
@@ -0,0 +1,246 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - GATEKEEPER CONSTRAINT TESTS
+// =============================================================================
+//
+// Tests for evaluating the Gatekeeper constraints in policies/kubernetes
+// against Kubernetes manifests: discovery of manifests and their kustomization
+// namespace, labels and images, rendering of skeleton placeholders, the
+// match and exclusion semantics of a constraint, and the violations reported.
+// The manifests under testdata/gatekeeper/manifests cover a compliant
+// skeleton, an excluded namespace and a workload missing labels.
+//
+// Run with: go test -v -run 'Gatekeeper|Manifest|Placeholder|Constraint' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFindManifests tests manifest discovery and kustomization handling
+func TestFindManifests(t *testing.T) {
+	t.Parallel()
+
+	manifests := loadTestManifests(t)
+
+	found := map[string]*Manifest{}
+	for _, manifest := range manifests {
+		found[manifest.String()+" "+manifest.Resource()] = manifest
+	}
+	require.Len(t, found, 4, "patches and non-manifest YAML are skipped")
+
+	app := found["app/deployment.yaml Deployment/name"]
+	require.NotNil(t, app)
+	assert.Equal(t, "payments", app.Namespace)
+	assert.Equal(t, map[string]string{
+		"app.kubernetes.io/name":       "name",
+		"app.kubernetes.io/instance":   "name",
+		"app.kubernetes.io/version":    "1.4.0",
+		"app.kubernetes.io/managed-by": "argocd",
+	}, app.Labels())
+	containers := app.Object["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+	assert.Equal(t, "${ACR_NAME}.azurecr.io/name:1.4.0", containers[0].(map[string]interface{})["image"])
+
+	assert.Equal(t, "kube-system", found["system/daemonset.yaml DaemonSet/node-agent"].Namespace)
+	assert.Equal(t, "default", found["legacy/workers.yaml ConfigMap/worker-settings"].Namespace)
+
+	worker := found["legacy/workers.yaml#1 Deployment/worker"]
+	require.NotNil(t, worker)
+	assert.Equal(t, "apps", worker.Group())
+	assert.Equal(t, "default", worker.Namespace)
+}
+
+// TestRenderPlaceholders tests the stand-in values for skeleton expressions
+func TestRenderPlaceholders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		src  string
+		want string
+	}{
+		{"name: ${{ values.name }}", "name: name"},
+		{"name: ${{values.serviceName}}", "name: servicename"},
+		{"namespace: ${{ values.namespace | default('payments') }}", "namespace: payments"},
+		{`tier: ${{ values.tier | default("standard") }}`, "tier: standard"},
+		{"image: ${{ values.registry }}/api", "image: ${ACR_NAME}.azurecr.io/api"},
+		{"image: ${{ parameters.containerRegistry }}/api", "image: ${ACR_NAME}.azurecr.io/api"},
+		{"a: 1\n{%- if values.debug %}\nb: 2\n{%- endif %}\nc: 3\n", "a: 1\nb: 2\nc: 3\n"},
+		{"plain: value", "plain: value"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, RenderPlaceholders(tc.src), tc.src)
+	}
+}
+
+// TestConstraintMatches tests kind, namespace and label selector matching
+func TestConstraintMatches(t *testing.T) {
+	t.Parallel()
+
+	constraint := &Constraint{
+		Kind: "K8sRequiredLabels",
+		Name: "test",
+		Match: ConstraintMatch{
+			Kinds: []KindMatch{
+				{APIGroups: []string{"apps"}, Kinds: []string{"Deployment"}},
+				{APIGroups: []string{"*"}, Kinds: []string{"Job"}},
+			},
+			ExcludedNamespaces: []string{"kube-*", "argocd"},
+			LabelSelector: &LabelSelector{
+				MatchExpressions: []LabelSelectorRequirement{
+					{Key: "policy.example.com/exempt", Operator: "DoesNotExist"},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		apiVersion string
+		kind       string
+		namespace  string
+		labels     map[string]interface{}
+		want       bool
+	}{
+		{"matching kind", "apps/v1", "Deployment", "payments", nil, true},
+		{"other group", "extensions/v1beta1", "Deployment", "payments", nil, false},
+		{"other kind", "apps/v1", "StatefulSet", "payments", nil, false},
+		{"wildcard group", "batch/v1", "Job", "payments", nil, true},
+		{"excluded namespace", "apps/v1", "Deployment", "argocd", nil, false},
+		{"excluded namespace prefix", "apps/v1", "Deployment", "kube-system", nil, false},
+		{"label selector", "apps/v1", "Deployment", "payments", map[string]interface{}{"policy.example.com/exempt": "true"}, false},
+	}
+
+	for _, tc := range testCases {
+		metadata := map[string]interface{}{"name": "test"}
+		if tc.labels != nil {
+			metadata["labels"] = tc.labels
+		}
+		manifest := &Manifest{
+			Path:      "test.yaml",
+			Namespace: tc.namespace,
+			Object:    map[string]interface{}{"apiVersion": tc.apiVersion, "kind": tc.kind, "metadata": metadata},
+		}
+		assert.Equal(t, tc.want, constraint.Matches(manifest), tc.name)
+	}
+
+	constraint.Match.Namespaces = []string{"payments"}
+	assert.False(t, constraint.Matches(&Manifest{
+		Namespace: "orders",
+		Object:    map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": map[string]interface{}{"name": "test"}},
+	}), "namespace not listed")
+}
+
+// TestLabelSelectorMatches tests matchLabels and each matchExpressions operator
+func TestLabelSelectorMatches(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"tier": "web", "team": "payments"}
+
+	testCases := []struct {
+		name     string
+		selector LabelSelector
+		want     bool
+	}{
+		{"empty", LabelSelector{}, true},
+		{"matchLabels", LabelSelector{MatchLabels: map[string]string{"tier": "web"}}, true},
+		{"matchLabels differs", LabelSelector{MatchLabels: map[string]string{"tier": "db"}}, false},
+		{"In", LabelSelector{MatchExpressions: []LabelSelectorRequirement{{Key: "team", Operator: "In", Values: []string{"payments", "orders"}}}}, true},
+		{"NotIn", LabelSelector{MatchExpressions: []LabelSelectorRequirement{{Key: "team", Operator: "NotIn", Values: []string{"payments"}}}}, false},
+		{"Exists", LabelSelector{MatchExpressions: []LabelSelectorRequirement{{Key: "tier", Operator: "Exists"}}}, true},
+		{"DoesNotExist", LabelSelector{MatchExpressions: []LabelSelectorRequirement{{Key: "tier", Operator: "DoesNotExist"}}}, false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, tc.selector.Matches(labels), tc.name)
+	}
+}
+
+// TestGatekeeperConstraints tests loading the constraints in policies/kubernetes
+func TestGatekeeperConstraints(t *testing.T) {
+	t.Parallel()
+
+	gatekeeper := KubernetesPolicies(t)
+
+	names := map[string]string{}
+	for _, constraint := range gatekeeper.Constraints() {
+		names[constraint.String()] = constraint.EnforcementAction
+	}
+	assert.Equal(t, map[string]string{
+		"K8sRequiredLabels/require-standard-labels":         "deny",
+		"K8sContainerResources/require-resource-limits":     "deny",
+		"K8sDenyPrivileged/deny-privileged-containers":      "deny",
+		"K8sRequireNonRoot/require-non-root-user":           "deny",
+		"K8sAllowedRegistries/allowed-container-registries": "deny",
+	}, names)
+
+	_, err := LoadGatekeeperE(t.TempDir())
+	assert.ErrorContains(t, err, "no Gatekeeper policies in")
+}
+
+// TestGatekeeperEvaluate tests the violations reported for each manifest
+func TestGatekeeperEvaluate(t *testing.T) {
+	t.Parallel()
+
+	gatekeeper := KubernetesPolicies(t)
+
+	violations := map[string][]ConstraintViolation{}
+	for _, manifest := range loadTestManifests(t) {
+		found, err := gatekeeper.EvaluateE(manifest)
+		require.NoError(t, err)
+		violations[manifest.String()] = found
+	}
+
+	assert.Empty(t, violations["app/deployment.yaml"], "the kustomized skeleton complies")
+	assert.Empty(t, violations["system/daemonset.yaml"], "kube-system is excluded")
+	assert.Empty(t, violations["legacy/workers.yaml"], "ConfigMaps are not matched")
+
+	worker := violations["legacy/workers.yaml#1"]
+	require.Len(t, worker, 2)
+	assert.Equal(t, "K8sRequiredLabels/require-standard-labels", worker[0].Constraint)
+	assert.Equal(t, "deny", worker[0].EnforcementAction)
+	assert.Equal(t, "Label app.kubernetes.io/version value 'latest' does not match pattern ^[0-9]+\\.[0-9]+\\.[0-9]+.*$", worker[0].Message)
+	assert.Empty(t, worker[0].MissingLabels)
+	assert.Equal(t, []string{"app.kubernetes.io/instance", "app.kubernetes.io/managed-by"}, worker[1].MissingLabels)
+	assert.Equal(t,
+		`legacy/workers.yaml#1 Deployment/worker [K8sRequiredLabels/require-standard-labels]: Resource Deployment/worker is missing required labels: {"app.kubernetes.io/instance", "app.kubernetes.io/managed-by"} (missing app.kubernetes.io/instance, app.kubernetes.io/managed-by)`,
+		worker[1].String())
+}
+
+// TestAssertManifestsComplyMessage tests that failures list each violation
+func TestAssertManifestsComplyMessage(t *testing.T) {
+	t.Parallel()
+
+	gatekeeper := KubernetesPolicies(t)
+	manifests := loadTestManifests(t)
+
+	recorder := &recordingT{}
+	assert.False(t, AssertManifestsComply(recorder, gatekeeper, manifests))
+	assert.Contains(t, recorder.output(), "2 constraint violation(s) in policies/kubernetes:")
+	assert.Contains(t, recorder.output(), "  - legacy/workers.yaml#1 Deployment/worker [K8sRequiredLabels/require-standard-labels]: Resource Deployment/worker is missing required labels")
+	assert.Contains(t, recorder.output(), "(missing app.kubernetes.io/instance, app.kubernetes.io/managed-by)")
+	assert.NotContains(t, recorder.output(), "app/deployment.yaml")
+
+	var compliant []*Manifest
+	for _, manifest := range manifests {
+		if manifest.Path != "legacy/workers.yaml" {
+			compliant = append(compliant, manifest)
+		}
+	}
+	recorder = &recordingT{}
+	assert.True(t, AssertManifestsComply(recorder, gatekeeper, compliant))
+	assert.Empty(t, recorder.errors)
+}
+
+// loadTestManifests returns the manifests under testdata/gatekeeper/manifests.
+func loadTestManifests(t *testing.T) []*Manifest {
+	manifests, err := FindManifestsE(filepath.Join("testdata", "gatekeeper", "manifests"))
+	require.NoError(t, err)
+	return manifests
+}
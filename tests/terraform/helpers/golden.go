@@ -0,0 +1,318 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	gotesting "testing"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites golden plans instead of comparing against them:
+//
+//	go test ./modules/ -run TestOfflineGoldenPlans -update
+//
+// The flag is only registered in test binaries, so commands that import the
+// helpers do not advertise it.
+var updateGolden = registerUpdateFlag()
+
+func registerUpdateFlag() *bool {
+	if !gotesting.Testing() {
+		return new(bool)
+	}
+	return flag.Bool("update", false, "rewrite golden plan snapshots under testdata/golden")
+}
+
+// Placeholders written into golden plans in place of values that are not
+// stable between runs.
+const (
+	GoldenUnknown   = "(known after apply)"
+	GoldenSensitive = "(sensitive)"
+	GoldenTimestamp = "(timestamp)"
+)
+
+// maxGoldenDiff caps the number of differences printed for one plan.
+const maxGoldenDiff = 50
+
+// timestampPattern matches RFC 3339 timestamps such as those produced by
+// timestamp() or returned by providers.
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`)
+
+// GoldenPlan is the normalized form of a plan stored as a snapshot. It keeps
+// what a module change can alter - which resources are planned, with which
+// action and values - and drops Terraform and provider versions, provider
+// addresses and values that differ between runs.
+type GoldenPlan struct {
+	Resources map[string]*GoldenResource `json:"resources"`
+	Outputs   map[string]interface{}     `json:"outputs,omitempty"`
+}
+
+// GoldenResource is the normalized change for one resource instance.
+type GoldenResource struct {
+	Type         string      `json:"type"`
+	Action       Action      `json:"action"`
+	Values       interface{} `json:"values,omitempty"`
+	ReplacePaths []string    `json:"replace_paths,omitempty"`
+}
+
+// NormalizePlan returns the golden form of a plan. Planned values that are
+// unknown or sensitive are replaced by GoldenUnknown and GoldenSensitive, and
+// timestamps by GoldenTimestamp.
+func NormalizePlan(plan *Plan) *GoldenPlan {
+	golden := &GoldenPlan{Resources: map[string]*GoldenResource{}}
+
+	for address, change := range plan.ResourceChangesMap {
+		resource := &ResourceChange{ResourceChange: change}
+		normalized := &GoldenResource{
+			Type:         change.Type,
+			Action:       resource.Action(),
+			ReplacePaths: resource.ReplacePaths(),
+		}
+		if change.Change != nil {
+			normalized.Values = normalizeValue(change.Change.After, change.Change.AfterUnknown, change.Change.AfterSensitive)
+		}
+		golden.Resources[address] = normalized
+	}
+
+	if len(plan.RawPlan.OutputChanges) > 0 {
+		golden.Outputs = map[string]interface{}{}
+		for name, change := range plan.RawPlan.OutputChanges {
+			if change == nil {
+				continue
+			}
+			golden.Outputs[name] = normalizeValue(change.After, change.AfterUnknown, change.AfterSensitive)
+		}
+	}
+	return golden
+}
+
+// normalizeValue merges a planned value with its after_unknown and
+// after_sensitive markers, which mirror the shape of the value.
+func normalizeValue(value, unknown, sensitive interface{}) interface{} {
+	if marked, ok := unknown.(bool); ok && marked {
+		return GoldenUnknown
+	}
+	if marked, ok := sensitive.(bool); ok && marked {
+		return GoldenSensitive
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		unknownMap, _ := unknown.(map[string]interface{})
+		sensitiveMap, _ := sensitive.(map[string]interface{})
+		normalized := make(map[string]interface{}, len(typed))
+		for key, elem := range typed {
+			normalized[key] = normalizeValue(elem, unknownMap[key], sensitiveMap[key])
+		}
+		// Computed attributes are absent or null in the planned value and
+		// only marked in after_unknown.
+		for key, marked := range unknownMap {
+			if isMarked, ok := marked.(bool); ok && isMarked {
+				normalized[key] = GoldenUnknown
+			}
+		}
+		return normalized
+	case []interface{}:
+		unknownList, _ := unknown.([]interface{})
+		sensitiveList, _ := sensitive.([]interface{})
+		normalized := make([]interface{}, len(typed))
+		for i, elem := range typed {
+			normalized[i] = normalizeValue(elem, indexOrNil(unknownList, i), indexOrNil(sensitiveList, i))
+		}
+		return normalized
+	case string:
+		if timestampPattern.MatchString(typed) {
+			return GoldenTimestamp
+		}
+		return typed
+	default:
+		return typed
+	}
+}
+
+// indexOrNil returns list[i], or nil when i is out of range.
+func indexOrNil(list []interface{}, i int) interface{} {
+	if i < len(list) {
+		return list[i]
+	}
+	return nil
+}
+
+// JSON encodes the golden plan as indented JSON with sorted keys, ending in a
+// newline so snapshots diff cleanly.
+func (g *GoldenPlan) JSON() ([]byte, error) {
+	encoded, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(encoded, '\n'), nil
+}
+
+// GoldenPath returns the snapshot file for a test name, relative to the test
+// package: testdata/golden/<test>/<subtest>.json.
+func GoldenPath(testName string) string {
+	return filepath.Join("testdata", "golden", filepath.FromSlash(testName)+".json")
+}
+
+// AssertGoldenPlan compares the normalized plan with the snapshot stored for
+// the running test at GoldenPath(t.Name()). Run the tests with -update to
+// write or rewrite the snapshot.
+func AssertGoldenPlan(t testing.TestingT, plan *Plan) bool {
+	markHelper(t)
+	return AssertGoldenPlanFile(t, plan, GoldenPath(t.Name()))
+}
+
+// AssertGoldenPlanFile compares the normalized plan with the snapshot at path,
+// reporting resources that were added or removed and every attribute whose
+// planned value changed. With -update the snapshot is rewritten instead.
+func AssertGoldenPlanFile(t testing.TestingT, plan *Plan, path string) bool {
+	markHelper(t)
+
+	if *updateGolden {
+		encoded, err := NormalizePlan(plan).JSON()
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, encoded, 0o644))
+		return true
+	}
+
+	diff, err := diffGoldenFile(path, plan)
+	if errors.Is(err, fs.ErrNotExist) {
+		return assert.Fail(t, fmt.Sprintf("no golden plan at %s; run the test with -update to create it", path))
+	}
+	if !assert.NoErrorf(t, err, "reading golden plan %s", path) {
+		return false
+	}
+	if len(diff) == 0 {
+		return true
+	}
+
+	if len(diff) > maxGoldenDiff {
+		diff = append(diff[:maxGoldenDiff], fmt.Sprintf("... and %d more", len(diff)-maxGoldenDiff))
+	}
+	return assert.Fail(t, fmt.Sprintf("plan differs from %s (- snapshot, + plan); run the test with -update to accept it:\n  %s",
+		path, strings.Join(diff, "\n  ")))
+}
+
+// diffGoldenFile returns the differences between the snapshot at path and
+// the normalized plan.
+func diffGoldenFile(path string, plan *Plan) ([]string, error) {
+	snapshot, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var want interface{}
+	if err := json.Unmarshal(snapshot, &want); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	encoded, err := NormalizePlan(plan).JSON()
+	if err != nil {
+		return nil, err
+	}
+	var got interface{}
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		return nil, err
+	}
+	return DiffJSON(want, got), nil
+}
+
+// DiffJSON compares two decoded JSON values and returns one line per
+// difference, sorted by path: "- path: value" for values only in want,
+// "+ path: value" for values only in got and "~ path: old -> new" for changed
+// values. Lists are compared element by element.
+func DiffJSON(want, got interface{}) []string {
+	var diff []string
+	diffJSON("", want, got, &diff)
+	return diff
+}
+
+func diffJSON(path string, want, got interface{}, diff *[]string) {
+	switch wantTyped := want.(type) {
+	case map[string]interface{}:
+		gotTyped, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for key := range wantTyped {
+			keys[key] = true
+		}
+		for key := range gotTyped {
+			keys[key] = true
+		}
+		for _, key := range sortedStrings(keys) {
+			wantElem, inWant := wantTyped[key]
+			gotElem, inGot := gotTyped[key]
+			elemPath := joinJSONPath(path, key)
+			switch {
+			case !inGot:
+				*diff = append(*diff, fmt.Sprintf("- %s: %s", elemPath, compactJSON(wantElem)))
+			case !inWant:
+				*diff = append(*diff, fmt.Sprintf("+ %s: %s", elemPath, compactJSON(gotElem)))
+			default:
+				diffJSON(elemPath, wantElem, gotElem, diff)
+			}
+		}
+		return
+	case []interface{}:
+		gotTyped, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(wantTyped) || i < len(gotTyped); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(gotTyped):
+				*diff = append(*diff, fmt.Sprintf("- %s: %s", elemPath, compactJSON(wantTyped[i])))
+			case i >= len(wantTyped):
+				*diff = append(*diff, fmt.Sprintf("+ %s: %s", elemPath, compactJSON(gotTyped[i])))
+			default:
+				diffJSON(elemPath, wantTyped[i], gotTyped[i], diff)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		*diff = append(*diff, fmt.Sprintf("~ %s: %s -> %s", path, compactJSON(want), compactJSON(got)))
+	}
+}
+
+// jsonIdentifier matches keys that can be written after a dot in a path.
+var jsonIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// joinJSONPath appends an object key to a path, quoting keys such as resource
+// addresses that contain dots or brackets.
+func joinJSONPath(path, key string) string {
+	if !jsonIdentifier.MatchString(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// compactJSON encodes a decoded JSON value on one line, shortened for diffs.
+func compactJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	const maxLen = 120
+	if len(encoded) > maxLen {
+		return string(encoded[:maxLen]) + "..."
+	}
+	return string(encoded)
+}
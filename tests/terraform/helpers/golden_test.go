@@ -0,0 +1,146 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - GOLDEN PLAN TESTS
+// =============================================================================
+//
+// Tests for plan normalization and snapshot diffs, using the recorded plan in
+// testdata. These run without Terraform or Azure credentials.
+//
+// Run with: go test -v -run TestGolden ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGoldenNormalizePlan tests that volatile plan fields are normalized
+func TestGoldenNormalizePlan(t *testing.T) {
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+	golden := NormalizePlan(plan)
+
+	assert.ElementsMatch(t, plan.Addresses(), sortedStrings(golden.Resources))
+
+	aks := golden.Resources["azurerm_kubernetes_cluster.main"]
+	require.NotNil(t, aks)
+	assert.Equal(t, ActionCreate, aks.Action)
+	values, ok := aks.Values.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "Standard", values["sku_tier"])
+	assert.Equal(t, GoldenUnknown, values["oidc_issuer_url"])
+
+	pool := golden.Resources[`azurerm_kubernetes_cluster_node_pool.user["general"]`]
+	require.NotNil(t, pool)
+	assert.Equal(t, ActionReplace, pool.Action)
+	assert.Equal(t, []string{"vm_size"}, pool.ReplacePaths)
+
+	encoded, err := golden.JSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "registry.terraform.io", "provider addresses are dropped")
+	assert.NotContains(t, string(encoded), "terraform_version")
+}
+
+// TestGoldenNormalizeValue tests unknown, sensitive and timestamp markers
+func TestGoldenNormalizeValue(t *testing.T) {
+	t.Parallel()
+
+	value := map[string]interface{}{
+		"name":       "kv-terratest",
+		"secret":     "hunter2",
+		"start_date": "2026-10-01T00:00:00Z",
+		"tags":       []interface{}{"a", nil},
+	}
+	unknown := map[string]interface{}{
+		"id":   true,
+		"tags": []interface{}{false, true},
+	}
+	sensitive := map[string]interface{}{"secret": true}
+
+	assert.Equal(t, map[string]interface{}{
+		"id":         GoldenUnknown,
+		"name":       "kv-terratest",
+		"secret":     GoldenSensitive,
+		"start_date": GoldenTimestamp,
+		"tags":       []interface{}{"a", GoldenUnknown},
+	}, normalizeValue(value, unknown, sensitive))
+}
+
+// TestGoldenPlanMatches tests the recorded plan against its snapshot
+func TestGoldenPlanMatches(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, AssertGoldenPlan(t, loadTestPlan(t)))
+}
+
+// TestGoldenPlanDiff tests that plan changes are reported by path
+func TestGoldenPlanDiff(t *testing.T) {
+	if *updateGolden {
+		t.Skip("reads the snapshot, which -update rewrites")
+	}
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+	aks := plan.ResourceChangesMap["azurerm_kubernetes_cluster.main"]
+	aks.Change.After.(map[string]interface{})["sku_tier"] = "Premium"
+	delete(plan.ResourceChangesMap, "azurerm_role_assignment.acr_pull[0]")
+
+	diff, err := diffGoldenFile(GoldenPath("TestGoldenPlanMatches"), plan)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`~ resources["azurerm_kubernetes_cluster.main"].values.sku_tier: "Standard" -> "Premium"`,
+		`- resources["azurerm_role_assignment.acr_pull[0]"]: {"action":"delete","type":"azurerm_role_assignment"}`,
+	}, diff)
+
+	_, err = diffGoldenFile(filepath.Join("testdata", "golden", "missing.json"), plan)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+// TestGoldenPlanFailureMessage tests the message shown for a changed plan
+func TestGoldenPlanFailureMessage(t *testing.T) {
+	if *updateGolden {
+		t.Skip("compares against the snapshot, which -update rewrites")
+	}
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+	plan.ResourceChangesMap["azurerm_kubernetes_cluster.main"].Change.After.(map[string]interface{})["sku_tier"] = "Free"
+
+	recorder := &recordingT{}
+	assert.False(t, AssertGoldenPlanFile(recorder, plan, GoldenPath("TestGoldenPlanMatches")))
+	assert.Contains(t, recorder.output(), "plan differs from "+GoldenPath("TestGoldenPlanMatches"))
+	assert.Contains(t, recorder.output(), "run the test with -update to accept it")
+	assert.Contains(t, recorder.output(), `values.sku_tier: "Standard" -> "Free"`)
+
+	recorder = &recordingT{}
+	assert.False(t, AssertGoldenPlanFile(recorder, plan, filepath.Join("testdata", "golden", "missing.json")))
+	assert.Contains(t, recorder.output(), "run the test with -update to create it")
+}
+
+// TestGoldenDiffJSON tests list and object differences
+func TestGoldenDiffJSON(t *testing.T) {
+	t.Parallel()
+
+	decode := func(s string) interface{} {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(s), &v))
+		return v
+	}
+
+	assert.Empty(t, DiffJSON(decode(`{"a":[1,2]}`), decode(`{"a":[1,2]}`)))
+	assert.Equal(t, []string{
+		"+ a[2]: 3",
+		"- b: true",
+		`+ c: {"d":null}`,
+	}, DiffJSON(decode(`{"a":[1,2],"b":true}`), decode(`{"a":[1,2,3],"c":{"d":null}}`)))
+	assert.Equal(t, []string{`~ a: [1] -> "x"`}, DiffJSON(decode(`{"a":[1]}`), decode(`{"a":"x"}`)))
+}
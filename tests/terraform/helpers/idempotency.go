@@ -0,0 +1,211 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Apply runs terraform apply on an initialized workspace, then plans again and
+// checks that the second plan is empty; see AssertIdempotent. A failed apply
+// stops the test. A non-empty second plan only marks it failed, so a deferred
+// terraform.Destroy still runs.
+func Apply(t testing.TestingT, options *terraform.Options) string {
+	markHelper(t)
+	out, err := terraform.ApplyE(t, options)
+	require.NoError(t, err)
+	AssertIdempotent(t, options)
+	return out
+}
+
+// AssertIdempotent plans an applied workspace again and checks that Terraform
+// has nothing left to do. Changes on the second plan are perpetual diffs,
+// usually from the provider normalizing a value the configuration spells
+// differently, such as tag keys, CIDRs or JSON documents; they show up as a
+// change on every pipeline run. The failure lists each attribute with its
+// state and configured values; see PlanDrift.
+func AssertIdempotent(t testing.TestingT, options *terraform.Options) bool {
+	markHelper(t)
+	plan, err := PlanJSONE(t, options)
+	if !assert.NoError(t, err, "planning again after apply") {
+		return false
+	}
+	drift := PlanDrift(plan)
+	if len(drift) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("the plan after apply is not empty, so these changes would be planned on every run:\n  %s",
+		strings.Join(drift, "\n  ")))
+}
+
+// PlanDrift describes every change in a plan, one line per changed attribute
+// of each updated resource and one line per other resource or output change,
+// sorted by address. An empty result means the plan has no changes. Sensitive
+// values are shown as (sensitive) and values only known after apply as
+// (known after apply).
+func PlanDrift(plan *Plan) []string {
+	var drift []string
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		switch action := change.Action(); action {
+		case ActionNoOp:
+		case ActionUpdate:
+			paths := changedPaths(change.Change)
+			if len(paths) == 0 {
+				drift = append(drift, fmt.Sprintf("%s: update", address))
+			}
+			for _, path := range paths {
+				drift = append(drift, fmt.Sprintf("%s: %s: %s => %s", address, path,
+					describeValue(change.Change, path, false), describeValue(change.Change, path, true)))
+			}
+		case ActionReplace:
+			if paths := change.ReplacePaths(); len(paths) > 0 {
+				drift = append(drift, fmt.Sprintf("%s: replace, forced by %s", address, strings.Join(paths, ", ")))
+			} else {
+				drift = append(drift, fmt.Sprintf("%s: replace", address))
+			}
+		default:
+			drift = append(drift, fmt.Sprintf("%s: %s", address, action))
+		}
+	}
+
+	for _, name := range sortedOutputNames(plan) {
+		change := plan.RawPlan.OutputChanges[name]
+		if change == nil || change.Actions.NoOp() {
+			continue
+		}
+		drift = append(drift, fmt.Sprintf("output.%s: %s => %s", name,
+			describeValue(change, "", false), describeValue(change, "", true)))
+	}
+	return drift
+}
+
+// sortedOutputNames returns the names of the plan's output changes, sorted.
+func sortedOutputNames(plan *Plan) []string {
+	names := make([]string, 0, len(plan.RawPlan.OutputChanges))
+	for name := range plan.RawPlan.OutputChanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changedPaths returns the dotted paths of the leaf values that differ between
+// before and after, including values only known after apply.
+func changedPaths(change *tfjson.Change) []string {
+	var paths []string
+	diffValues("", change.Before, change.After, change.AfterUnknown, &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+// diffValues appends the paths below prefix at which before and after differ.
+// Objects and lists of the same length are compared element by element; any
+// other difference is reported at prefix.
+func diffValues(prefix string, before, after, unknown interface{}, paths *[]string) {
+	if isUnknown, _ := unknown.(bool); isUnknown {
+		*paths = append(*paths, prefix)
+		return
+	}
+
+	switch beforeValue := before.(type) {
+	case map[string]interface{}:
+		afterValue, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		unknownValues, _ := unknown.(map[string]interface{})
+		keys := map[string]bool{}
+		for key := range beforeValue {
+			keys[key] = true
+		}
+		for key := range afterValue {
+			keys[key] = true
+		}
+		for key := range keys {
+			diffValues(joinPath(prefix, key), beforeValue[key], afterValue[key], unknownValues[key], paths)
+		}
+		return
+	case []interface{}:
+		afterValue, ok := after.([]interface{})
+		if !ok || len(afterValue) != len(beforeValue) {
+			break
+		}
+		unknownValues, _ := unknown.([]interface{})
+		for i := range beforeValue {
+			var unknownValue interface{}
+			if i < len(unknownValues) {
+				unknownValue = unknownValues[i]
+			}
+			diffValues(joinPath(prefix, strconv.Itoa(i)), beforeValue[i], afterValue[i], unknownValue, paths)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*paths = append(*paths, prefix)
+	}
+}
+
+// joinPath appends a step to a dotted path.
+func joinPath(prefix, step string) string {
+	if prefix == "" {
+		return step
+	}
+	return prefix + "." + step
+}
+
+// describeValue renders the before or after value at path for a drift line.
+func describeValue(change *tfjson.Change, path string, after bool) string {
+	value, sensitive := change.Before, change.BeforeSensitive
+	if after {
+		if isMarked(change.AfterUnknown, path) {
+			return "(known after apply)"
+		}
+		value, sensitive = change.After, change.AfterSensitive
+	}
+	if isMarked(sensitive, path) {
+		return "(sensitive)"
+	}
+
+	found, ok := LookupPath(value, path)
+	if !ok || found == nil {
+		return "null"
+	}
+	encoded, err := json.Marshal(found)
+	if err != nil {
+		return fmt.Sprint(found)
+	}
+	return string(encoded)
+}
+
+// isMarked reports whether path, or any value containing it, is marked true in
+// an after_unknown or *_sensitive tree.
+func isMarked(marks interface{}, path string) bool {
+	if marked, _ := marks.(bool); marked {
+		return true
+	}
+	if path == "" {
+		return false
+	}
+	steps := strings.Split(path, ".")
+	for i := range steps {
+		value, ok := LookupPath(marks, strings.Join(steps[:i+1], "."))
+		if !ok {
+			return false
+		}
+		if marked, _ := value.(bool); marked {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - IDEMPOTENCY TESTS
+// =============================================================================
+//
+// Tests for the description of a second plan after apply, using a recorded
+// `terraform show -json` plan with perpetual diffs. These run without
+// Terraform or Azure credentials.
+//
+// Run with: go test -v -run TestPlanDrift ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlanDriftListsChangedAttributes tests that every perpetual diff is described
+func TestPlanDriftListsChangedAttributes(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "plan_drift.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`azurerm_key_vault_secret.token: value: (sensitive) => (sensitive)`,
+		`azurerm_policy_definition.tags: id: "/policy" => (known after apply)`,
+		`azurerm_policy_definition.tags: policy_rule: "{\"if\":{\"field\":\"tags\",\"exists\":false}}" => "{\n  \"if\": {\"field\": \"tags\", \"exists\": false}\n}"`,
+		`azurerm_resource_group.main: tags.Environment: null => "dev"`,
+		`azurerm_resource_group.main: tags.environment: "dev" => null`,
+		`azurerm_subnet.app: address_prefixes.0: "10.0.1.0/24" => "10.0.1.4/24"`,
+		`azurerm_user_assigned_identity.app: replace, forced by location`,
+		`output.policy_id: "/policy" => (known after apply)`,
+	}, PlanDrift(plan))
+}
+
+// TestPlanDriftEmptyPlan tests that a plan of no-ops has no drift
+func TestPlanDriftEmptyPlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlan([]byte(`{
+		"format_version": "1.2",
+		"planned_values": {"root_module": {}},
+		"resource_changes": [{
+			"address": "azurerm_resource_group.main",
+			"mode": "managed",
+			"type": "azurerm_resource_group",
+			"name": "main",
+			"change": {"actions": ["no-op"], "before": {"name": "rg"}, "after": {"name": "rg"}, "after_unknown": {}}
+		}],
+		"output_changes": {
+			"resource_group": {"actions": ["no-op"], "before": "rg", "after": "rg", "after_unknown": false}
+		}
+	}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, PlanDrift(plan))
+}
@@ -0,0 +1,446 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Bump is a semantic version increment.
+type Bump int
+
+// Bumps in increasing order, so the largest change decides a module's bump.
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	default:
+		return "none"
+	}
+}
+
+// Output is an output value declared by a Terraform module.
+type Output struct {
+	Name      string
+	Sensitive bool
+	Range     hcl.Range
+}
+
+// ModuleInterface is what callers of a module depend on: its input variables
+// and its outputs.
+type ModuleInterface struct {
+	Variables Variables
+	Outputs   map[string]*Output
+}
+
+// outputBlockSchema selects the output blocks from a configuration file.
+var outputBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "output", LabelNames: []string{"name"}},
+	},
+}
+
+var outputSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "sensitive"}},
+}
+
+// LoadInterfaceE parses the variables and outputs declared in a module
+// directory.
+func LoadInterfaceE(dir string) (*ModuleInterface, error) {
+	variables, err := LoadVariablesE(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	parser := hclparse.NewParser()
+	outputs := map[string]*Output{}
+	var diags hcl.Diagnostics
+	for _, file := range files {
+		parsed, parseDiags := parser.ParseHCLFile(file)
+		diags = append(diags, parseDiags...)
+		if parseDiags.HasErrors() {
+			continue
+		}
+
+		content, _, contentDiags := parsed.Body.PartialContent(outputBlockSchema)
+		diags = append(diags, contentDiags...)
+		for _, block := range content.Blocks {
+			output := &Output{Name: block.Labels[0], Range: block.DefRange}
+			attrs, _, attrDiags := block.Body.PartialContent(outputSchema)
+			diags = append(diags, attrDiags...)
+			if attr, ok := attrs.Attributes["sensitive"]; ok {
+				value, valueDiags := attr.Expr.Value(nil)
+				diags = append(diags, valueDiags...)
+				output.Sensitive = !valueDiags.HasErrors() && value.Type() == cty.Bool && value.True()
+			}
+			outputs[output.Name] = output
+		}
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parsing outputs in %s: %s", dir, diags.Error())
+	}
+	return &ModuleInterface{Variables: variables, Outputs: outputs}, nil
+}
+
+// InterfaceChange is one difference between two versions of a module's
+// interface, with the version bump it calls for.
+type InterfaceChange struct {
+	Bump        Bump
+	Description string
+
+	// Note is what callers should check before taking the new version, for
+	// changes whose effect on them cannot be told from the interface alone.
+	Note string
+}
+
+// validationNote is the note on a validation condition that was added or
+// changed: the checker cannot tell which values it now rejects.
+const validationNote = "values accepted before may now be rejected; check what callers pass"
+
+// CompareInterfaces lists the differences from one version of a module's
+// interface to the next, breaking changes first and then by description.
+// Removing a variable or output, adding a required variable, removing a
+// default, narrowing a type and marking an output sensitive break callers;
+// adding an optional variable or an output, adding a default and widening a
+// type do not. Adding, changing or removing a validation condition is a
+// minor change, with a note when the new conditions may reject more.
+func CompareInterfaces(from, to *ModuleInterface) []InterfaceChange {
+	var changes []InterfaceChange
+	add := func(bump Bump, format string, args ...interface{}) {
+		changes = append(changes, InterfaceChange{Bump: bump, Description: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range from.Variables.Names() {
+		before, after := from.Variables[name], to.Variables[name]
+		switch {
+		case after == nil:
+			add(BumpMajor, "variable %s removed", name)
+			continue
+		case after.Required && !before.Required:
+			add(BumpMajor, "variable %s no longer has a default", name)
+		case !after.Required && before.Required:
+			add(BumpMinor, "variable %s now has a default", name)
+		}
+		if !before.Type.Equals(after.Type) {
+			bump := BumpMinor
+			if !typeAccepts(after.Type, before.Type) {
+				bump = BumpMajor
+			}
+			add(bump, "variable %s type changed from %s to %s", name,
+				typeexpr.TypeString(before.Type), typeexpr.TypeString(after.Type))
+		}
+		switch {
+		case reflect.DeepEqual(before.Validations, after.Validations):
+		case len(before.Validations) == 0:
+			changes = append(changes, InterfaceChange{Bump: BumpMinor,
+				Description: fmt.Sprintf("variable %s validation added", name), Note: validationNote})
+		case len(after.Validations) == 0:
+			add(BumpMinor, "variable %s validation removed", name)
+		default:
+			changes = append(changes, InterfaceChange{Bump: BumpMinor,
+				Description: fmt.Sprintf("variable %s validation changed", name), Note: validationNote})
+		}
+	}
+	for _, name := range to.Variables.Names() {
+		if from.Variables[name] != nil {
+			continue
+		}
+		if to.Variables[name].Required {
+			add(BumpMajor, "variable %s added without a default", name)
+		} else {
+			add(BumpMinor, "variable %s added", name)
+		}
+	}
+
+	for _, name := range sortedStrings(from.Outputs) {
+		before, after := from.Outputs[name], to.Outputs[name]
+		switch {
+		case after == nil:
+			add(BumpMajor, "output %s removed", name)
+		case after.Sensitive && !before.Sensitive:
+			add(BumpMajor, "output %s is now sensitive", name)
+		case !after.Sensitive && before.Sensitive:
+			add(BumpMinor, "output %s is no longer sensitive", name)
+		}
+	}
+	for _, name := range sortedStrings(to.Outputs) {
+		if from.Outputs[name] == nil {
+			add(BumpMinor, "output %s added", name)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Bump != changes[j].Bump {
+			return changes[i].Bump > changes[j].Bump
+		}
+		return changes[i].Description < changes[j].Description
+	})
+	return changes
+}
+
+// typeAccepts reports whether every value of type from is also valid for
+// type to: to is any, or has the same shape with element types that accept
+// from's, and objects only add optional attributes.
+func typeAccepts(to, from cty.Type) bool {
+	switch {
+	case to == cty.DynamicPseudoType:
+		return true
+	case to.Equals(from):
+		return true
+	case to.IsListType() && from.IsListType(),
+		to.IsSetType() && from.IsSetType(),
+		to.IsMapType() && from.IsMapType():
+		return typeAccepts(to.ElementType(), from.ElementType())
+	case to.IsObjectType() && from.IsObjectType():
+		for name, attrType := range to.AttributeTypes() {
+			if !from.HasAttribute(name) {
+				if !to.AttributeOptional(name) {
+					return false
+				}
+				continue
+			}
+			if from.AttributeOptional(name) && !to.AttributeOptional(name) {
+				return false
+			}
+			if !typeAccepts(attrType, from.AttributeType(name)) {
+				return false
+			}
+		}
+		for name := range from.AttributeTypes() {
+			if !to.HasAttribute(name) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// ModuleReport is the suggested version bump for one module between two
+// versions of the repository, and the interface changes behind it.
+type ModuleReport struct {
+	Module  string
+	Bump    Bump
+	Changes []InterfaceChange
+}
+
+// CompareModulesE compares every module under terraform/modules in two
+// repository trees, such as a checkout from CheckoutE and RepoRoot. A module
+// whose interface is unchanged but whose other files differ gets a patch
+// bump; README changes are ignored. Reports are sorted by module.
+func CompareModulesE(oldRoot, newRoot string) ([]*ModuleReport, error) {
+	oldModules, err := moduleNamesE(oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newModules, err := moduleNamesE(newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string]bool{}
+	for module := range oldModules {
+		all[module] = true
+	}
+	for module := range newModules {
+		all[module] = true
+	}
+
+	var reports []*ModuleReport
+	for _, module := range sortedStrings(all) {
+		oldDir := filepath.Join(oldRoot, "terraform", "modules", module)
+		newDir := filepath.Join(newRoot, "terraform", "modules", module)
+		report := &ModuleReport{Module: module}
+
+		switch {
+		case !newModules[module]:
+			report.Changes = []InterfaceChange{{Bump: BumpMajor, Description: "module removed"}}
+		case !oldModules[module]:
+			report.Changes = []InterfaceChange{{Bump: BumpMinor, Description: "module added"}}
+		default:
+			oldInterface, err := LoadInterfaceE(oldDir)
+			if err != nil {
+				return nil, err
+			}
+			newInterface, err := LoadInterfaceE(newDir)
+			if err != nil {
+				return nil, err
+			}
+			report.Changes = CompareInterfaces(oldInterface, newInterface)
+		}
+
+		for _, change := range report.Changes {
+			if change.Bump > report.Bump {
+				report.Bump = change.Bump
+			}
+		}
+		if report.Bump == BumpNone {
+			same, err := sameModuleFilesE(oldDir, newDir)
+			if err != nil {
+				return nil, err
+			}
+			if !same {
+				report.Bump = BumpPatch
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// CompareRefsE compares the modules at git ref from with those at ref to, or
+// with the working tree when to is empty; see CompareModulesE.
+func CompareRefsE(from, to string) ([]*ModuleReport, error) {
+	oldRoot, err := CheckoutE(from)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(oldRoot)
+
+	newRoot, err := RepoRootE()
+	if err != nil {
+		return nil, err
+	}
+	if to != "" {
+		if newRoot, err = CheckoutE(to); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(newRoot)
+	}
+	return CompareModulesE(oldRoot, newRoot)
+}
+
+// moduleNamesE returns the directories under terraform/modules of a
+// repository tree that contain .tf files.
+func moduleNamesE(root string) (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(root, "terraform", "modules", "*", "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, file := range files {
+		found[filepath.Base(filepath.Dir(file))] = true
+	}
+	return found, nil
+}
+
+// sameModuleFilesE reports whether two module directories hold the same files
+// with the same contents, ignoring Markdown and Terraform's working files.
+func sameModuleFilesE(oldDir, newDir string) (bool, error) {
+	oldFiles, err := moduleFilesE(oldDir)
+	if err != nil {
+		return false, err
+	}
+	newFiles, err := moduleFilesE(newDir)
+	if err != nil {
+		return false, err
+	}
+	if len(oldFiles) != len(newFiles) {
+		return false, nil
+	}
+	for path, content := range oldFiles {
+		if other, ok := newFiles[path]; !ok || !bytes.Equal(content, other) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// moduleFilesE reads the files of a module directory, keyed by relative path.
+func moduleFilesE(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(entry.Name(), ".md") || entry.Name() == ".terraform.lock.hcl" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	return files, err
+}
+
+// FormatModuleReports renders reports as text, one line per module with its
+// suggested bump and one indented line per change, followed by its note.
+func FormatModuleReports(reports []*ModuleReport) string {
+	var b strings.Builder
+	for _, report := range reports {
+		fmt.Fprintf(&b, "%s: %s\n", report.Module, report.Bump)
+		for _, change := range report.Changes {
+			kind := "compatible"
+			if change.Bump == BumpMajor {
+				kind = "breaking"
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", kind, change.Description)
+			if change.Note != "" {
+				fmt.Fprintf(&b, "    note: %s\n", change.Note)
+			}
+		}
+	}
+	return b.String()
+}
+
+// AssertCompatibleInterfaces checks that no module needs a major version bump
+// since ref, listing every breaking change otherwise. Callers of the modules
+// pin them by version, so a breaking change must ship as a major release with
+// a CHANGELOG entry telling them what to change.
+func AssertCompatibleInterfaces(t testing.TestingT, reports []*ModuleReport, ref string) bool {
+	markHelper(t)
+	var breaking []string
+	for _, report := range reports {
+		for _, change := range report.Changes {
+			if change.Bump == BumpMajor {
+				breaking = append(breaking, fmt.Sprintf("%s: %s", report.Module, change.Description))
+			}
+		}
+	}
+	if len(breaking) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("module interfaces changed incompatibly since %s, which needs a major release:\n  %s",
+		ref, strings.Join(breaking, "\n  ")))
+}
@@ -0,0 +1,142 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - MODULE INTERFACE TESTS
+// =============================================================================
+//
+// Tests for comparing module variables and outputs between two versions of
+// the repository. testdata/interface holds an old and a new tree with one
+// module per kind of change.
+//
+// Run with: go test -v -run TestInterface ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// TestInterfaceLoad tests parsing the variables and outputs of a module
+func TestInterfaceLoad(t *testing.T) {
+	t.Parallel()
+
+	iface, err := LoadInterfaceE(filepath.Join("testdata", "interface", "old", "terraform", "modules", "app"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"legacy", "name", "replicas", "settings", "sku", "tags", "zone"}, iface.Variables.Names())
+	assert.Equal(t, []string{"connection_string", "endpoint", "id", "password"}, sortedStrings(iface.Outputs))
+	assert.True(t, iface.Outputs["password"].Sensitive)
+	assert.False(t, iface.Outputs["endpoint"].Sensitive)
+	assert.Equal(t, "outputs.tf", filepath.Base(iface.Outputs["id"].Range.Filename))
+	assert.Equal(t, []string{`contains(["standard","premium"],var.settings.tier)`}, iface.Variables["settings"].Validations)
+	assert.Empty(t, iface.Variables["zone"].Validations)
+}
+
+// TestInterfaceCompare tests classifying variable and output changes
+func TestInterfaceCompare(t *testing.T) {
+	t.Parallel()
+
+	oldInterface, err := LoadInterfaceE(filepath.Join("testdata", "interface", "old", "terraform", "modules", "app"))
+	require.NoError(t, err)
+	newInterface, err := LoadInterfaceE(filepath.Join("testdata", "interface", "new", "terraform", "modules", "app"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []InterfaceChange{
+		{Bump: BumpMajor, Description: "output connection_string is now sensitive"},
+		{Bump: BumpMajor, Description: "output endpoint removed"},
+		{Bump: BumpMajor, Description: "variable legacy removed"},
+		{Bump: BumpMajor, Description: "variable region added without a default"},
+		{Bump: BumpMajor, Description: "variable replicas no longer has a default"},
+		{Bump: BumpMajor, Description: "variable replicas type changed from number to string"},
+		{Bump: BumpMinor, Description: "output fqdn added"},
+		{Bump: BumpMinor, Description: "output password is no longer sensitive"},
+		{Bump: BumpMinor, Description: "variable labels added"},
+		{Bump: BumpMinor, Description: "variable name validation changed", Note: validationNote},
+		{Bump: BumpMinor, Description: "variable settings type changed from object({tier=string}) to object({capacity=number,tier=string})"},
+		{Bump: BumpMinor, Description: "variable sku now has a default"},
+		{Bump: BumpMinor, Description: "variable sku validation removed"},
+		{Bump: BumpMinor, Description: "variable tags validation added", Note: validationNote},
+		{Bump: BumpMinor, Description: "variable zone type changed from string to any"},
+	}, CompareInterfaces(oldInterface, newInterface))
+
+	assert.Empty(t, CompareInterfaces(oldInterface, oldInterface))
+}
+
+// TestInterfaceTypeAccepts tests which type changes still accept every old value
+func TestInterfaceTypeAccepts(t *testing.T) {
+	t.Parallel()
+
+	object := cty.Object(map[string]cty.Type{"tier": cty.String})
+	withOptional := cty.ObjectWithOptionalAttrs(map[string]cty.Type{"tier": cty.String, "size": cty.Number}, []string{"size"})
+	withRequired := cty.Object(map[string]cty.Type{"tier": cty.String, "size": cty.Number})
+
+	testCases := []struct {
+		name     string
+		from, to cty.Type
+		want     bool
+	}{
+		{"any", cty.String, cty.DynamicPseudoType, true},
+		{"primitive", cty.Number, cty.String, false},
+		{"list element", cty.List(cty.String), cty.List(cty.DynamicPseudoType), true},
+		{"list to set", cty.List(cty.String), cty.Set(cty.String), false},
+		{"optional attribute added", object, withOptional, true},
+		{"required attribute added", object, withRequired, false},
+		{"attribute removed", withRequired, object, false},
+		{"attribute made required", withOptional, withRequired, false},
+		{"attribute made optional", withRequired, withOptional, true},
+		{"nested", cty.Map(object), cty.Map(withOptional), true},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, typeAccepts(tc.to, tc.from), tc.name)
+	}
+}
+
+// TestInterfaceCompareModules tests suggesting a version bump for every module
+func TestInterfaceCompareModules(t *testing.T) {
+	t.Parallel()
+
+	reports, err := CompareModulesE(filepath.Join("testdata", "interface", "old"), filepath.Join("testdata", "interface", "new"))
+	require.NoError(t, err)
+
+	bumps := map[string]Bump{}
+	for _, report := range reports {
+		bumps[report.Module] = report.Bump
+	}
+	assert.Equal(t, map[string]Bump{
+		"app":      BumpMajor,
+		"fresh":    BumpMinor,
+		"internal": BumpPatch,
+		"retired":  BumpMajor,
+		"stable":   BumpNone,
+	}, bumps)
+
+	text := FormatModuleReports(reports)
+	assert.Contains(t, text, "app: major\n  breaking: output connection_string is now sensitive\n")
+	assert.Contains(t, text, "  compatible: variable zone type changed from string to any\n")
+	assert.Contains(t, text, "  compatible: variable name validation changed\n    note: "+validationNote+"\n")
+	assert.Contains(t, text, "  compatible: variable sku validation removed\n  compatible: variable tags")
+	assert.Contains(t, text, "fresh: minor\n  compatible: module added\ninternal: patch\n")
+	assert.Contains(t, text, "retired: major\n  breaking: module removed\nstable: none\n")
+}
+
+// TestInterfaceAssertion tests the failure message for breaking changes
+func TestInterfaceAssertion(t *testing.T) {
+	t.Parallel()
+
+	reports, err := CompareModulesE(filepath.Join("testdata", "interface", "old"), filepath.Join("testdata", "interface", "new"))
+	require.NoError(t, err)
+
+	rec := &recordingT{}
+	assert.False(t, AssertCompatibleInterfaces(rec, reports, "v4.0.0"))
+	assert.Contains(t, rec.output(), "module interfaces changed incompatibly since v4.0.0, which needs a major release:")
+	assert.Contains(t, rec.output(), "app: variable legacy removed")
+	assert.Contains(t, rec.output(), "retired: module removed")
+	assert.NotContains(t, rec.output(), "fresh")
+
+	assert.True(t, AssertCompatibleInterfaces(rec, reports[1:3], "v4.0.0"))
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package helpers
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until it holds an exclusive advisory lock on file.
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock taken by lockFile.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package helpers
+
+import "os"
+
+// lockFile is a no-op on Windows, where syscall has no flock. Tests in one
+// process are still serialized by pluginCacheMu; run one test process at a
+// time against a shared cache.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on Windows.
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,351 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+// NameScope is where Azure requires a resource name to be unique.
+type NameScope string
+
+// Name scopes, from widest to narrowest.
+const (
+	// ScopeGlobal names are unique across Azure, usually because they become
+	// part of a DNS name such as <name>.vault.azure.net.
+	ScopeGlobal NameScope = "global"
+
+	// ScopeSubscription names are unique within a subscription.
+	ScopeSubscription NameScope = "subscription"
+
+	// ScopeResourceGroup names are unique per type within a resource group.
+	ScopeResourceGroup NameScope = "resource group"
+
+	// ScopeParent names are unique within the parent resource, e.g. a subnet
+	// within its virtual network.
+	ScopeParent NameScope = "parent resource"
+)
+
+// NameRule is the Azure naming constraint for one resource type.
+type NameRule struct {
+	// Min and Max bound the length in characters.
+	Min, Max int
+
+	// Chars is the regexp character class, without brackets, of every
+	// character in the name; a leading ^ lists forbidden characters instead.
+	// Start and End further restrict the first and last character; empty
+	// means any character of Chars.
+	Chars, Start, End string
+
+	// NoDoubleHyphen forbids consecutive hyphens.
+	NoDoubleHyphen bool
+
+	Scope NameScope
+}
+
+// Character classes shared by several rules.
+const (
+	alphanumeric      = `a-zA-Z0-9`
+	lowerAlphanumeric = `a-z0-9`
+	letters           = `a-zA-Z`
+
+	// networkChars are allowed in Microsoft.Network names, which must end
+	// with networkEnd.
+	networkChars = `a-zA-Z0-9_.-`
+	networkEnd   = `a-zA-Z0-9_`
+)
+
+// armNameRule is the general Azure Resource Manager rule, for types whose
+// documentation adds nothing to it: up to 260 characters, none of them <, >,
+// %, &, \, ?, / or a control character.
+func armNameRule(scope NameScope) NameRule {
+	return NameRule{Min: 1, Max: 260, Chars: `^<>%&\\?/\x00-\x1f`, Scope: scope}
+}
+
+// NameRules maps each azurerm resource type the platform creates to the
+// constraint on its name attribute, from
+// https://learn.microsoft.com/azure/azure-resource-manager/management/resource-name-rules
+// or, where that page is silent, the azurerm provider's validation. Types
+// whose name is not chosen by the configuration are in UnnamedResourceTypes
+// instead; every azurerm type under terraform/ must be in one of the two.
+var NameRules = map[string]NameRule{
+	// Management and cost.
+	"azurerm_resource_group":                        {Min: 1, Max: 90, Chars: `\p{L}\p{N}_().-`, End: `\p{L}\p{N}_()-`, Scope: ScopeSubscription},
+	"azurerm_role_assignment":                       {Min: 36, Max: 36, Chars: `0-9a-fA-F-`, Scope: ScopeParent},
+	"azurerm_consumption_budget_resource_group":     {Min: 1, Max: 63, Chars: `a-zA-Z0-9_-`, Scope: ScopeParent},
+	"azurerm_consumption_budget_subscription":       {Min: 1, Max: 63, Chars: `a-zA-Z0-9_-`, Scope: ScopeSubscription},
+	"azurerm_cost_anomaly_alert":                    armNameRule(ScopeSubscription),
+	"azurerm_resource_group_cost_management_export": {Min: 3, Max: 64, Chars: `a-zA-Z0-9-`, Scope: ScopeParent},
+	"azurerm_security_center_automation":            armNameRule(ScopeResourceGroup),
+	"azurerm_security_center_contact":               armNameRule(ScopeSubscription),
+
+	// Networking.
+	"azurerm_virtual_network":                       {Min: 2, Max: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_subnet":                                {Min: 1, Max: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	"azurerm_network_security_group":                {Min: 1, Max: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_public_ip":                             {Min: 1, Max: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_bastion_host":                          {Min: 1, Max: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_private_endpoint":                      {Min: 2, Max: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_private_dns_zone_virtual_network_link": {Min: 1, Max: 80, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	"azurerm_private_dns_zone":                      {Min: 1, Max: 253, Chars: `a-zA-Z0-9.-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_dns_zone":                              {Min: 1, Max: 253, Chars: `a-zA-Z0-9.-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+
+	// Containers.
+	"azurerm_kubernetes_cluster":             {Min: 1, Max: 63, Chars: `a-zA-Z0-9_-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_kubernetes_cluster_node_pool":   {Min: 1, Max: 12, Chars: lowerAlphanumeric, Start: `a-z`, Scope: ScopeParent},
+	"azurerm_container_registry":             {Min: 5, Max: 50, Chars: alphanumeric, Scope: ScopeGlobal},
+	"azurerm_container_registry_replication": {Min: 5, Max: 50, Chars: alphanumeric, Scope: ScopeParent},
+	"azurerm_container_registry_scope_map":   {Min: 5, Max: 50, Chars: `a-zA-Z0-9_-`, Scope: ScopeParent},
+	"azurerm_container_registry_task":        {Min: 5, Max: 50, Chars: `a-zA-Z0-9_-`, Scope: ScopeParent},
+	"azurerm_container_registry_webhook":     {Min: 5, Max: 50, Chars: alphanumeric, Scope: ScopeParent},
+
+	// Data.
+	"azurerm_storage_account":                          {Min: 3, Max: 24, Chars: lowerAlphanumeric, Scope: ScopeGlobal},
+	"azurerm_storage_container":                        {Min: 3, Max: 63, Chars: `a-z0-9-`, Start: lowerAlphanumeric, End: lowerAlphanumeric, NoDoubleHyphen: true, Scope: ScopeParent},
+	"azurerm_postgresql_flexible_server":               {Min: 3, Max: 63, Chars: `a-z0-9-`, Start: lowerAlphanumeric, End: lowerAlphanumeric, Scope: ScopeGlobal},
+	"azurerm_postgresql_flexible_server_database":      {Min: 1, Max: 63, Chars: `a-zA-Z0-9_-`, Start: `a-zA-Z_`, Scope: ScopeParent},
+	"azurerm_postgresql_flexible_server_firewall_rule": {Min: 1, Max: 128, Chars: `a-zA-Z0-9_-`, Scope: ScopeParent},
+	"azurerm_redis_cache":                              {Min: 1, Max: 63, Chars: `a-zA-Z0-9-`, Start: alphanumeric, End: alphanumeric, NoDoubleHyphen: true, Scope: ScopeGlobal},
+	"azurerm_purview_account":                          {Min: 3, Max: 63, Chars: `a-zA-Z0-9-`, Start: letters, End: alphanumeric, Scope: ScopeGlobal},
+
+	// Security and identity.
+	"azurerm_key_vault":                     {Min: 3, Max: 24, Chars: `a-zA-Z0-9-`, Start: letters, End: alphanumeric, NoDoubleHyphen: true, Scope: ScopeGlobal},
+	"azurerm_key_vault_secret":              {Min: 1, Max: 127, Chars: `a-zA-Z0-9-`, Scope: ScopeParent},
+	"azurerm_user_assigned_identity":        {Min: 3, Max: 128, Chars: `a-zA-Z0-9_-`, Start: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_federated_identity_credential": {Min: 3, Max: 120, Chars: `a-zA-Z0-9_-`, Start: alphanumeric, Scope: ScopeParent},
+
+	// AI.
+	"azurerm_cognitive_account":    {Min: 2, Max: 64, Chars: `a-zA-Z0-9-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_cognitive_deployment": {Min: 2, Max: 64, Chars: `a-zA-Z0-9_.-`, Start: alphanumeric, Scope: ScopeParent},
+	"azurerm_search_service":       {Min: 2, Max: 60, Chars: `a-z0-9-`, Start: lowerAlphanumeric, End: lowerAlphanumeric, NoDoubleHyphen: true, Scope: ScopeGlobal},
+
+	// Monitoring.
+	"azurerm_log_analytics_workspace":                  {Min: 4, Max: 63, Chars: `a-zA-Z0-9-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_monitor_workspace":                        {Min: 4, Max: 44, Chars: `a-zA-Z0-9-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_dashboard_grafana":                        {Min: 2, Max: 23, Chars: `a-zA-Z0-9-`, Start: letters, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_monitor_action_group":                     {Min: 1, Max: 260, Chars: `^:<>+/&%\\?\x00-\x1f`, End: `^ .:<>+/&%\\?\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_alert_prometheus_rule_group":      {Min: 1, Max: 260, Chars: `^<>*%{}&:\\?/#|\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_metric_alert":                     {Min: 1, Max: 260, Chars: `^*#&+:<>?@%{}\\/\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_scheduled_query_rules_alert_v2":   {Min: 1, Max: 260, Chars: `^*<>%{}&:\\?/#|\x00-\x1f`, Scope: ScopeResourceGroup},
+	"azurerm_monitor_diagnostic_setting":               {Min: 1, Max: 260, Chars: `^*%&:\\?+#/<>\x00-\x1f`, Scope: ScopeParent},
+	"azurerm_monitor_data_collection_endpoint":         {Min: 3, Max: 44, Chars: `a-zA-Z0-9-`, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	"azurerm_monitor_data_collection_rule":             {Min: 1, Max: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	"azurerm_monitor_data_collection_rule_association": {Min: 1, Max: 64, Chars: networkChars, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+
+	// Backup and disaster recovery.
+	"azurerm_recovery_services_vault":                    {Min: 2, Max: 50, Chars: `a-zA-Z0-9-`, Start: letters, Scope: ScopeResourceGroup},
+	"azurerm_backup_policy_vm":                           {Min: 3, Max: 150, Chars: `a-zA-Z0-9_!-`, Start: letters, Scope: ScopeParent},
+	"azurerm_backup_policy_file_share":                   {Min: 3, Max: 150, Chars: `a-zA-Z0-9_!-`, Start: letters, Scope: ScopeParent},
+	"azurerm_site_recovery_fabric":                       armNameRule(ScopeParent),
+	"azurerm_site_recovery_network_mapping":              armNameRule(ScopeParent),
+	"azurerm_site_recovery_protection_container":         armNameRule(ScopeParent),
+	"azurerm_site_recovery_protection_container_mapping": armNameRule(ScopeParent),
+	"azurerm_site_recovery_replication_policy":           armNameRule(ScopeParent),
+}
+
+// UnnamedResourceTypes are the azurerm resource types the platform creates
+// whose name is not chosen by the configuration, with the reason.
+var UnnamedResourceTypes = map[string]string{
+	"azurerm_key_vault_access_policy":                   "no name attribute",
+	"azurerm_log_analytics_solution":                    "Azure names it after solution_name and the workspace",
+	"azurerm_postgresql_flexible_server_configuration":  "the name is a PostgreSQL server parameter",
+	"azurerm_security_center_auto_provisioning":         "no name attribute",
+	"azurerm_security_center_subscription_pricing":      "no name attribute",
+	"azurerm_subnet_network_security_group_association": "no name attribute",
+}
+
+// Check describes every way name breaks the rule, or returns nil.
+func (r NameRule) Check(name string) []string {
+	var problems []string
+	if length := utf8.RuneCountInString(name); length < r.Min {
+		problems = append(problems, fmt.Sprintf("is %d characters, fewer than %d", length, r.Min))
+	} else if length > r.Max {
+		problems = append(problems, fmt.Sprintf("is %d characters, more than %d", length, r.Max))
+	}
+
+	allowed := regexp.MustCompile("^[" + r.Chars + "]$")
+	var invalid []string
+	seen := map[rune]bool{}
+	for _, c := range name {
+		if !seen[c] && !allowed.MatchString(string(c)) {
+			invalid = append(invalid, fmt.Sprintf("%q", c))
+		}
+		seen[c] = true
+	}
+	if len(invalid) > 0 {
+		if strings.HasPrefix(r.Chars, "^") {
+			problems = append(problems, fmt.Sprintf("must not contain %s", strings.Join(invalid, ", ")))
+		} else {
+			problems = append(problems, fmt.Sprintf("may only contain [%s], not %s", r.Chars, strings.Join(invalid, ", ")))
+		}
+	}
+
+	if name != "" {
+		first, _ := utf8.DecodeRuneInString(name)
+		last, _ := utf8.DecodeLastRuneInString(name)
+		if problem := edgeProblem("start", r.Start, first); problem != "" {
+			problems = append(problems, problem)
+		}
+		if problem := edgeProblem("end", r.End, last); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if r.NoDoubleHyphen && strings.Contains(name, "--") {
+		problems = append(problems, "must not contain consecutive hyphens")
+	}
+	return problems
+}
+
+// edgeProblem describes a first or last character outside class, or returns
+// "" when it is inside or class is empty.
+func edgeProblem(edge, class string, c rune) string {
+	if class == "" || regexp.MustCompile("^["+class+"]$").MatchString(string(c)) {
+		return ""
+	}
+	if strings.HasPrefix(class, "^") {
+		return fmt.Sprintf("must not %s with %q", edge, c)
+	}
+	return fmt.Sprintf("must %s with [%s]", edge, class)
+}
+
+// NameViolations checks the planned name of every resource with a rule in
+// NameRules, sorted by address, followed by names planned twice where Azure
+// requires them to be unique across Azure or within a resource group. Names
+// only known after apply and resources planned for deletion are skipped.
+func NameViolations(plan *Plan) []string {
+	var violations []string
+	planned := map[string][]string{}
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		rule, ok := NameRules[change.Type]
+		if change.Mode != "managed" || !ok {
+			continue
+		}
+		if action := change.Action(); action == ActionDelete || action == ActionRead {
+			continue
+		}
+		value, _ := change.After("name")
+		name, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		if problems := rule.Check(name); len(problems) > 0 {
+			violations = append(violations, fmt.Sprintf("%s: name %q %s", address, name, strings.Join(problems, "; ")))
+		}
+
+		switch rule.Scope {
+		case ScopeGlobal:
+			key := change.Type + "\x00" + strings.ToLower(name)
+			planned[key] = append(planned[key], address)
+		case ScopeResourceGroup:
+			if group, ok := change.After("resource_group_name"); ok {
+				key := fmt.Sprintf("%s\x00%s\x00%v", change.Type, strings.ToLower(name), group)
+				planned[key] = append(planned[key], address)
+			}
+		}
+	}
+
+	var duplicates []string
+	for key, addresses := range planned {
+		if len(addresses) < 2 {
+			continue
+		}
+		parts := strings.Split(key, "\x00")
+		within := "across Azure"
+		if NameRules[parts[0]].Scope == ScopeResourceGroup {
+			within = fmt.Sprintf("within resource group %s", parts[2])
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s: name %q is planned %d times, but %s names must be unique %s",
+			strings.Join(addresses, ", "), parts[1], len(addresses), parts[0], within))
+	}
+	sort.Strings(duplicates)
+	return append(violations, duplicates...)
+}
+
+// AssertResourceNames checks that every planned resource name follows the
+// Azure naming rules in NameRules; see NameViolations. Azure rejects a bad
+// name only when the resource is created, so a plan alone cannot catch it.
+func AssertResourceNames(t testing.TestingT, plan *Plan) bool {
+	markHelper(t)
+	violations := NameViolations(plan)
+	if len(violations) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("planned resource names break Azure naming rules:\n  %s",
+		strings.Join(violations, "\n  ")))
+}
+
+// NamingOutputTypes maps naming module outputs to the azurerm resource type
+// whose name they are. Outputs for types the platform does not create, and
+// prefixes that other modules extend, are not listed.
+var NamingOutputTypes = map[string]string{
+	"resource_group":          "azurerm_resource_group",
+	"virtual_network":         "azurerm_virtual_network",
+	"subnet_aks":              "azurerm_subnet",
+	"subnet_db":               "azurerm_subnet",
+	"subnet_pe":               "azurerm_subnet",
+	"network_security_group":  "azurerm_network_security_group",
+	"public_ip":               "azurerm_public_ip",
+	"bastion":                 "azurerm_bastion_host",
+	"private_dns_zone":        "azurerm_private_dns_zone",
+	"aks_cluster":             "azurerm_kubernetes_cluster",
+	"aks_node_pool":           "azurerm_kubernetes_cluster_node_pool",
+	"aks_node_pool_system":    "azurerm_kubernetes_cluster_node_pool",
+	"aks_node_pool_user":      "azurerm_kubernetes_cluster_node_pool",
+	"container_registry":      "azurerm_container_registry",
+	"postgresql_server":       "azurerm_postgresql_flexible_server",
+	"postgresql_database":     "azurerm_postgresql_flexible_server_database",
+	"redis_cache":             "azurerm_redis_cache",
+	"storage_account":         "azurerm_storage_account",
+	"storage_account_diag":    "azurerm_storage_account",
+	"storage_container":       "azurerm_storage_container",
+	"key_vault":               "azurerm_key_vault",
+	"key_vault_secret":        "azurerm_key_vault_secret",
+	"managed_identity":        "azurerm_user_assigned_identity",
+	"managed_identity_aks":    "azurerm_user_assigned_identity",
+	"log_analytics_workspace": "azurerm_log_analytics_workspace",
+	"action_group":            "azurerm_monitor_action_group",
+	"monitor_workspace":       "azurerm_monitor_workspace",
+	"grafana":                 "azurerm_dashboard_grafana",
+	"cognitive_services":      "azurerm_cognitive_account",
+	"openai_service":          "azurerm_cognitive_account",
+	"search_service":          "azurerm_search_service",
+	"purview_account":         "azurerm_purview_account",
+}
+
+// NamingOutputViolations checks the naming module outputs in
+// NamingOutputTypes against the rule for their resource type, sorted by
+// output. Outputs that are missing or not strings are reported too.
+func NamingOutputViolations(outputs map[string]interface{}) []string {
+	var violations []string
+	for _, output := range sortedStrings(NamingOutputTypes) {
+		resourceType := NamingOutputTypes[output]
+		name, ok := outputs[output].(string)
+		if !ok {
+			violations = append(violations, fmt.Sprintf("output.%s: not a string: %v", output, outputs[output]))
+			continue
+		}
+		if problems := NameRules[resourceType].Check(name); len(problems) > 0 {
+			violations = append(violations, fmt.Sprintf("output.%s: %s name %q %s", output, resourceType, name, strings.Join(problems, "; ")))
+		}
+	}
+	return violations
+}
+
+// AssertNamingOutputs checks the naming module outputs against NameRules; see
+// NamingOutputViolations.
+func AssertNamingOutputs(t testing.TestingT, outputs map[string]interface{}) bool {
+	markHelper(t)
+	violations := NamingOutputViolations(outputs)
+	if len(violations) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("naming module outputs break Azure naming rules:\n  %s",
+		strings.Join(violations, "\n  ")))
+}
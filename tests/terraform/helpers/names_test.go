@@ -0,0 +1,134 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - AZURE NAMING RULE TESTS
+// =============================================================================
+//
+// Tests for the Azure naming rules applied to planned resource names. The
+// plan in testdata/plan_names.json has one resource per kind of violation.
+//
+// Run with: go test -v -run TestNameRule ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNameRuleCheck tests length, character, edge and hyphen checks
+func TestNameRuleCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		resourceType string
+		name         string
+		want         []string
+	}{
+		{"azurerm_storage_account", "stcontosodevbrs", nil},
+		{"azurerm_storage_account", "st", []string{"is 2 characters, fewer than 3"}},
+		{"azurerm_storage_account", "st-Contoso", []string{`may only contain [a-z0-9], not '-', 'C'`}},
+		{"azurerm_key_vault", "kv-contoso-dev-brs", nil},
+		{"azurerm_key_vault", "1kv--contoso-", []string{"must start with [a-zA-Z]", "must end with [a-zA-Z0-9]", "must not contain consecutive hyphens"}},
+		{"azurerm_key_vault", "kv-contoso-production-brs", []string{"is 25 characters, more than 24"}},
+		{"azurerm_resource_group", "rg-contoso-(dev)", nil},
+		{"azurerm_resource_group", "rg-são-paulo.", []string{"must end with [\\p{L}\\p{N}_()-]"}},
+		{"azurerm_monitor_action_group", "ag: contoso.", []string{"must not contain ':'", "must not end with '.'"}},
+		{"azurerm_kubernetes_cluster_node_pool", "user01", nil},
+		{"azurerm_kubernetes_cluster_node_pool", "1user", []string{"must start with [a-z]"}},
+		{"azurerm_subnet", "snet-aks_", nil},
+	}
+	for _, tc := range testCases {
+		rule, ok := NameRules[tc.resourceType]
+		require.True(t, ok, tc.resourceType)
+		assert.Equal(t, tc.want, rule.Check(tc.name), "%s %q", tc.resourceType, tc.name)
+	}
+
+	for resourceType, rule := range NameRules {
+		assert.NotPanics(t, func() { rule.Check("a.b-c") }, resourceType)
+		assert.LessOrEqual(t, rule.Min, rule.Max, resourceType)
+	}
+}
+
+// TestNameRuleViolations tests checking every planned name and unique names
+func TestNameRuleViolations(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "plan_names.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`module.aks.azurerm_kubernetes_cluster_node_pool.user: name "usernodepool01" is 14 characters, more than 12`,
+		`module.networking.azurerm_subnet.subnets["db"]: name "snet-db." must end with [a-zA-Z0-9_]`,
+		`module.observability.azurerm_monitor_action_group.main: name "ag: contoso." must not contain ':'; must not end with '.'`,
+		`module.security.azurerm_key_vault.main: name "kv-contoso-production-b-" must end with [a-zA-Z0-9]`,
+		`module.storage.azurerm_storage_account.logs: name "stContoso_logs" may only contain [a-z0-9], not 'C', '_'`,
+		`module.backup.azurerm_storage_account.main, module.storage.azurerm_storage_account.audit: name "stcontosologs" is planned 2 times, but azurerm_storage_account names must be unique across Azure`,
+		`module.networking.azurerm_network_security_group.aks, module.networking.azurerm_network_security_group.app: name "nsg-app" is planned 2 times, but azurerm_network_security_group names must be unique within resource group rg-contoso`,
+	}, NameViolations(plan))
+
+	rec := &recordingT{}
+	assert.False(t, AssertResourceNames(rec, plan))
+	assert.Contains(t, rec.output(), "planned resource names break Azure naming rules:")
+	assert.Contains(t, rec.output(), `module.security.azurerm_key_vault.main: name "kv-contoso-production-b-"`)
+}
+
+// TestNameRuleNamingOutputs tests checking naming module outputs by resource type
+func TestNameRuleNamingOutputs(t *testing.T) {
+	t.Parallel()
+
+	outputs := map[string]interface{}{}
+	for output := range NamingOutputTypes {
+		outputs[output] = "valid01"
+	}
+	outputs["private_dns_zone"] = "privatelink.azurecr.io"
+	outputs["key_vault"] = "kv-azr-compliance-prd-b-"
+	delete(outputs, "grafana")
+
+	assert.Equal(t, []string{
+		"output.grafana: not a string: <nil>",
+		`output.key_vault: azurerm_key_vault name "kv-azr-compliance-prd-b-" must end with [a-zA-Z0-9]`,
+	}, NamingOutputViolations(outputs))
+
+	for output, resourceType := range NamingOutputTypes {
+		_, ok := NameRules[resourceType]
+		assert.True(t, ok, "%s maps to %s, which has no rule", output, resourceType)
+	}
+}
+
+// TestNameRulesCoverPlatform tests that every azurerm resource type under terraform/ has a naming rule
+func TestNameRulesCoverPlatform(t *testing.T) {
+	t.Parallel()
+
+	root, err := RepoRootE()
+	require.NoError(t, err)
+
+	resourceBlock := regexp.MustCompile(`(?m)^resource "(azurerm_[a-z0-9_]+)"`)
+	found := map[string]bool{}
+	err = filepath.WalkDir(filepath.Join(root, "terraform"), func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".tf" {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range resourceBlock.FindAllStringSubmatch(string(src), -1) {
+			found[match[1]] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, found)
+
+	for _, resourceType := range sortedStrings(found) {
+		_, hasRule := NameRules[resourceType]
+		_, unnamed := UnnamedResourceTypes[resourceType]
+		assert.True(t, hasRule != unnamed, "%s needs an entry in exactly one of NameRules and UnnamedResourceTypes", resourceType)
+	}
+}
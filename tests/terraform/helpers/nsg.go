@@ -0,0 +1,471 @@
+package helpers
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+// NSGRule is a network security rule as Azure evaluates it, whether it is a
+// security_rule block of an azurerm_network_security_group or an
+// azurerm_network_security_rule of its own. Empty lists match anything.
+type NSGRule struct {
+	// Address is the resource that declares the rule.
+	Address string `json:"address"`
+
+	// NSG is the name of the network security group the rule belongs to.
+	NSG string `json:"nsg"`
+
+	Name      string `json:"name"`
+	Priority  int    `json:"priority"`
+	Direction string `json:"direction"`
+	Access    string `json:"access"`
+	Protocol  string `json:"protocol"`
+
+	// Sources and Destinations are address prefixes, service tags such as
+	// Internet, or application security group IDs.
+	Sources      []string `json:"sources"`
+	Destinations []string `json:"destinations"`
+
+	// SourcePorts and DestinationPorts are ports or ranges such as 80-443,
+	// or *.
+	SourcePorts      []string `json:"source_ports"`
+	DestinationPorts []string `json:"destination_ports"`
+}
+
+// String names the rule, e.g.
+// "nsg-aks-nodes-contoso-dev/AllowVNetInbound (priority 100, Inbound Allow)".
+func (r NSGRule) String() string {
+	return fmt.Sprintf("%s/%s (priority %d, %s %s)", r.NSG, r.Name, r.Priority, r.Direction, r.Access)
+}
+
+// NSGFindingKind is a kind of risky network security rule.
+type NSGFindingKind string
+
+// Kinds of NSGFinding.
+const (
+	// NSGExposedManagementPort is an inbound allow from any address or the
+	// Internet to one of ManagementPorts.
+	NSGExposedManagementPort NSGFindingKind = "exposed-management-port"
+
+	// NSGAnyAnyAllow is an allow of every protocol and port from any source
+	// to any destination.
+	NSGAnyAnyAllow NSGFindingKind = "any-any-allow"
+
+	// NSGShadowedDeny is a deny that never applies, because an allow with a
+	// lower priority number matches all the traffic it would deny.
+	NSGShadowedDeny NSGFindingKind = "shadowed-deny"
+)
+
+// Severities of NSGFinding.
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+)
+
+// NSGFinding is one risky network security rule.
+type NSGFinding struct {
+	Kind     NSGFindingKind `json:"kind"`
+	Severity string         `json:"severity"`
+	Rule     NSGRule        `json:"rule"`
+	Message  string         `json:"message"`
+}
+
+// String formats the finding for test failures and reports.
+func (f NSGFinding) String() string {
+	return fmt.Sprintf("[%s] %s: %s: %s", f.Severity, f.Rule.Address, f.Rule, f.Message)
+}
+
+// ManagementPorts are the ports an NSG must not open to the Internet, by
+// the service that listens on them.
+var ManagementPorts = map[int]string{
+	22:    "SSH",
+	23:    "Telnet",
+	445:   "SMB",
+	3389:  "RDP",
+	5985:  "WinRM",
+	5986:  "WinRM over HTTPS",
+	10250: "kubelet",
+}
+
+// anySources are the source prefixes that include the whole Internet.
+var anySources = map[string]bool{
+	"*":         true,
+	"any":       true,
+	"internet":  true,
+	"0.0.0.0/0": true,
+	"::/0":      true,
+}
+
+// PlannedNSGRules returns the network security rules of a plan, sorted by
+// security group, direction and priority. Resources planned for deletion
+// and rules with values only known after apply are skipped.
+func PlannedNSGRules(plan *Plan) []NSGRule {
+	var rules []NSGRule
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		if change.Mode != "managed" {
+			continue
+		}
+		if action := change.Action(); action == ActionDelete || action == ActionRead {
+			continue
+		}
+
+		switch change.Type {
+		case "azurerm_network_security_group":
+			name, _ := change.After("name")
+			blocks, _ := change.After("security_rule")
+			list, _ := blocks.([]interface{})
+			for i := range list {
+				if rule, ok := readNSGRule(change, fmt.Sprintf("security_rule.%d.", i)); ok {
+					rule.Address = address
+					rule.NSG = fmt.Sprint(name)
+					rules = append(rules, rule)
+				}
+			}
+		case "azurerm_network_security_rule":
+			if rule, ok := readNSGRule(change, ""); ok {
+				nsg, _ := change.After("network_security_group_name")
+				rule.Address = address
+				rule.NSG = fmt.Sprint(nsg)
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.NSG != b.NSG {
+			return a.NSG < b.NSG
+		}
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		return a.Priority < b.Priority
+	})
+	return rules
+}
+
+// readNSGRule reads the rule attributes under prefix. The singular and
+// plural forms of each attribute are merged.
+func readNSGRule(change *ResourceChange, prefix string) (NSGRule, bool) {
+	for _, attr := range []string{"name", "priority", "direction", "access", "protocol"} {
+		if change.IsUnknown(prefix + attr) {
+			return NSGRule{}, false
+		}
+	}
+	name, _ := change.After(prefix + "name")
+	priority, _ := change.After(prefix + "priority")
+	direction, _ := change.After(prefix + "direction")
+	access, _ := change.After(prefix + "access")
+	protocol, _ := change.After(prefix + "protocol")
+	number, ok := priority.(float64)
+	if !ok {
+		return NSGRule{}, false
+	}
+
+	rule := NSGRule{
+		Name:      fmt.Sprint(name),
+		Priority:  int(number),
+		Direction: fmt.Sprint(direction),
+		Access:    fmt.Sprint(access),
+		Protocol:  fmt.Sprint(protocol),
+	}
+	for _, field := range []struct {
+		values               *[]string
+		single, plural, asgs string
+	}{
+		{&rule.Sources, "source_address_prefix", "source_address_prefixes", "source_application_security_group_ids"},
+		{&rule.Destinations, "destination_address_prefix", "destination_address_prefixes", "destination_application_security_group_ids"},
+		{&rule.SourcePorts, "source_port_range", "source_port_ranges", ""},
+		{&rule.DestinationPorts, "destination_port_range", "destination_port_ranges", ""},
+	} {
+		for _, attr := range []string{field.single, field.plural, field.asgs} {
+			if attr == "" {
+				continue
+			}
+			if change.IsUnknown(prefix + attr) {
+				return NSGRule{}, false
+			}
+			value, _ := change.After(prefix + attr)
+			switch typed := value.(type) {
+			case string:
+				if typed != "" {
+					*field.values = append(*field.values, typed)
+				}
+			case []interface{}:
+				for _, item := range typed {
+					if text, ok := item.(string); ok && text != "" {
+						*field.values = append(*field.values, text)
+					}
+				}
+			}
+		}
+	}
+	return rule, true
+}
+
+// NSGFindings checks the network security rules of the plans: inbound
+// allows from any address or the Internet to ManagementPorts, allows of
+// every protocol and port between any addresses, and denies an earlier
+// allow shadows. A deny counts as shadowed when a single allow of the same
+// security group and direction with a lower priority number matches all of
+// its traffic; service tags only match themselves and *. Findings are
+// sorted by severity, then by rule.
+func NSGFindings(plans ...*Plan) []NSGFinding {
+	var findings []NSGFinding
+	for _, plan := range plans {
+		rules := PlannedNSGRules(plan)
+		for i, rule := range rules {
+			if !strings.EqualFold(rule.Access, "Allow") {
+				if shadow, ok := shadowingAllow(rule, rules[:i]); ok {
+					findings = append(findings, NSGFinding{
+						Kind:     NSGShadowedDeny,
+						Severity: SeverityMedium,
+						Rule:     rule,
+						Message:  fmt.Sprintf("never applies: %s/%s at priority %d allows all the traffic it denies", shadow.NSG, shadow.Name, shadow.Priority),
+					})
+				}
+				continue
+			}
+
+			inbound := strings.EqualFold(rule.Direction, "Inbound")
+			if inbound && matchesAnySource(rule.Sources) && !strings.EqualFold(rule.Protocol, "Icmp") {
+				if exposed := exposedManagementPorts(rule.DestinationPorts); len(exposed) > 0 {
+					findings = append(findings, NSGFinding{
+						Kind:     NSGExposedManagementPort,
+						Severity: SeverityHigh,
+						Rule:     rule,
+						Message:  fmt.Sprintf("allows %s from %s", strings.Join(exposed, ", "), strings.Join(rule.Sources, ", ")),
+					})
+				}
+			}
+
+			if isWildcard(rule.Protocol) && matchesAny(rule.Sources) && matchesAny(rule.Destinations) &&
+				matchesAny(rule.SourcePorts) && matchesAny(rule.DestinationPorts) {
+				severity := SeverityMedium
+				if inbound {
+					severity = SeverityHigh
+				}
+				findings = append(findings, NSGFinding{
+					Kind:     NSGAnyAnyAllow,
+					Severity: severity,
+					Rule:     rule,
+					Message:  fmt.Sprintf("allows every protocol and port from any source to any destination, %s", strings.ToLower(rule.Direction)),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity == SeverityHigh
+		}
+		return findings[i].String() < findings[j].String()
+	})
+	return findings
+}
+
+// FormatNSGFindings renders findings as text, one line each.
+func FormatNSGFindings(findings []NSGFinding) string {
+	if len(findings) == 0 {
+		return "no risky network security rules\n"
+	}
+	var b strings.Builder
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "%s\n", finding)
+	}
+	return b.String()
+}
+
+// AssertNSGRules checks the network security rules of the plans and fails
+// the test listing every finding; see NSGFindings.
+func AssertNSGRules(t testing.TestingT, plans ...*Plan) bool {
+	markHelper(t)
+	findings := NSGFindings(plans...)
+	if len(findings) == 0 {
+		return true
+	}
+	lines := make([]string, len(findings))
+	for i, finding := range findings {
+		lines[i] = finding.String()
+	}
+	return assert.Fail(t, fmt.Sprintf("%d risky network security rule(s):\n  - %s",
+		len(findings), strings.Join(lines, "\n  - ")))
+}
+
+// shadowingAllow returns the first earlier allow of the same security group
+// and direction that matches all the traffic of rule.
+func shadowingAllow(rule NSGRule, earlier []NSGRule) (NSGRule, bool) {
+	for _, other := range earlier {
+		if other.NSG != rule.NSG || !strings.EqualFold(other.Direction, rule.Direction) ||
+			!strings.EqualFold(other.Access, "Allow") || other.Priority >= rule.Priority {
+			continue
+		}
+		if (isWildcard(other.Protocol) || strings.EqualFold(other.Protocol, rule.Protocol)) &&
+			prefixesCover(other.Sources, rule.Sources) && prefixesCover(other.Destinations, rule.Destinations) &&
+			portsCover(other.SourcePorts, rule.SourcePorts) && portsCover(other.DestinationPorts, rule.DestinationPorts) {
+			return other, true
+		}
+	}
+	return NSGRule{}, false
+}
+
+// isWildcard reports whether a rule value matches anything.
+func isWildcard(value string) bool {
+	return value == "*" || strings.EqualFold(value, "Any")
+}
+
+// matchesAny reports whether a list of prefixes or ports matches anything.
+func matchesAny(values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if isWildcard(value) || value == "0.0.0.0/0" || value == "0-65535" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnySource reports whether sources include the whole Internet.
+func matchesAnySource(sources []string) bool {
+	if len(sources) == 0 {
+		return true
+	}
+	for _, source := range sources {
+		if anySources[strings.ToLower(source)] {
+			return true
+		}
+	}
+	return false
+}
+
+// exposedManagementPorts returns the ManagementPorts the port ranges open,
+// e.g. "SSH (22)", in port order.
+func exposedManagementPorts(ranges []string) []string {
+	var ports []int
+	for port := range ManagementPorts {
+		for _, text := range ranges {
+			if low, high, ok := parsePortRange(text); ok && low <= port && port <= high {
+				ports = append(ports, port)
+				break
+			}
+		}
+	}
+	sort.Ints(ports)
+	exposed := make([]string, len(ports))
+	for i, port := range ports {
+		exposed[i] = fmt.Sprintf("%s (%d)", ManagementPorts[port], port)
+	}
+	return exposed
+}
+
+// parsePortRange reads *, a port or a range such as 80-443.
+func parsePortRange(text string) (int, int, bool) {
+	if isWildcard(text) {
+		return 0, 65535, true
+	}
+	lowText, highText, isRange := strings.Cut(text, "-")
+	low, err := strconv.Atoi(strings.TrimSpace(lowText))
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return low, low, true
+	}
+	high, err := strconv.Atoi(strings.TrimSpace(highText))
+	if err != nil {
+		return 0, 0, false
+	}
+	return low, high, true
+}
+
+// portsCover reports whether every range of inner lies within a range of
+// outer.
+func portsCover(outer, inner []string) bool {
+	if matchesAny(outer) {
+		return true
+	}
+	if matchesAny(inner) {
+		return false
+	}
+	for _, in := range inner {
+		inLow, inHigh, ok := parsePortRange(in)
+		if !ok {
+			return false
+		}
+		covered := false
+		for _, out := range outer {
+			if outLow, outHigh, ok := parsePortRange(out); ok && outLow <= inLow && inHigh <= outHigh {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixesCover reports whether every prefix of inner lies within a prefix
+// of outer. Service tags and application security groups only cover
+// themselves.
+func prefixesCover(outer, inner []string) bool {
+	if matchesAny(outer) {
+		return true
+	}
+	if matchesAny(inner) {
+		return false
+	}
+	for _, in := range inner {
+		covered := false
+		for _, out := range outer {
+			if prefixCovers(out, in) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixCovers reports whether the prefix, address or tag inner lies within
+// outer.
+func prefixCovers(outer, inner string) bool {
+	if strings.EqualFold(outer, inner) {
+		return true
+	}
+	outerPrefix, err := parseAddressPrefix(outer)
+	if err != nil {
+		return false
+	}
+	innerPrefix, err := parseAddressPrefix(inner)
+	if err != nil {
+		return false
+	}
+	return outerPrefix.Bits() <= innerPrefix.Bits() && outerPrefix.Contains(innerPrefix.Addr())
+}
+
+// parseAddressPrefix reads a CIDR block or a single address.
+func parseAddressPrefix(text string) (netip.Prefix, error) {
+	if !strings.Contains(text, "/") {
+		addr, err := netip.ParseAddr(text)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(text)
+	return prefix.Masked(), err
+}
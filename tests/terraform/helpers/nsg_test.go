@@ -0,0 +1,98 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - NSG RULE TESTS
+// =============================================================================
+//
+// Tests for the risk checks on planned network security rules.
+// testdata/nsg/plan_nsg.json has inline and standalone rules: SSH and RDP
+// open to the Internet, an any-any outbound allow, and two denies that allows
+// with lower priority numbers make unreachable.
+//
+// Run with: go test -v -run TestNSG ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNSGRules tests the rules read from inline and standalone resources
+func TestNSGRules(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "nsg", "plan_nsg.json"))
+	require.NoError(t, err)
+	rules := PlannedNSGRules(plan)
+
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.String()
+	}
+	assert.Equal(t, []string{
+		"nsg-app/AllowSSH (priority 100, Inbound Allow)",
+		"nsg-app/AllowVNetInbound (priority 110, Inbound Allow)",
+		"nsg-app/AllowRDPRange (priority 150, Inbound Allow)",
+		"nsg-app/DenyVNetAdmin (priority 300, Inbound Deny)",
+		"nsg-app/DenyAllInbound (priority 4096, Inbound Deny)",
+		"nsg-app/AllowAllOutbound (priority 200, Outbound Allow)",
+		"nsg-corp/AllowCorpHTTPS (priority 100, Inbound Allow)",
+		"nsg-corp/DenyBranchHTTPS (priority 200, Inbound Deny)",
+		"nsg-corp/DenyCorpWeb (priority 210, Inbound Deny)",
+		"nsg-corp/AllowHTTPS (priority 300, Inbound Allow)",
+	}, names, "the unknown AllowPending and the deleted nsg-old are skipped")
+
+	rdp := rules[2]
+	assert.Equal(t, "azurerm_network_security_rule.rdp", rdp.Address)
+	assert.Equal(t, []string{"Internet"}, rdp.Sources)
+	assert.Equal(t, []string{"*"}, rdp.Destinations)
+	assert.Equal(t, []string{"3380-3390", "443"}, rdp.DestinationPorts)
+}
+
+// TestNSGFindings tests the findings for a plan
+func TestNSGFindings(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "nsg", "plan_nsg.json"))
+	require.NoError(t, err)
+	findings := NSGFindings(plan)
+
+	lines := make([]string, len(findings))
+	for i, finding := range findings {
+		lines[i] = finding.String()
+	}
+	assert.Equal(t, []string{
+		"[high] azurerm_network_security_group.app: nsg-app/AllowSSH (priority 100, Inbound Allow): allows SSH (22) from *",
+		"[high] azurerm_network_security_rule.rdp: nsg-app/AllowRDPRange (priority 150, Inbound Allow): allows RDP (3389) from Internet",
+		"[medium] azurerm_network_security_group.app: nsg-app/AllowAllOutbound (priority 200, Outbound Allow): allows every protocol and port from any source to any destination, outbound",
+		"[medium] azurerm_network_security_group.app: nsg-app/DenyVNetAdmin (priority 300, Inbound Deny): never applies: nsg-app/AllowVNetInbound at priority 110 allows all the traffic it denies",
+		"[medium] azurerm_network_security_group.corp: nsg-corp/DenyBranchHTTPS (priority 200, Inbound Deny): never applies: nsg-corp/AllowCorpHTTPS at priority 100 allows all the traffic it denies",
+	}, lines)
+	assert.Equal(t, NSGExposedManagementPort, findings[0].Kind)
+	assert.Equal(t, NSGAnyAnyAllow, findings[2].Kind)
+	assert.Equal(t, NSGShadowedDeny, findings[3].Kind)
+}
+
+// TestNSGCoverage tests when one rule's prefixes and ports cover another's
+func TestNSGCoverage(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, prefixesCover([]string{"10.0.0.0/8"}, []string{"10.1.2.0/24", "10.3.0.4"}))
+	assert.True(t, prefixesCover(nil, []string{"Internet"}))
+	assert.True(t, prefixesCover([]string{"virtualnetwork"}, []string{"VirtualNetwork"}))
+	assert.False(t, prefixesCover([]string{"10.0.0.0/16"}, []string{"10.0.0.0/8"}))
+	assert.False(t, prefixesCover([]string{"VirtualNetwork"}, []string{"10.0.0.0/24"}))
+	assert.False(t, prefixesCover([]string{"10.0.0.0/8"}, []string{"*"}))
+
+	assert.True(t, portsCover([]string{"80-443"}, []string{"80", "100-200"}))
+	assert.True(t, portsCover([]string{"*"}, []string{"22"}))
+	assert.False(t, portsCover([]string{"80", "443"}, []string{"80-443"}))
+	assert.False(t, portsCover([]string{"22"}, nil))
+
+	assert.Equal(t, []string{"SSH (22)", "Telnet (23)"}, exposedManagementPorts([]string{"20-25", "80"}))
+	assert.Len(t, exposedManagementPorts([]string{"*"}), len(ManagementPorts))
+}
@@ -0,0 +1,498 @@
+package helpers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PluginDirEnv names a directory of pre-installed provider plugins. When it is
+// set, offline tests run `terraform init -plugin-dir` against it, so Init
+// never reaches the registry.
+const PluginDirEnv = "TERRATEST_PLUGIN_DIR"
+
+const (
+	// offlineTestFile is the test file written into the module copy. Terraform
+	// only supports mock providers inside `terraform test`.
+	offlineTestFile = "offline.tftest.hcl"
+
+	// offlineRunName is the single plan run in offlineTestFile.
+	offlineRunName = "offline_plan"
+
+	// offlineApplyRunName is the single apply run in the test file written by
+	// OfflineStateOptions.
+	offlineApplyRunName = "offline_apply"
+)
+
+// providerSchema selects the blocks that name the providers a configuration
+// uses, and the module calls whose providers it inherits.
+var providerSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+	},
+}
+
+var moduleSourceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "source"}},
+}
+
+var requiredProvidersSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+}
+
+// OfflineOptions copies the fixture's module to a temporary directory and adds
+// a test file that mocks every provider the module uses, so that
+// OfflinePlanJSON plans it without credentials or network access. The vars are
+// checked against variables.tf first, as in Options. ARM_* variables are
+// cleared for the Terraform process.
+func (f *Fixture) OfflineOptions(t testing.TestingT) *terraform.Options {
+	require.NoError(t, f.CheckE())
+
+	dir, err := PrepareOfflineE(ModuleDir(t, f.Module))
+	require.NoError(t, err)
+	return f.offlineOptions(t, dir, f.Vars.Clone())
+}
+
+// offlineOptions returns the options for a prepared offline workspace, which
+// is removed when the test finishes.
+func (f *Fixture) offlineOptions(t testing.TestingT, dir string, vars Vars) *terraform.Options {
+	removeOnCleanup(t, filepath.Dir(dir))
+
+	env := pluginCacheEnvVars(t)
+	for name, value := range offlineEnvVars() {
+		env[name] = value
+	}
+
+	options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: dir,
+		Vars:         vars,
+		EnvVars:      env,
+		PluginDir:    os.Getenv(PluginDirEnv),
+		NoColor:      true,
+	})
+	f.registerPolicyWaivers(t, options)
+	return options
+}
+
+// offlineMockData are the values mocked data sources return. Without them
+// Terraform fills computed strings with random characters, which would make
+// every plan that reads a data source different from the last.
+var offlineMockData = map[string]map[string]string{
+	"azurerm_client_config": {
+		"client_id":       FakeObjectID,
+		"object_id":       FakeObjectID,
+		"subscription_id": FakeSubscriptionID,
+		"tenant_id":       FakeTenantID,
+	},
+	"azurerm_kubernetes_cluster": {
+		"id":              KubernetesClusterID("aks-" + DefaultCustomerName + "-" + DefaultEnvironment),
+		"location":        DefaultLocation,
+		"oidc_issuer_url": "https://" + DefaultLocation + ".oic.prod-aks.azure.com/" + FakeTenantID + "/",
+	},
+	"azurerm_resource_group": {
+		"id":       ResourceGroupID(FakeResourceGroup),
+		"location": DefaultLocation,
+	},
+	"azurerm_subscription": {
+		"display_name":    "terratest",
+		"id":              "/subscriptions/" + FakeSubscriptionID,
+		"subscription_id": FakeSubscriptionID,
+		"tenant_id":       FakeTenantID,
+	},
+}
+
+// PrepareOfflineE copies the Terraform configuration in dir to a new workspace
+// and writes a test file with a mock_provider block for every
+// provider the configuration uses. It returns the path of the copy.
+func PrepareOfflineE(dir string) (string, error) {
+	return prepareOfflineE(dir, offlineRunName, "plan")
+}
+
+// prepareOfflineE is PrepareOfflineE with a single run of the given name and
+// command.
+func prepareOfflineE(dir, runName, command string) (string, error) {
+	copyDir, err := WorkspaceE(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := writeOfflineTestE(copyDir, runName, command); err != nil {
+		os.RemoveAll(filepath.Dir(copyDir))
+		return "", err
+	}
+	return copyDir, nil
+}
+
+// writeOfflineTestE writes the test file for the configuration in dir, which
+// is already a workspace copy.
+func writeOfflineTestE(dir, runName, command string) error {
+	usage, err := readModuleUsageE(dir)
+	if err != nil {
+		return err
+	}
+	testFile := filepath.Join(dir, offlineTestFile)
+	return os.WriteFile(testFile, []byte(offlineTestConfig(usage, runName, command)), 0o644)
+}
+
+// offlineTestConfig returns a test file that mocks the providers a module
+// uses, with fixed values for the data sources it reads, and runs the command,
+// plan or apply, once.
+func offlineTestConfig(usage *moduleUsage, runName, command string) string {
+	var b strings.Builder
+	b.WriteString("# Written by the offline test harness. Every provider is mocked, so the\n")
+	b.WriteString("# plan runs without credentials or network access.\n\n")
+	for _, provider := range usage.providers {
+		var mocks []string
+		for _, dataSource := range usage.dataSources {
+			if impliedProvider(dataSource) == provider && offlineMockData[dataSource] != nil {
+				mocks = append(mocks, dataSource)
+			}
+		}
+		if len(mocks) == 0 {
+			fmt.Fprintf(&b, "mock_provider %q {}\n\n", provider)
+			continue
+		}
+
+		fmt.Fprintf(&b, "mock_provider %q {\n", provider)
+		for i, dataSource := range mocks {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "  mock_data %q {\n    defaults = {\n", dataSource)
+			values := offlineMockData[dataSource]
+			for _, name := range sortedStrings(values) {
+				fmt.Fprintf(&b, "      %s = %q\n", name, values[name])
+			}
+			b.WriteString("    }\n  }\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	fmt.Fprintf(&b, "run %q {\n  command = %s\n}\n", runName, command)
+	return b.String()
+}
+
+// ModuleProvidersE returns the local names of the providers a configuration
+// uses, sorted: those declared in required_providers and those implied by
+// resource and data source types.
+func ModuleProvidersE(dir string) ([]string, error) {
+	usage, err := readModuleUsageE(dir)
+	if err != nil {
+		return nil, err
+	}
+	return usage.providers, nil
+}
+
+// moduleUsage is what a configuration needs mocked: its providers and the
+// data source types it reads, each sorted.
+type moduleUsage struct {
+	providers   []string
+	dataSources []string
+}
+
+// readModuleUsageE parses the .tf files in dir for provider requirements and
+// resource and data source blocks, following calls to local modules such as
+// ./modules/aks-cluster from the root configuration.
+func readModuleUsageE(dir string) (*moduleUsage, error) {
+	found := map[string]bool{}
+	dataSources := map[string]bool{}
+	if err := collectModuleUsageE(dir, found, dataSources, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	// terraform_data and friends belong to the built-in provider, which cannot
+	// be mocked and needs no plugin.
+	delete(found, "terraform")
+
+	return &moduleUsage{
+		providers:   sortedStrings(found),
+		dataSources: sortedStrings(dataSources),
+	}, nil
+}
+
+// collectModuleUsageE adds the providers and data source types of the
+// configuration in dir and of the local modules it calls to found and
+// dataSources. Directories in visited are skipped.
+func collectModuleUsageE(dir string, found, dataSources, visited map[string]bool) error {
+	dir = filepath.Clean(dir)
+	if visited[dir] {
+		return nil
+	}
+	visited[dir] = true
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no .tf files in %s", dir)
+	}
+
+	parser := hclparse.NewParser()
+	var calls []string
+	var diags hcl.Diagnostics
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, parseDiags := parser.ParseHCL(src, path)
+		diags = append(diags, parseDiags...)
+		if parseDiags.HasErrors() {
+			continue
+		}
+
+		content, _, contentDiags := file.Body.PartialContent(providerSchema)
+		diags = append(diags, contentDiags...)
+		for _, block := range content.Blocks {
+			if block.Type == "module" {
+				attrs, _, attrDiags := block.Body.PartialContent(moduleSourceSchema)
+				diags = append(diags, attrDiags...)
+				if attr, ok := attrs.Attributes["source"]; ok {
+					source, valDiags := attr.Expr.Value(nil)
+					diags = append(diags, valDiags...)
+					if !valDiags.HasErrors() && source.Type() == cty.String {
+						if path := source.AsString(); strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") {
+							calls = append(calls, filepath.Join(dir, path))
+						}
+					}
+				}
+				continue
+			}
+			if block.Type == "data" {
+				dataSources[block.Labels[0]] = true
+			}
+			if block.Type != "terraform" {
+				found[impliedProvider(block.Labels[0])] = true
+				continue
+			}
+
+			tfContent, _, tfDiags := block.Body.PartialContent(requiredProvidersSchema)
+			diags = append(diags, tfDiags...)
+			for _, required := range tfContent.Blocks {
+				attrs, attrDiags := required.Body.JustAttributes()
+				diags = append(diags, attrDiags...)
+				for name := range attrs {
+					found[name] = true
+				}
+			}
+		}
+	}
+
+	if diags.HasErrors() {
+		return fmt.Errorf("reading providers in %s: %s", dir, diags.Error())
+	}
+
+	for _, call := range calls {
+		if err := collectModuleUsageE(call, found, dataSources, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedStrings returns the keys of a set or string map, sorted.
+func sortedStrings[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// impliedProvider returns the provider local name Terraform infers from a
+// resource type, e.g. azurerm for azurerm_subnet.
+func impliedProvider(resourceType string) string {
+	if i := strings.Index(resourceType, "_"); i > 0 {
+		return resourceType[:i]
+	}
+	return resourceType
+}
+
+// offlineEnvVars blanks every ARM_* variable in the environment, so an
+// offline test cannot silently depend on Azure credentials.
+func offlineEnvVars() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, "ARM_") {
+			env[name] = ""
+		}
+	}
+	return env
+}
+
+// OfflinePlanJSON runs terraform init and the mocked plan prepared by
+// OfflineOptions, returning the plan. This will fail the test if either
+// command fails, the plan violates policies/terraform or a planned name breaks
+// the Azure naming rules; see AssertPolicies and AssertResourceNames.
+func OfflinePlanJSON(t testing.TestingT, options *terraform.Options) *Plan {
+	plan, err := OfflinePlanJSONE(t, options)
+	require.NoError(t, err)
+	AssertPolicies(t, plan, PolicyWaivers(options)...)
+	AssertResourceNames(t, plan)
+	return plan
+}
+
+// OfflinePlanJSONE runs terraform init and `terraform test -json -verbose`
+// against options from OfflineOptions, and reads the plan from the test
+// output. Values that a real provider would compute are unknown in the
+// result, including attributes the provider would default.
+func OfflinePlanJSONE(t testing.TestingT, options *terraform.Options) (*Plan, error) {
+	if _, err := InitE(t, options); err != nil {
+		return nil, err
+	}
+
+	stdout, runErr := runOfflineTestE(t, options)
+	plan, err := ParseOfflineTestOutput([]byte(stdout))
+	if err != nil {
+		return nil, err
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+	return plan, nil
+}
+
+// runOfflineTestE runs the prepared test file in an initialized workspace,
+// returning the JSON output of `terraform test` even when the run fails.
+func runOfflineTestE(t testing.TestingT, options *terraform.Options) (string, error) {
+	return terraform.RunTerraformCommandAndGetStdoutE(t, options,
+		terraform.FormatArgs(options, "test", "-json", "-verbose", "-filter="+offlineTestFile)...)
+}
+
+// offlineMessage is one line of `terraform test -json` output.
+type offlineMessage struct {
+	Type       string          `json:"type"`
+	Run        string          `json:"@testrun"`
+	Plan       json.RawMessage `json:"test_plan"`
+	State      json.RawMessage `json:"test_state"`
+	Diagnostic *struct {
+		Severity string `json:"severity"`
+		Summary  string `json:"summary"`
+		Detail   string `json:"detail"`
+	} `json:"diagnostic"`
+}
+
+// offlinePlan is the plan printed by `terraform test -verbose`. Its resource
+// and output changes have the same shape as `terraform show -json`.
+type offlinePlan struct {
+	FormatVersion   string                    `json:"plan_format_version"`
+	OutputChanges   map[string]*tfjson.Change `json:"output_changes,omitempty"`
+	ResourceChanges []*tfjson.ResourceChange  `json:"resource_changes,omitempty"`
+}
+
+// offlineState is the state printed by `terraform test -verbose` after an
+// apply run. Its modules have the same shape as `terraform show -json`.
+type offlineState struct {
+	FormatVersion string              `json:"state_format_version"`
+	RootModule    *tfjson.StateModule `json:"root_module"`
+}
+
+// ParseOfflineTestOutput reads the plan of the offline run from the output of
+// `terraform test -json -verbose`. Error diagnostics are returned as an error
+// even when a plan was printed.
+func ParseOfflineTestOutput(output []byte) (*Plan, error) {
+	planJSON, _, err := scanOfflineTestOutput(output, offlineRunName)
+	if err != nil {
+		return nil, err
+	}
+	if planJSON == nil {
+		return nil, errors.New("terraform test printed no plan; was the module prepared with OfflineOptions?")
+	}
+
+	var testPlan offlinePlan
+	if err := json.Unmarshal(planJSON, &testPlan); err != nil {
+		return nil, fmt.Errorf("parsing test plan: %w", err)
+	}
+	showJSON, err := json.Marshal(tfjson.Plan{
+		FormatVersion:   testPlan.FormatVersion,
+		ResourceChanges: testPlan.ResourceChanges,
+		OutputChanges:   testPlan.OutputChanges,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlan(showJSON)
+}
+
+// ParseOfflineTestState reads the state left by the offline apply run from the
+// output of `terraform test -json -verbose`, in the format of
+// `terraform show -json`. Error diagnostics are returned as an error.
+func ParseOfflineTestState(output []byte) (*tfjson.State, error) {
+	_, stateJSON, err := scanOfflineTestOutput(output, offlineApplyRunName)
+	if err != nil {
+		return nil, err
+	}
+	if stateJSON == nil {
+		return nil, errors.New("terraform test printed no state; was the module prepared with OfflineStateOptions?")
+	}
+
+	var testState offlineState
+	if err := json.Unmarshal(stateJSON, &testState); err != nil {
+		return nil, fmt.Errorf("parsing test state: %w", err)
+	}
+	return &tfjson.State{
+		FormatVersion: testState.FormatVersion,
+		Values:        &tfjson.StateValues{RootModule: testState.RootModule},
+	}, nil
+}
+
+// scanOfflineTestOutput returns the plan and state printed for a run, each nil
+// if there is none, and collects error diagnostics into an error.
+func scanOfflineTestOutput(output []byte, runName string) (plan, state json.RawMessage, err error) {
+	var problems []string
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var msg offlineMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+
+		switch {
+		case msg.Type == "test_plan" && msg.Run == runName:
+			plan = msg.Plan
+		case msg.Type == "test_state" && msg.Run == runName:
+			state = msg.State
+		case msg.Type == "diagnostic" && msg.Diagnostic != nil && msg.Diagnostic.Severity == "error":
+			problem := msg.Diagnostic.Summary
+			if msg.Diagnostic.Detail != "" {
+				problem += ": " + msg.Diagnostic.Detail
+			}
+			problems = append(problems, problem)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("offline %s failed:\n  - %s", runCommand(runName), strings.Join(problems, "\n  - "))
+	}
+	return plan, state, nil
+}
+
+// runCommand names the command of an offline run for error messages.
+func runCommand(runName string) string {
+	if runName == offlineApplyRunName {
+		return "apply"
+	}
+	return "plan"
+}
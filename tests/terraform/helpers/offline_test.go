@@ -0,0 +1,111 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - OFFLINE HARNESS TESTS
+// =============================================================================
+//
+// Tests for the mocked-provider harness, using recorded `terraform test -json
+// -verbose` output. These run without Terraform or Azure credentials.
+//
+// Run with: go test -v -run 'TestOffline|TestModuleProviders' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModuleProviders tests finding declared and implied providers
+func TestModuleProviders(t *testing.T) {
+	t.Parallel()
+
+	providers, err := ModuleProvidersE(filepath.Join("testdata", "offline"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"azurerm", "random"}, providers)
+
+	providers, err = ModuleProvidersE(ModuleDir(t, "naming"))
+	require.NoError(t, err)
+	assert.Empty(t, providers)
+
+	providers, err = ModuleProvidersE(ModuleDir(t, "argocd"))
+	require.NoError(t, err)
+	assert.Contains(t, providers, "kubectl")
+	assert.Contains(t, providers, "helm")
+
+	// time is only used by a module the configuration calls
+	providers, err = ModuleProvidersE(filepath.Join("testdata", "wiring"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"random", "time"}, providers)
+}
+
+// TestOfflinePrepare tests that the module copy gets the mock test file
+func TestOfflinePrepare(t *testing.T) {
+	t.Parallel()
+
+	dir, err := PrepareOfflineE(filepath.Join("testdata", "offline"))
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Dir(dir))
+
+	assert.FileExists(t, filepath.Join(dir, "main.tf"))
+
+	testFile, err := os.ReadFile(filepath.Join(dir, offlineTestFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(testFile), "mock_provider \"azurerm\" {\n  mock_data \"azurerm_client_config\" {\n    defaults = {\n")
+	assert.Contains(t, string(testFile), `      tenant_id = "`+FakeTenantID+`"`)
+	assert.Contains(t, string(testFile), `mock_provider "random" {}`)
+	assert.Contains(t, string(testFile), "run \"offline_plan\" {\n  command = plan\n}")
+
+	_, err = os.Stat(filepath.Join("testdata", "offline", offlineTestFile))
+	assert.True(t, os.IsNotExist(err), "the source module must not be modified")
+}
+
+// TestOfflineOptionsClearCredentials tests that ARM_* variables are blanked
+func TestOfflineOptionsClearCredentials(t *testing.T) {
+	t.Setenv("ARM_CLIENT_ID", "00000000-0000-0000-0000-000000000001")
+	t.Setenv(PluginDirEnv, "/opt/terraform/plugins")
+
+	options := Naming().OfflineOptions(t)
+
+	assert.Equal(t, "", options.EnvVars["ARM_CLIENT_ID"])
+	assert.Equal(t, "/opt/terraform/plugins", options.PluginDir)
+	assert.NotEqual(t, ModuleDir(t, "naming"), options.TerraformDir)
+	assert.FileExists(t, filepath.Join(options.TerraformDir, offlineTestFile))
+}
+
+// TestOfflineParsePlan tests reading the plan from terraform test output
+func TestOfflineParsePlan(t *testing.T) {
+	t.Parallel()
+
+	output, err := os.ReadFile(filepath.Join("testdata", "offline_plan.jsonl"))
+	require.NoError(t, err)
+
+	plan, err := ParseOfflineTestOutput(output)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"terraform_data.main"}, plan.Addresses())
+	assert.True(t, AssertCreated(t, plan, "terraform_data.main"))
+	assert.True(t, AssertAttribute(t, plan, "terraform_data.main", "input", "terratest"))
+	assert.True(t, RequireChange(t, plan, "terraform_data.main").IsUnknown("output"))
+	assert.Contains(t, plan.RawPlan.OutputChanges, "name")
+}
+
+// TestOfflineParseErrors tests that diagnostics are reported as errors
+func TestOfflineParseErrors(t *testing.T) {
+	t.Parallel()
+
+	output, err := os.ReadFile(filepath.Join("testdata", "offline_plan_error.jsonl"))
+	require.NoError(t, err)
+
+	_, err = ParseOfflineTestOutput(output)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid value for variable: name must be 10 characters or less.")
+
+	_, err = ParseOfflineTestOutput([]byte(`{"type":"test_summary"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "printed no plan")
+}
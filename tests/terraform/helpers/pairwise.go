@@ -0,0 +1,219 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Factor is one input of a combinatorial test and the values it takes.
+type Factor struct {
+	Name   string
+	Values []interface{}
+}
+
+// BoolFactor returns a factor that takes false and true.
+func BoolFactor(name string) Factor {
+	return Factor{Name: name, Values: []interface{}{false, true}}
+}
+
+// Pairwise returns combinations of the factors' values in which every pair of
+// values of any two factors appears at least once, except the excluded pairs.
+// This will fail the test if the factors are invalid; see PairwiseE.
+func Pairwise(t testing.TestingT, factors []Factor, excluded ...Vars) []Vars {
+	combinations, err := PairwiseE(factors, excluded...)
+	require.NoError(t, err)
+	return combinations
+}
+
+// PairwiseE returns an all-pairs covering of the factors: combinations that
+// set every factor, in which each pair of values of any two factors appears
+// at least once. A bug that needs two settings to show up is caught by
+// far fewer plans than the full product: the root module's three modes and
+// eleven flags take 12 combinations instead of 6144.
+//
+// Combinations are built greedily and deterministically: each starts from
+// the first pair still uncovered and picks, factor by factor, the value
+// that covers the most new pairs, preferring earlier values on ties. The
+// same factors always give the same combinations, so a failing combination
+// keeps its index between runs.
+//
+// Each excluded entry sets two factors to values that cannot be combined,
+// such as a flag that needs another flag enabled. No combination contains an
+// excluded pair, and the pair is not counted as uncovered.
+func PairwiseE(factors []Factor, excluded ...Vars) ([]Vars, error) {
+	if len(factors) == 0 {
+		return nil, fmt.Errorf("no factors to combine")
+	}
+	seen := map[string]bool{}
+	for _, factor := range factors {
+		if len(factor.Values) == 0 {
+			return nil, fmt.Errorf("factor %q has no values", factor.Name)
+		}
+		if seen[factor.Name] {
+			return nil, fmt.Errorf("factor %q is listed twice", factor.Name)
+		}
+		seen[factor.Name] = true
+	}
+
+	if len(factors) == 1 {
+		combinations := make([]Vars, 0, len(factors[0].Values))
+		for _, value := range factors[0].Values {
+			combinations = append(combinations, Vars{factors[0].Name: value})
+		}
+		return combinations, nil
+	}
+
+	uncovered := newPairSet(factors)
+	invalid := map[valuePair]bool{}
+	for _, pair := range excluded {
+		key, err := excludedPair(factors, pair)
+		if err != nil {
+			return nil, err
+		}
+		invalid[key] = true
+		uncovered.remove(key.i, key.vi, key.j, key.vj)
+	}
+
+	var combinations []Vars
+	for uncovered.remaining > 0 {
+		// row holds the chosen value index per factor, -1 while unset.
+		row := make([]int, len(factors))
+		for i := range row {
+			row[i] = -1
+		}
+		seed := uncovered.first()
+		row[seed.i], row[seed.j] = seed.vi, seed.vj
+
+		for f := range factors {
+			if row[f] >= 0 {
+				continue
+			}
+			best, bestGain := -1, -1
+		values:
+			for v := range factors[f].Values {
+				gain := 0
+				for other, value := range row {
+					if value < 0 || other == f {
+						continue
+					}
+					if invalid[uncovered.key(f, v, other, value)] {
+						continue values
+					}
+					if uncovered.has(f, v, other, value) {
+						gain++
+					}
+				}
+				if gain > bestGain {
+					best, bestGain = v, gain
+				}
+			}
+			if best < 0 {
+				return nil, fmt.Errorf("every value of factor %q is excluded by the values chosen for the other factors", factors[f].Name)
+			}
+			row[f] = best
+		}
+
+		combination := Vars{}
+		for f, v := range row {
+			combination[factors[f].Name] = factors[f].Values[v]
+			for other := f + 1; other < len(row); other++ {
+				uncovered.remove(f, v, other, row[other])
+			}
+		}
+		combinations = append(combinations, combination)
+	}
+	return combinations, nil
+}
+
+// excludedPair returns the value pair set by an excluded entry, which must
+// name two factors and one of each factor's values.
+func excludedPair(factors []Factor, pair Vars) (valuePair, error) {
+	if len(pair) != 2 {
+		return valuePair{}, fmt.Errorf("excluded pair %v must set exactly two factors", pair)
+	}
+	var indexes []int
+	for f, factor := range factors {
+		value, ok := pair[factor.Name]
+		if !ok {
+			continue
+		}
+		v := -1
+		for i, candidate := range factor.Values {
+			if reflect.DeepEqual(candidate, value) {
+				v = i
+				break
+			}
+		}
+		if v < 0 {
+			return valuePair{}, fmt.Errorf("excluded pair %v: %v is not a value of factor %q", pair, value, factor.Name)
+		}
+		indexes = append(indexes, f, v)
+	}
+	if len(indexes) != 4 {
+		return valuePair{}, fmt.Errorf("excluded pair %v names a factor that is not being combined", pair)
+	}
+	return valuePair{indexes[0], indexes[1], indexes[2], indexes[3]}, nil
+}
+
+// valuePair is value vi of factor i together with value vj of factor j, i < j.
+type valuePair struct {
+	i, vi, j, vj int
+}
+
+// pairSet tracks the value pairs not yet covered by a combination.
+type pairSet struct {
+	pairs     map[valuePair]bool
+	order     []valuePair
+	remaining int
+}
+
+// newPairSet returns every value pair of the factors, in factor then value
+// order.
+func newPairSet(factors []Factor) *pairSet {
+	set := &pairSet{pairs: map[valuePair]bool{}}
+	for i := range factors {
+		for j := i + 1; j < len(factors); j++ {
+			for vi := range factors[i].Values {
+				for vj := range factors[j].Values {
+					pair := valuePair{i, vi, j, vj}
+					set.pairs[pair] = true
+					set.order = append(set.order, pair)
+				}
+			}
+		}
+	}
+	set.remaining = len(set.order)
+	return set
+}
+
+// key orders the two factor values of a pair.
+func (s *pairSet) key(f, v, other, value int) valuePair {
+	if f < other {
+		return valuePair{f, v, other, value}
+	}
+	return valuePair{other, value, f, v}
+}
+
+func (s *pairSet) has(f, v, other, value int) bool {
+	return f != other && s.pairs[s.key(f, v, other, value)]
+}
+
+func (s *pairSet) remove(f, v, other, value int) {
+	key := s.key(f, v, other, value)
+	if s.pairs[key] {
+		s.pairs[key] = false
+		s.remaining--
+	}
+}
+
+// first returns the earliest uncovered pair. It must only be called while
+// pairs remain.
+func (s *pairSet) first() valuePair {
+	for len(s.order) > 0 && !s.pairs[s.order[0]] {
+		s.order = s.order[1:]
+	}
+	return s.order[0]
+}
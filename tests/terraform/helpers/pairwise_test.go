@@ -0,0 +1,137 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - PAIRWISE COMBINATION TESTS
+// =============================================================================
+//
+// Tests for the all-pairs generator behind the root module's feature-flag
+// tests: every pair of values is covered, the output is stable between runs
+// and far smaller than the full product.
+//
+// Run with: go test -v -run Pairwise ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPairwiseCoversEveryPair tests that each pair of values appears in some combination
+func TestPairwiseCoversEveryPair(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		factors []Factor
+		max     int
+	}{
+		{"two flags", []Factor{BoolFactor("a"), BoolFactor("b")}, 4},
+		{"mode and ten flags", append([]Factor{{Name: "mode", Values: []interface{}{"express", "standard", "enterprise"}}}, boolFactors(10)...), 12},
+		{"mixed sizes", []Factor{
+			{Name: "size", Values: []interface{}{"small", "medium", "large", "xlarge"}},
+			{Name: "region", Values: []interface{}{"brazilsouth", "eastus2", "westus2"}},
+			BoolFactor("ha"),
+			BoolFactor("dr"),
+		}, 14},
+	}
+
+	for _, tc := range testCases {
+		combinations, err := PairwiseE(tc.factors)
+		require.NoError(t, err, tc.name)
+		assert.LessOrEqual(t, len(combinations), tc.max, tc.name)
+
+		for _, combination := range combinations {
+			assert.Len(t, combination, len(tc.factors), tc.name)
+		}
+		for i, a := range tc.factors {
+			for _, b := range tc.factors[i+1:] {
+				for _, va := range a.Values {
+					for _, vb := range b.Values {
+						assert.True(t, coversPair(combinations, a.Name, va, b.Name, vb),
+							"%s: no combination has %s=%v and %s=%v", tc.name, a.Name, va, b.Name, vb)
+					}
+				}
+			}
+		}
+
+		again, err := PairwiseE(tc.factors)
+		require.NoError(t, err)
+		assert.Equal(t, combinations, again, "%s: combinations must be deterministic", tc.name)
+	}
+}
+
+// TestPairwiseSingleFactor tests that one factor yields each of its values
+func TestPairwiseSingleFactor(t *testing.T) {
+	t.Parallel()
+
+	combinations, err := PairwiseE([]Factor{BoolFactor("enable_defender")})
+	require.NoError(t, err)
+	assert.Equal(t, []Vars{{"enable_defender": false}, {"enable_defender": true}}, combinations)
+}
+
+// TestPairwiseExcluded tests that excluded pairs appear in no combination while the others stay covered
+func TestPairwiseExcluded(t *testing.T) {
+	t.Parallel()
+
+	factors := append([]Factor{{Name: "mode", Values: []interface{}{"express", "standard", "enterprise"}}}, boolFactors(4)...)
+	excluded := []Vars{
+		{"flag_0": true, "flag_1": false},
+		{"mode": "express", "flag_3": true},
+	}
+	combinations, err := PairwiseE(factors, excluded...)
+	require.NoError(t, err)
+
+	for _, pair := range excluded {
+		var names []string
+		for name := range pair {
+			names = append(names, name)
+		}
+		assert.False(t, coversPair(combinations, names[0], pair[names[0]], names[1], pair[names[1]]), "%v is excluded", pair)
+	}
+	assert.True(t, coversPair(combinations, "flag_0", true, "flag_1", true))
+	assert.True(t, coversPair(combinations, "flag_0", false, "flag_1", false))
+	assert.True(t, coversPair(combinations, "mode", "express", "flag_3", false))
+	assert.True(t, coversPair(combinations, "mode", "enterprise", "flag_3", true))
+
+	_, err = PairwiseE(factors, Vars{"flag_0": true})
+	assert.ErrorContains(t, err, "must set exactly two factors")
+	_, err = PairwiseE(factors, Vars{"flag_0": true, "flag_9": false})
+	assert.ErrorContains(t, err, "names a factor that is not being combined")
+	_, err = PairwiseE(factors, Vars{"flag_0": true, "mode": "premium"})
+	assert.ErrorContains(t, err, `premium is not a value of factor "mode"`)
+}
+
+// TestPairwiseErrors tests that invalid factors are rejected
+func TestPairwiseErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := PairwiseE(nil)
+	assert.ErrorContains(t, err, "no factors to combine")
+	_, err = PairwiseE([]Factor{BoolFactor("a"), {Name: "mode"}})
+	assert.ErrorContains(t, err, `factor "mode" has no values`)
+	_, err = PairwiseE([]Factor{BoolFactor("a"), BoolFactor("a")})
+	assert.ErrorContains(t, err, `factor "a" is listed twice`)
+}
+
+// boolFactors returns n bool factors named flag_0 to flag_<n-1>.
+func boolFactors(n int) []Factor {
+	factors := make([]Factor, n)
+	for i := range factors {
+		factors[i] = BoolFactor(fmt.Sprintf("flag_%d", i))
+	}
+	return factors
+}
+
+// coversPair reports whether some combination sets a to va and b to vb.
+func coversPair(combinations []Vars, a string, va interface{}, b string, vb interface{}) bool {
+	for _, combination := range combinations {
+		if combination[a] == va && combination[b] == vb {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// Action is the single action Terraform plans for a resource instance. A
+// delete followed by a create, in either order, is reported as ActionReplace.
+type Action string
+
+// Actions reported by ResourceChange.Action.
+const (
+	ActionNoOp    Action = "no-op"
+	ActionCreate  Action = "create"
+	ActionRead    Action = "read"
+	ActionUpdate  Action = "update"
+	ActionReplace Action = "replace"
+	ActionDelete  Action = "delete"
+)
+
+// Plan is the machine-readable plan produced by `terraform show -json`, indexed
+// by resource address.
+type Plan struct {
+	*terraform.PlanStruct
+}
+
+// ResourceChange is the planned change for one resource instance.
+type ResourceChange struct {
+	*tfjson.ResourceChange
+}
+
+// InitAndPlanJSON runs terraform init and plan, then reads the saved plan with
+// `terraform show -json`. This will fail the test if any command fails, the
+// plan violates policies/terraform or a planned name breaks the Azure naming
+// rules; see AssertPolicies and AssertResourceNames.
+func InitAndPlanJSON(t testing.TestingT, options *terraform.Options) *Plan {
+	plan, err := InitAndPlanJSONE(t, options)
+	require.NoError(t, err)
+	AssertPolicies(t, plan, PolicyWaivers(options)...)
+	AssertResourceNames(t, plan)
+	return plan
+}
+
+// InitAndPlanJSONE runs terraform init and plan, then reads the saved plan with
+// `terraform show -json`. Init holds the plugin cache lock, as in InitE. The
+// plan is written to a temporary file, so the caller's options are left
+// untouched.
+func InitAndPlanJSONE(t testing.TestingT, options *terraform.Options) (*Plan, error) {
+	if _, err := InitE(t, options); err != nil {
+		return nil, err
+	}
+	return PlanJSONE(t, options)
+}
+
+// PlanJSONE plans a workspace that InitE has initialized and reads the saved
+// plan with `terraform show -json`, so a test can plan one workspace many
+// times without running init again. Extra arguments, such as -refresh=false,
+// are passed to terraform plan.
+func PlanJSONE(t testing.TestingT, options *terraform.Options, extraArgs ...string) (*Plan, error) {
+	planOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	planFile, err := os.CreateTemp("", "terratest-plan-")
+	if err != nil {
+		return nil, err
+	}
+	if err := planFile.Close(); err != nil {
+		return nil, err
+	}
+	defer os.Remove(planFile.Name())
+	planOptions.PlanFilePath = planFile.Name()
+
+	args := append([]string{"plan", "-input=false", "-lock=false"}, extraArgs...)
+	if _, err := terraform.RunTerraformCommandE(t, planOptions, terraform.FormatArgs(planOptions, args...)...); err != nil {
+		return nil, err
+	}
+	planStruct, err := terraform.ShowWithStructE(t, planOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{PlanStruct: planStruct}, nil
+}
+
+// ParsePlan parses the output of `terraform show -json`.
+func ParsePlan(planJSON []byte) (*Plan, error) {
+	planStruct, err := terraform.ParsePlanJSON(string(planJSON))
+	if err != nil {
+		return nil, fmt.Errorf("parsing plan JSON: %w", err)
+	}
+	return &Plan{PlanStruct: planStruct}, nil
+}
+
+// LoadPlan reads and parses a file written by `terraform show -json`.
+func LoadPlan(path string) (*Plan, error) {
+	planJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlan(planJSON)
+}
+
+// Addresses returns the address of every resource change in the plan, sorted.
+func (p *Plan) Addresses() []string {
+	addresses := make([]string, 0, len(p.ResourceChangesMap))
+	for address := range p.ResourceChangesMap {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// Change returns the change planned for an exact resource instance address,
+// e.g. azurerm_subnet.bastion[0] or module.aks.azurerm_kubernetes_cluster.main.
+func (p *Plan) Change(address string) (*ResourceChange, error) {
+	change, ok := p.ResourceChangesMap[address]
+	if !ok {
+		return nil, fmt.Errorf("no resource change for %s; the plan contains %s", address, p.describeAddresses())
+	}
+	return &ResourceChange{ResourceChange: change}, nil
+}
+
+// Changes returns every instance of a resource, so azurerm_subnet.bastion
+// matches azurerm_subnet.bastion[0] and private DNS zones keyed by for_each
+// all match azurerm_private_dns_zone.zones. An instance address matches only
+// itself. Results are sorted by address.
+func (p *Plan) Changes(address string) []*ResourceChange {
+	var changes []*ResourceChange
+	for _, candidate := range p.Addresses() {
+		if candidate == address || strings.HasPrefix(candidate, address+"[") {
+			changes = append(changes, &ResourceChange{ResourceChange: p.ResourceChangesMap[candidate]})
+		}
+	}
+	return changes
+}
+
+// ChangesByType returns every change to resources of the given type, sorted by
+// address.
+func (p *Plan) ChangesByType(resourceType string) []*ResourceChange {
+	var changes []*ResourceChange
+	for _, address := range p.Addresses() {
+		if change := p.ResourceChangesMap[address]; change.Type == resourceType {
+			changes = append(changes, &ResourceChange{ResourceChange: change})
+		}
+	}
+	return changes
+}
+
+// Output returns the planned value of a root module output. The second result
+// is false when the output is not in the plan or is only known after apply.
+func (p *Plan) Output(name string) (interface{}, bool) {
+	change, ok := p.RawPlan.OutputChanges[name]
+	if !ok || change == nil {
+		return nil, false
+	}
+	if unknown, isBool := change.AfterUnknown.(bool); isBool && unknown {
+		return nil, false
+	}
+	return change.After, true
+}
+
+// Outputs returns the planned values of the root module outputs, leaving out
+// those only known after apply.
+func (p *Plan) Outputs() map[string]interface{} {
+	outputs := map[string]interface{}{}
+	for name := range p.RawPlan.OutputChanges {
+		if value, ok := p.Output(name); ok {
+			outputs[name] = value
+		}
+	}
+	return outputs
+}
+
+// describeAddresses lists the planned addresses for failure messages.
+func (p *Plan) describeAddresses() string {
+	addresses := p.Addresses()
+	if len(addresses) == 0 {
+		return "no resource changes"
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// Action returns the planned action for the instance.
+func (c *ResourceChange) Action() Action {
+	if c.Change == nil {
+		return ActionNoOp
+	}
+
+	actions := c.Change.Actions
+	switch {
+	case actions.Replace():
+		return ActionReplace
+	case actions.Create():
+		return ActionCreate
+	case actions.Update():
+		return ActionUpdate
+	case actions.Delete():
+		return ActionDelete
+	case actions.Read():
+		return ActionRead
+	default:
+		return ActionNoOp
+	}
+}
+
+// After returns the planned value at path, e.g. "sku_tier" or
+// "default_node_pool.0.zones". The second result is false when the path does
+// not exist or the value is only known after apply.
+func (c *ResourceChange) After(path string) (interface{}, bool) {
+	if c.Change == nil || c.IsUnknown(path) {
+		return nil, false
+	}
+	return LookupPath(c.Change.After, path)
+}
+
+// Before returns the prior value at path for updates, replacements and
+// deletes.
+func (c *ResourceChange) Before(path string) (interface{}, bool) {
+	if c.Change == nil {
+		return nil, false
+	}
+	return LookupPath(c.Change.Before, path)
+}
+
+// IsUnknown reports whether the value at path will only be known after apply.
+func (c *ResourceChange) IsUnknown(path string) bool {
+	if c.Change == nil {
+		return false
+	}
+	unknown, ok := LookupPath(c.Change.AfterUnknown, path)
+	if !ok {
+		return false
+	}
+	known, isBool := unknown.(bool)
+	return isBool && known
+}
+
+// ReplacePaths returns the attribute paths that force the instance to be
+// replaced, in the same dotted form accepted by After.
+func (c *ResourceChange) ReplacePaths() []string {
+	if c.Change == nil {
+		return nil
+	}
+
+	paths := make([]string, 0, len(c.Change.ReplacePaths))
+	for _, raw := range c.Change.ReplacePaths {
+		steps, ok := raw.([]interface{})
+		if !ok {
+			continue
+		}
+		parts := make([]string, 0, len(steps))
+		for _, step := range steps {
+			switch typed := step.(type) {
+			case float64:
+				parts = append(parts, strconv.Itoa(int(typed)))
+			default:
+				parts = append(parts, fmt.Sprint(typed))
+			}
+		}
+		paths = append(paths, strings.Join(parts, "."))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// LookupPath walks a decoded JSON value along a dotted path. Object keys are
+// matched by name and list elements by index; an empty path returns value.
+func LookupPath(value interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return value, true
+	}
+
+	current := value
+	for _, part := range strings.Split(path, ".") {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			next, ok := typed[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			current = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// normalizeJSON converts a Go value to the shape encoding/json produces when
+// decoding, so expected values such as 3 or []string{"1"} compare equal to
+// planned values such as float64(3) or []interface{}{"1"}.
+func normalizeJSON(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
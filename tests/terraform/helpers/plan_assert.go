@@ -0,0 +1,136 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tHelper is implemented by *testing.T so failures point at the caller.
+type tHelper interface {
+	Helper()
+}
+
+// markHelper marks the calling assertion as a test helper when supported.
+func markHelper(t testing.TestingT) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+}
+
+// RequireChange returns the change for an exact resource instance address.
+// This will fail and stop the test if the address is not in the plan.
+func RequireChange(t testing.TestingT, plan *Plan, address string) *ResourceChange {
+	markHelper(t)
+	change, err := plan.Change(address)
+	require.NoError(t, err)
+	return change
+}
+
+// AssertAction checks that every instance of the resource at address is
+// planned with the given action. address may be an instance address or a
+// resource address without an instance key.
+func AssertAction(t testing.TestingT, plan *Plan, address string, want Action) bool {
+	markHelper(t)
+
+	changes := plan.Changes(address)
+	if len(changes) == 0 {
+		return assert.Fail(t, fmt.Sprintf("expected %s to be planned for %s, but it has no resource change; the plan contains %s",
+			want, address, plan.describeAddresses()))
+	}
+
+	ok := true
+	for _, change := range changes {
+		if got := change.Action(); got != want {
+			ok = assert.Fail(t, fmt.Sprintf("%s: planned action is %s, want %s", change.Address, got, want)) && ok
+		}
+	}
+	return ok
+}
+
+// AssertCreated checks that every instance of the resource will be created.
+func AssertCreated(t testing.TestingT, plan *Plan, address string) bool {
+	markHelper(t)
+	return AssertAction(t, plan, address, ActionCreate)
+}
+
+// AssertNotPlanned checks that no instance of the resource is created,
+// updated, replaced or deleted.
+func AssertNotPlanned(t testing.TestingT, plan *Plan, address string) bool {
+	markHelper(t)
+
+	ok := true
+	for _, change := range plan.Changes(address) {
+		if got := change.Action(); got != ActionNoOp && got != ActionRead {
+			ok = assert.Fail(t, fmt.Sprintf("%s: planned action is %s, want no change", change.Address, got)) && ok
+		}
+	}
+	return ok
+}
+
+// AssertActionCount checks how many instances of a resource type are planned
+// with the given action.
+func AssertActionCount(t testing.TestingT, plan *Plan, resourceType string, want Action, count int) bool {
+	markHelper(t)
+
+	var matched []string
+	for _, change := range plan.ChangesByType(resourceType) {
+		if change.Action() == want {
+			matched = append(matched, change.Address)
+		}
+	}
+	return assert.Lenf(t, matched, count, "%s instances planned for %s: [%s]",
+		resourceType, want, strings.Join(matched, ", "))
+}
+
+// AssertAttribute checks the planned value of an attribute on every instance
+// of the resource. path uses the dotted form accepted by ResourceChange.After;
+// want may be any value that encodes to the same JSON, so 3 matches a planned
+// 3 and []string{"1", "2"} matches a planned list of strings.
+func AssertAttribute(t testing.TestingT, plan *Plan, address, path string, want interface{}) bool {
+	markHelper(t)
+
+	changes := plan.Changes(address)
+	if len(changes) == 0 {
+		return assert.Fail(t, fmt.Sprintf("cannot check %s.%s: %s has no resource change; the plan contains %s",
+			address, path, address, plan.describeAddresses()))
+	}
+
+	normalized, err := normalizeJSON(want)
+	if !assert.NoErrorf(t, err, "cannot encode expected value for %s.%s", address, path) {
+		return false
+	}
+
+	ok := true
+	for _, change := range changes {
+		if change.IsUnknown(path) {
+			ok = assert.Fail(t, fmt.Sprintf("%s: %s is only known after apply, want %#v", change.Address, path, want)) && ok
+			continue
+		}
+		got, found := change.After(path)
+		if !found {
+			ok = assert.Fail(t, fmt.Sprintf("%s: %s is not set in the planned values, want %#v", change.Address, path, want)) && ok
+			continue
+		}
+		ok = assert.Equalf(t, normalized, got, "%s: planned value of %s", change.Address, path) && ok
+	}
+	return ok
+}
+
+// AttributeString returns the planned string value of an attribute on an
+// exact resource instance address. This will fail the test if the value is
+// missing, unknown or not a string.
+func AttributeString(t testing.TestingT, plan *Plan, address, path string) string {
+	markHelper(t)
+
+	change := RequireChange(t, plan, address)
+	require.Falsef(t, change.IsUnknown(path), "%s: %s is only known after apply", address, path)
+	value, found := change.After(path)
+	require.Truef(t, found, "%s: %s is not set in the planned values", address, path)
+	str, isString := value.(string)
+	require.Truef(t, isString, "%s: %s is %T, not a string", address, path, value)
+	return str
+}
@@ -0,0 +1,223 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - PLAN INSPECTION TESTS
+// =============================================================================
+//
+// Tests for the plan JSON model and assertions, using a recorded
+// `terraform show -json` plan. These run without Terraform or Azure
+// credentials.
+//
+// Run with: go test -v -run TestPlan ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlanChangeLookup tests resource lookups by address and type
+func TestPlanChangeLookup(t *testing.T) {
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+
+	change, err := plan.Change("azurerm_kubernetes_cluster.main")
+	require.NoError(t, err)
+	assert.Equal(t, "azurerm_kubernetes_cluster", change.Type)
+
+	_, err = plan.Change("azurerm_kubernetes_cluster.missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no resource change for azurerm_kubernetes_cluster.missing")
+	assert.Contains(t, err.Error(), "azurerm_kubernetes_cluster.main")
+
+	pools := plan.Changes("azurerm_kubernetes_cluster_node_pool.user")
+	require.Len(t, pools, 2)
+	assert.Equal(t, `azurerm_kubernetes_cluster_node_pool.user["general"]`, pools[0].Address)
+	assert.Len(t, plan.Changes(`azurerm_kubernetes_cluster_node_pool.user["gpu"]`), 1)
+	assert.Empty(t, plan.Changes("azurerm_kubernetes_cluster_node_pool.use"))
+	assert.Len(t, plan.ChangesByType("azurerm_role_assignment"), 1)
+}
+
+// TestPlanActions tests that Terraform action lists map to a single action
+func TestPlanActions(t *testing.T) {
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+
+	testCases := []struct {
+		address string
+		action  Action
+	}{
+		{"azurerm_kubernetes_cluster.main", ActionCreate},
+		{`azurerm_kubernetes_cluster_node_pool.user["gpu"]`, ActionUpdate},
+		{`azurerm_kubernetes_cluster_node_pool.user["general"]`, ActionReplace},
+		{"azurerm_role_assignment.acr_pull[0]", ActionDelete},
+		{"azurerm_monitor_diagnostic_setting.aks[0]", ActionNoOp},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.action, RequireChange(t, plan, tc.address).Action(), tc.address)
+	}
+
+	replaced := RequireChange(t, plan, `azurerm_kubernetes_cluster_node_pool.user["general"]`)
+	assert.Equal(t, []string{"vm_size"}, replaced.ReplacePaths())
+}
+
+// TestPlanAttributes tests reading planned, prior and unknown values
+func TestPlanAttributes(t *testing.T) {
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+	aks := RequireChange(t, plan, "azurerm_kubernetes_cluster.main")
+
+	skuTier, ok := aks.After("sku_tier")
+	assert.True(t, ok)
+	assert.Equal(t, "Standard", skuTier)
+
+	zone, ok := aks.After("default_node_pool.0.zones.2")
+	assert.True(t, ok)
+	assert.Equal(t, "3", zone)
+
+	_, ok = aks.After("default_node_pool.1.zones")
+	assert.False(t, ok, "index out of range")
+
+	assert.True(t, aks.IsUnknown("oidc_issuer_url"))
+	_, ok = aks.After("oidc_issuer_url")
+	assert.False(t, ok, "unknown values are not readable")
+	assert.False(t, aks.IsUnknown("default_node_pool.0.zones.0"))
+
+	pool := RequireChange(t, plan, `azurerm_kubernetes_cluster_node_pool.user["gpu"]`)
+	before, ok := pool.Before("max_count")
+	assert.True(t, ok)
+	assert.Equal(t, float64(3), before)
+
+	assert.Equal(t, "aks-terratest-dev", AttributeString(t, plan, "azurerm_kubernetes_cluster.main", "name"))
+}
+
+// TestPlanAssertionsPass tests the assertions against matching expectations
+func TestPlanAssertionsPass(t *testing.T) {
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+
+	assert.True(t, AssertCreated(t, plan, "azurerm_kubernetes_cluster.main"))
+	assert.True(t, AssertAction(t, plan, `azurerm_kubernetes_cluster_node_pool.user["general"]`, ActionReplace))
+	assert.True(t, AssertNotPlanned(t, plan, "azurerm_monitor_diagnostic_setting.aks"))
+	assert.True(t, AssertNotPlanned(t, plan, "azurerm_bastion_host.main"))
+	assert.True(t, AssertActionCount(t, plan, "azurerm_role_assignment", ActionDelete, 1))
+	assert.True(t, AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oidc_issuer_enabled", true))
+	assert.True(t, AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.zones", []string{"1", "2", "3"}))
+	assert.True(t, AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.node_count", 3))
+}
+
+// TestPlanAssertionMessages tests that failures name the address and values
+func TestPlanAssertionMessages(t *testing.T) {
+	t.Parallel()
+
+	plan := loadTestPlan(t)
+
+	testCases := []struct {
+		name   string
+		assert func(t *recordingT) bool
+		want   []string
+	}{
+		{
+			name: "wrong_action",
+			assert: func(t *recordingT) bool {
+				return AssertCreated(t, plan, "azurerm_kubernetes_cluster_node_pool.user")
+			},
+			want: []string{
+				`azurerm_kubernetes_cluster_node_pool.user["general"]: planned action is replace, want create`,
+				`azurerm_kubernetes_cluster_node_pool.user["gpu"]: planned action is update, want create`,
+			},
+		},
+		{
+			name: "missing_resource",
+			assert: func(t *recordingT) bool {
+				return AssertCreated(t, plan, "azurerm_bastion_host.main")
+			},
+			want: []string{"expected create to be planned for azurerm_bastion_host.main, but it has no resource change"},
+		},
+		{
+			name: "unexpected_change",
+			assert: func(t *recordingT) bool {
+				return AssertNotPlanned(t, plan, "azurerm_role_assignment.acr_pull")
+			},
+			want: []string{"azurerm_role_assignment.acr_pull[0]: planned action is delete, want no change"},
+		},
+		{
+			name: "wrong_value",
+			assert: func(t *recordingT) bool {
+				return AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "sku_tier", "Premium")
+			},
+			want: []string{"azurerm_kubernetes_cluster.main: planned value of sku_tier", `expected: "Premium"`, `actual  : "Standard"`},
+		},
+		{
+			name: "unknown_value",
+			assert: func(t *recordingT) bool {
+				return AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oidc_issuer_url", "https://example.com")
+			},
+			want: []string{"azurerm_kubernetes_cluster.main: oidc_issuer_url is only known after apply"},
+		},
+		{
+			name: "missing_attribute",
+			assert: func(t *recordingT) bool {
+				return AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.max_pods", 110)
+			},
+			want: []string{"azurerm_kubernetes_cluster.main: default_node_pool.0.max_pods is not set in the planned values"},
+		},
+		{
+			name: "wrong_count",
+			assert: func(t *recordingT) bool {
+				return AssertActionCount(t, plan, "azurerm_kubernetes_cluster_node_pool", ActionCreate, 2)
+			},
+			want: []string{"azurerm_kubernetes_cluster_node_pool instances planned for create: []"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := &recordingT{}
+			assert.False(t, tc.assert(recorder))
+			for _, want := range tc.want {
+				assert.Contains(t, recorder.output(), want)
+			}
+		})
+	}
+}
+
+// loadTestPlan parses the recorded plan in testdata.
+func loadTestPlan(t *testing.T) *Plan {
+	plan, err := LoadPlan(filepath.Join("testdata", "plan_aks.json"))
+	require.NoError(t, err)
+	return plan
+}
+
+// recordingT collects assertion failures instead of failing the test.
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Fail()                                     {}
+func (r *recordingT) FailNow()                                  {}
+func (r *recordingT) Fatal(args ...interface{})                 { r.Error(args...) }
+func (r *recordingT) Fatalf(format string, args ...interface{}) { r.Errorf(format, args...) }
+func (r *recordingT) Error(args ...interface{})                 { r.errors = append(r.errors, fmt.Sprint(args...)) }
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+func (r *recordingT) Name() string { return "recordingT" }
+
+func (r *recordingT) output() string {
+	return strings.Join(r.errors, "\n")
+}
@@ -0,0 +1,275 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// terraformPoliciesPath holds the Rego policies for Terraform plans, relative
+// to the repository root.
+const terraformPoliciesPath = "policies/terraform"
+
+// PolicyModeEnv selects how plan policy results are reported:
+//
+//	enforce  deny messages fail the test, warn messages are logged (default)
+//	warn     deny and warn messages are both logged
+//	off      plans are not evaluated
+const PolicyModeEnv = "TERRATEST_POLICY_MODE"
+
+// PolicyResult holds the messages produced by the deny and warn rules for one
+// plan, each sorted.
+type PolicyResult struct {
+	Deny []string
+	Warn []string
+}
+
+// Policies are compiled Rego policies, evaluated with a plan as input the way
+// `conftest test tfplan.json --all-namespaces` does.
+type Policies struct {
+	queries []policyQuery
+	rules   []PolicyRule
+}
+
+// policyQuery evaluates one deny or warn rule in one package.
+type policyQuery struct {
+	kind  string
+	query rego.PreparedEvalQuery
+}
+
+var (
+	policiesMu    sync.Mutex
+	policiesCache = map[string]*Policies{}
+)
+
+// policyWaivers maps options built from a fixture to the fixture's waivers,
+// so the plan helpers can find them.
+var policyWaivers sync.Map
+
+// TerraformPolicies returns the policies under policies/terraform. This will
+// fail the test if they cannot be compiled.
+func TerraformPolicies(t testing.TestingT) *Policies {
+	policies, err := LoadPoliciesE(filepath.Join(RepoRoot(t), terraformPoliciesPath))
+	require.NoError(t, err)
+	return policies
+}
+
+// LoadPoliciesE compiles every .rego file in dir and prepares a query for the
+// deny and warn rules of each package. Results are cached per directory.
+func LoadPoliciesE(dir string) (*Policies, error) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+
+	if policies, ok := policiesCache[dir]; ok {
+		return policies, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.rego"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .rego files in %s", dir)
+	}
+
+	var options []func(*rego.Rego)
+	var rules []PolicyRule
+	packages := map[string]bool{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		module, err := ast.ParseModule(path, string(src))
+		if err != nil {
+			return nil, err
+		}
+		packages[module.Package.Path.String()] = true
+		options = append(options, rego.Module(path, string(src)))
+		rules = append(rules, moduleRules(module)...)
+	}
+
+	policies := &Policies{rules: rules}
+	for _, pkg := range sortedStrings(packages) {
+		for _, kind := range []string{"deny", "warn"} {
+			query, err := rego.New(append(options, rego.Query(pkg+"."+kind))...).PrepareForEval(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("compiling policies in %s: %w", dir, err)
+			}
+			policies.queries = append(policies.queries, policyQuery{kind: kind, query: query})
+		}
+	}
+
+	policiesCache[dir] = policies
+	return policies, nil
+}
+
+// EvaluateE runs the deny and warn rules against the plan, using the same JSON
+// document `terraform show -json` writes as input.
+func (p *Policies) EvaluateE(plan *Plan) (*PolicyResult, error) {
+	input, err := planInput(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PolicyResult{}
+	for _, q := range p.queries {
+		results, err := q.query.Eval(context.Background(), rego.EvalInput(input))
+		if err != nil {
+			return nil, fmt.Errorf("evaluating %s rules: %w", q.kind, err)
+		}
+		messages, err := policyMessages(results)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating %s rules: %w", q.kind, err)
+		}
+		if q.kind == "deny" {
+			result.Deny = append(result.Deny, messages...)
+		} else {
+			result.Warn = append(result.Warn, messages...)
+		}
+	}
+
+	sort.Strings(result.Deny)
+	sort.Strings(result.Warn)
+	return result, nil
+}
+
+// planInput converts the plan to the generic JSON value OPA expects as input.
+// Attributes that are only known after apply are set to GoldenUnknown in each
+// change's after value. Otherwise a rule such as
+// `object.get(after, "role_based_access_control_enabled", false) == false`
+// would deny every attribute a provider computes, which in an offline plan is
+// every attribute the module leaves to the provider's default.
+func planInput(plan *Plan) (interface{}, error) {
+	encoded, err := json.Marshal(plan.RawPlan)
+	if err != nil {
+		return nil, err
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(encoded, &input); err != nil {
+		return nil, err
+	}
+
+	changes, _ := input["resource_changes"].([]interface{})
+	for _, raw := range changes {
+		resource, _ := raw.(map[string]interface{})
+		change, _ := resource["change"].(map[string]interface{})
+		if change == nil || change["after"] == nil {
+			continue
+		}
+		change["after"] = normalizeValue(change["after"], change["after_unknown"], nil)
+	}
+	return input, nil
+}
+
+// policyMessages reads the strings of a deny or warn set. An undefined rule
+// produces no results.
+func policyMessages(results rego.ResultSet) ([]string, error) {
+	var messages []string
+	for _, result := range results {
+		for _, expression := range result.Expressions {
+			values, ok := expression.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is %T, want a set of messages", expression.Text, expression.Value)
+			}
+			for _, value := range values {
+				messages = append(messages, fmt.Sprint(value))
+			}
+		}
+	}
+	return messages, nil
+}
+
+// WaivePolicy excuses deny messages that contain substring for plans of this
+// fixture, e.g. a development configuration that deliberately disables
+// geo-redundant backup. Waived messages are logged instead of failing.
+func (f *Fixture) WaivePolicy(substring string) *Fixture {
+	f.PolicyWaivers = append(f.PolicyWaivers, substring)
+	return f
+}
+
+// registerPolicyWaivers remembers the fixture's waivers for options built
+// from it until the test finishes.
+func (f *Fixture) registerPolicyWaivers(t testing.TestingT, options *terraform.Options) {
+	if len(f.PolicyWaivers) == 0 {
+		return
+	}
+	policyWaivers.Store(options, append([]string(nil), f.PolicyWaivers...))
+	if c, ok := t.(cleaner); ok {
+		c.Cleanup(func() { policyWaivers.Delete(options) })
+	}
+}
+
+// PolicyWaivers returns the waivers of the fixture that built options.
+func PolicyWaivers(options *terraform.Options) []string {
+	if waivers, ok := policyWaivers.Load(options); ok {
+		return waivers.([]string)
+	}
+	return nil
+}
+
+// AssertPolicies evaluates the plan against policies/terraform. Each deny
+// message fails the test unless it contains one of the waivers; warn messages
+// and waived denials are logged. PolicyModeEnv can downgrade or disable the
+// check.
+func AssertPolicies(t testing.TestingT, plan *Plan, waivers ...string) bool {
+	markHelper(t)
+
+	mode := os.Getenv(PolicyModeEnv)
+	switch mode {
+	case "", "enforce", "warn":
+	case "off":
+		return true
+	default:
+		return assert.Fail(t, fmt.Sprintf("%s must be enforce, warn or off, not %q", PolicyModeEnv, mode))
+	}
+
+	result, err := TerraformPolicies(t).EvaluateE(plan)
+	if !assert.NoError(t, err) {
+		return false
+	}
+
+	for _, message := range result.Warn {
+		logger.Default.Logf(t, "policy warning: %s", message)
+	}
+
+	var denied []string
+	for _, message := range result.Deny {
+		switch {
+		case policyWaived(message, waivers):
+			logger.Default.Logf(t, "policy denial waived: %s", message)
+		case mode == "warn":
+			logger.Default.Logf(t, "policy denial (%s=warn): %s", PolicyModeEnv, message)
+		default:
+			denied = append(denied, message)
+		}
+	}
+	if len(denied) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("plan violates %d policy rule(s) in %s:\n  - %s",
+		len(denied), terraformPoliciesPath, strings.Join(denied, "\n  - ")))
+}
+
+// policyWaived reports whether message contains any of the waivers.
+func policyWaived(message string, waivers []string) bool {
+	for _, waiver := range waivers {
+		if strings.Contains(message, waiver) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// PolicyRule is one deny or warn rule body in a policy file, identified by
+// where it is defined and the message it produces.
+type PolicyRule struct {
+	Kind    string
+	Package string
+	File    string
+	Line    int
+
+	// Format is the message format passed to sprintf, or the message itself
+	// for a rule with a constant message.
+	Format string
+
+	pattern *regexp.Regexp
+}
+
+// formatVerb matches the sprintf verbs in a message format.
+var formatVerb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// moduleRules returns the deny and warn rules defined in a module.
+func moduleRules(module *ast.Module) []PolicyRule {
+	var rules []PolicyRule
+	for _, rule := range module.Rules {
+		kind := rule.Head.Ref()[0].String()
+		if kind != "deny" && kind != "warn" {
+			continue
+		}
+		policyRule := PolicyRule{
+			Kind:    kind,
+			Package: strings.TrimPrefix(module.Package.Path.String(), "data."),
+			File:    filepath.Base(rule.Location.File),
+			Line:    rule.Location.Row,
+			Format:  ruleMessageFormat(rule),
+		}
+		if policyRule.Format != "" {
+			policyRule.pattern = formatPattern(policyRule.Format)
+		}
+		rules = append(rules, policyRule)
+	}
+	return rules
+}
+
+// ruleMessageFormat finds the format of the first sprintf call in the rule
+// body, or returns a constant message key.
+func ruleMessageFormat(rule *ast.Rule) string {
+	if rule.Head.Key != nil {
+		if key, ok := rule.Head.Key.Value.(ast.String); ok {
+			return string(key)
+		}
+	}
+	var format string
+	ast.WalkTerms(rule.Body, func(term *ast.Term) bool {
+		if format != "" {
+			return true
+		}
+		if call, ok := term.Value.(ast.Call); ok && len(call) > 1 && call[0].String() == "sprintf" {
+			if s, ok := call[1].Value.(ast.String); ok {
+				format = string(s)
+			}
+		}
+		return false
+	})
+	return format
+}
+
+// formatPattern turns a message format into a pattern matching any message
+// it can produce.
+func formatPattern(format string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, verb := range formatVerb.FindAllStringIndex(format, -1) {
+		pattern.WriteString(regexp.QuoteMeta(format[last:verb[0]]))
+		pattern.WriteString("(.*)")
+		last = verb[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(format[last:]))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
+
+// String identifies the rule, e.g. "deny azure.rego:57".
+func (r PolicyRule) String() string {
+	return fmt.Sprintf("%s %s:%d", r.Kind, r.File, r.Line)
+}
+
+// Matches reports whether the rule could have produced message.
+func (r PolicyRule) Matches(message string) bool {
+	return r.pattern != nil && r.pattern.MatchString(message)
+}
+
+// Fired reports whether the rule produced one of the messages in result.
+func (r PolicyRule) Fired(result *PolicyResult) bool {
+	messages := result.Deny
+	if r.Kind == "warn" {
+		messages = result.Warn
+	}
+	for _, message := range messages {
+		if r.Matches(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns every deny and warn rule, in file and line order.
+func (p *Policies) Rules() []PolicyRule {
+	rules := append([]PolicyRule(nil), p.rules...)
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].File != rules[j].File {
+			return rules[i].File < rules[j].File
+		}
+		return rules[i].Line < rules[j].Line
+	})
+	return rules
+}
+
+// RuleE returns the one rule whose message format contains substring, e.g.
+// "must use TLS 1.2".
+func (p *Policies) RuleE(substring string) (PolicyRule, error) {
+	var found []PolicyRule
+	for _, rule := range p.Rules() {
+		if strings.Contains(rule.Format, substring) {
+			found = append(found, rule)
+		}
+	}
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		return PolicyRule{}, fmt.Errorf("no policy rule has a message containing %q", substring)
+	default:
+		names := make([]string, len(found))
+		for i, rule := range found {
+			names[i] = rule.String()
+		}
+		return PolicyRule{}, fmt.Errorf("%d policy rules have a message containing %q: %s", len(found), substring, strings.Join(names, ", "))
+	}
+}
+
+// PolicyCoverage records which fixtures each rule fires on and which it
+// passes, so a rule that no fixture exercises both ways is reported.
+type PolicyCoverage struct {
+	mu     sync.Mutex
+	rules  []PolicyRule
+	fires  map[string][]string
+	passes map[string][]string
+}
+
+// NewPolicyCoverage starts recording coverage of the rules in policies.
+func NewPolicyCoverage(policies *Policies) *PolicyCoverage {
+	return &PolicyCoverage{
+		rules:  policies.Rules(),
+		fires:  map[string][]string{},
+		passes: map[string][]string{},
+	}
+}
+
+// Record notes whether rule fired on the fixture, given its policy result.
+func (c *PolicyCoverage) Record(rule PolicyRule, fixture string, result *PolicyResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rule.Fired(result) {
+		c.fires[rule.String()] = append(c.fires[rule.String()], fixture)
+	} else {
+		c.passes[rule.String()] = append(c.passes[rule.String()], fixture)
+	}
+}
+
+// Uncovered describes each rule without a fixture it fires on or without one
+// it passes.
+func (c *PolicyCoverage) Uncovered() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var uncovered []string
+	for _, rule := range c.rules {
+		var missing []string
+		if len(c.fires[rule.String()]) == 0 {
+			missing = append(missing, "no fixture it fires on")
+		}
+		if len(c.passes[rule.String()]) == 0 {
+			missing = append(missing, "no fixture it passes")
+		}
+		if len(missing) > 0 {
+			uncovered = append(uncovered, fmt.Sprintf("%s (%q): %s", rule, rule.Format, strings.Join(missing, " and ")))
+		}
+	}
+	return uncovered
+}
+
+// Matrix formats the coverage as a table with one row per rule and the
+// number of fixtures the rule fires on and passes.
+func (c *PolicyCoverage) Matrix() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tFIRES\tPASSES\tCOVERED\tMESSAGE")
+	for _, rule := range c.rules {
+		fires, passes := len(c.fires[rule.String()]), len(c.passes[rule.String()])
+		covered := "yes"
+		if fires == 0 || passes == 0 {
+			covered = "NO"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", rule, fires, passes, covered, rule.Format)
+	}
+	w.Flush()
+	return buf.String()
+}
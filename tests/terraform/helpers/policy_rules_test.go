@@ -0,0 +1,176 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - POLICY RULE TESTS
+// =============================================================================
+//
+// Tests for each deny and warn rule in policies/terraform. Every rule has a
+// directory under testdata/policy/rules with a plan it fires on (fires.json)
+// and a plan it passes (passes.json); the test asserts exactly which messages
+// each plan produces and logs a per-rule coverage matrix. A rule without both
+// fixtures fails the test.
+//
+// Run with: go test -v -run TestPolicyRules ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// policyRuleCases lists, for each fixture directory, the message its
+// fires.json plan produces. Its passes.json plan produces no messages.
+var policyRuleCases = []struct {
+	dir     string
+	kind    string
+	message string
+}{
+	{"required-tags", "deny", `Resource azurerm_resource_group.main is missing required tags: {"cost-center", "owner"}`},
+	{"storage-tls", "deny", "Storage account azurerm_storage_account.main must use TLS 1.2 minimum (current: TLS1_0)"},
+	{"postgres-ssl", "deny", "PostgreSQL server azurerm_postgresql_flexible_server.main must have SSL enforcement enabled"},
+	{"storage-infrastructure-encryption", "deny", "Storage account azurerm_storage_account.main should have infrastructure encryption enabled"},
+	{"key-vault-purge-protection", "warn", "Key Vault azurerm_key_vault.main should have purge protection enabled for production"},
+	{"storage-public-access", "deny", "Storage account azurerm_storage_account.main should not allow public network access"},
+	{"key-vault-public-access", "deny", "Key Vault azurerm_key_vault.main should not allow public network access"},
+	{"aks-public-api", "warn", "AKS cluster azurerm_kubernetes_cluster.main has public API access enabled. Consider using private cluster."},
+	{"storage-https-only", "deny", "Storage account azurerm_storage_account.main must enforce HTTPS only traffic"},
+	{"storage-private-endpoint", "warn", "Storage account azurerm_storage_account.main should use private endpoints for secure access"},
+	{"aks-rbac", "deny", "AKS cluster azurerm_kubernetes_cluster.main must have RBAC enabled"},
+	{"aks-managed-identity", "deny", "AKS cluster azurerm_kubernetes_cluster.main must use managed identity"},
+	{"aks-azure-policy", "warn", "AKS cluster azurerm_kubernetes_cluster.main should have Azure Policy enabled"},
+	{"aks-defender", "warn", "AKS cluster azurerm_kubernetes_cluster.main should have Microsoft Defender enabled"},
+	{"postgres-geo-backup", "deny", "PostgreSQL server azurerm_postgresql_flexible_server.main must have geo-redundant backup enabled for production"},
+	{"node-pool-autoscaling", "warn", "Node pool azurerm_kubernetes_cluster_node_pool.general should have autoscaling enabled for cost optimization"},
+	{"vm-expensive-size", "warn", "VM azurerm_virtual_machine.jumpbox uses expensive size Standard_E64s_v5. Consider if this is necessary."},
+}
+
+// TestPolicyRules tests every rule against its fixtures and reports coverage
+func TestPolicyRules(t *testing.T) {
+	policies := TerraformPolicies(t)
+	coverage := NewPolicyCoverage(policies)
+
+	t.Run("fixtures", func(t *testing.T) {
+		for _, tc := range policyRuleCases {
+			tc := tc
+			t.Run(tc.dir, func(t *testing.T) {
+				t.Parallel()
+
+				rule := policyRuleFor(t, policies, tc.kind, tc.message)
+				dir := filepath.Join("testdata", "policy", "rules", tc.dir)
+
+				fires := evaluatePolicyFixture(t, policies, filepath.Join(dir, "fires.json"))
+				want := &PolicyResult{}
+				if tc.kind == "deny" {
+					want.Deny = []string{tc.message}
+				} else {
+					want.Warn = []string{tc.message}
+				}
+				assert.Equal(t, want, fires, "fires.json")
+				coverage.Record(rule, filepath.Join(tc.dir, "fires.json"), fires)
+
+				passes := evaluatePolicyFixture(t, policies, filepath.Join(dir, "passes.json"))
+				assert.Equal(t, &PolicyResult{}, passes, "passes.json")
+				coverage.Record(rule, filepath.Join(tc.dir, "passes.json"), passes)
+			})
+		}
+	})
+
+	t.Logf("policy rule coverage:\n%s", coverage.Matrix())
+	assert.Empty(t, coverage.Uncovered(), "every rule needs a fixture it fires on and one it passes under testdata/policy/rules")
+}
+
+// TestPolicyRuleFixturesListed tests that every fixture directory has a case
+func TestPolicyRuleFixturesListed(t *testing.T) {
+	t.Parallel()
+
+	entries, err := os.ReadDir(filepath.Join("testdata", "policy", "rules"))
+	require.NoError(t, err)
+
+	listed := map[string]bool{}
+	for _, tc := range policyRuleCases {
+		listed[tc.dir] = true
+	}
+	for _, entry := range entries {
+		assert.True(t, listed[entry.Name()], "testdata/policy/rules/%s has no entry in policyRuleCases", entry.Name())
+	}
+}
+
+// TestPolicyRuleMessages tests matching messages to the rule that produced them
+func TestPolicyRuleMessages(t *testing.T) {
+	t.Parallel()
+
+	policies := TerraformPolicies(t)
+
+	rule, err := policies.RuleE("must use TLS 1.2")
+	require.NoError(t, err)
+	assert.Equal(t, "deny", rule.Kind)
+	assert.Equal(t, "terraform.azure", rule.Package)
+	assert.Equal(t, "Storage account %s must use TLS 1.2 minimum (current: %s)", rule.Format)
+	assert.True(t, strings.HasPrefix(rule.String(), "deny azure.rego:"))
+	assert.True(t, rule.Matches("Storage account module.x.azurerm_storage_account.logs must use TLS 1.2 minimum (current: TLS1_1)"))
+	assert.False(t, rule.Matches("Storage account azurerm_storage_account.main must enforce HTTPS only traffic"))
+
+	_, err = policies.RuleE("Storage account")
+	assert.ErrorContains(t, err, "policy rules have a message containing")
+	_, err = policies.RuleE("no such message")
+	assert.ErrorContains(t, err, "no policy rule has a message containing")
+
+	for _, rule := range policies.Rules() {
+		assert.NotEmpty(t, rule.Format, "%s has no message format", rule)
+	}
+}
+
+// TestPolicyPrivateEndpointModules tests that a private endpoint only covers storage accounts in its own module
+func TestPolicyPrivateEndpointModules(t *testing.T) {
+	t.Parallel()
+
+	result := evaluatePolicyFixture(t, TerraformPolicies(t), filepath.Join("testdata", "policy", "private_endpoint_modules.json"))
+
+	assert.Empty(t, result.Deny)
+	assert.Equal(t, []string{
+		"Storage account module.data.azurerm_storage_account.main should use private endpoints for secure access",
+	}, result.Warn)
+}
+
+// TestPolicyCoverageUncovered tests that partly covered rules are reported
+func TestPolicyCoverageUncovered(t *testing.T) {
+	t.Parallel()
+
+	policies := TerraformPolicies(t)
+	rule, err := policies.RuleE("must have RBAC enabled")
+	require.NoError(t, err)
+
+	coverage := NewPolicyCoverage(policies)
+	coverage.Record(rule, "plan_aks.json", &PolicyResult{Deny: []string{"AKS cluster azurerm_kubernetes_cluster.main must have RBAC enabled"}})
+
+	uncovered := coverage.Uncovered()
+	assert.Len(t, uncovered, len(policies.Rules()))
+	assert.Contains(t, uncovered, rule.String()+` ("AKS cluster %s must have RBAC enabled"): no fixture it passes`)
+	assert.Contains(t, coverage.Matrix(), rule.String()+"  1      0       NO")
+}
+
+// policyRuleFor returns the rule that produces message.
+func policyRuleFor(t *testing.T, policies *Policies, kind, message string) PolicyRule {
+	for _, rule := range policies.Rules() {
+		if rule.Kind == kind && rule.Matches(message) {
+			return rule
+		}
+	}
+	require.FailNowf(t, "unknown policy message", "no %s rule produces %q", kind, message)
+	return PolicyRule{}
+}
+
+// evaluatePolicyFixture evaluates the policies against a plan fixture.
+func evaluatePolicyFixture(t *testing.T, policies *Policies, path string) *PolicyResult {
+	plan, err := LoadPlan(path)
+	require.NoError(t, err)
+	result, err := policies.EvaluateE(plan)
+	require.NoError(t, err)
+	return result
+}
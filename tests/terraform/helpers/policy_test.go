@@ -0,0 +1,92 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - PLAN POLICY TESTS
+// =============================================================================
+//
+// Tests for evaluating policies/terraform against plans, using recorded plan
+// JSON. These run without Terraform, conftest or Azure credentials.
+//
+// Run with: go test -v -run TestPolicy ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPolicyEvaluateRecordedPlan tests deny and warn rules on a recorded plan
+func TestPolicyEvaluateRecordedPlan(t *testing.T) {
+	t.Parallel()
+
+	result, err := TerraformPolicies(t).EvaluateE(loadTestPlan(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"AKS cluster azurerm_kubernetes_cluster.main must have RBAC enabled",
+		"AKS cluster azurerm_kubernetes_cluster.main must use managed identity",
+		`Resource azurerm_kubernetes_cluster.main is missing required tags: {"cost-center", "environment", "owner", "project"}`,
+	}, result.Deny)
+	assert.Contains(t, result.Warn, `Node pool azurerm_kubernetes_cluster_node_pool.user["general"] should have autoscaling enabled for cost optimization`)
+	assert.NotContains(t, result.Warn, `Node pool azurerm_kubernetes_cluster_node_pool.user["gpu"] should have autoscaling enabled for cost optimization`,
+		"the rule only covers node pools being created")
+}
+
+// TestPolicyCompliantPlan tests that a compliant plan is not denied
+func TestPolicyCompliantPlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "policy", "compliant_plan.json"))
+	require.NoError(t, err)
+
+	result, err := TerraformPolicies(t).EvaluateE(plan)
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Deny)
+	assert.Equal(t, []string{
+		"Storage account azurerm_storage_account.main should use private endpoints for secure access",
+	}, result.Warn, "unknown purge_protection_enabled must not be reported as disabled")
+}
+
+// TestPolicyAssertions tests failures, waivers and the policy mode
+func TestPolicyAssertions(t *testing.T) {
+	plan := loadTestPlan(t)
+
+	recorder := &recordingT{}
+	assert.False(t, AssertPolicies(recorder, plan))
+	assert.Contains(t, recorder.output(), "plan violates 3 policy rule(s) in policies/terraform")
+	assert.Contains(t, recorder.output(), "  - AKS cluster azurerm_kubernetes_cluster.main must have RBAC enabled")
+
+	recorder = &recordingT{}
+	assert.True(t, AssertPolicies(recorder, plan, "must have RBAC enabled", "must use managed identity", "missing required tags"))
+	assert.Empty(t, recorder.output())
+
+	recorder = &recordingT{}
+	assert.False(t, AssertPolicies(recorder, plan, "must have RBAC enabled"))
+	assert.Contains(t, recorder.output(), "plan violates 2 policy rule(s)")
+
+	t.Setenv(PolicyModeEnv, "warn")
+	assert.True(t, AssertPolicies(&recordingT{}, plan))
+
+	t.Setenv(PolicyModeEnv, "off")
+	assert.True(t, AssertPolicies(&recordingT{}, plan))
+
+	t.Setenv(PolicyModeEnv, "strict")
+	recorder = &recordingT{}
+	assert.False(t, AssertPolicies(recorder, plan))
+	assert.Contains(t, recorder.output(), `TERRATEST_POLICY_MODE must be enforce, warn or off, not "strict"`)
+}
+
+// TestPolicyWaiversFollowOptions tests that fixture waivers reach the plan helpers
+func TestPolicyWaiversFollowOptions(t *testing.T) {
+	t.Parallel()
+
+	waived := Naming().WaivePolicy("geo-redundant backup").Options(t)
+	assert.Equal(t, []string{"geo-redundant backup"}, PolicyWaivers(waived))
+
+	assert.Empty(t, PolicyWaivers(Naming().Options(t)))
+}
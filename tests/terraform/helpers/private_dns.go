@@ -0,0 +1,612 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PrivateLinkTarget is what a private endpoint connects to: the resource
+// type, the kind for resource types whose kinds resolve in different zones,
+// and the subresource (group ID) named in subresource_names.
+type PrivateLinkTarget struct {
+	Type        string
+	Kind        string
+	Subresource string
+}
+
+// PrivateLinkZones maps private endpoint targets to the privatelink zone
+// their records must live in. An entry without a kind applies to every kind
+// without an entry of its own. Subresources compare case-insensitively, as in
+// Azure. See
+// https://learn.microsoft.com/azure/private-link/private-endpoint-dns.
+var PrivateLinkZones = map[PrivateLinkTarget]string{
+	{Type: "azurerm_cognitive_account", Kind: "OpenAI", Subresource: "account"}:   PrivateDNSZoneNames["openai"],
+	{Type: "azurerm_cognitive_account", Subresource: "account"}:                   PrivateDNSZoneNames["cognitiveservices"],
+	{Type: "azurerm_search_service", Subresource: "searchService"}:                PrivateDNSZoneNames["search"],
+	{Type: "azurerm_redis_cache", Subresource: "redisCache"}:                      PrivateDNSZoneNames["redis"],
+	{Type: "azurerm_postgresql_flexible_server", Subresource: "postgresqlServer"}: PrivateDNSZoneNames["postgres"],
+	{Type: "azurerm_key_vault", Subresource: "vault"}:                             PrivateDNSZoneNames["keyvault"],
+	{Type: "azurerm_container_registry", Subresource: "registry"}:                 PrivateDNSZoneNames["acr"],
+	{Type: "azurerm_purview_account", Subresource: "account"}:                     PrivateDNSZoneNames["purview"],
+	{Type: "azurerm_purview_account", Subresource: "portal"}:                      PrivateDNSZoneNames["purview_studio"],
+	{Type: "azurerm_storage_account", Subresource: "blob"}:                        PrivateDNSZoneNames["storage_blob"],
+	{Type: "azurerm_storage_account", Subresource: "queue"}:                       PrivateDNSZoneNames["storage_queue"],
+	{Type: "azurerm_storage_account", Subresource: "file"}:                        "privatelink.file.core.windows.net",
+	{Type: "azurerm_storage_account", Subresource: "table"}:                       "privatelink.table.core.windows.net",
+	{Type: "azurerm_storage_account", Subresource: "dfs"}:                         "privatelink.dfs.core.windows.net",
+	{Type: "azurerm_servicebus_namespace", Subresource: "namespace"}:              PrivateDNSZoneNames["servicebus"],
+	{Type: "azurerm_eventhub_namespace", Subresource: "namespace"}:                PrivateDNSZoneNames["eventhub"],
+	{Type: "azurerm_cosmosdb_account", Subresource: "Sql"}:                        "privatelink.documents.azure.com",
+	{Type: "azurerm_mssql_server", Subresource: "sqlServer"}:                      "privatelink.database.windows.net",
+}
+
+// PrivateLinkZone returns the privatelink zone for a private endpoint target;
+// see PrivateLinkZones.
+func PrivateLinkZone(target PrivateLinkTarget) (string, bool) {
+	var fallback string
+	for key, zone := range PrivateLinkZones {
+		if key.Type != target.Type || !strings.EqualFold(key.Subresource, target.Subresource) {
+			continue
+		}
+		if key.Kind != "" && key.Kind == target.Kind {
+			return zone, true
+		}
+		if key.Kind == "" {
+			fallback = zone
+		}
+	}
+	return fallback, fallback != ""
+}
+
+// PrivateEndpointDNS is a planned private endpoint and the private DNS zone
+// its records need.
+type PrivateEndpointDNS struct {
+	// Address is the private endpoint instance.
+	Address string
+
+	// Target is the instance the endpoint connects to, and Subresource the
+	// subresource it connects to.
+	Target      string
+	Subresource string
+
+	// Zone is the privatelink zone the endpoint needs, and ZoneGroup the
+	// zones its private_dns_zone_group points at.
+	Zone      string
+	ZoneGroup []string
+}
+
+// String describes the endpoint, e.g. "azurerm_private_endpoint.openai[0]
+// (azurerm_cognitive_account.openai[0] account): privatelink.openai.azure.com".
+func (e PrivateEndpointDNS) String() string {
+	return fmt.Sprintf("%s (%s %s): %s", e.Address, e.Target, e.Subresource, e.Zone)
+}
+
+// PrivateDNSReport is the private DNS coverage of the private endpoints of
+// one or more plans.
+type PrivateDNSReport struct {
+	// Endpoints are the endpoints checked, sorted by address.
+	Endpoints []PrivateEndpointDNS
+
+	// Problems are endpoints whose records would not resolve, sorted.
+	Problems []string
+
+	// Unchecked are endpoints whose target or zone group the plans do not
+	// tell, sorted.
+	Unchecked []string
+}
+
+// AnalyzePrivateDNS checks every planned azurerm_private_endpoint of the
+// plans: its subresource maps to a privatelink zone in PrivateLinkZones, its
+// private_dns_zone_group points at that zone, and a planned
+// azurerm_private_dns_zone creates it, i.e. the networking module's
+// azurerm_private_dns_zone.zones. When no plan creates private DNS zones the
+// last check cannot be made, and the endpoint is listed as unchecked.
+//
+// config is the configuration the plans with private endpoints were made
+// from: a module, or the root module for endpoints in module calls. Plans
+// from mocked providers carry no configuration, so the endpoint's resource
+// block is read from config: the target is the resource
+// private_connection_resource_id references. Zone group IDs known at plan
+// time, as with the fixtures' PrivateDNSZoneIDs, name their zone. IDs only
+// known after apply are followed through the configuration: from the
+// variable the zone group reads to the module call argument, e.g.
+// private_dns_zone_ids = { openai =
+// module.networking.private_dns_zone_ids.openai }, and on to the
+// azurerm_private_dns_zone instance that module output is built from.
+func AnalyzePrivateDNS(config *Configuration, plans ...*Plan) *PrivateDNSReport {
+	report := &PrivateDNSReport{}
+	zones := map[string]bool{}
+	for _, plan := range plans {
+		for _, change := range plannedChanges(plan, "azurerm_private_dns_zone") {
+			if name, ok := change.After("name"); ok {
+				zones[fmt.Sprint(name)] = true
+			}
+		}
+	}
+
+	for _, plan := range plans {
+		for _, change := range plannedChanges(plan, "azurerm_private_endpoint") {
+			address := change.Address
+			endpoint, err := privateEndpointConfigE(config, change)
+			if err != nil {
+				report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s: %s", address, err))
+				continue
+			}
+			target, subresources, err := endpoint.target(plan, change)
+			if err != nil {
+				report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s: %s", address, err))
+				continue
+			}
+			zoneGroup, missing, err := endpoint.zoneGroup(plan, change)
+			if err != nil {
+				report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s: %s", address, err))
+				continue
+			}
+
+			for _, subresource := range subresources {
+				zone, ok := PrivateLinkZone(PrivateLinkTarget{Type: target.Type, Kind: target.Kind, Subresource: subresource})
+				if !ok {
+					report.Problems = append(report.Problems, fmt.Sprintf(
+						"%s: no privatelink zone is known for %s subresource %q; add it to helpers.PrivateLinkZones",
+						address, target.Type, subresource))
+					continue
+				}
+				report.Endpoints = append(report.Endpoints, PrivateEndpointDNS{
+					Address:     address,
+					Target:      target.Address,
+					Subresource: subresource,
+					Zone:        zone,
+					ZoneGroup:   zoneGroup,
+				})
+
+				switch {
+				case len(missing) > 0 && len(zoneGroup) == 0:
+					// Reported below
+				case len(zoneGroup) == 0:
+					report.Problems = append(report.Problems, fmt.Sprintf(
+						"%s: %s %s needs %s, but the endpoint has no private_dns_zone_group",
+						address, target.Address, subresource, zone))
+				case !containsString(zoneGroup, zone):
+					report.Problems = append(report.Problems, fmt.Sprintf(
+						"%s: %s %s needs %s, but its private_dns_zone_group points at %s",
+						address, target.Address, subresource, zone, strings.Join(zoneGroup, ", ")))
+				case len(zones) == 0:
+					report.Unchecked = append(report.Unchecked, fmt.Sprintf(
+						"%s: no plan creates private DNS zones, so %s may not exist; pass the networking plan too",
+						address, zone))
+				case !zones[zone]:
+					report.Problems = append(report.Problems, fmt.Sprintf(
+						"%s: %s is not created by any planned azurerm_private_dns_zone", address, zone))
+				}
+			}
+			for _, instance := range missing {
+				report.Problems = append(report.Problems, fmt.Sprintf(
+					"%s: its private_dns_zone_group reads %s, which is not planned", address, instance))
+			}
+		}
+	}
+
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return report.Endpoints[i].String() < report.Endpoints[j].String()
+	})
+	report.Problems = dedupe(report.Problems)
+	report.Unchecked = dedupe(report.Unchecked)
+	sort.Strings(report.Problems)
+	sort.Strings(report.Unchecked)
+	return report
+}
+
+// AssertPrivateDNS checks the private endpoints of the plans with
+// AnalyzePrivateDNS and fails the test listing every problem. Endpoints it
+// cannot check are logged.
+func AssertPrivateDNS(t testing.TestingT, config *Configuration, plans ...*Plan) bool {
+	markHelper(t)
+	report := AnalyzePrivateDNS(config, plans...)
+	for _, endpoint := range report.Endpoints {
+		logger.Default.Logf(t, "private endpoint %s", endpoint)
+	}
+	for _, unchecked := range report.Unchecked {
+		logger.Default.Logf(t, "private endpoint not checked: %s", unchecked)
+	}
+	if len(report.Problems) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("%d private endpoint DNS problem(s):\n  - %s",
+		len(report.Problems), strings.Join(report.Problems, "\n  - ")))
+}
+
+// plannedChanges returns the managed instances of a resource type in the
+// plan that are not planned for deletion, by address.
+func plannedChanges(plan *Plan, resourceType string) []*ResourceChange {
+	var changes []*ResourceChange
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		if change.Mode != "managed" || change.Type != resourceType {
+			continue
+		}
+		if action := change.Action(); action == ActionDelete || action == ActionRead {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// privateEndpointConfig is the resource block of a planned private endpoint
+// and the configuration that calls its module.
+type privateEndpointConfig struct {
+	block *hclsyntax.Block
+
+	// parent calls the endpoint's module with call; both are nil for an
+	// endpoint in config itself. prefix is the module address of parent
+	// without instance keys, e.g. "" for the root module.
+	parent *Configuration
+	call   *ModuleCall
+	prefix string
+}
+
+// privateEndpointConfigE finds the resource block of a planned private
+// endpoint, following the module calls in its address from config.
+func privateEndpointConfigE(config *Configuration, change *ResourceChange) (*privateEndpointConfig, error) {
+	if config == nil {
+		return nil, fmt.Errorf("no configuration to read the endpoint from")
+	}
+	endpoint := &privateEndpointConfig{}
+	dir := config.Dir
+	calls := moduleCalls(change.ModuleAddress)
+	for i, name := range calls {
+		call, ok := config.Calls[name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no module call %q", config.Dir, name)
+		}
+		endpoint.parent, endpoint.call = config, call
+		endpoint.prefix = strings.Join(calls[:i], ".module.")
+		if i > 0 {
+			endpoint.prefix = "module." + endpoint.prefix
+		}
+		dir = call.Dir
+		if i < len(calls)-1 {
+			var err error
+			if config, err = LoadConfigurationE(dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	block, err := moduleBlockE(dir, "resource", change.Type, change.Name)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("%s declares no resource %s.%s", dir, change.Type, change.Name)
+	}
+	endpoint.block = block
+	return endpoint, nil
+}
+
+// endpointTarget is the planned resource a private endpoint connects to.
+type endpointTarget struct {
+	Address string
+	Type    string
+	Kind    string
+}
+
+// target returns the planned resource the private endpoint connects to and
+// the subresources it connects to.
+func (e *privateEndpointConfig) target(plan *Plan, change *ResourceChange) (endpointTarget, []string, error) {
+	value, ok := change.After("private_service_connection.0.subresource_names")
+	if !ok {
+		return endpointTarget{}, nil, fmt.Errorf("subresource_names are not known until apply")
+	}
+	var subresources []string
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			subresources = append(subresources, fmt.Sprint(item))
+		}
+	}
+
+	for _, traversal := range nestedVariables(e.block, "private_service_connection", "private_connection_resource_id") {
+		instance, ok := traversalInstance(traversal)
+		if !ok {
+			continue
+		}
+		target, ok := plan.ResourceChangesMap[modulePrefix(change.ModuleAddress)+instance]
+		if !ok {
+			continue
+		}
+		found := endpointTarget{Address: target.Address, Type: target.Type}
+		if kind, ok := (&ResourceChange{ResourceChange: target}).After("kind"); ok && kind != nil {
+			found.Kind = fmt.Sprint(kind)
+		}
+		return found, subresources, nil
+	}
+	return endpointTarget{}, nil, fmt.Errorf("private_connection_resource_id does not reference a resource planned in the same module")
+}
+
+// zoneGroup returns the zones the private DNS zone group of the endpoint
+// points at. When the zone IDs are only known after apply, it follows them
+// through the configuration to azurerm_private_dns_zone instances, and also
+// returns the instances it reaches that are not planned.
+func (e *privateEndpointConfig) zoneGroup(plan *Plan, change *ResourceChange) ([]string, []string, error) {
+	groups, _ := change.After("private_dns_zone_group")
+	if list, _ := groups.([]interface{}); len(list) == 0 {
+		return nil, nil, nil
+	}
+	if value, ok := change.After("private_dns_zone_group.0.private_dns_zone_ids"); ok {
+		var zones []string
+		known := true
+		list, _ := value.([]interface{})
+		for i, item := range list {
+			if change.IsUnknown(fmt.Sprintf("private_dns_zone_group.0.private_dns_zone_ids.%d", i)) {
+				known = false
+				break
+			}
+			if zone, ok := privateDNSZoneName(fmt.Sprint(item)); ok {
+				zones = append(zones, zone)
+			}
+		}
+		if known {
+			return zones, nil, nil
+		}
+	}
+
+	var instances []string
+	for _, traversal := range nestedVariables(e.block, "private_dns_zone_group", "private_dns_zone_ids") {
+		switch traversal.RootName() {
+		case "azurerm_private_dns_zone":
+			if instance, ok := traversalInstance(traversal); ok {
+				instances = append(instances, modulePrefix(moduleIndex.ReplaceAllString(change.ModuleAddress, "$1"))+instance)
+			}
+		case "var":
+			found, err := e.argumentZones(traversal)
+			if err != nil {
+				return nil, nil, err
+			}
+			instances = append(instances, found...)
+		}
+	}
+	if len(instances) == 0 {
+		return nil, nil, fmt.Errorf("private DNS zone IDs are not known until apply and do not come from an azurerm_private_dns_zone")
+	}
+
+	var zones, missing []string
+	for _, instance := range instances {
+		if zone, ok := plannedZone(plan, instance); ok {
+			zones = append(zones, zone)
+		} else {
+			missing = append(missing, instance)
+		}
+	}
+	return zones, missing, nil
+}
+
+// argumentZones follows a variable reference such as
+// var.private_dns_zone_ids.openai to the module call argument that sets it
+// and returns the azurerm_private_dns_zone instances that argument reads
+// through module outputs.
+func (e *privateEndpointConfig) argumentZones(traversal hcl.Traversal) ([]string, error) {
+	if e.call == nil {
+		return nil, nil
+	}
+	name, ok := traversalAttr(traversal, 1)
+	if !ok {
+		return nil, nil
+	}
+	arg, ok := e.call.Arguments[name]
+	if !ok {
+		return nil, nil
+	}
+	var expr hcl.Expression = arg.Expr
+	if key, ok := traversalKey(traversal, 2); ok {
+		if expr, ok = objectItem(expr, key); !ok {
+			return nil, nil
+		}
+	}
+
+	var instances []string
+	for _, ref := range expr.Variables() {
+		if ref.RootName() != "module" {
+			continue
+		}
+		callName, ok := traversalAttr(ref, 1)
+		if !ok {
+			continue
+		}
+		output, ok := traversalAttr(ref, 2)
+		if !ok {
+			continue
+		}
+		key, ok := traversalKey(ref, 3)
+		if !ok {
+			continue
+		}
+		call, ok := e.parent.Calls[callName]
+		if !ok {
+			continue
+		}
+		block, err := moduleBlockE(call.Dir, "output", output)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil || block.Body.Attributes["value"] == nil {
+			continue
+		}
+		for _, zoneRef := range block.Body.Attributes["value"].Expr.Variables() {
+			zoneName, ok := traversalAttr(zoneRef, 1)
+			if zoneRef.RootName() != "azurerm_private_dns_zone" || !ok {
+				continue
+			}
+			instance := fmt.Sprintf("module.%s.azurerm_private_dns_zone.%s[%q]", callName, zoneName, key)
+			if e.prefix != "" {
+				instance = e.prefix + "." + instance
+			}
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// plannedZone returns the planned name of a zone instance. Instance keys of
+// module calls are ignored, so module.networking.azurerm_private_dns_zone
+// also finds module.networking[0].azurerm_private_dns_zone.
+func plannedZone(plan *Plan, address string) (string, bool) {
+	for _, change := range plannedChanges(plan, "azurerm_private_dns_zone") {
+		if moduleIndex.ReplaceAllString(change.Address, "$1") != address {
+			continue
+		}
+		if name, ok := change.After("name"); ok {
+			return fmt.Sprint(name), true
+		}
+	}
+	return "", false
+}
+
+// moduleBlockE returns the first block of a module with the given type and
+// labels, or nil when the module has none.
+func moduleBlockE(dir, blockType string, labels ...string) (*hclsyntax.Block, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			if block.Type == blockType && strings.Join(block.Labels, ".") == strings.Join(labels, ".") {
+				return block, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// nestedVariables returns the references of an argument in the first nested
+// block of the given type.
+func nestedVariables(block *hclsyntax.Block, blockType, attribute string) []hcl.Traversal {
+	for _, nested := range block.Body.Blocks {
+		if nested.Type != blockType {
+			continue
+		}
+		if attr, ok := nested.Body.Attributes[attribute]; ok {
+			return attr.Expr.Variables()
+		}
+		return nil
+	}
+	return nil
+}
+
+// traversalInstance returns the resource instance a reference such as
+// azurerm_cognitive_account.openai[0].id points at, e.g.
+// azurerm_cognitive_account.openai[0].
+func traversalInstance(traversal hcl.Traversal) (string, bool) {
+	switch traversal.RootName() {
+	case "var", "local", "module", "data", "each", "count", "path", "self", "terraform":
+		return "", false
+	}
+	name, ok := traversalAttr(traversal, 1)
+	if !ok {
+		return "", false
+	}
+	address := traversal.RootName() + "." + name
+	if len(traversal) > 2 {
+		if index, ok := traversal[2].(hcl.TraverseIndex); ok {
+			switch index.Key.Type() {
+			case cty.Number:
+				n, _ := index.Key.AsBigFloat().Int64()
+				address += fmt.Sprintf("[%d]", n)
+			case cty.String:
+				address += fmt.Sprintf("[%q]", index.Key.AsString())
+			}
+		}
+	}
+	return address, true
+}
+
+// traversalKey returns step i of a traversal as a map key, written either
+// as an attribute or as a string index.
+func traversalKey(traversal hcl.Traversal, i int) (string, bool) {
+	if key, ok := traversalAttr(traversal, i); ok {
+		return key, true
+	}
+	if len(traversal) > i {
+		if index, ok := traversal[i].(hcl.TraverseIndex); ok && index.Key.Type() == cty.String {
+			return index.Key.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// objectItem returns the value of an object constructor's item by key.
+func objectItem(expr hcl.Expression, key string) (hcl.Expression, bool) {
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil, false
+	}
+	for _, item := range object.Items {
+		if name := hcl.ExprAsKeyword(item.KeyExpr); name == key {
+			return item.ValueExpr, true
+		}
+		if value, diags := item.KeyExpr.Value(nil); !diags.HasErrors() && value.Type() == cty.String && value.AsString() == key {
+			return item.ValueExpr, true
+		}
+	}
+	return nil, false
+}
+
+// privateDNSZoneName returns the zone name at the end of a private DNS zone
+// ID.
+func privateDNSZoneName(id string) (string, bool) {
+	const segment = "/privatednszones/"
+	i := strings.Index(strings.ToLower(id), segment)
+	if i < 0 {
+		return "", false
+	}
+	name, _, _ := strings.Cut(id[i+len(segment):], "/")
+	return name, name != ""
+}
+
+var (
+	// moduleCall matches a module call in an address.
+	moduleCall = regexp.MustCompile(`module\.([A-Za-z0-9_-]+)`)
+
+	// moduleIndex matches a module call and its instance key.
+	moduleIndex = regexp.MustCompile(`(module\.[A-Za-z0-9_-]+)\[[^\]]*\]`)
+)
+
+// moduleCalls returns the module call names in a module address, e.g.
+// ["ai_foundry"] for module.ai_foundry[0].
+func moduleCalls(moduleAddress string) []string {
+	var calls []string
+	for _, match := range moduleCall.FindAllStringSubmatch(moduleAddress, -1) {
+		calls = append(calls, match[1])
+	}
+	return calls
+}
+
+// modulePrefix returns the prefix of resource addresses in a module, e.g.
+// "module.ai_foundry[0]." for module.ai_foundry[0].
+func modulePrefix(moduleAddress string) string {
+	if moduleAddress == "" {
+		return ""
+	}
+	return moduleAddress + "."
+}
@@ -0,0 +1,122 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - PRIVATE DNS TESTS
+// =============================================================================
+//
+// Tests for the private DNS coverage of planned private endpoints. Each plan
+// in testdata/private_dns sits next to the configuration it was made from.
+// plan_module.json is a module plan whose zone group IDs are known: an
+// endpoint in the wrong zone, one without a zone group, one whose zone is
+// not created and one with an unknown subresource. plan_root.json is a root
+// plan whose zone IDs come from module.networking and are only known after
+// apply.
+//
+// Run with: go test -v -run TestPrivateDNS ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrivateDNSModulePlan tests endpoints whose zone group IDs are known
+func TestPrivateDNSModulePlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "private_dns", "plan_module.json"))
+	require.NoError(t, err)
+	config, err := LoadConfigurationE(filepath.Join("testdata", "private_dns", "module"))
+	require.NoError(t, err)
+	report := AnalyzePrivateDNS(config, plan)
+
+	assert.Equal(t, []string{
+		"azurerm_private_endpoint.acr: privatelink.azurecr.io is not created by any planned azurerm_private_dns_zone",
+		"azurerm_private_endpoint.content_safety[0]: azurerm_cognitive_account.content_safety[0] account needs privatelink.cognitiveservices.azure.com, but its private_dns_zone_group points at privatelink.openai.azure.com",
+		"azurerm_private_endpoint.key_vault: azurerm_key_vault.main vault needs privatelink.vaultcore.azure.net, but the endpoint has no private_dns_zone_group",
+		`azurerm_private_endpoint.redis: no privatelink zone is known for azurerm_redis_cache subresource "redisEnterprise"; add it to helpers.PrivateLinkZones`,
+	}, report.Problems)
+	assert.Equal(t, []string{
+		"azurerm_private_endpoint.external: private_connection_resource_id does not reference a resource planned in the same module",
+	}, report.Unchecked)
+
+	if assert.Len(t, report.Endpoints, 4) {
+		assert.Equal(t, "azurerm_private_endpoint.openai[0] (azurerm_cognitive_account.openai[0] account): privatelink.openai.azure.com",
+			report.Endpoints[3].String())
+		assert.Equal(t, []string{"privatelink.openai.azure.com"}, report.Endpoints[3].ZoneGroup)
+	}
+
+	// Without a zone plan, whether the zones exist cannot be checked
+	for _, address := range plan.Addresses() {
+		if plan.ResourceChangesMap[address].Type == "azurerm_private_dns_zone" {
+			delete(plan.ResourceChangesMap, address)
+		}
+	}
+	report = AnalyzePrivateDNS(config, plan)
+	assert.Len(t, report.Problems, 3)
+	assert.NotContains(t, report.Problems,
+		"azurerm_private_endpoint.acr: privatelink.azurecr.io is not created by any planned azurerm_private_dns_zone")
+	assert.Contains(t, report.Unchecked,
+		"azurerm_private_endpoint.openai[0]: no plan creates private DNS zones, so privatelink.openai.azure.com may not exist; pass the networking plan too")
+}
+
+// TestPrivateDNSRootPlan tests endpoints whose zone IDs are resolved through the root configuration
+func TestPrivateDNSRootPlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "private_dns", "plan_root.json"))
+	require.NoError(t, err)
+	config, err := LoadConfigurationE(filepath.Join("testdata", "private_dns", "root"))
+	require.NoError(t, err)
+
+	report := AnalyzePrivateDNS(config, plan)
+	assert.Equal(t, []string{
+		`module.purview.azurerm_private_endpoint.portal: its private_dns_zone_group reads module.networking.azurerm_private_dns_zone.zones["purview_studio"], which is not planned`,
+	}, report.Problems)
+	assert.Empty(t, report.Unchecked)
+
+	zones := map[string][]string{}
+	for _, endpoint := range report.Endpoints {
+		zones[endpoint.Address] = endpoint.ZoneGroup
+	}
+	assert.Equal(t, map[string][]string{
+		"module.ai[0].azurerm_private_endpoint.content_safety": {"privatelink.cognitiveservices.azure.com"},
+		"module.ai[0].azurerm_private_endpoint.openai":         {"privatelink.openai.azure.com"},
+		"module.purview.azurerm_private_endpoint.portal":       nil,
+		"module.security.azurerm_private_endpoint.key_vault":   {"privatelink.vaultcore.azure.net"},
+	}, zones)
+
+	// Without the configuration no endpoint can be checked
+	report = AnalyzePrivateDNS(nil, plan)
+	assert.Empty(t, report.Problems)
+	assert.Len(t, report.Unchecked, 4)
+}
+
+// TestPrivateLinkZone tests the zone lookup by target type, kind and subresource
+func TestPrivateLinkZone(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		target PrivateLinkTarget
+		zone   string
+	}{
+		{PrivateLinkTarget{Type: "azurerm_cognitive_account", Kind: "OpenAI", Subresource: "account"}, "privatelink.openai.azure.com"},
+		{PrivateLinkTarget{Type: "azurerm_cognitive_account", Kind: "ContentSafety", Subresource: "account"}, "privatelink.cognitiveservices.azure.com"},
+		{PrivateLinkTarget{Type: "azurerm_purview_account", Subresource: "portal"}, "privatelink.purviewstudio.azure.com"},
+		{PrivateLinkTarget{Type: "azurerm_storage_account", Subresource: "BLOB"}, "privatelink.blob.core.windows.net"},
+		{PrivateLinkTarget{Type: "azurerm_key_vault", Subresource: "registry"}, ""},
+	}
+	for _, tc := range testCases {
+		zone, ok := PrivateLinkZone(tc.target)
+		assert.Equal(t, tc.zone, zone, "%+v", tc.target)
+		assert.Equal(t, tc.zone != "", ok, "%+v", tc.target)
+	}
+
+	name, ok := privateDNSZoneName(PrivateDNSZoneID("privatelink.vaultcore.azure.net"))
+	assert.True(t, ok)
+	assert.Equal(t, "privatelink.vaultcore.azure.net", name)
+}
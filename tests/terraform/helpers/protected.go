@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+// ProtectedResourceTypes are the stateful resources whose deletion loses data
+// or identity that cannot be recreated: secrets, databases, caches, backups,
+// the data catalog and pushed images. AssertNoProtectedDestroy uses them when
+// no types are given.
+var ProtectedResourceTypes = []string{
+	"azurerm_container_registry",
+	"azurerm_key_vault",
+	"azurerm_postgresql_flexible_server",
+	"azurerm_purview_account",
+	"azurerm_recovery_services_vault",
+	"azurerm_redis_cache",
+}
+
+// ProtectedChanges describes every planned delete or replace of a resource of
+// the given types, sorted by address. A replacement names the attributes that
+// force it with their prior and planned values.
+func ProtectedChanges(plan *Plan, protectedTypes []string) []string {
+	protected := map[string]bool{}
+	for _, resourceType := range protectedTypes {
+		protected[resourceType] = true
+	}
+
+	var changes []string
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		if change.Mode != "managed" || !protected[change.Type] {
+			continue
+		}
+
+		switch change.Action() {
+		case ActionDelete:
+			changes = append(changes, fmt.Sprintf("%s: planned delete of protected %s; if it was renamed or moved, add a moved block",
+				address, change.Type))
+		case ActionReplace:
+			changes = append(changes, fmt.Sprintf("%s: planned replace of protected %s, %s",
+				address, change.Type, replaceReason(change)))
+		}
+	}
+	return changes
+}
+
+// replaceReason names the attributes that force a replacement, with their
+// prior and planned values.
+func replaceReason(change *ResourceChange) string {
+	paths := change.ReplacePaths()
+	if len(paths) == 0 {
+		return "not forced by an attribute; check -replace and replace_triggered_by"
+	}
+	forcedBy := make([]string, 0, len(paths))
+	for _, path := range paths {
+		forcedBy = append(forcedBy, fmt.Sprintf("%s: %s => %s", path,
+			describeValue(change.Change, path, false), describeValue(change.Change, path, true)))
+	}
+	return "forced by " + strings.Join(forcedBy, "; ")
+}
+
+// AssertNoProtectedDestroy checks that the plan deletes or replaces no
+// resource of the protected types, or of ProtectedResourceTypes when none are
+// given. Run it on plans against existing state, where a module refactor that
+// renames a resource or changes a ForceNew attribute would otherwise wipe its
+// data.
+func AssertNoProtectedDestroy(t testing.TestingT, plan *Plan, protectedTypes ...string) bool {
+	markHelper(t)
+	if len(protectedTypes) == 0 {
+		protectedTypes = ProtectedResourceTypes
+	}
+	changes := ProtectedChanges(plan, protectedTypes)
+	if len(changes) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("the plan destroys protected resources:\n  %s", strings.Join(changes, "\n  ")))
+}
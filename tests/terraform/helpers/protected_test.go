@@ -0,0 +1,71 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - PROTECTED RESOURCE TESTS
+// =============================================================================
+//
+// Tests for the guard against deleting or replacing stateful resources, using
+// a recorded `terraform show -json` plan. These run without Terraform or Azure
+// credentials.
+//
+// Run with: go test -v -run TestProtected ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProtectedChangesDefaultTypes tests that deletes and replacements of the default types are reported
+func TestProtectedChangesDefaultTypes(t *testing.T) {
+	t.Parallel()
+
+	plan := loadProtectedPlan(t)
+
+	assert.Equal(t, []string{
+		`module.databases.azurerm_postgresql_flexible_server.main[0]: planned replace of protected azurerm_postgresql_flexible_server, forced by administrator_password: (sensitive) => (sensitive); high_availability.0.mode: "SameZone" => "ZoneRedundant"; version: "15" => "16"`,
+		`module.databases.azurerm_redis_cache.main[0]: planned delete of protected azurerm_redis_cache; if it was renamed or moved, add a moved block`,
+		`module.disaster_recovery.azurerm_recovery_services_vault.main: planned replace of protected azurerm_recovery_services_vault, not forced by an attribute; check -replace and replace_triggered_by`,
+		`module.security.azurerm_key_vault.main: planned replace of protected azurerm_key_vault, forced by name: "kv-contoso-dev-brs" => "kv-contoso-dev-brs01"`,
+	}, ProtectedChanges(plan, ProtectedResourceTypes))
+}
+
+// TestProtectedChangesCustomTypes tests that the protected list replaces the defaults
+func TestProtectedChangesCustomTypes(t *testing.T) {
+	t.Parallel()
+
+	plan := loadProtectedPlan(t)
+
+	assert.Equal(t, []string{
+		`module.security.azurerm_storage_account.logs: planned delete of protected azurerm_storage_account; if it was renamed or moved, add a moved block`,
+	}, ProtectedChanges(plan, []string{"azurerm_storage_account", "azurerm_container_registry"}))
+	assert.Empty(t, ProtectedChanges(plan, nil))
+}
+
+// TestProtectedAssertion tests the failure message of AssertNoProtectedDestroy
+func TestProtectedAssertion(t *testing.T) {
+	t.Parallel()
+
+	plan := loadProtectedPlan(t)
+
+	rec := &recordingT{}
+	assert.False(t, AssertNoProtectedDestroy(rec, plan))
+	assert.Contains(t, rec.output(), "the plan destroys protected resources:")
+	assert.Contains(t, rec.output(), "module.security.azurerm_key_vault.main: planned replace")
+	assert.NotContains(t, rec.output(), "azurerm_storage_account")
+
+	rec = &recordingT{}
+	assert.True(t, AssertNoProtectedDestroy(rec, plan, "azurerm_container_registry", "azurerm_purview_account"))
+	assert.Empty(t, rec.errors)
+}
+
+// loadProtectedPlan parses the recorded plan with protected resource changes.
+func loadProtectedPlan(t *testing.T) *Plan {
+	plan, err := LoadPlan(filepath.Join("testdata", "plan_protected.json"))
+	require.NoError(t, err)
+	return plan
+}
@@ -0,0 +1,349 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// regionAvailabilityPath holds the region availability matrix, relative to
+// the repository root.
+const regionAvailabilityPath = "config/region-availability.yaml"
+
+// RegionMatrix is config/region-availability.yaml. Regions without an entry
+// are not checked.
+type RegionMatrix struct {
+	Regions            map[string]*RegionEntry       `yaml:"regions"`
+	DeploymentPatterns map[string]*DeploymentPattern `yaml:"deployment_patterns"`
+}
+
+// RegionEntry records what one region offers the platform.
+type RegionEntry struct {
+	DisplayName   string `yaml:"display_name"`
+	DataResidency string `yaml:"data_residency"`
+	Tier          int    `yaml:"tier"`
+
+	// Services maps a service, e.g. ai_foundry or openai_gpt4o, to a note on
+	// its availability. A note starting "Not available" forbids it.
+	Services map[string]string `yaml:"services"`
+
+	RecommendedFor     []string                      `yaml:"recommended_for"`
+	Limitations        []string                      `yaml:"limitations"`
+	SizingAvailability map[string]SizingAvailability `yaml:"sizing_availability"`
+
+	name string
+}
+
+// SizingAvailability is a sizing_availability value: true, false, or a
+// condition under which the profile is available, e.g. "With AI spillover".
+type SizingAvailability struct {
+	Available bool
+	Condition string
+}
+
+// UnmarshalYAML reads a boolean or a condition string.
+func (s *SizingAvailability) UnmarshalYAML(node *yaml.Node) error {
+	if node.Tag == "!!bool" {
+		return node.Decode(&s.Available)
+	}
+	if node.Kind == yaml.ScalarNode && node.Value != "" {
+		s.Available = true
+		s.Condition = node.Value
+		return nil
+	}
+	return fmt.Errorf("line %d: sizing availability must be true, false or a condition", node.Line)
+}
+
+// DeploymentPattern is a recommended combination of regions.
+type DeploymentPattern struct {
+	Name             string            `yaml:"name"`
+	Description      string            `yaml:"description"`
+	PrimaryRegion    string            `yaml:"primary_region"`
+	AIRegion         string            `yaml:"ai_region"`
+	DRRegion         string            `yaml:"dr_region"`
+	SecondaryRegions []string          `yaml:"secondary_regions"`
+	Compliance       map[string]string `yaml:"compliance"`
+}
+
+// RegionViolation is a planned configuration a region entry forbids.
+type RegionViolation struct {
+	// Entry is the path of the forbidding value in the matrix, e.g.
+	// "regions.brazilsouth.services.openai_gpt4o".
+	Entry   string
+	Value   string
+	Message string
+}
+
+// String formats the violation with the entry that forbids it.
+func (v RegionViolation) String() string {
+	return fmt.Sprintf("%s (%s: %q)", v.Message, v.Entry, v.Value)
+}
+
+// RegionAvailability returns the matrix in config/region-availability.yaml.
+// This will fail the test if the file cannot be read.
+func RegionAvailability(t testing.TestingT) *RegionMatrix {
+	matrix, err := LoadRegionAvailabilityE(filepath.Join(RepoRoot(t), regionAvailabilityPath))
+	require.NoError(t, err)
+	return matrix
+}
+
+// LoadRegionAvailabilityE reads a region availability matrix. Deployment
+// patterns must name regions that have an entry.
+func LoadRegionAvailabilityE(path string) (*RegionMatrix, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var matrix RegionMatrix
+	if err := yaml.Unmarshal(src, &matrix); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(matrix.Regions) == 0 {
+		return nil, fmt.Errorf("%s defines no regions", path)
+	}
+	for name, entry := range matrix.Regions {
+		if entry == nil {
+			return nil, fmt.Errorf("%s: region %s is empty", path, name)
+		}
+		entry.name = name
+	}
+	for _, key := range sortedStrings(matrix.DeploymentPatterns) {
+		pattern := matrix.DeploymentPatterns[key]
+		regions := append([]string{pattern.PrimaryRegion, pattern.AIRegion, pattern.DRRegion}, pattern.SecondaryRegions...)
+		for _, region := range regions {
+			if region != "" && matrix.Regions[region] == nil {
+				return nil, fmt.Errorf("%s: deployment pattern %s uses region %s, which has no entry", path, key, region)
+			}
+		}
+	}
+	return &matrix, nil
+}
+
+// serviceAvailable reports whether a service note allows the service.
+func serviceAvailable(note string) bool {
+	return !strings.HasPrefix(strings.ToLower(strings.TrimSpace(note)), "not available")
+}
+
+// modelFamilyPattern splits a model name into its family, e.g. "gpt" and
+// "4o" in gpt-4o-mini, or "o3" in o3-mini.
+var modelFamilyPattern = regexp.MustCompile(`^([a-z]+)(?:-?([0-9][0-9a-z]*))?`)
+
+// modelFamily returns the family of a model or of a model named in a note:
+// gpt-4o-mini and GPT-4o are gpt4o, gpt-35-turbo and GPT-3.5 are gpt35, and
+// text-embedding-3-large is text.
+func modelFamily(model string) string {
+	model = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(model)), ".", "")
+	match := modelFamilyPattern.FindStringSubmatch(model)
+	if match == nil {
+		return model
+	}
+	return match[1] + match[2]
+}
+
+// limitedModels matches the models listed in a note such as "Limited models
+// (GPT-4, GPT-3.5)".
+var limitedModels = regexp.MustCompile(`\(([^)]*)\)`)
+
+// CheckModel checks that the region offers an OpenAI model. A service entry
+// for the model's family, e.g. openai_gpt4o for gpt-4o-mini, decides. Without
+// one, ai_foundry decides: a "Limited" note that lists models in parentheses
+// allows only those families, and any other note allows every model.
+func (m *RegionMatrix) CheckModel(region, model string) *RegionViolation {
+	entry := m.Regions[region]
+	if entry == nil {
+		return nil
+	}
+
+	family := modelFamily(model)
+	if note, ok := entry.Services["openai_"+family]; ok {
+		if serviceAvailable(note) {
+			return nil
+		}
+		return &RegionViolation{
+			Entry:   entry.path("services", "openai_"+family),
+			Value:   note,
+			Message: fmt.Sprintf("model %s is not available in %s", model, region),
+		}
+	}
+
+	note, ok := entry.Services["ai_foundry"]
+	if !ok || !serviceAvailable(note) {
+		return &RegionViolation{
+			Entry:   entry.path("services", "ai_foundry"),
+			Value:   note,
+			Message: fmt.Sprintf("AI Foundry is not available in %s for model %s", region, model),
+		}
+	}
+	listed := limitedModels.FindStringSubmatch(note)
+	if listed == nil || !strings.HasPrefix(strings.ToLower(note), "limited") {
+		return nil
+	}
+	for _, name := range strings.Split(listed[1], ",") {
+		if modelFamily(name) == family {
+			return nil
+		}
+	}
+	return &RegionViolation{
+		Entry:   entry.path("services", "ai_foundry"),
+		Value:   note,
+		Message: fmt.Sprintf("model %s is not among the models %s offers", model, region),
+	}
+}
+
+// CheckSizing checks that a sizing profile may be deployed in the region. A
+// profile missing from sizing_availability is not checked.
+func (m *RegionMatrix) CheckSizing(region, profile string) *RegionViolation {
+	entry := m.Regions[region]
+	if entry == nil {
+		return nil
+	}
+	availability, ok := entry.SizingAvailability[profile]
+	if !ok || availability.Available {
+		return nil
+	}
+	return &RegionViolation{
+		Entry:   entry.path("sizing_availability", profile),
+		Value:   "false",
+		Message: fmt.Sprintf("sizing profile %s is unavailable in %s", profile, region),
+	}
+}
+
+// CheckDRLocation checks that a disaster recovery region keeps data within
+// the primary region's data residency.
+func (m *RegionMatrix) CheckDRLocation(primary, dr string) *RegionViolation {
+	primaryEntry, drEntry := m.Regions[primary], m.Regions[dr]
+	if primaryEntry == nil || drEntry == nil || primaryEntry.DataResidency == "" {
+		return nil
+	}
+	if strings.EqualFold(primaryEntry.DataResidency, drEntry.DataResidency) {
+		return nil
+	}
+	return &RegionViolation{
+		Entry: primaryEntry.path("data_residency"),
+		Value: primaryEntry.DataResidency,
+		Message: fmt.Sprintf("dr_location %s keeps data in %s, outside the data residency of primary region %s",
+			dr, drEntry.DataResidency, primary),
+	}
+}
+
+// path returns the matrix path of a value under the entry.
+func (e *RegionEntry) path(keys ...string) string {
+	return strings.Join(append([]string{"regions", e.name}, keys...), ".")
+}
+
+// CheckPlan checks the OpenAI model deployments and disaster recovery
+// regions of a plan. A model's region is the location of the OpenAI account
+// planned in the same module. DR regions are read from the plan's
+// location (or primary_location) and dr_location variables, and from the
+// dr_configuration output of the disaster-recovery module, since offline
+// plans carry no variables. Violations are sorted by message.
+func (m *RegionMatrix) CheckPlan(plan *Plan) []RegionViolation {
+	var violations []RegionViolation
+
+	accounts := map[string]string{}
+	for _, account := range plan.ChangesByType("azurerm_cognitive_account") {
+		kind, _ := account.After("kind")
+		location, ok := account.After("location")
+		if kind == "OpenAI" && ok {
+			accounts[account.ModuleAddress], _ = location.(string)
+		}
+	}
+	for _, deployment := range plan.ChangesByType("azurerm_cognitive_deployment") {
+		region := accounts[deployment.ModuleAddress]
+		model, ok := deployment.After("model.0.name")
+		if region == "" || !ok {
+			continue
+		}
+		if violation := m.CheckModel(region, fmt.Sprint(model)); violation != nil {
+			violation.Message = deployment.Address + ": " + violation.Message
+			violations = append(violations, *violation)
+		}
+	}
+
+	for _, pair := range planDRLocations(plan) {
+		if violation := m.CheckDRLocation(pair[0], pair[1]); violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Message < violations[j].Message })
+	return violations
+}
+
+// planDRLocations returns the distinct primary and DR region pairs of a
+// plan.
+func planDRLocations(plan *Plan) [][2]string {
+	seen := map[[2]string]bool{}
+	var pairs [][2]string
+	add := func(primary, dr interface{}) {
+		p, pok := primary.(string)
+		d, dok := dr.(string)
+		if !pok || !dok || p == "" || d == "" || seen[[2]string{p, d}] {
+			return
+		}
+		seen[[2]string{p, d}] = true
+		pairs = append(pairs, [2]string{p, d})
+	}
+
+	if dr, ok := planVariable(plan, "dr_location"); ok {
+		if primary, ok := planVariable(plan, "primary_location"); ok {
+			add(primary, dr)
+		} else if primary, ok := planVariable(plan, "location"); ok {
+			add(primary, dr)
+		}
+	}
+	if config, ok := plan.Output("dr_configuration"); ok {
+		primary, _ := LookupPath(config, "primary_region")
+		dr, _ := LookupPath(config, "dr_region")
+		add(primary, dr)
+	}
+	return pairs
+}
+
+// planVariable returns the value of a root module variable in the plan.
+func planVariable(plan *Plan, name string) (interface{}, bool) {
+	if plan.RawPlan.Variables == nil {
+		return nil, false
+	}
+	variable, ok := plan.RawPlan.Variables[name]
+	if !ok || variable == nil {
+		return nil, false
+	}
+	return variable.Value, true
+}
+
+// AssertRegionAvailability checks a plan against the matrix and fails the
+// test listing each violation with the region entry that forbids it.
+func AssertRegionAvailability(t testing.TestingT, matrix *RegionMatrix, plan *Plan) bool {
+	markHelper(t)
+
+	violations := matrix.CheckPlan(plan)
+	if len(violations) == 0 {
+		return true
+	}
+	lines := make([]string, len(violations))
+	for i, violation := range violations {
+		lines[i] = violation.String()
+	}
+	return assert.Fail(t, fmt.Sprintf("%d region availability conflict(s) with %s:\n  - %s",
+		len(violations), regionAvailabilityPath, strings.Join(lines, "\n  - ")))
+}
+
+// AssertSizingAvailable checks that a sizing profile may be deployed in the
+// region.
+func AssertSizingAvailable(t testing.TestingT, matrix *RegionMatrix, region, profile string) bool {
+	markHelper(t)
+
+	if violation := matrix.CheckSizing(region, profile); violation != nil {
+		return assert.Fail(t, fmt.Sprintf("%s conflicts with %s: %s", profile, regionAvailabilityPath, violation))
+	}
+	return true
+}
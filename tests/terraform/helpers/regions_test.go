@@ -0,0 +1,200 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - REGION AVAILABILITY TESTS
+// =============================================================================
+//
+// Tests for checking planned configurations against the region availability
+// matrix: OpenAI models a region does not offer, sizing profiles a region
+// marks unavailable and disaster recovery regions outside the primary
+// region's data residency. The trimmed matrix and plans under
+// testdata/regions cover each conflict; config/region-availability.yaml is
+// checked for the facts the tests rely on.
+//
+// Run with: go test -v -run 'Region|ModelFamily' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegionAvailabilityLoad tests reading config/region-availability.yaml
+func TestRegionAvailabilityLoad(t *testing.T) {
+	t.Parallel()
+
+	matrix := RegionAvailability(t)
+	assert.Equal(t, []string{"brazilsouth", "eastus2", "southcentralus", "westus2"}, sortedStrings(matrix.Regions))
+	assert.Equal(t, "Brazil", matrix.Regions["brazilsouth"].DataResidency)
+	assert.Equal(t, SizingAvailability{Available: true, Condition: "With AI spillover"}, matrix.Regions["brazilsouth"].SizingAvailability["xlarge"])
+	assert.Equal(t, SizingAvailability{Available: true}, matrix.Regions["eastus2"].SizingAvailability["large"])
+	assert.Equal(t, "southcentralus", matrix.DeploymentPatterns["brazil_centric"].DRRegion)
+
+	assert.NotNil(t, matrix.CheckModel("brazilsouth", "gpt-4o"), "GPT-4o is not offered in Brazil South")
+	assert.Nil(t, matrix.CheckModel("eastus2", "gpt-4o"))
+	assert.NotNil(t, matrix.CheckDRLocation("brazilsouth", "eastus2"), "Brazil South data stays in Brazil")
+	assert.Nil(t, matrix.CheckDRLocation("eastus2", "southcentralus"))
+}
+
+// TestLoadRegionAvailabilityErrors tests that malformed matrices are rejected
+func TestLoadRegionAvailabilityErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no regions", "metadata:\n  last_updated: 2024-12\n", "defines no regions"},
+		{"empty region", "regions:\n  eastus2:\n", "region eastus2 is empty"},
+		{"bad sizing", "regions:\n  eastus2:\n    sizing_availability:\n      small: [true]\n", "sizing availability must be true, false or a condition"},
+		{"unknown pattern region", "regions:\n  eastus2:\n    tier: 1\ndeployment_patterns:\n  us:\n    primary_region: eastus2\n    dr_region: centralus\n", "deployment pattern us uses region centralus, which has no entry"},
+	}
+
+	for _, tc := range testCases {
+		path := filepath.Join(t.TempDir(), "region-availability.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(tc.src), 0o644))
+		_, err := LoadRegionAvailabilityE(path)
+		assert.ErrorContains(t, err, tc.want, tc.name)
+	}
+}
+
+// TestModelFamily tests grouping model names with the names used in notes
+func TestModelFamily(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"gpt-4o":                 "gpt4o",
+		"gpt-4o-mini":            "gpt4o",
+		"GPT-4o":                 "gpt4o",
+		"gpt-4":                  "gpt4",
+		"GPT-4":                  "gpt4",
+		"gpt-35-turbo":           "gpt35",
+		" GPT-3.5":               "gpt35",
+		"o3-mini":                "o3",
+		"gpt-5":                  "gpt5",
+		"text-embedding-3-large": "text",
+	}
+
+	for model, want := range testCases {
+		assert.Equal(t, want, modelFamily(model), model)
+	}
+}
+
+// TestRegionCheckModel tests model availability per region
+func TestRegionCheckModel(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadTestRegionMatrix(t)
+
+	testCases := []struct {
+		region string
+		model  string
+		entry  string
+	}{
+		{"brazilsouth", "gpt-4o", "regions.brazilsouth.services.openai_gpt4o"},
+		{"brazilsouth", "gpt-4o-mini", "regions.brazilsouth.services.openai_gpt4o"},
+		{"brazilsouth", "gpt-4", ""},
+		{"brazilsouth", "gpt-35-turbo", ""},
+		{"brazilsouth", "text-embedding-3-large", "regions.brazilsouth.services.ai_foundry"},
+		{"eastus2", "gpt-4o", ""},
+		{"eastus2", "text-embedding-3-large", ""},
+		{"westus2", "gpt-4o", ""},
+		{"westus2", "gpt-4", "regions.westus2.services.ai_foundry"},
+		{"centralus", "gpt-4o", ""},
+	}
+
+	for _, tc := range testCases {
+		violation := matrix.CheckModel(tc.region, tc.model)
+		if tc.entry == "" {
+			assert.Nil(t, violation, "%s in %s", tc.model, tc.region)
+			continue
+		}
+		if assert.NotNil(t, violation, "%s in %s", tc.model, tc.region) {
+			assert.Equal(t, tc.entry, violation.Entry, "%s in %s", tc.model, tc.region)
+		}
+	}
+
+	assert.Equal(t,
+		`model gpt-4o is not available in brazilsouth (regions.brazilsouth.services.openai_gpt4o: "Not available (use East US 2)")`,
+		matrix.CheckModel("brazilsouth", "gpt-4o").String())
+}
+
+// TestRegionCheckSizing tests sizing profile availability per region
+func TestRegionCheckSizing(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadTestRegionMatrix(t)
+
+	violation := matrix.CheckSizing("brazilsouth", "large")
+	require.NotNil(t, violation)
+	assert.Equal(t, `sizing profile large is unavailable in brazilsouth (regions.brazilsouth.sizing_availability.large: "false")`, violation.String())
+
+	assert.Nil(t, matrix.CheckSizing("brazilsouth", "small"))
+	assert.Nil(t, matrix.CheckSizing("brazilsouth", "xlarge"), "a condition makes the profile available")
+	assert.Nil(t, matrix.CheckSizing("eastus2", "medium"), "profiles not listed are not checked")
+	assert.Nil(t, matrix.CheckSizing("centralus", "large"), "regions without an entry are not checked")
+
+	recorder := &recordingT{}
+	assert.False(t, AssertSizingAvailable(recorder, matrix, "brazilsouth", "large"))
+	assert.Contains(t, recorder.output(), "large conflicts with config/region-availability.yaml: sizing profile large is unavailable in brazilsouth")
+}
+
+// TestRegionCheckDRLocation tests DR regions against data residency
+func TestRegionCheckDRLocation(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadTestRegionMatrix(t)
+
+	violation := matrix.CheckDRLocation("brazilsouth", "eastus2")
+	require.NotNil(t, violation)
+	assert.Equal(t, "regions.brazilsouth.data_residency", violation.Entry)
+	assert.Equal(t,
+		`dr_location eastus2 keeps data in United States, outside the data residency of primary region brazilsouth (regions.brazilsouth.data_residency: "Brazil")`,
+		violation.String())
+
+	assert.Nil(t, matrix.CheckDRLocation("eastus2", "westus2"))
+	assert.Nil(t, matrix.CheckDRLocation("brazilsouth", "centralus"), "regions without an entry are not checked")
+}
+
+// TestRegionCheckPlan tests reading models and DR regions from plans
+func TestRegionCheckPlan(t *testing.T) {
+	t.Parallel()
+
+	matrix := loadTestRegionMatrix(t)
+
+	plan, err := LoadPlan(filepath.Join("testdata", "regions", "plan_ai_foundry.json"))
+	require.NoError(t, err)
+	violations := matrix.CheckPlan(plan)
+	require.Len(t, violations, 2)
+	assert.Equal(t, `azurerm_cognitive_deployment.models["embedding"]: model text-embedding-3-large is not among the models brazilsouth offers`, violations[0].Message)
+	assert.Equal(t, `azurerm_cognitive_deployment.models["gpt-4o"]: model gpt-4o is not available in brazilsouth`, violations[1].Message)
+
+	recorder := &recordingT{}
+	assert.False(t, AssertRegionAvailability(recorder, matrix, plan))
+	assert.Contains(t, recorder.output(), "2 region availability conflict(s) with config/region-availability.yaml:")
+	assert.Contains(t, recorder.output(), `  - azurerm_cognitive_deployment.models["gpt-4o"]: model gpt-4o is not available in brazilsouth (regions.brazilsouth.services.openai_gpt4o: "Not available (use East US 2)")`)
+
+	plan, err = LoadPlan(filepath.Join("testdata", "regions", "plan_disaster_recovery.json"))
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{{"eastus2", "westus2"}, {"brazilsouth", "eastus2"}}, planDRLocations(plan))
+	violations = matrix.CheckPlan(plan)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "regions.brazilsouth.data_residency", violations[0].Entry)
+
+	recorder = &recordingT{}
+	assert.True(t, AssertRegionAvailability(recorder, matrix, loadTestPlan(t)))
+	assert.Empty(t, recorder.errors)
+}
+
+// loadTestRegionMatrix returns the matrix in testdata/regions.
+func loadTestRegionMatrix(t *testing.T) *RegionMatrix {
+	matrix, err := LoadRegionAvailabilityE(filepath.Join("testdata", "regions", "region-availability.yaml"))
+	require.NoError(t, err)
+	return matrix
+}
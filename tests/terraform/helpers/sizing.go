@@ -0,0 +1,645 @@
+package helpers
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// sizingProfilesPath holds the t-shirt sizing profiles, relative to the
+// repository root.
+const sizingProfilesPath = "config/sizing-profiles.yaml"
+
+// SizingModules lists the modules a sizing profile is translated into.
+var SizingModules = []string{"aks-cluster", "container-registry", "databases", "networking"}
+
+// SizingProfileSet is config/sizing-profiles.yaml.
+type SizingProfileSet struct {
+	Profiles map[string]*SizingProfile `yaml:"profiles"`
+	Metadata struct {
+		Version string `yaml:"version"`
+	} `yaml:"metadata"`
+}
+
+// SizingProfile is one t-shirt size. Only the infrastructure and databases
+// sections are read; AI Foundry, observability and disaster recovery sizing
+// have no module inputs in SizingModules.
+type SizingProfile struct {
+	Name           string               `yaml:"profile"`
+	Description    string               `yaml:"description"`
+	MultiRegion    bool                 `yaml:"multi_region"`
+	Regions        *SizingRegions       `yaml:"regions"`
+	Infrastructure SizingInfrastructure `yaml:"infrastructure"`
+	Databases      SizingDatabases      `yaml:"databases"`
+}
+
+// SizingRegions are the regions of a multi-region profile.
+type SizingRegions struct {
+	Primary   string `yaml:"primary"`
+	Secondary string `yaml:"secondary"`
+}
+
+// SizingInfrastructure is a profile's infrastructure section. A multi-region
+// profile sizes AKS per region under Primary and Secondary instead of AKS.
+type SizingInfrastructure struct {
+	AKS        *AKSSizing        `yaml:"aks"`
+	Primary    *RegionalSizing   `yaml:"primary"`
+	Secondary  *RegionalSizing   `yaml:"secondary"`
+	ACR        *ACRSizing        `yaml:"acr"`
+	KeyVault   *KeyVaultSizing   `yaml:"keyvault"`
+	Networking *NetworkingSizing `yaml:"networking"`
+	FrontDoor  *FrontDoorSizing  `yaml:"front_door"`
+}
+
+// RegionalSizing is the per-region infrastructure of a multi-region profile.
+type RegionalSizing struct {
+	AKS *AKSSizing `yaml:"aks"`
+}
+
+// AKSSizing sizes a cluster. Smaller profiles give a single NodePool with
+// cluster-wide AutoScaling; larger ones list NodePools; see Pools.
+type AKSSizing struct {
+	Name              string             `yaml:"name"`
+	KubernetesVersion string             `yaml:"kubernetes_version"`
+	NodePool          *NodePoolSizing    `yaml:"node_pool"`
+	NodePools         []NodePoolSizing   `yaml:"node_pools"`
+	AutoScaling       *AutoScalingSizing `yaml:"auto_scaling"`
+	Features          AKSFeatures        `yaml:"features"`
+}
+
+// NodePoolSizing sizes one node pool. Zero values are unset.
+type NodePoolSizing struct {
+	Name         string             `yaml:"name"`
+	NodeCount    int                `yaml:"node_count"`
+	VMSize       string             `yaml:"vm_size"`
+	OSDiskSizeGB int                `yaml:"os_disk_size_gb"`
+	MaxPods      int                `yaml:"max_pods"`
+	Mode         string             `yaml:"mode"`
+	Zones        []string           `yaml:"zones"`
+	Taints       []string           `yaml:"taints"`
+	AutoScaling  *AutoScalingSizing `yaml:"auto_scaling"`
+}
+
+// AutoScalingSizing is the autoscaler range of a pool.
+type AutoScalingSizing struct {
+	Enabled  bool `yaml:"enabled"`
+	MinNodes int  `yaml:"min_nodes"`
+	MaxNodes int  `yaml:"max_nodes"`
+}
+
+// AKSFeatures are optional cluster features. A nil field is not set by the
+// profile and keeps the module default.
+type AKSFeatures struct {
+	AzurePolicy      *bool `yaml:"azure_policy"`
+	OIDCIssuer       *bool `yaml:"oidc_issuer"`
+	WorkloadIdentity *bool `yaml:"workload_identity"`
+	AzureMonitor     *bool `yaml:"azure_monitor"`
+	Defender         *bool `yaml:"defender"`
+	PrivateCluster   *bool `yaml:"private_cluster"`
+	UptimeSLA        *bool `yaml:"uptime_sla"`
+}
+
+// ACRSizing sizes the container registry.
+type ACRSizing struct {
+	Name           string           `yaml:"name"`
+	SKU            string           `yaml:"sku"`
+	GeoReplication bool             `yaml:"geo_replication"`
+	Replications   []ACRReplication `yaml:"replications"`
+	RetentionDays  int              `yaml:"retention_days"`
+	ZoneRedundancy bool             `yaml:"zone_redundancy"`
+}
+
+// ACRReplication is one geo-replica of the registry.
+type ACRReplication struct {
+	Location       string `yaml:"location"`
+	ZoneRedundancy bool   `yaml:"zone_redundancy"`
+}
+
+// KeyVaultSizing sizes the Key Vault.
+type KeyVaultSizing struct {
+	Name            string `yaml:"name"`
+	SKU             string `yaml:"sku"`
+	SoftDeleteDays  int    `yaml:"soft_delete_days"`
+	PurgeProtection bool   `yaml:"purge_protection"`
+}
+
+// NetworkingSizing gives the VNet and subnet CIDRs. Empty CIDRs are unset.
+type NetworkingSizing struct {
+	VNetCIDR               string                    `yaml:"vnet_cidr"`
+	AKSSubnet              string                    `yaml:"aks_subnet"`
+	ServicesSubnet         string                    `yaml:"services_subnet"`
+	PrivateEndpointsSubnet string                    `yaml:"private_endpoints_subnet"`
+	AppGatewaySubnet       string                    `yaml:"appgw_subnet"`
+	PrivateCluster         bool                      `yaml:"private_cluster"`
+	ApplicationGateway     *ApplicationGatewaySizing `yaml:"application_gateway"`
+}
+
+// ApplicationGatewaySizing sizes the Application Gateway.
+type ApplicationGatewaySizing struct {
+	Enabled  bool   `yaml:"enabled"`
+	SKU      string `yaml:"sku"`
+	Capacity int    `yaml:"capacity"`
+}
+
+// FrontDoorSizing sizes Azure Front Door.
+type FrontDoorSizing struct {
+	Enabled   bool   `yaml:"enabled"`
+	SKU       string `yaml:"sku"`
+	WAFPolicy bool   `yaml:"waf_policy"`
+}
+
+// SizingDatabases is a profile's databases section.
+type SizingDatabases struct {
+	PostgreSQL *PostgreSQLSizing `yaml:"postgresql"`
+	Redis      *RedisSizing      `yaml:"redis"`
+	CosmosDB   *CosmosDBSizing   `yaml:"cosmos_db"`
+}
+
+// PostgreSQLSizing sizes the flexible server. SKU is the VM size without the
+// tier prefix, e.g. Standard_D2ds_v5. A multi-region profile sizes the
+// server under Primary and lists ReadReplicas.
+type PostgreSQLSizing struct {
+	Enabled             *bool               `yaml:"enabled"`
+	Name                string              `yaml:"name"`
+	Version             string              `yaml:"version"`
+	SKU                 string              `yaml:"sku"`
+	StorageGB           int                 `yaml:"storage_gb"`
+	HAEnabled           bool                `yaml:"ha_enabled"`
+	HAMode              string              `yaml:"ha_mode"`
+	BackupRetentionDays int                 `yaml:"backup_retention_days"`
+	GeoRedundantBackup  bool                `yaml:"geo_redundant_backup"`
+	Primary             *PostgreSQLSizing   `yaml:"primary"`
+	ReadReplicas        []PostgreSQLReplica `yaml:"read_replicas"`
+}
+
+// PostgreSQLReplica is a read replica in another region.
+type PostgreSQLReplica struct {
+	Region string `yaml:"region"`
+	SKU    string `yaml:"sku"`
+}
+
+// RedisSizing sizes the cache.
+type RedisSizing struct {
+	Enabled        *bool    `yaml:"enabled"`
+	Name           string   `yaml:"name"`
+	SKU            string   `yaml:"sku"`
+	Family         string   `yaml:"family"`
+	Capacity       int      `yaml:"capacity"`
+	Zones          []string `yaml:"zones"`
+	GeoReplication bool     `yaml:"geo_replication"`
+}
+
+// CosmosDBSizing sizes Cosmos DB.
+type CosmosDBSizing struct {
+	Enabled           bool     `yaml:"enabled"`
+	API               string   `yaml:"api"`
+	MultiRegionWrites bool     `yaml:"multi_region_writes"`
+	Regions           []string `yaml:"regions"`
+}
+
+// SizingProfiles returns the profiles in config/sizing-profiles.yaml. This
+// will fail the test if the file cannot be read.
+func SizingProfiles(t testing.TestingT) *SizingProfileSet {
+	profiles, err := LoadSizingProfilesE(filepath.Join(RepoRoot(t), sizingProfilesPath))
+	require.NoError(t, err)
+	return profiles
+}
+
+// LoadSizingProfilesE reads a sizing profiles file. Every profile must size
+// at least one AKS cluster.
+func LoadSizingProfilesE(path string) (*SizingProfileSet, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profiles SizingProfileSet
+	if err := yaml.Unmarshal(src, &profiles); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(profiles.Profiles) == 0 {
+		return nil, fmt.Errorf("%s defines no profiles", path)
+	}
+	for key, profile := range profiles.Profiles {
+		if profile.Name == "" {
+			profile.Name = key
+		}
+		if profile.Name != key {
+			return nil, fmt.Errorf("%s: profile %s is named %q", path, key, profile.Name)
+		}
+		if len(profile.Clusters()) == 0 {
+			return nil, fmt.Errorf("%s: profile %s sizes no AKS cluster", path, key)
+		}
+		for _, cluster := range profile.Clusters() {
+			if len(cluster.AKS.Pools()) == 0 {
+				return nil, fmt.Errorf("%s: profile %s has no node pools for %s", path, key, cluster)
+			}
+		}
+	}
+	return &profiles, nil
+}
+
+// sizingOrder is the order of the sizing matrix in the file's header.
+var sizingOrder = map[string]int{"small": 1, "medium": 2, "large": 3, "xlarge": 4}
+
+// Names returns the profile names, smallest first; profiles outside the
+// sizing matrix follow in alphabetical order.
+func (s *SizingProfileSet) Names() []string {
+	names := sortedStrings(s.Profiles)
+	sortSizingNames(names)
+	return names
+}
+
+// sortSizingNames orders sorted names by the sizing matrix, keeping the rest
+// in place after the profiles.
+func sortSizingNames(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		oi, oj := sizingOrder[names[i]], sizingOrder[names[j]]
+		if oi == 0 || oj == 0 {
+			return oi != 0 && oj == 0
+		}
+		return oi < oj
+	})
+}
+
+// ProfileE returns the named profile.
+func (s *SizingProfileSet) ProfileE(name string) (*SizingProfile, error) {
+	profile, ok := s.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no sizing profile %q; profiles are %s", name, strings.Join(s.Names(), ", "))
+	}
+	return profile, nil
+}
+
+// Pools returns the cluster's node pools with the system pool first. A
+// single NodePool is a system pool that takes the cluster's AutoScaling.
+func (a *AKSSizing) Pools() []NodePoolSizing {
+	if a.NodePool != nil {
+		pool := *a.NodePool
+		pool.Mode = "System"
+		if pool.AutoScaling == nil {
+			pool.AutoScaling = a.AutoScaling
+		}
+		return []NodePoolSizing{pool}
+	}
+	pools := append([]NodePoolSizing(nil), a.NodePools...)
+	sort.SliceStable(pools, func(i, j int) bool {
+		return pools[i].isSystem() && !pools[j].isSystem()
+	})
+	return pools
+}
+
+// isSystem reports whether the pool is the system pool: one with mode System
+// or, when no mode is given, the one named system.
+func (p NodePoolSizing) isSystem() bool {
+	if p.Mode != "" {
+		return p.Mode == "System"
+	}
+	return p.Name == "system"
+}
+
+// Autoscaled reports whether the autoscaler manages the pool.
+func (p NodePoolSizing) Autoscaled() bool {
+	return p.AutoScaling != nil && p.AutoScaling.Enabled
+}
+
+// ClusterSizing is one AKS cluster of a profile and the region it runs in;
+// Location is empty for the fixture's default location.
+type ClusterSizing struct {
+	Role     string
+	Location string
+	AKS      *AKSSizing
+}
+
+// String names the cluster, e.g. "primary cluster".
+func (c ClusterSizing) String() string {
+	return c.Role + " cluster"
+}
+
+// Clusters returns the AKS clusters the profile sizes: one, or a primary and
+// a secondary for a multi-region profile.
+func (p *SizingProfile) Clusters() []ClusterSizing {
+	var clusters []ClusterSizing
+	if p.Infrastructure.AKS != nil {
+		clusters = append(clusters, ClusterSizing{Role: "primary", AKS: p.Infrastructure.AKS})
+	}
+	regions := p.Regions
+	if regions == nil {
+		regions = &SizingRegions{}
+	}
+	if p.Infrastructure.Primary != nil && p.Infrastructure.Primary.AKS != nil {
+		clusters = append(clusters, ClusterSizing{Role: "primary", Location: regions.Primary, AKS: p.Infrastructure.Primary.AKS})
+	}
+	if p.Infrastructure.Secondary != nil && p.Infrastructure.Secondary.AKS != nil {
+		clusters = append(clusters, ClusterSizing{Role: "secondary", Location: regions.Secondary, AKS: p.Infrastructure.Secondary.AKS})
+	}
+	return clusters
+}
+
+// PostgreSQLServer returns the sizing of the primary PostgreSQL server, or
+// nil if the profile has none.
+func (p *SizingProfile) PostgreSQLServer() *PostgreSQLSizing {
+	server := p.Databases.PostgreSQL
+	if server != nil && server.Primary != nil {
+		return server.Primary
+	}
+	return server
+}
+
+// PostgreSQLSKUName converts a VM size into a flexible server sku_name, which
+// prefixes the tier: B_ for burstable B-series, GP_ for general purpose
+// D-series and MO_ for memory optimized E-series.
+func PostgreSQLSKUName(vmSize string) (string, error) {
+	series := strings.TrimPrefix(vmSize, "Standard_")
+	switch {
+	case series == vmSize || series == "":
+		return "", fmt.Errorf("PostgreSQL size %q is not a Standard_ VM size", vmSize)
+	case strings.HasPrefix(series, "B"):
+		return "B_" + vmSize, nil
+	case strings.HasPrefix(series, "D"):
+		return "GP_" + vmSize, nil
+	case strings.HasPrefix(series, "E"):
+		return "MO_" + vmSize, nil
+	default:
+		return "", fmt.Errorf("PostgreSQL size %q is not a B, D or E series VM size", vmSize)
+	}
+}
+
+// AKSClusterFixtureE returns the aks-cluster baseline fixture sized for one
+// cluster of the profile. The system pool becomes default_node_pool and the
+// other pools additional_node_pools, keyed by name. Values the profile leaves
+// unset keep the baseline.
+func (p *SizingProfile) AKSClusterFixtureE(cluster ClusterSizing) (*Fixture, error) {
+	fixture := AKSCluster()
+	if cluster.Location != "" {
+		fixture.With("location", cluster.Location)
+	}
+	if cluster.AKS.KubernetesVersion != "" {
+		fixture.With("kubernetes_version", cluster.AKS.KubernetesVersion)
+	}
+
+	pools := cluster.AKS.Pools()
+	if !pools[0].isSystem() {
+		return nil, fmt.Errorf("profile %s %s has no system node pool", p.Name, cluster)
+	}
+	baseline := fixture.Vars["default_node_pool"].(map[string]interface{})
+	system := pools[0]
+	fixture.WithAttr("default_node_pool", "name", system.Name)
+	setPoolSize(baseline, system)
+	if system.OSDiskSizeGB > 0 {
+		fixture.WithAttr("default_node_pool", "os_disk_size_gb", system.OSDiskSizeGB)
+	}
+
+	additional := map[string]interface{}{}
+	for _, pool := range pools[1:] {
+		if pool.isSystem() {
+			return nil, fmt.Errorf("profile %s %s has more than one system node pool", p.Name, cluster)
+		}
+		object := map[string]interface{}{
+			"name":        pool.Name,
+			"max_pods":    baseline["max_pods"],
+			"node_labels": map[string]string{},
+			"node_taints": []string{},
+			"zones":       baseline["zones"],
+		}
+		setPoolSize(object, pool)
+		if len(pool.Taints) > 0 {
+			object["node_taints"] = append([]string(nil), pool.Taints...)
+		}
+		additional[pool.Name] = object
+	}
+	fixture.With("additional_node_pools", additional)
+
+	features := cluster.AKS.Features
+	if features.AzurePolicy != nil {
+		fixture.With("enable_azure_policy", *features.AzurePolicy)
+	}
+	if features.WorkloadIdentity != nil {
+		fixture.With("enable_workload_identity", *features.WorkloadIdentity)
+	}
+	if features.Defender != nil {
+		fixture.With("enable_defender", *features.Defender)
+	}
+	if features.UptimeSLA != nil && *features.UptimeSLA {
+		fixture.With("sku_tier", "Standard")
+	}
+	return fixture, nil
+}
+
+// setPoolSize sets the size attributes shared by default_node_pool and
+// additional_node_pools. A pool without autoscaling gets min_count and
+// max_count equal to node_count, which the module ignores.
+func setPoolSize(object map[string]interface{}, pool NodePoolSizing) {
+	object["vm_size"] = pool.VMSize
+	object["node_count"] = pool.NodeCount
+	object["enable_auto_scaling"] = pool.Autoscaled()
+	object["min_count"] = pool.NodeCount
+	object["max_count"] = pool.NodeCount
+	if pool.Autoscaled() {
+		object["min_count"] = pool.AutoScaling.MinNodes
+		object["max_count"] = pool.AutoScaling.MaxNodes
+	}
+	if pool.MaxPods > 0 {
+		object["max_pods"] = pool.MaxPods
+	}
+	if len(pool.Zones) > 0 {
+		object["zones"] = append([]string(nil), pool.Zones...)
+	}
+}
+
+// ContainerRegistryFixtureE returns the container-registry baseline fixture
+// with the profile's SKU, replica locations and retention.
+func (p *SizingProfile) ContainerRegistryFixtureE() (*Fixture, error) {
+	fixture := p.regional(ContainerRegistry())
+	acr := p.Infrastructure.ACR
+	if acr == nil {
+		return fixture, nil
+	}
+	if acr.SKU == "" {
+		return nil, fmt.Errorf("profile %s sizes acr without a sku", p.Name)
+	}
+	fixture.With("sku", acr.SKU)
+	locations := []string{}
+	for _, replication := range acr.Replications {
+		locations = append(locations, replication.Location)
+	}
+	fixture.With("geo_replication_locations", locations)
+	if acr.RetentionDays > 0 {
+		fixture.With("retention_policy_days", acr.RetentionDays)
+	}
+	return fixture, nil
+}
+
+// DatabasesFixtureE returns the databases baseline fixture with the
+// profile's PostgreSQL and Redis sizing. Read replicas have no module input
+// and are not translated.
+func (p *SizingProfile) DatabasesFixtureE() (*Fixture, error) {
+	fixture := p.regional(Databases())
+
+	if server := p.PostgreSQLServer(); server != nil {
+		if server.Enabled != nil && !*server.Enabled {
+			fixture.WithAttr("postgresql_config", "enabled", false)
+		} else {
+			skuName, err := PostgreSQLSKUName(server.SKU)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+			}
+			fixture.WithAttr("postgresql_config", "sku_name", skuName)
+			fixture.WithAttr("postgresql_config", "storage_mb", server.StorageGB*1024)
+			fixture.WithAttr("postgresql_config", "high_availability", server.HAEnabled)
+			fixture.WithAttr("postgresql_config", "geo_redundant_backup", server.GeoRedundantBackup)
+			if server.Version != "" {
+				fixture.WithAttr("postgresql_config", "version", server.Version)
+			}
+			if server.BackupRetentionDays > 0 {
+				fixture.WithAttr("postgresql_config", "backup_retention_days", server.BackupRetentionDays)
+			}
+		}
+	}
+
+	if redis := p.Databases.Redis; redis != nil {
+		if redis.Enabled != nil && !*redis.Enabled {
+			fixture.WithAttr("redis_config", "enabled", false)
+		} else {
+			fixture.WithAttr("redis_config", "sku_name", redis.SKU)
+			fixture.WithAttr("redis_config", "family", redis.Family)
+			fixture.WithAttr("redis_config", "capacity", redis.Capacity)
+		}
+	}
+	return fixture, nil
+}
+
+// NetworkingFixtureE returns the networking baseline fixture with the
+// profile's VNet and subnets. The module also needs pods, private endpoint,
+// bastion and Application Gateway subnets; those the profile does not give
+// are allocated from the first free range of the VNet, at the baseline's
+// prefix length, so no two subnets overlap.
+func (p *SizingProfile) NetworkingFixtureE() (*Fixture, error) {
+	fixture := p.regional(Networking())
+	networking := p.Infrastructure.Networking
+	if networking == nil {
+		return fixture, nil
+	}
+
+	vnetCIDR := networking.VNetCIDR
+	if vnetCIDR == "" {
+		vnetCIDR = fixture.Vars["vnet_cidr"].(string)
+	}
+	vnet, err := netip.ParsePrefix(vnetCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s vnet_cidr: %w", p.Name, err)
+	}
+	fixture.With("vnet_cidr", vnetCIDR)
+
+	baseline := fixture.Vars["subnet_config"].(map[string]interface{})
+	given := map[string]string{
+		"aks_nodes_cidr":         networking.AKSSubnet,
+		"private_endpoints_cidr": networking.PrivateEndpointsSubnet,
+		"app_gateway_cidr":       networking.AppGatewaySubnet,
+	}
+
+	var used []netip.Prefix
+	for _, cidr := range []string{networking.AKSSubnet, networking.ServicesSubnet, networking.PrivateEndpointsSubnet, networking.AppGatewaySubnet} {
+		if cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s subnet: %w", p.Name, err)
+		}
+		if !vnet.Contains(prefix.Addr()) || prefix.Bits() < vnet.Bits() {
+			return nil, fmt.Errorf("profile %s subnet %s is outside vnet %s", p.Name, cidr, vnetCIDR)
+		}
+		used = append(used, prefix)
+	}
+
+	subnets := map[string]interface{}{}
+	for _, name := range []string{"aks_nodes_cidr", "private_endpoints_cidr", "app_gateway_cidr", "aks_pods_cidr", "bastion_cidr"} {
+		if given[name] != "" {
+			subnets[name] = given[name]
+			continue
+		}
+		bits := netip.MustParsePrefix(baseline[name].(string)).Bits()
+		prefix, err := freeSubnet(vnet, bits, used)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s %s: %w", p.Name, name, err)
+		}
+		used = append(used, prefix)
+		subnets[name] = prefix.String()
+	}
+	fixture.With("subnet_config", subnets)
+
+	if networking.ApplicationGateway != nil {
+		fixture.With("enable_app_gateway", networking.ApplicationGateway.Enabled)
+	}
+	return fixture, nil
+}
+
+// freeSubnet returns the first /bits range of vnet that overlaps none of
+// used.
+func freeSubnet(vnet netip.Prefix, bits int, used []netip.Prefix) (netip.Prefix, error) {
+	if bits < vnet.Bits() || bits > 32 || !vnet.Addr().Is4() {
+		return netip.Prefix{}, fmt.Errorf("no /%d subnet fits in %s", bits, vnet)
+	}
+	size := uint32(1) << (32 - bits)
+	start := ipv4ToUint(vnet.Masked().Addr())
+	end := start + (uint32(1) << (32 - vnet.Bits()))
+	for addr := start; addr < end && addr >= start; addr += size {
+		candidate := netip.PrefixFrom(uintToIPv4(addr), bits)
+		free := true
+		for _, prefix := range used {
+			if prefix.Overlaps(candidate) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return candidate, nil
+		}
+	}
+	return netip.Prefix{}, fmt.Errorf("no free /%d subnet left in %s", bits, vnet)
+}
+
+func ipv4ToUint(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func uintToIPv4(n uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+}
+
+// regional places a fixture in the profile's primary region, if it has one.
+func (p *SizingProfile) regional(fixture *Fixture) *Fixture {
+	if p.Regions != nil && p.Regions.Primary != "" {
+		fixture.With("location", p.Regions.Primary)
+	}
+	return fixture
+}
+
+// FixturesE returns the fixtures for every module in SizingModules, sized by
+// the profile, with one aks-cluster fixture per cluster.
+func (p *SizingProfile) FixturesE() ([]*Fixture, error) {
+	var fixtures []*Fixture
+	for _, cluster := range p.Clusters() {
+		fixture, err := p.AKSClusterFixtureE(cluster)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	for _, build := range []func() (*Fixture, error){p.ContainerRegistryFixtureE, p.DatabasesFixtureE, p.NetworkingFixtureE} {
+		fixture, err := build()
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
@@ -0,0 +1,202 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// SizingMap is a local of a module that maps each sizing profile to values
+// also kept in config/sizing-profiles.yaml, at Path inside every profile.
+type SizingMap struct {
+	Module string
+	Local  string
+
+	// Path is dot-separated, e.g. "security.defender.pricing".
+	Path string
+}
+
+// String names the local, e.g. "defender local.pricing_config".
+func (m SizingMap) String() string {
+	return m.Module + " local." + m.Local
+}
+
+// SizingMaps lists the per-profile maps in module locals. aks-cluster,
+// container-registry, databases and networking have none; their sizing
+// inputs are translated from the profiles file itself, see SizingModules.
+var SizingMaps = []SizingMap{
+	{Module: "defender", Local: "pricing_config", Path: "security.defender.pricing"},
+	{Module: "defender", Local: "compliance_by_profile", Path: "security.defender.compliance_standards"},
+	{Module: "purview", Local: "capacity_config", Path: "governance.purview.capacity"},
+	{Module: "purview", Local: "scan_frequency", Path: "governance.purview.scan_frequency"},
+}
+
+// SizingMapDivergencesE evaluates the local of every SizingMap with the
+// module's baseline fixture and compares it with the sizing profiles file at
+// path; see CompareSizingMap.
+func SizingMapDivergencesE(path string) ([]string, error) {
+	profiles, err := loadSizingDocumentE(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var divergences []string
+	for _, sizingMap := range SizingMaps {
+		fixture, err := ForModule(sizingMap.Module)
+		if err != nil {
+			return nil, err
+		}
+		evaluation, err := fixture.EvaluateE()
+		if err != nil {
+			return nil, err
+		}
+		value, ok := evaluation.Locals[sizingMap.Local]
+		if !ok {
+			return nil, fmt.Errorf("%s cannot be evaluated: %s", sizingMap, evaluation.Skipped["local."+sizingMap.Local])
+		}
+		local, err := ctyToGo(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sizingMap, err)
+		}
+		divergences = append(divergences, CompareSizingMap(sizingMap, local, profiles)...)
+	}
+	return divergences, nil
+}
+
+// CompareSizingMap compares a per-profile local, decoded from JSON, with the
+// profiles of an untyped sizing profiles document and returns one message
+// per divergence: a profile only one side has, and every value that differs
+// or is missing on one side, by path. Null and missing are different, so a
+// module that sets a value to null needs an explicit null in the file.
+func CompareSizingMap(sizingMap SizingMap, local interface{}, profiles map[string]interface{}) []string {
+	byProfile, ok := local.(map[string]interface{})
+	if !ok {
+		return []string{fmt.Sprintf("%s is not a map of sizing profiles", sizingMap)}
+	}
+
+	var divergences []string
+	for _, profile := range unionKeys(byProfile, profiles) {
+		fileValue, inFile := lookupSizingPath(profiles[profile], sizingMap.Path)
+		moduleValue, inModule := byProfile[profile]
+		name := fmt.Sprintf("%s[%q]", sizingMap, profile)
+		filePath := "profiles." + profile + "." + sizingMap.Path
+		switch {
+		case !inModule:
+			divergences = append(divergences, fmt.Sprintf("%s: profile missing from the module, file has %s", name, formatSizingValue(fileValue)))
+		case !inFile:
+			divergences = append(divergences, fmt.Sprintf("%s: %s missing from the file, module has %s", name, filePath, formatSizingValue(moduleValue)))
+		default:
+			divergences = append(divergences, compareSizingValues(name, filePath, moduleValue, fileValue)...)
+		}
+	}
+	return divergences
+}
+
+// compareSizingValues walks a module value and a file value together.
+func compareSizingValues(name, filePath string, moduleValue, fileValue interface{}) []string {
+	moduleMap, moduleIsMap := moduleValue.(map[string]interface{})
+	fileMap, fileIsMap := fileValue.(map[string]interface{})
+	if moduleIsMap && fileIsMap {
+		var divergences []string
+		for _, key := range unionKeys(moduleMap, fileMap) {
+			moduleAttr, inModule := moduleMap[key]
+			fileAttr, inFile := fileMap[key]
+			switch {
+			case !inModule:
+				divergences = append(divergences, fmt.Sprintf("%s.%s: missing from the module, %s.%s is %s", name, key, filePath, key, formatSizingValue(fileAttr)))
+			case !inFile:
+				divergences = append(divergences, fmt.Sprintf("%s.%s is %s, missing from the file at %s.%s", name, key, formatSizingValue(moduleAttr), filePath, key))
+			default:
+				divergences = append(divergences, compareSizingValues(name+"."+key, filePath+"."+key, moduleAttr, fileAttr)...)
+			}
+		}
+		return divergences
+	}
+
+	if reflect.DeepEqual(moduleValue, fileValue) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s is %s, %s is %s", name, formatSizingValue(moduleValue), filePath, formatSizingValue(fileValue))}
+}
+
+// AssertSizingMapsMatchProfiles checks that every per-profile map in
+// SizingMaps equals config/sizing-profiles.yaml; see CompareSizingMap.
+func AssertSizingMapsMatchProfiles(t testing.TestingT) bool {
+	markHelper(t)
+	divergences, err := SizingMapDivergencesE(filepath.Join(RepoRoot(t), sizingProfilesPath))
+	require.NoError(t, err)
+	if len(divergences) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("module sizing maps differ from %s:\n  %s",
+		sizingProfilesPath, strings.Join(divergences, "\n  ")))
+}
+
+// loadSizingDocumentE reads the profiles of a sizing profiles file as
+// untyped values in the shapes encoding/json produces, so they compare
+// equal to evaluated locals.
+func loadSizingDocumentE(path string) (map[string]interface{}, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document struct {
+		Profiles map[string]interface{} `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(src, &document); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	encoded, err := json.Marshal(document.Profiles)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var profiles map[string]interface{}
+	err = json.Unmarshal(encoded, &profiles)
+	return profiles, err
+}
+
+// lookupSizingPath follows a dot-separated path through nested maps.
+func lookupSizingPath(value interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// formatSizingValue formats a value as JSON for messages.
+func formatSizingValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// unionKeys returns the keys of two maps sorted by name, with profile names
+// first in sizing order.
+func unionKeys(a, b map[string]interface{}) []string {
+	seen := map[string]bool{}
+	for key := range a {
+		seen[key] = true
+	}
+	for key := range b {
+		seen[key] = true
+	}
+	keys := sortedStrings(seen)
+	sortSizingNames(keys)
+	return keys
+}
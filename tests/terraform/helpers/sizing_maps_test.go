@@ -0,0 +1,97 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - SIZING MAP TESTS
+// =============================================================================
+//
+// Tests for comparing the per-profile maps in module locals with
+// config/sizing-profiles.yaml, and that the two agree today.
+//
+// Run with: go test -v -run TestSizingMaps ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSizingMapsMatchProfiles tests that module sizing maps equal the sizing profiles file
+func TestSizingMapsMatchProfiles(t *testing.T) {
+	t.Parallel()
+
+	AssertSizingMapsMatchProfiles(t)
+}
+
+// TestSizingMapsCompare tests the divergences reported for one sizing map
+func TestSizingMapsCompare(t *testing.T) {
+	t.Parallel()
+
+	sizingMap := SizingMap{Module: "defender", Local: "pricing_config", Path: "security.defender.pricing"}
+	profiles := map[string]interface{}{
+		"small": map[string]interface{}{
+			"security": map[string]interface{}{"defender": map[string]interface{}{
+				"pricing": map[string]interface{}{"servers": "Free", "servers_plan": nil, "dns": "Free"},
+			}},
+		},
+		"medium": map[string]interface{}{
+			"security": map[string]interface{}{"defender": map[string]interface{}{
+				"pricing": map[string]interface{}{"servers": "Standard", "servers_plan": "P1"},
+			}},
+		},
+		"large":  map[string]interface{}{},
+		"xlarge": map[string]interface{}{},
+	}
+	local := map[string]interface{}{
+		"small":  map[string]interface{}{"servers": "Standard", "servers_plan": nil, "ai": "Free"},
+		"medium": map[string]interface{}{"servers": "Standard", "servers_plan": "P1"},
+		"large":  map[string]interface{}{"servers": "Standard"},
+	}
+
+	assert.Equal(t, []string{
+		`defender local.pricing_config["small"].ai is "Free", missing from the file at profiles.small.security.defender.pricing.ai`,
+		`defender local.pricing_config["small"].dns: missing from the module, profiles.small.security.defender.pricing.dns is "Free"`,
+		`defender local.pricing_config["small"].servers is "Standard", profiles.small.security.defender.pricing.servers is "Free"`,
+		`defender local.pricing_config["large"]: profiles.large.security.defender.pricing missing from the file, module has {"servers":"Standard"}`,
+		`defender local.pricing_config["xlarge"]: profile missing from the module, file has null`,
+	}, CompareSizingMap(sizingMap, local, profiles))
+
+	assert.Equal(t, []string{"defender local.pricing_config is not a map of sizing profiles"},
+		CompareSizingMap(sizingMap, "Free", profiles))
+
+	// Lists and numbers compare as a whole
+	capacity := SizingMap{Module: "purview", Local: "capacity_config", Path: "governance.purview.capacity"}
+	assert.Equal(t, []string{
+		`purview local.capacity_config["small"] is 1, profiles.small.governance.purview.capacity is 0`,
+	}, CompareSizingMap(capacity, map[string]interface{}{"small": float64(1)}, map[string]interface{}{
+		"small": map[string]interface{}{"governance": map[string]interface{}{"purview": map[string]interface{}{"capacity": float64(0)}}},
+	}))
+}
+
+// TestSizingMapsDivergences tests that an edited profiles file is reported against the modules
+func TestSizingMapsDivergences(t *testing.T) {
+	t.Parallel()
+
+	src, err := os.ReadFile(filepath.Join(RepoRoot(t), sizingProfilesPath))
+	require.NoError(t, err)
+	edited := strings.Replace(string(src), "capacity: 16", "capacity: 8", 1)
+	edited = strings.Replace(edited, `- "LGPD"`, `- "LGPD"`+"\n          - \"HIPAA\"", 1)
+	require.NotEqual(t, string(src), edited)
+
+	path := filepath.Join(t.TempDir(), "sizing-profiles.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(edited), 0o644))
+
+	divergences, err := SizingMapDivergencesE(path)
+	require.NoError(t, err)
+	assert.Len(t, divergences, 2)
+	assert.Contains(t, divergences, `purview local.capacity_config["xlarge"] is 16, profiles.xlarge.governance.purview.capacity is 8`)
+	if assert.NotEmpty(t, divergences) {
+		assert.True(t, strings.HasPrefix(divergences[0], `defender local.compliance_by_profile["xlarge"] is [`), divergences[0])
+		assert.Contains(t, divergences[0], `"HIPAA"]`)
+	}
+}
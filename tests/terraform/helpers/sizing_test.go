@@ -0,0 +1,274 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - SIZING PROFILE TESTS
+// =============================================================================
+//
+// Tests for loading config/sizing-profiles.yaml and translating each profile
+// into module variables: node pools, SKUs, storage and subnets, and the
+// variable contract of every fixture a profile produces.
+//
+// Run with: go test -v -run 'Sizing|PostgreSQLSKU' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSizingProfilesLoad tests reading the profiles in config/sizing-profiles.yaml
+func TestSizingProfilesLoad(t *testing.T) {
+	t.Parallel()
+
+	profiles := SizingProfiles(t)
+	assert.Equal(t, []string{"small", "medium", "large", "xlarge"}, profiles.Names())
+	assert.Equal(t, "1.0.0", profiles.Metadata.Version)
+
+	small, err := profiles.ProfileE("small")
+	require.NoError(t, err)
+	pools := small.Clusters()[0].AKS.Pools()
+	require.Len(t, pools, 1)
+	assert.Equal(t, "Standard_D2s_v5", pools[0].VMSize)
+	assert.Equal(t, "System", pools[0].Mode)
+	assert.False(t, pools[0].Autoscaled())
+
+	medium, err := profiles.ProfileE("medium")
+	require.NoError(t, err)
+	assert.Equal(t, &AutoScalingSizing{Enabled: true, MinNodes: 3, MaxNodes: 10}, medium.Clusters()[0].AKS.Pools()[0].AutoScaling)
+
+	xlarge, err := profiles.ProfileE("xlarge")
+	require.NoError(t, err)
+	clusters := xlarge.Clusters()
+	require.Len(t, clusters, 2)
+	assert.Equal(t, "brazilsouth", clusters[0].Location)
+	assert.Equal(t, "eastus", clusters[1].Location)
+	assert.Equal(t, "Standard_D8ds_v5", xlarge.PostgreSQLServer().SKU)
+
+	_, err = profiles.ProfileE("huge")
+	assert.ErrorContains(t, err, `no sizing profile "huge"; profiles are small, medium, large, xlarge`)
+}
+
+// TestLoadSizingProfilesErrors tests that malformed profile files are rejected
+func TestLoadSizingProfilesErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no profiles", "metadata:\n  version: 1.0.0\n", "defines no profiles"},
+		{"name mismatch", "profiles:\n  small:\n    profile: medium\n", `profile small is named "medium"`},
+		{"no cluster", "profiles:\n  small:\n    profile: small\n", "profile small sizes no AKS cluster"},
+		{"no pools", "profiles:\n  small:\n    infrastructure:\n      aks:\n        name: aks\n", "profile small has no node pools for primary cluster"},
+	}
+
+	for _, tc := range testCases {
+		path := filepath.Join(t.TempDir(), "sizing-profiles.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(tc.src), 0o644))
+		_, err := LoadSizingProfilesE(path)
+		assert.ErrorContains(t, err, tc.want, tc.name)
+	}
+}
+
+// TestSizingProfileFixturesContract tests every fixture of every profile against its module's variables
+func TestSizingProfileFixturesContract(t *testing.T) {
+	t.Parallel()
+
+	profiles := SizingProfiles(t)
+	for _, name := range profiles.Names() {
+		profile := profiles.Profiles[name]
+		fixtures, err := profile.FixturesE()
+		require.NoError(t, err, name)
+		require.Len(t, fixtures, len(SizingModules)+len(profile.Clusters())-1, name)
+		for _, fixture := range fixtures {
+			assert.NoError(t, fixture.CheckE(), "%s %s", name, fixture.Module)
+		}
+	}
+}
+
+// TestSizingProfileAKSCluster tests the translation of node pools and features
+func TestSizingProfileAKSCluster(t *testing.T) {
+	t.Parallel()
+
+	profiles := SizingProfiles(t)
+
+	small, err := profiles.ProfileE("small")
+	require.NoError(t, err)
+	fixture, err := small.AKSClusterFixtureE(small.Clusters()[0])
+	require.NoError(t, err)
+	assert.Equal(t, "1.30", fixture.Vars["kubernetes_version"])
+	assert.Equal(t, map[string]interface{}{
+		"name":                "system",
+		"node_count":          3,
+		"vm_size":             "Standard_D2s_v5",
+		"min_count":           3,
+		"max_count":           3,
+		"os_disk_size_gb":     50,
+		"os_disk_type":        "Managed",
+		"max_pods":            30,
+		"enable_auto_scaling": false,
+		"zones":               []string{"1", "2", "3"},
+	}, fixture.Vars["default_node_pool"])
+	assert.Equal(t, map[string]interface{}{}, fixture.Vars["additional_node_pools"])
+	assert.Equal(t, false, fixture.Vars["enable_azure_policy"])
+	assert.Equal(t, true, fixture.Vars["enable_workload_identity"])
+
+	large, err := profiles.ProfileE("large")
+	require.NoError(t, err)
+	fixture, err = large.AKSClusterFixtureE(large.Clusters()[0])
+	require.NoError(t, err)
+	pools := fixture.Vars["additional_node_pools"].(map[string]interface{})
+	require.Len(t, pools, 2)
+	assert.Equal(t, map[string]interface{}{
+		"name":                "workloads",
+		"node_count":          5,
+		"vm_size":             "Standard_D8s_v5",
+		"min_count":           3,
+		"max_count":           15,
+		"max_pods":            100,
+		"enable_auto_scaling": true,
+		"node_labels":         map[string]string{},
+		"node_taints":         []string{},
+		"zones":               []string{"1", "2", "3"},
+	}, pools["workloads"])
+	assert.Equal(t, []string{"workload=ai:NoSchedule"}, pools["ai"].(map[string]interface{})["node_taints"])
+
+	xlarge, err := profiles.ProfileE("xlarge")
+	require.NoError(t, err)
+	fixture, err = xlarge.AKSClusterFixtureE(xlarge.Clusters()[1])
+	require.NoError(t, err)
+	assert.Equal(t, "eastus", fixture.Vars["location"])
+	assert.Equal(t, "1.29", fixture.Vars["kubernetes_version"], "the secondary cluster keeps the baseline version")
+	assert.Equal(t, "Standard_D4s_v5", fixture.Vars["default_node_pool"].(map[string]interface{})["vm_size"])
+}
+
+// TestSizingProfileDatabases tests the translation of PostgreSQL and Redis sizing
+func TestSizingProfileDatabases(t *testing.T) {
+	t.Parallel()
+
+	profiles := SizingProfiles(t)
+
+	testCases := []struct {
+		profile   string
+		skuName   string
+		storageMB int
+		redis     []interface{}
+	}{
+		{"small", "B_Standard_B1ms", 32768, []interface{}{"Basic", "C", 0}},
+		{"medium", "GP_Standard_D2ds_v5", 131072, []interface{}{"Standard", "C", 1}},
+		{"large", "GP_Standard_D4ds_v5", 262144, []interface{}{"Premium", "P", 1}},
+		{"xlarge", "GP_Standard_D8ds_v5", 524288, []interface{}{"Premium", "P", 3}},
+	}
+
+	for _, tc := range testCases {
+		profile, err := profiles.ProfileE(tc.profile)
+		require.NoError(t, err)
+		fixture, err := profile.DatabasesFixtureE()
+		require.NoError(t, err)
+
+		postgres := fixture.Vars["postgresql_config"].(map[string]interface{})
+		assert.Equal(t, tc.skuName, postgres["sku_name"], tc.profile)
+		assert.Equal(t, tc.storageMB, postgres["storage_mb"], tc.profile)
+
+		redis := fixture.Vars["redis_config"].(map[string]interface{})
+		assert.Equal(t, tc.redis, []interface{}{redis["sku_name"], redis["family"], redis["capacity"]}, tc.profile)
+	}
+}
+
+// TestSizingProfileNetworking tests subnet translation and allocation
+func TestSizingProfileNetworking(t *testing.T) {
+	t.Parallel()
+
+	profiles := SizingProfiles(t)
+
+	small, err := profiles.ProfileE("small")
+	require.NoError(t, err)
+	fixture, err := small.NetworkingFixtureE()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/16", fixture.Vars["vnet_cidr"])
+	assert.Equal(t, map[string]interface{}{
+		"aks_nodes_cidr":         "10.0.0.0/23",
+		"private_endpoints_cidr": "10.0.2.0/24",
+		"app_gateway_cidr":       "10.0.3.0/24",
+		"aks_pods_cidr":          "10.0.16.0/20",
+		"bastion_cidr":           "10.0.4.0/26",
+	}, fixture.Vars["subnet_config"])
+
+	for _, name := range profiles.Names() {
+		fixture, err := profiles.Profiles[name].NetworkingFixtureE()
+		require.NoError(t, err, name)
+
+		vnet := netip.MustParsePrefix(fixture.Vars["vnet_cidr"].(string))
+		var subnets []netip.Prefix
+		for key, cidr := range fixture.Vars["subnet_config"].(map[string]interface{}) {
+			subnet := netip.MustParsePrefix(cidr.(string))
+			assert.True(t, vnet.Contains(subnet.Addr()), "%s %s %s is outside %s", name, key, subnet, vnet)
+			for _, other := range subnets {
+				assert.False(t, subnet.Overlaps(other), "%s %s %s overlaps %s", name, key, subnet, other)
+			}
+			subnets = append(subnets, subnet)
+		}
+	}
+
+	large, err := profiles.ProfileE("large")
+	require.NoError(t, err)
+	fixture, err = large.NetworkingFixtureE()
+	require.NoError(t, err)
+	assert.Equal(t, true, fixture.Vars["enable_app_gateway"])
+	assert.Equal(t, "10.0.21.0/24", fixture.Vars["subnet_config"].(map[string]interface{})["app_gateway_cidr"])
+}
+
+// TestFreeSubnet tests allocating subnets around used ranges
+func TestFreeSubnet(t *testing.T) {
+	t.Parallel()
+
+	vnet := netip.MustParsePrefix("10.0.0.0/22")
+	used := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.0.2.0/25")}
+
+	subnet, err := freeSubnet(vnet, 24, used)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.1.0/24", subnet.String())
+
+	subnet, err = freeSubnet(vnet, 25, append(used, subnet))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.2.128/25", subnet.String())
+
+	_, err = freeSubnet(vnet, 23, used)
+	assert.ErrorContains(t, err, "no free /23 subnet left in 10.0.0.0/22")
+	_, err = freeSubnet(vnet, 20, nil)
+	assert.ErrorContains(t, err, "no /20 subnet fits in 10.0.0.0/22")
+}
+
+// TestPostgreSQLSKUName tests converting VM sizes into flexible server SKUs
+func TestPostgreSQLSKUName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		vmSize string
+		want   string
+		err    string
+	}{
+		{"Standard_B1ms", "B_Standard_B1ms", ""},
+		{"Standard_D4ds_v5", "GP_Standard_D4ds_v5", ""},
+		{"Standard_E8ds_v5", "MO_Standard_E8ds_v5", ""},
+		{"GP_Standard_D2s_v3", "", "is not a Standard_ VM size"},
+		{"Standard_NC6s_v3", "", "is not a B, D or E series VM size"},
+	}
+
+	for _, tc := range testCases {
+		got, err := PostgreSQLSKUName(tc.vmSize)
+		if tc.err != "" {
+			assert.ErrorContains(t, err, tc.err, tc.vmSize)
+			continue
+		}
+		require.NoError(t, err, tc.vmSize)
+		assert.Equal(t, tc.want, got)
+	}
+}
@@ -0,0 +1,120 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - TERRATEST HELPERS
+// =============================================================================
+//
+// Shared harness used by every suite under tests/terraform. It locates the
+// Terraform modules in the repository and turns module fixtures into
+// terraform.Options so individual tests only describe what they change.
+//
+// =============================================================================
+
+// Package helpers contains the fixtures and harness utilities shared by the
+// Terraform module tests.
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// modulesPath is the location of the Terraform modules relative to the
+// repository root.
+const modulesPath = "terraform/modules"
+
+// RootModule names the root configuration in terraform/ wherever a module
+// directory name is expected, e.g. in ModuleDir and Fixture.Module.
+const RootModule = "root"
+
+// rootModuleFile marks the repository root. tests/terraform/modules also
+// exists, so the modules directory alone is not enough to find it.
+const rootModuleFile = "terraform/main.tf"
+
+// RepoRoot returns the repository root. This will fail the test if the root
+// cannot be found.
+func RepoRoot(t testing.TestingT) string {
+	root, err := RepoRootE()
+	require.NoError(t, err)
+	return root
+}
+
+// RepoRootE walks up from the working directory until it finds the directory
+// that contains the root Terraform module, so tests work from any package
+// depth.
+func RepoRootE() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if info, err := os.Stat(filepath.Join(dir, rootModuleFile)); err == nil && !info.IsDir() {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("could not find %s above the working directory", rootModuleFile)
+		}
+		dir = parent
+	}
+}
+
+// ModuleDir returns the absolute path of the named module under
+// terraform/modules, or of terraform/ for RootModule. This will fail the test
+// if the module does not exist.
+func ModuleDir(t testing.TestingT, module string) string {
+	dir, err := ModuleDirE(module)
+	require.NoError(t, err)
+	return dir
+}
+
+// ModuleDirE returns the absolute path of the named module under
+// terraform/modules, or of terraform/ for RootModule.
+func ModuleDirE(module string) (string, error) {
+	root, err := RepoRootE()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, modulesPath, module)
+	if module == RootModule {
+		dir = filepath.Join(root, filepath.Dir(rootModuleFile))
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("terraform module %q not found at %s", module, dir)
+	}
+	return dir, nil
+}
+
+// Options returns the terraform.Options used to run the fixture's module with
+// the default retryable errors and colorless output. The vars are checked
+// against the module's variables.tf first, so a stale or misspelled key fails
+// the test before Terraform runs.
+func (f *Fixture) Options(t testing.TestingT) *terraform.Options {
+	require.NoError(t, f.CheckE())
+	return f.UncheckedOptions(t)
+}
+
+// UncheckedOptions returns the same terraform.Options as Options without the
+// variable contract check, for tests that expect Terraform itself to reject
+// the inputs.
+//
+// Each call copies the module to its own Workspace, removed when the test
+// finishes, and points Terraform at the shared plugin cache. Run init with
+// helpers.Init rather than terraform.Init so the cache is filled under its
+// lock.
+func (f *Fixture) UncheckedOptions(t testing.TestingT) *terraform.Options {
+	options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: Workspace(t, f.Module),
+		Vars:         f.Vars.Clone(),
+		EnvVars:      pluginCacheEnvVars(t),
+		NoColor:      true,
+	})
+	f.registerPolicyWaivers(t, options)
+	return options
+}
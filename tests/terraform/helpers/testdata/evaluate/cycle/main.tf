@@ -0,0 +1,6 @@
+# Module for EvaluateModuleE tests: locals that refer to each other.
+
+locals {
+  a = "${local.b}-a"
+  b = "${local.a}-b"
+}
@@ -0,0 +1,67 @@
+# Module for EvaluateModuleE tests: locals that chain, locals and outputs
+# that need a plan, and variables with defaults and validation.
+
+variable "name" {
+  type = string
+  validation {
+    condition     = can(regex("^[a-z]+$", var.name))
+    error_message = "Name must be lowercase letters."
+  }
+}
+
+variable "region" {
+  type    = string
+  default = "brazilsouth"
+}
+
+variable "settings" {
+  type = object({
+    tier     = string
+    replicas = optional(number, 2)
+  })
+  default = { tier = "basic" }
+}
+
+locals {
+  region_codes = {
+    brazilsouth = "brs"
+    eastus2     = "eus2"
+  }
+  region_code = lookup(local.region_codes, var.region, substr(var.region, 0, 4))
+  prefix      = "${var.name}-${local.region_code}"
+  compact     = replace(local.prefix, "/[^a-z0-9]/", "")
+  sizes       = { for tier, size in { basic = 1, premium = 3 } : tier => size * var.settings.replicas }
+  manifest    = yamlencode({ name = local.prefix })
+  created     = timestamp()
+  tags        = { created = local.created }
+  group_id    = azurerm_resource_group.main.id
+}
+
+resource "azurerm_resource_group" "main" {
+  name     = "rg-${local.prefix}"
+  location = var.region
+}
+
+output "resource_group" {
+  value = "rg-${local.prefix}"
+}
+
+output "storage_account" {
+  value = substr("st${local.compact}001", 0, 24)
+}
+
+output "replicas" {
+  value = local.sizes[var.settings.tier]
+}
+
+output "group_id" {
+  value = local.group_id
+}
+
+output "location" {
+  value = azurerm_resource_group.main.location
+}
+
+output "tags" {
+  value = local.tags
+}
@@ -0,0 +1,16 @@
+output "id" {
+  value = "app"
+}
+
+output "password" {
+  value = "secret"
+}
+
+output "connection_string" {
+  value     = "app;secret"
+  sensitive = true
+}
+
+output "fqdn" {
+  value = "app.example.com"
+}
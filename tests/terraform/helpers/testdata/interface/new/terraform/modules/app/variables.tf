@@ -0,0 +1,57 @@
+variable "name" {
+  type = string
+
+  validation {
+    condition     = length(var.name) <= 12
+    error_message = "name must be at most 12 characters."
+  }
+}
+
+variable "replicas" {
+  type = string
+}
+
+variable "zone" {
+  type    = any
+  default = "1"
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+
+  validation {
+    condition     = alltrue([for key in keys(var.tags) : length(key) <= 512])
+    error_message = "tag names must be at most 512 characters."
+  }
+}
+
+variable "settings" {
+  type = object({
+    tier     = string
+    capacity = optional(number, 1)
+  })
+  default = { tier = "standard" }
+
+  validation {
+    condition = contains(
+      ["standard", "premium"],
+      var.settings.tier
+    )
+    error_message = "settings.tier must be one of standard and premium."
+  }
+}
+
+variable "sku" {
+  type    = string
+  default = "Basic"
+}
+
+variable "region" {
+  type = string
+}
+
+variable "labels" {
+  type    = map(string)
+  default = {}
+}
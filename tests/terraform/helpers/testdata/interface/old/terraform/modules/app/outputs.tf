@@ -0,0 +1,16 @@
+output "id" {
+  value = "app"
+}
+
+output "endpoint" {
+  value = "https://app"
+}
+
+output "password" {
+  value     = "secret"
+  sensitive = true
+}
+
+output "connection_string" {
+  value = "app;secret"
+}
@@ -0,0 +1,49 @@
+variable "name" {
+  type = string
+
+  validation {
+    condition     = length(var.name) <= 24
+    error_message = "name must be at most 24 characters."
+  }
+}
+
+variable "replicas" {
+  type    = number
+  default = 1
+}
+
+variable "zone" {
+  type    = string
+  default = "1"
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "settings" {
+  type = object({
+    tier = string
+  })
+  default = { tier = "standard" }
+
+  validation {
+    condition     = contains(["standard", "premium"], var.settings.tier)
+    error_message = "settings.tier must be standard or premium."
+  }
+}
+
+variable "legacy" {
+  type    = bool
+  default = false
+}
+
+variable "sku" {
+  type = string
+
+  validation {
+    condition     = var.sku != ""
+    error_message = "sku must not be empty."
+  }
+}
@@ -0,0 +1,33 @@
+# Configuration for the offline harness tests in offline_test.go. Providers
+# are read from required_providers (azurerm) and resource types (random).
+
+terraform {
+  required_providers {
+    azurerm = {
+      source = "hashicorp/azurerm"
+    }
+  }
+}
+
+variable "name" {
+  type = string
+
+  validation {
+    condition     = length(var.name) <= 10
+    error_message = "name must be 10 characters or less."
+  }
+}
+
+resource "terraform_data" "main" {
+  input = var.name
+}
+
+data "azurerm_client_config" "current" {}
+
+resource "random_id" "suffix" {
+  byte_length = 4
+}
+
+output "name" {
+  value = terraform_data.main.output
+}
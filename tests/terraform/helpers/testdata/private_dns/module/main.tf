@@ -0,0 +1,104 @@
+# Module configuration of plan_module.json. Only the private endpoints'
+# references are read.
+
+variable "private_dns_zone_ids" {
+  type = object({
+    openai = string
+  })
+}
+
+variable "private_dns_zone_id" {
+  type = string
+}
+
+variable "target_id" {
+  type = string
+}
+
+resource "azurerm_cognitive_account" "openai" {
+  count = 1
+  kind  = "OpenAI"
+}
+
+resource "azurerm_private_endpoint" "openai" {
+  count = 1
+
+  private_service_connection {
+    private_connection_resource_id = azurerm_cognitive_account.openai[0].id
+    subresource_names              = ["account"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_ids.openai]
+  }
+}
+
+resource "azurerm_cognitive_account" "content_safety" {
+  count = 1
+  kind  = "ContentSafety"
+}
+
+# Points at the OpenAI zone instead of the Cognitive Services zone
+resource "azurerm_private_endpoint" "content_safety" {
+  count = 1
+
+  private_service_connection {
+    private_connection_resource_id = azurerm_cognitive_account.content_safety[0].id
+    subresource_names              = ["account"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_ids.openai]
+  }
+}
+
+resource "azurerm_key_vault" "main" {}
+
+resource "azurerm_private_endpoint" "key_vault" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_key_vault.main.id
+    subresource_names              = ["vault"]
+  }
+}
+
+resource "azurerm_container_registry" "main" {}
+
+resource "azurerm_private_endpoint" "acr" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_container_registry.main.id
+    subresource_names              = ["registry"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_id]
+  }
+}
+
+resource "azurerm_redis_cache" "main" {}
+
+resource "azurerm_private_endpoint" "redis" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_redis_cache.main.id
+    subresource_names              = ["redisEnterprise"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_id]
+  }
+}
+
+resource "azurerm_private_endpoint" "external" {
+  private_service_connection {
+    private_connection_resource_id = var.target_id
+    subresource_names              = ["blob"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_id]
+  }
+}
+
+resource "azurerm_private_dns_zone" "zones" {
+  for_each = toset(["openai", "keyvault", "acr"])
+  name     = each.key
+}
@@ -0,0 +1,31 @@
+# Root configuration of plan_root.json.
+# Only the module calls and the resources they reference are read.
+
+module "networking" {
+  source = "./modules/networking"
+}
+
+module "ai" {
+  source = "./modules/ai"
+  count  = 1
+
+  private_dns_zone_ids = {
+    openai            = module.networking.private_dns_zone_ids.openai
+    cognitiveservices = module.networking.private_dns_zone_ids.cognitiveservices
+  }
+}
+
+module "purview" {
+  source = "./modules/purview"
+
+  private_dns_zone_ids = {
+    purview        = module.networking.private_dns_zone_ids.purview
+    purview_studio = module.networking.private_dns_zone_ids.purview_studio
+  }
+}
+
+module "security" {
+  source = "./modules/security"
+
+  private_dns_zone_id = module.networking.private_dns_zone_ids["keyvault"]
+}
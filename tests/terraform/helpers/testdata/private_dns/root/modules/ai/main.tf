@@ -0,0 +1,29 @@
+resource "azurerm_cognitive_account" "openai" {
+  kind = "OpenAI"
+}
+
+resource "azurerm_private_endpoint" "openai" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_cognitive_account.openai.id
+    subresource_names              = ["account"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_ids.openai]
+  }
+}
+
+resource "azurerm_cognitive_account" "content_safety" {
+  kind = "ContentSafety"
+}
+
+resource "azurerm_private_endpoint" "content_safety" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_cognitive_account.content_safety.id
+    subresource_names              = ["account"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_ids.cognitiveservices]
+  }
+}
@@ -0,0 +1,8 @@
+resource "azurerm_private_dns_zone" "zones" {
+  for_each = var.zones
+  name     = each.value
+}
+
+output "private_dns_zone_ids" {
+  value = { for key, zone in azurerm_private_dns_zone.zones : key => zone.id }
+}
@@ -0,0 +1,12 @@
+resource "azurerm_purview_account" "main" {}
+
+resource "azurerm_private_endpoint" "portal" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_purview_account.main.id
+    subresource_names              = ["portal"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_ids.purview_studio]
+  }
+}
@@ -0,0 +1,12 @@
+resource "azurerm_key_vault" "main" {}
+
+resource "azurerm_private_endpoint" "key_vault" {
+  private_service_connection {
+    private_connection_resource_id = azurerm_key_vault.main.id
+    subresource_names              = ["vault"]
+  }
+
+  private_dns_zone_group {
+    private_dns_zone_ids = [var.private_dns_zone_id]
+  }
+}
@@ -0,0 +1,34 @@
+# The working-tree module: renamed resources with and without moved blocks,
+# count added to a resource and a module call, and a module renamed.
+
+resource "terraform_data" "keep" {
+  input = "keep"
+}
+
+resource "terraform_data" "newname" {
+  input = "renamed"
+}
+
+moved {
+  from = terraform_data.renamed
+  to   = terraform_data.newname
+}
+
+resource "terraform_data" "counted" {
+  count = 1
+  input = "counted"
+}
+
+resource "terraform_data" "added" {
+  input = "added"
+}
+
+module "app" {
+  source = "./modules/store"
+  count  = 1
+}
+
+moved {
+  from = module.legacy
+  to   = module.app[0]
+}
@@ -0,0 +1,8 @@
+resource "terraform_data" "records" {
+  input = "store"
+}
+
+moved {
+  from = terraform_data.data
+  to   = terraform_data.records
+}
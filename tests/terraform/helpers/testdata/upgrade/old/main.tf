@@ -0,0 +1,21 @@
+# The module as deployed at the older ref.
+
+resource "terraform_data" "keep" {
+  input = "keep"
+}
+
+resource "terraform_data" "renamed" {
+  input = "renamed"
+}
+
+resource "terraform_data" "gone" {
+  input = "gone"
+}
+
+resource "terraform_data" "counted" {
+  input = "counted"
+}
+
+module "legacy" {
+  source = "./modules/store"
+}
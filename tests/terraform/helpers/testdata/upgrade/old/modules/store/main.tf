@@ -0,0 +1,3 @@
+resource "terraform_data" "data" {
+  input = "store"
+}
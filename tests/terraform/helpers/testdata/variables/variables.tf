@@ -0,0 +1,30 @@
+# Variables for the contract checker tests in variables_test.go.
+
+variable "name" {
+  type = string
+}
+
+variable "replicas" {
+  type    = number
+  default = 1
+}
+
+variable "pool" {
+  type = object({
+    vm_size = string
+    zones   = list(string)
+    labels  = optional(map(string), {})
+  })
+  default = null
+}
+
+variable "pools" {
+  type = map(object({
+    vm_size = string
+  }))
+  default = {}
+}
+
+variable "anything" {
+  default = ""
+}
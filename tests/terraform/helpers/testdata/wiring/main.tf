@@ -0,0 +1,73 @@
+# Root configuration for the wiring tests. module.ai reads module.monitoring
+# without a guard, output.ai_endpoint assumes enable_ai alone enables
+# module.ai, and two outputs are broken whatever the variables.
+
+variable "name" {
+  type = string
+}
+
+variable "mode" {
+  type    = string
+  default = "small"
+}
+
+variable "enable_monitoring" {
+  type    = bool
+  default = true
+}
+
+variable "enable_ai" {
+  type    = bool
+  default = false
+}
+
+locals {
+  modes = {
+    small = { ai = false }
+    large = { ai = true }
+  }
+
+  config = local.modes[var.mode]
+}
+
+resource "random_pet" "suffix" {}
+
+module "monitoring" {
+  source = "./modules/monitoring"
+  count  = var.enable_monitoring ? 1 : 0
+
+  name = "${var.name}-${random_pet.suffix.id}"
+}
+
+module "ai" {
+  source = "./modules/ai"
+  count  = var.enable_ai && local.config.ai ? 1 : 0
+
+  name           = var.name
+  workspace_id   = module.monitoring[0].workspace_id
+  workspace_name = var.enable_monitoring ? module.monitoring[0].workspace_name : ""
+  sku            = "S0"
+
+  depends_on = [module.monitoring]
+}
+
+module "registry" {
+  source  = "Azure/naming/azurerm"
+  version = "0.4.0"
+}
+
+output "ai_endpoint" {
+  value = var.enable_ai ? module.ai[0].endpoint : null
+}
+
+output "ai_endpoints" {
+  value = module.ai[*].endpoint
+}
+
+output "monitoring_id" {
+  value = module.monitoring.workspace_id
+}
+
+output "workspace_key" {
+  value = module.monitoring[0].workspace_key
+}
@@ -0,0 +1,20 @@
+variable "name" {
+  type = string
+}
+
+variable "workspace_id" {
+  type = string
+}
+
+variable "workspace_name" {
+  type    = string
+  default = ""
+}
+
+variable "region" {
+  type = string
+}
+
+output "endpoint" {
+  value = "https://${var.name}.openai.azure.com"
+}
@@ -0,0 +1,13 @@
+variable "name" {
+  type = string
+}
+
+resource "time_static" "created" {}
+
+output "workspace_id" {
+  value = "/workspaces/${var.name}"
+}
+
+output "workspace_name" {
+  value = var.name
+}
@@ -0,0 +1,143 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Tier classifies a test by what it needs to run.
+type Tier string
+
+// Test tiers, from cheapest to most expensive.
+const (
+	// TierValidate runs init and validate. It downloads providers but needs no
+	// credentials.
+	TierValidate Tier = "validate"
+
+	// TierOffline plans against mocked providers; see OfflineOptions.
+	TierOffline Tier = "offline"
+
+	// TierPlan plans against Azure and needs ARM_* credentials.
+	TierPlan Tier = "plan"
+
+	// TierApply creates real resources and destroys them.
+	TierApply Tier = "apply"
+)
+
+// AllTiers lists every tier in order.
+var AllTiers = []Tier{TierValidate, TierOffline, TierPlan, TierApply}
+
+// TiersEnv selects tiers at run time, e.g. TERRATEST_TIERS=validate,offline or
+// TERRATEST_TIERS=all. When set it replaces the tiers chosen by build tags.
+const TiersEnv = "TERRATEST_TIERS"
+
+// defaultTiers run when neither TiersEnv nor a tier build tag is given: the
+// tiers that need no Azure credentials.
+var defaultTiers = []Tier{TierValidate, TierOffline}
+
+// tagTiers are added by the unit and integration build tags, in tier_unit.go
+// and tier_integration.go.
+var tagTiers []Tier
+
+// tierT is implemented by *testing.T.
+type tierT interface {
+	testing.TestingT
+	Helper()
+	Skipf(format string, args ...interface{})
+}
+
+// EnabledTiersE returns the tiers selected for this run and a description of
+// where the selection came from, for skip messages.
+func EnabledTiersE() ([]Tier, string, error) {
+	if value, ok := os.LookupEnv(TiersEnv); ok && strings.TrimSpace(value) != "" {
+		tiers, err := ParseTiers(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return tiers, TiersEnv + "=" + value, nil
+	}
+	if len(tagTiers) > 0 {
+		var tiers []Tier
+		for _, tier := range AllTiers {
+			if containsTier(tagTiers, tier) {
+				tiers = append(tiers, tier)
+			}
+		}
+		return tiers, "build tags", nil
+	}
+	return defaultTiers, "default without tier build tags", nil
+}
+
+// ParseTiers parses a comma-separated list of tier names. "all" selects every
+// tier.
+func ParseTiers(value string) ([]Tier, error) {
+	var tiers []Tier
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			return AllTiers, nil
+		}
+		tier := Tier(name)
+		if !tier.valid() {
+			return nil, fmt.Errorf("unknown test tier %q in %s; valid tiers are %s and all", name, TiersEnv, joinTiers(AllTiers))
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// RequireTier skips the test, saying why, unless its tier is enabled. Call it
+// first in every module test.
+func RequireTier(t tierT, tier Tier) {
+	t.Helper()
+
+	enabled, source, err := EnabledTiersE()
+	if err != nil {
+		t.Fatalf("%s", err)
+		return
+	}
+	if containsTier(enabled, tier) {
+		return
+	}
+	t.Skipf("skipping %s-tier test: enabled tiers are %s (%s); run with %s=%s or %s",
+		tier, joinTiers(enabled), source, TiersEnv, tier, tier.buildTag())
+}
+
+func (tier Tier) valid() bool {
+	return containsTier(AllTiers, tier)
+}
+
+func containsTier(tiers []Tier, tier Tier) bool {
+	for _, candidate := range tiers {
+		if candidate == tier {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTag returns the go test flag that enables the tier.
+func (tier Tier) buildTag() string {
+	if tier == TierPlan || tier == TierApply {
+		return "-tags=integration"
+	}
+	return "-tags=unit"
+}
+
+// joinTiers formats tiers for messages.
+func joinTiers(tiers []Tier) string {
+	if len(tiers) == 0 {
+		return "none"
+	}
+	names := make([]string, len(tiers))
+	for i, tier := range tiers {
+		names[i] = string(tier)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,9 @@
+//go:build integration
+
+package helpers
+
+// The integration tag runs the tiers that talk to Azure. Combine it with the
+// unit tag to run every tier.
+func init() {
+	tagTiers = append(tagTiers, TierPlan, TierApply)
+}
@@ -0,0 +1,90 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - TEST TIER TESTS
+// =============================================================================
+//
+// Tests for selecting test tiers from TERRATEST_TIERS and build tags.
+//
+// Run with: go test -v -run TestTier ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTierParse tests parsing tier lists
+func TestTierParse(t *testing.T) {
+	t.Parallel()
+
+	tiers, err := ParseTiers("validate, plan,")
+	require.NoError(t, err)
+	assert.Equal(t, []Tier{TierValidate, TierPlan}, tiers)
+
+	tiers, err = ParseTiers("offline,all")
+	require.NoError(t, err)
+	assert.Equal(t, AllTiers, tiers)
+
+	_, err = ParseTiers("unit")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown test tier "unit"`)
+	assert.Contains(t, err.Error(), "validate, offline, plan, apply")
+}
+
+// TestTierFromEnv tests that TERRATEST_TIERS overrides build tags
+func TestTierFromEnv(t *testing.T) {
+	t.Setenv(TiersEnv, "apply")
+
+	tiers, source, err := EnabledTiersE()
+	require.NoError(t, err)
+	assert.Equal(t, []Tier{TierApply}, tiers)
+	assert.Equal(t, "TERRATEST_TIERS=apply", source)
+}
+
+// TestTierDefault tests the tiers enabled without tags or TERRATEST_TIERS
+func TestTierDefault(t *testing.T) {
+	if len(tagTiers) > 0 {
+		t.Skip("tier build tags are set")
+	}
+	t.Setenv(TiersEnv, "")
+
+	tiers, _, err := EnabledTiersE()
+	require.NoError(t, err)
+	assert.Equal(t, []Tier{TierValidate, TierOffline}, tiers)
+}
+
+// TestTierRequire tests that disabled tiers skip with a reason
+func TestTierRequire(t *testing.T) {
+	t.Setenv(TiersEnv, "validate,offline")
+
+	recorder := &skipRecorder{}
+	RequireTier(recorder, TierOffline)
+	assert.Empty(t, recorder.skipped)
+
+	RequireTier(recorder, TierApply)
+	assert.Equal(t, "skipping apply-tier test: enabled tiers are validate, offline (TERRATEST_TIERS=validate,offline); "+
+		"run with TERRATEST_TIERS=apply or -tags=integration", recorder.skipped)
+
+	t.Setenv(TiersEnv, "everything")
+	recorder = &skipRecorder{}
+	RequireTier(recorder, TierPlan)
+	assert.Contains(t, recorder.output(), `unknown test tier "everything"`)
+	assert.Empty(t, recorder.skipped)
+}
+
+// skipRecorder records a skip instead of skipping the test.
+type skipRecorder struct {
+	recordingT
+	skipped string
+}
+
+func (s *skipRecorder) Helper() {}
+
+func (s *skipRecorder) Skipf(format string, args ...interface{}) {
+	s.skipped = fmt.Sprintf(format, args...)
+}
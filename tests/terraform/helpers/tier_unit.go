@@ -0,0 +1,8 @@
+//go:build unit
+
+package helpers
+
+// The unit tag runs the tiers that need no Azure credentials.
+func init() {
+	tagTiers = append(tagTiers, TierValidate, TierOffline)
+}
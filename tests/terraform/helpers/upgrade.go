@@ -0,0 +1,667 @@
+package helpers
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// UpgradeRefEnv names the git ref whose modules upgrade tests deploy before
+// planning the working tree, e.g. a release tag or origin/main. It defaults to
+// DefaultUpgradeRef.
+const UpgradeRefEnv = "TERRATEST_UPGRADE_REF"
+
+// DefaultUpgradeRef compares the working tree with the last commit.
+const DefaultUpgradeRef = "HEAD"
+
+// ErrNotAtRef is returned when a module does not exist at the upgrade ref, so
+// there is nothing deployed to upgrade from.
+var ErrNotAtRef = errors.New("module does not exist at ref")
+
+// UpgradeRef returns the ref set in UpgradeRefEnv, or DefaultUpgradeRef.
+func UpgradeRef() string {
+	if ref := os.Getenv(UpgradeRefEnv); ref != "" {
+		return ref
+	}
+	return DefaultUpgradeRef
+}
+
+// CheckoutE writes the terraform directory of the repository as of ref to a
+// new temporary directory laid out like the repository, and returns it.
+// Modules are read with `git archive`, so the working tree is untouched.
+func CheckoutE(ref string) (string, error) {
+	root, err := RepoRootE()
+	if err != nil {
+		return "", err
+	}
+	if err := exec.Command("git", "-C", root, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil {
+		return "", fmt.Errorf("unknown git ref %q", ref)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", root, "archive", "--format=tar", ref, "--", "terraform")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git archive %s: %v: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+
+	dir, err := os.MkdirTemp("", "terratest-upgrade-")
+	if err != nil {
+		return "", err
+	}
+	if err := extractTar(&stdout, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// extractTar writes the directories, files and symlinks of a tar stream
+// below dir.
+func extractTar(r io.Reader, dir string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q is outside %s", header.Name, dir)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0o777)
+			if err != nil {
+				return err
+			}
+			_, copyErr := io.Copy(file, archive)
+			if err := file.Close(); err != nil {
+				return err
+			}
+			if copyErr != nil {
+				return copyErr
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// moduleAtRefE returns the directory of the named module in a checkout of
+// ref, and the checkout to remove afterwards. The error wraps ErrNotAtRef
+// when the module is newer than ref.
+func moduleAtRefE(ref, module string) (dir, checkout string, err error) {
+	root, err := RepoRootE()
+	if err != nil {
+		return "", "", err
+	}
+	current, err := ModuleDirE(module)
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(root, current)
+	if err != nil {
+		return "", "", err
+	}
+
+	checkout, err = CheckoutE(ref)
+	if err != nil {
+		return "", "", err
+	}
+	dir = filepath.Join(checkout, rel)
+	if _, err := os.Stat(dir); err != nil {
+		os.RemoveAll(checkout)
+		return "", "", fmt.Errorf("%s at %s: %w", module, ref, ErrNotAtRef)
+	}
+	return dir, checkout, nil
+}
+
+// OfflineStateOptionsE prepares the fixture's module as of ref for a mocked
+// apply with OfflineStateE. Vars the older module does not declare are
+// dropped, since a module release may add inputs. The error wraps
+// ErrNotAtRef when the module is newer than ref.
+func (f *Fixture) OfflineStateOptionsE(t testing.TestingT, ref string) (*terraform.Options, error) {
+	dir, checkout, err := moduleAtRefE(ref, f.Module)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(checkout)
+
+	variables, err := LoadVariablesE(dir)
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	for name, value := range f.Vars.Clone() {
+		if variables[name] != nil {
+			vars[name] = value
+		}
+	}
+
+	workspace, err := prepareOfflineE(dir, offlineApplyRunName, "apply")
+	if err != nil {
+		return nil, err
+	}
+	return f.offlineOptions(t, workspace, vars), nil
+}
+
+// OfflineStateE runs terraform init and the mocked apply prepared by
+// OfflineStateOptionsE, and returns the resulting state. Every value a real
+// provider would compute is filled in by the mock, so the state stands in
+// for a deployment of the older module.
+func OfflineStateE(t testing.TestingT, options *terraform.Options) (*tfjson.State, error) {
+	if _, err := InitE(t, options); err != nil {
+		return nil, err
+	}
+
+	stdout, runErr := runOfflineTestE(t, options)
+	state, err := ParseOfflineTestState([]byte(stdout))
+	if err != nil {
+		return nil, err
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+	return state, nil
+}
+
+// OfflineUpgradePlanE deploys the fixture's module as of ref with mocked
+// providers and compares that state with an offline plan of the working
+// tree, following the working tree's moved blocks; see compareUpgrade. The
+// error wraps ErrNotAtRef when the module is newer than ref.
+//
+// Mocked providers never force replacement, so the result only shows deletes
+// and creates. UpgradePlanE finds replacements with the real providers.
+func (f *Fixture) OfflineUpgradePlanE(t testing.TestingT, ref string) (*Plan, error) {
+	oldOptions, err := f.OfflineStateOptionsE(t, ref)
+	if err != nil {
+		return nil, err
+	}
+	state, err := OfflineStateE(t, oldOptions)
+	if err != nil {
+		return nil, fmt.Errorf("deploying %s at %s: %w", f.Module, ref, err)
+	}
+
+	plan, err := OfflinePlanJSONE(t, f.OfflineOptions(t))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ModuleDirE(f.Module)
+	if err != nil {
+		return nil, err
+	}
+	moves, err := moduleMovesE(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	return compareUpgrade(state, plan, moves)
+}
+
+// UpgradePlanE deploys the fixture's module as of ref with mocked providers,
+// then plans the working tree against that state with the real providers and
+// without refreshing; see PlanAgainstStateE. The providers decide which
+// changes force replacement, so this needs the credentials of the plan tier.
+// The error wraps ErrNotAtRef when the module is newer than ref.
+func (f *Fixture) UpgradePlanE(t testing.TestingT, ref string) (*Plan, error) {
+	oldOptions, err := f.OfflineStateOptionsE(t, ref)
+	if err != nil {
+		return nil, err
+	}
+	state, err := OfflineStateE(t, oldOptions)
+	if err != nil {
+		return nil, fmt.Errorf("deploying %s at %s: %w", f.Module, ref, err)
+	}
+	return PlanAgainstStateE(t, f.Options(t), state)
+}
+
+// PlanAgainstStateE writes the state to the local state file of the options'
+// workspace, runs terraform init, and plans with -refresh=false, so the
+// providers plan against the state as written instead of reading real
+// infrastructure.
+func PlanAgainstStateE(t testing.TestingT, options *terraform.Options, state *tfjson.State) (*Plan, error) {
+	stateFile, err := StateFileE(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(options.TerraformDir, "terraform.tfstate"), stateFile, 0o644); err != nil {
+		return nil, err
+	}
+	if _, err := InitE(t, options); err != nil {
+		return nil, err
+	}
+	return PlanJSONE(t, options, "-refresh=false")
+}
+
+// stateFile is version 4 of Terraform's local state file.
+type stateFile struct {
+	Version          int              `json:"version"`
+	TerraformVersion string           `json:"terraform_version"`
+	Serial           int              `json:"serial"`
+	Lineage          string           `json:"lineage"`
+	Outputs          struct{}         `json:"outputs"`
+	Resources        []*stateResource `json:"resources"`
+}
+
+type stateResource struct {
+	Module    string           `json:"module,omitempty"`
+	Mode      string           `json:"mode"`
+	Type      string           `json:"type"`
+	Name      string           `json:"name"`
+	Provider  string           `json:"provider"`
+	Instances []*stateInstance `json:"instances"`
+}
+
+type stateInstance struct {
+	IndexKey            interface{}            `json:"index_key,omitempty"`
+	SchemaVersion       uint64                 `json:"schema_version"`
+	Attributes          map[string]interface{} `json:"attributes"`
+	SensitiveAttributes []interface{}          `json:"sensitive_attributes"`
+	Dependencies        []string               `json:"dependencies,omitempty"`
+}
+
+// StateFileE converts state in the format of `terraform show -json` to a local
+// state file Terraform can plan against. Resources use the default
+// configuration of their provider, and sensitive marks are left to the
+// provider schemas.
+func StateFileE(state *tfjson.State) ([]byte, error) {
+	lineage := make([]byte, 16)
+	if _, err := rand.Read(lineage); err != nil {
+		return nil, err
+	}
+	file := &stateFile{
+		Version:          4,
+		TerraformVersion: state.TerraformVersion,
+		Serial:           1,
+		Lineage:          hex.EncodeToString(lineage),
+		Resources:        []*stateResource{},
+	}
+	if file.TerraformVersion == "" {
+		file.TerraformVersion = "1.7.5"
+	}
+
+	byKey := map[string]*stateResource{}
+	for _, resource := range stateResources(state) {
+		module := moduleOfAddress(resource.Address)
+		key := strings.Join([]string{module, string(resource.Mode), resource.Type, resource.Name}, " ")
+		entry, ok := byKey[key]
+		if !ok {
+			entry = &stateResource{
+				Module:   module,
+				Mode:     string(resource.Mode),
+				Type:     resource.Type,
+				Name:     resource.Name,
+				Provider: fmt.Sprintf("provider[%q]", resource.ProviderName),
+			}
+			byKey[key] = entry
+			file.Resources = append(file.Resources, entry)
+		}
+		attributes := resource.AttributeValues
+		if attributes == nil {
+			attributes = map[string]interface{}{}
+		}
+		entry.Instances = append(entry.Instances, &stateInstance{
+			IndexKey:            resource.Index,
+			SchemaVersion:       resource.SchemaVersion,
+			Attributes:          attributes,
+			SensitiveAttributes: []interface{}{},
+			Dependencies:        resource.DependsOn,
+		})
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// stateResources returns every resource instance in the state, root module
+// first.
+func stateResources(state *tfjson.State) []*tfjson.StateResource {
+	if state == nil || state.Values == nil || state.Values.RootModule == nil {
+		return nil
+	}
+	var resources []*tfjson.StateResource
+	var walk func(module *tfjson.StateModule)
+	walk = func(module *tfjson.StateModule) {
+		resources = append(resources, module.Resources...)
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(state.Values.RootModule)
+	return resources
+}
+
+// moduleOfAddress returns the module instance part of a resource instance
+// address, e.g. module.purview[0] for module.purview[0].azurerm_purview_account.main.
+func moduleOfAddress(address string) string {
+	modules, _ := splitAddress(address)
+	return strings.Join(modules, ".")
+}
+
+// splitAddress splits a resource instance address into its module instance
+// steps, such as module.purview[0], and the resource instance within them.
+func splitAddress(address string) (modules []string, resource string) {
+	rest := address
+	for strings.HasPrefix(rest, "module.") {
+		end := len("module.")
+		for end < len(rest) && rest[end] != '.' && rest[end] != '[' {
+			end++
+		}
+		if end < len(rest) && rest[end] == '[' {
+			end = closingBracket(rest, end) + 1
+		}
+		modules = append(modules, rest[:end])
+		rest = strings.TrimPrefix(rest[end:], ".")
+	}
+	return modules, rest
+}
+
+// closingBracket returns the index of the ] closing the [ at start, skipping
+// quoted keys, or the last index when there is none.
+func closingBracket(s string, start int) int {
+	quoted := false
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == ']':
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// moduleName strips the instance key from a module step.
+func moduleName(step string) string {
+	if i := strings.Index(step, "["); i >= 0 {
+		return step[:i]
+	}
+	return step
+}
+
+// scopedMove is a moved block together with the module calls, by name, that
+// lead to the module declaring it.
+type scopedMove struct {
+	scope []string
+	*Move
+}
+
+// moduleMovesE collects the moved blocks of the configuration in dir and of
+// the local modules it calls.
+func moduleMovesE(dir string, scope []string) ([]scopedMove, error) {
+	config, err := LoadConfigurationE(dir)
+	if err != nil {
+		return nil, err
+	}
+	var moves []scopedMove
+	for _, move := range config.Moves {
+		moves = append(moves, scopedMove{scope: scope, Move: move})
+	}
+
+	names := make([]string, 0, len(config.Calls))
+	for name := range config.Calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childScope := append(append([]string(nil), scope...), "module."+name)
+		childMoves, err := moduleMovesE(config.Calls[name].Dir, childScope)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, childMoves...)
+	}
+	return moves, nil
+}
+
+// apply returns where the moved block puts the resource instance at address,
+// and whether it applies. Like Terraform, a move of a resource or module
+// without an instance key also moves each of its instances.
+func (m scopedMove) apply(address string) (string, bool) {
+	modules, resource := splitAddress(address)
+	if len(modules) < len(m.scope) {
+		return "", false
+	}
+	for i, name := range m.scope {
+		if moduleName(modules[i]) != name {
+			return "", false
+		}
+	}
+
+	prefix := strings.Join(modules[:len(m.scope)], ".")
+	rel := strings.Join(append(append([]string(nil), modules[len(m.scope):]...), resource), ".")
+
+	var moved string
+	switch {
+	case rel == m.From:
+		moved = m.To
+	case strings.HasPrefix(rel, m.From+"."):
+		moved = m.To + rel[len(m.From):]
+	case !strings.HasSuffix(m.From, "]") && strings.HasPrefix(rel, m.From+"["):
+		moved = m.To + rel[len(m.From):]
+	default:
+		return "", false
+	}
+	if prefix != "" {
+		moved = prefix + "." + moved
+	}
+	return moved, true
+}
+
+// compareUpgrade compares a deployed state with a plan of the new module
+// from empty state, the way Terraform would plan the new module against that
+// state. Each deployed instance is followed through the moved blocks, and
+// through the implicit move between no instance key and [0] when count is
+// added or removed. The result has one change per instance: a no-op, with
+// its previous address when moved, for instances the new module still
+// declares; a delete for the rest; and the plan's own changes for new
+// instances. Data sources are ignored.
+func compareUpgrade(state *tfjson.State, plan *Plan, moves []scopedMove) (*Plan, error) {
+	planned := map[string]*tfjson.ResourceChange{}
+	for address, change := range plan.ResourceChangesMap {
+		if change.Mode == tfjson.ManagedResourceMode {
+			planned[address] = change
+		}
+	}
+
+	var changes []*tfjson.ResourceChange
+	kept := map[string]bool{}
+	for _, resource := range stateResources(state) {
+		if resource.Mode != tfjson.ManagedResourceMode {
+			continue
+		}
+		address := followMoves(resource.Address, moves)
+		if _, ok := planned[address]; !ok {
+			address = implicitMove(address, planned)
+		}
+
+		change, ok := planned[address]
+		if !ok {
+			changes = append(changes, &tfjson.ResourceChange{
+				Address:       resource.Address,
+				ModuleAddress: moduleOfAddress(resource.Address),
+				Mode:          resource.Mode,
+				Type:          resource.Type,
+				Name:          resource.Name,
+				Index:         resource.Index,
+				ProviderName:  resource.ProviderName,
+				Change: &tfjson.Change{
+					Actions: tfjson.Actions{tfjson.ActionDelete},
+					Before:  resource.AttributeValues,
+				},
+			})
+			continue
+		}
+
+		kept[address] = true
+		noOp := *change
+		noOp.Change = &tfjson.Change{
+			Actions: tfjson.Actions{tfjson.ActionNoop},
+			Before:  resource.AttributeValues,
+			After:   resource.AttributeValues,
+		}
+		if address != resource.Address {
+			noOp.PreviousAddress = resource.Address
+		}
+		changes = append(changes, &noOp)
+	}
+	for address, change := range planned {
+		if !kept[address] {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Address < changes[j].Address })
+
+	comparedJSON, err := json.Marshal(tfjson.Plan{
+		FormatVersion:   plan.RawPlan.FormatVersion,
+		ResourceChanges: changes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlan(comparedJSON)
+}
+
+// followMoves applies moved blocks to an address until none applies, so
+// chained moves end at the last address.
+func followMoves(address string, moves []scopedMove) string {
+	for range moves {
+		moved := false
+		for _, move := range moves {
+			if next, ok := move.apply(address); ok && next != address {
+				address, moved = next, true
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return address
+}
+
+// implicitMove returns the address Terraform moves an instance to when count
+// is added to or removed from its resource, or address when there is none.
+func implicitMove(address string, planned map[string]*tfjson.ResourceChange) string {
+	if strings.HasSuffix(address, "[0]") {
+		if _, ok := planned[strings.TrimSuffix(address, "[0]")]; ok {
+			return strings.TrimSuffix(address, "[0]")
+		}
+		return address
+	}
+	if !strings.HasSuffix(address, "]") {
+		if _, ok := planned[address+"[0]"]; ok {
+			return address + "[0]"
+		}
+	}
+	return address
+}
+
+// UpgradeProblems describes every change in a plan against deployed state
+// that would lose a resource: deletes and replacements, sorted by address. A
+// delete names the resources of the same type the plan creates, since a
+// rename without a moved block shows up as that pair.
+func UpgradeProblems(plan *Plan) []string {
+	created := map[string][]string{}
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		if change.Mode == tfjson.ManagedResourceMode && change.Action() == ActionCreate {
+			created[change.Type] = append(created[change.Type], address)
+		}
+	}
+
+	var problems []string
+	for _, address := range plan.Addresses() {
+		change := &ResourceChange{ResourceChange: plan.ResourceChangesMap[address]}
+		if change.Mode != tfjson.ManagedResourceMode {
+			continue
+		}
+		switch change.Action() {
+		case ActionDelete:
+			candidates := created[change.Type]
+			switch len(candidates) {
+			case 0:
+				problems = append(problems, fmt.Sprintf("%s: planned delete; no %s is created in its place", address, change.Type))
+			case 1:
+				from, to := movedAddresses(address, candidates[0])
+				problems = append(problems, fmt.Sprintf("%s: planned delete while %s is created; if it was renamed, add moved { from = %s to = %s }",
+					address, candidates[0], from, to))
+			default:
+				problems = append(problems, fmt.Sprintf("%s: planned delete while %s are created; if it was renamed, add a moved block to one of them",
+					address, strings.Join(candidates, ", ")))
+			}
+		case ActionReplace:
+			problems = append(problems, fmt.Sprintf("%s: planned replace, %s", address, replaceReason(change)))
+		}
+	}
+	return problems
+}
+
+// movedAddresses returns the addresses for a moved block from one instance
+// to another. When both have the same instance key the block moves the whole
+// resource, which also covers its other instances.
+func movedAddresses(from, to string) (string, string) {
+	fromModules, fromResource := splitAddress(from)
+	toModules, toResource := splitAddress(to)
+	fromKey, toKey := instanceKey(fromResource), instanceKey(toResource)
+	if fromKey != "" && fromKey == toKey {
+		fromResource = strings.TrimSuffix(fromResource, fromKey)
+		toResource = strings.TrimSuffix(toResource, toKey)
+	}
+	return strings.Join(append(fromModules, fromResource), "."), strings.Join(append(toModules, toResource), ".")
+}
+
+// instanceKey returns the trailing [key] of a resource instance address, or
+// "" when it has none.
+func instanceKey(resource string) string {
+	if !strings.HasSuffix(resource, "]") {
+		return ""
+	}
+	for i := 0; i < len(resource); i++ {
+		if resource[i] == '[' && closingBracket(resource, i) == len(resource)-1 {
+			return resource[i:]
+		}
+	}
+	return ""
+}
+
+// AssertUpgrade checks that a plan against state deployed from ref deletes and
+// replaces nothing. The failure lists each such change, with the attributes
+// that force a replacement and a moved block for a likely rename.
+func AssertUpgrade(t testing.TestingT, plan *Plan, ref string) bool {
+	markHelper(t)
+	problems := UpgradeProblems(plan)
+	if len(problems) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("upgrading from %s would destroy deployed resources:\n  %s",
+		ref, strings.Join(problems, "\n  ")))
+}
@@ -0,0 +1,185 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - MODULE UPGRADE TESTS
+// =============================================================================
+//
+// Tests for upgrade checks: state recorded from a mocked apply of an older
+// module, moved blocks, and the comparison with a plan of the newer module.
+// The recorded `terraform test -json -verbose` output in testdata/upgrade
+// comes from the old and new configurations next to it. Only
+// TestUpgradeCheckout needs git; none need Terraform.
+//
+// Run with: go test -v -run TestUpgrade ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUpgradeParseState tests reading the state of a mocked apply
+func TestUpgradeParseState(t *testing.T) {
+	t.Parallel()
+
+	state := loadUpgradeState(t)
+
+	var addresses []string
+	for _, resource := range stateResources(state) {
+		addresses = append(addresses, resource.Address)
+	}
+	assert.ElementsMatch(t, []string{
+		"terraform_data.counted",
+		"terraform_data.gone",
+		"terraform_data.keep",
+		"terraform_data.renamed",
+		"module.legacy.terraform_data.data",
+	}, addresses)
+
+	output, err := os.ReadFile(filepath.Join("testdata", "upgrade", "plan.jsonl"))
+	require.NoError(t, err)
+	_, err = ParseOfflineTestState(output)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "terraform test printed no state")
+}
+
+// TestUpgradeMoves tests following moved blocks declared at different module depths
+func TestUpgradeMoves(t *testing.T) {
+	t.Parallel()
+
+	moves, err := moduleMovesE(filepath.Join("testdata", "upgrade", "new"), nil)
+	require.NoError(t, err)
+	require.Len(t, moves, 3)
+
+	testCases := []struct {
+		address string
+		want    string
+	}{
+		{"terraform_data.renamed", "terraform_data.newname"},
+		{"terraform_data.keep", "terraform_data.keep"},
+		{"terraform_data.renamedx", "terraform_data.renamedx"},
+		{"module.legacy.terraform_data.data", "module.app[0].terraform_data.records"},
+		{"module.legacy.terraform_data.other", "module.app[0].terraform_data.other"},
+		{"module.app[0].terraform_data.data", "module.app[0].terraform_data.records"},
+		{"module.app[\"a.b\"].terraform_data.data[\"x]\"]", "module.app[\"a.b\"].terraform_data.records[\"x]\"]"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, followMoves(tc.address, moves), tc.address)
+	}
+}
+
+// TestUpgradeCompare tests comparing deployed state with a plan of the new module
+func TestUpgradeCompare(t *testing.T) {
+	t.Parallel()
+
+	output, err := os.ReadFile(filepath.Join("testdata", "upgrade", "plan.jsonl"))
+	require.NoError(t, err)
+	plan, err := ParseOfflineTestOutput(output)
+	require.NoError(t, err)
+	moves, err := moduleMovesE(filepath.Join("testdata", "upgrade", "new"), nil)
+	require.NoError(t, err)
+
+	compared, err := compareUpgrade(loadUpgradeState(t), plan, moves)
+	require.NoError(t, err)
+
+	for address, previous := range map[string]string{
+		"terraform_data.keep":                  "",
+		"terraform_data.newname":               "terraform_data.renamed",
+		"terraform_data.counted[0]":            "terraform_data.counted",
+		"module.app[0].terraform_data.records": "module.legacy.terraform_data.data",
+	} {
+		change := RequireChange(t, compared, address)
+		assert.Equal(t, ActionNoOp, change.Action(), address)
+		assert.Equal(t, previous, change.PreviousAddress, address)
+	}
+	AssertAction(t, compared, "terraform_data.gone", ActionDelete)
+	AssertCreated(t, compared, "terraform_data.added")
+
+	assert.Equal(t, []string{
+		"terraform_data.gone: planned delete while terraform_data.added is created; if it was renamed, add moved { from = terraform_data.gone to = terraform_data.added }",
+	}, UpgradeProblems(compared))
+
+	rec := &recordingT{}
+	assert.False(t, AssertUpgrade(rec, compared, "v1.2.0"))
+	assert.Contains(t, rec.output(), "upgrading from v1.2.0 would destroy deployed resources:")
+	assert.Contains(t, rec.output(), "terraform_data.gone: planned delete")
+}
+
+// TestUpgradeProblems tests the descriptions of deletes and replacements in a provider plan
+func TestUpgradeProblems(t *testing.T) {
+	t.Parallel()
+
+	plan := loadProtectedPlan(t)
+
+	assert.Equal(t, []string{
+		`module.databases.azurerm_postgresql_flexible_server.main[0]: planned replace, forced by administrator_password: (sensitive) => (sensitive); high_availability.0.mode: "SameZone" => "ZoneRedundant"; version: "15" => "16"`,
+		`module.databases.azurerm_redis_cache.main[0]: planned delete; no azurerm_redis_cache is created in its place`,
+		`module.disaster_recovery.azurerm_recovery_services_vault.main: planned replace, not forced by an attribute; check -replace and replace_triggered_by`,
+		`module.security.azurerm_key_vault.main: planned replace, forced by name: "kv-contoso-dev-brs" => "kv-contoso-dev-brs01"`,
+		`module.security.azurerm_storage_account.logs: planned delete; no azurerm_storage_account is created in its place`,
+	}, UpgradeProblems(plan))
+
+	from, to := movedAddresses(`module.db.azurerm_redis_cache.main[0]`, `module.db.azurerm_redis_cache.cache[0]`)
+	assert.Equal(t, "module.db.azurerm_redis_cache.main", from)
+	assert.Equal(t, "module.db.azurerm_redis_cache.cache", to)
+	from, to = movedAddresses(`azurerm_subnet.app`, `azurerm_subnet.subnets["app"]`)
+	assert.Equal(t, "azurerm_subnet.app", from)
+	assert.Equal(t, `azurerm_subnet.subnets["app"]`, to)
+}
+
+// TestUpgradeStateFile tests converting state to a local state file
+func TestUpgradeStateFile(t *testing.T) {
+	t.Parallel()
+
+	stateFileJSON, err := StateFileE(loadUpgradeState(t))
+	require.NoError(t, err)
+
+	var file stateFile
+	require.NoError(t, json.Unmarshal(stateFileJSON, &file))
+	assert.Equal(t, 4, file.Version)
+	assert.NotEmpty(t, file.Lineage)
+	require.Len(t, file.Resources, 5)
+
+	var module *stateResource
+	for _, resource := range file.Resources {
+		assert.Equal(t, `provider["terraform.io/builtin/terraform"]`, resource.Provider)
+		require.Len(t, resource.Instances, 1)
+		if resource.Module != "" {
+			module = resource
+		}
+	}
+	require.NotNil(t, module)
+	assert.Equal(t, "module.legacy", module.Module)
+	assert.Equal(t, "data", module.Name)
+	assert.Equal(t, "store", module.Instances[0].Attributes["input"])
+}
+
+// TestUpgradeCheckout tests extracting the Terraform tree of a git ref
+func TestUpgradeCheckout(t *testing.T) {
+	t.Parallel()
+
+	dir, err := CheckoutE("HEAD")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.FileExists(t, filepath.Join(dir, "terraform", "modules", "naming", "main.tf"))
+
+	_, err = CheckoutE("no-such-ref-for-terratest")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown git ref "no-such-ref-for-terratest"`)
+}
+
+// loadUpgradeState parses the recorded mocked apply of testdata/upgrade/old.
+func loadUpgradeState(t *testing.T) *tfjson.State {
+	output, err := os.ReadFile(filepath.Join("testdata", "upgrade", "state.jsonl"))
+	require.NoError(t, err)
+	state, err := ParseOfflineTestState(output)
+	require.NoError(t, err)
+	return state
+}
@@ -0,0 +1,311 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Variable is an input variable declared by a Terraform module.
+type Variable struct {
+	Name string
+
+	// Type is the declared type constraint, or cty.DynamicPseudoType when the
+	// variable has no type argument.
+	Type cty.Type
+
+	// Required is true when the variable has no default.
+	Required bool
+
+	// Validations are the conditions of the variable's validation blocks as
+	// written with whitespace removed, so that reformatting a condition does
+	// not change it.
+	Validations []string
+
+	// Range is where the variable block is declared, for error messages.
+	Range hcl.Range
+}
+
+// Variables are the input variables of a module, keyed by name.
+type Variables map[string]*Variable
+
+// Names returns the declared variable names, sorted.
+func (v Variables) Names() []string {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	variablesMu    sync.Mutex
+	variablesCache = map[string]Variables{}
+)
+
+// variableBlockSchema selects the variable blocks from a configuration file.
+var variableBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+	},
+}
+
+// variableSchema is the subset of a variable block the checker reads.
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "default"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{{Type: "validation"}},
+}
+
+// validationConditionSchema selects the condition of a validation block.
+var validationConditionSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "condition"}},
+}
+
+// ModuleVariables returns the variables declared by the named module under
+// terraform/modules. This will fail the test if the module cannot be parsed.
+func ModuleVariables(t testing.TestingT, module string) Variables {
+	dir := ModuleDir(t, module)
+	variables, err := LoadVariablesE(dir)
+	require.NoError(t, err)
+	return variables
+}
+
+// LoadVariablesE parses every .tf file in a module directory with HCL and
+// returns the declared input variables. Results are cached per directory, so
+// parallel tests parse each module once.
+func LoadVariablesE(dir string) (Variables, error) {
+	variablesMu.Lock()
+	defer variablesMu.Unlock()
+
+	if variables, ok := variablesCache[dir]; ok {
+		return variables, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .tf files in %s", dir)
+	}
+
+	parser := hclparse.NewParser()
+	variables := Variables{}
+	var diags hcl.Diagnostics
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, parseDiags := parser.ParseHCL(src, file)
+		diags = append(diags, parseDiags...)
+		if parseDiags.HasErrors() {
+			continue
+		}
+
+		content, _, contentDiags := parsed.Body.PartialContent(variableBlockSchema)
+		diags = append(diags, contentDiags...)
+		for _, block := range content.Blocks {
+			variable, varDiags := decodeVariable(block, src)
+			diags = append(diags, varDiags...)
+			if variable != nil {
+				variables[variable.Name] = variable
+			}
+		}
+	}
+
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parsing variables in %s: %s", dir, diags.Error())
+	}
+	variablesCache[dir] = variables
+	return variables, nil
+}
+
+// decodeVariable reads the type constraint, default and validation
+// conditions of a variable block declared in src.
+func decodeVariable(block *hcl.Block, src []byte) (*Variable, hcl.Diagnostics) {
+	content, _, diags := block.Body.PartialContent(variableSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	variable := &Variable{
+		Name:     block.Labels[0],
+		Type:     cty.DynamicPseudoType,
+		Required: true,
+		Range:    block.DefRange,
+	}
+
+	if attr, ok := content.Attributes["type"]; ok {
+		ty, _, typeDiags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+		diags = append(diags, typeDiags...)
+		variable.Type = ty
+	}
+	if _, ok := content.Attributes["default"]; ok {
+		variable.Required = false
+	}
+	for _, validation := range content.Blocks {
+		attrs, _, validationDiags := validation.Body.PartialContent(validationConditionSchema)
+		diags = append(diags, validationDiags...)
+		if condition, ok := attrs.Attributes["condition"]; ok {
+			text := string(condition.Expr.Range().SliceBytes(src))
+			variable.Validations = append(variable.Validations, strings.Join(strings.Fields(text), ""))
+		}
+	}
+	return variable, diags
+}
+
+// CheckVars cross-checks vars against a module's declared variables and
+// returns one message per problem: keys the module does not declare
+// (including attributes of object-typed variables), required variables that
+// are missing, and values that cannot be converted to the declared type.
+func CheckVars(variables Variables, vars Vars) []string {
+	var problems []string
+
+	for _, name := range sortedKeys(vars) {
+		variable, ok := variables[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown variable %q", name))
+			continue
+		}
+		problems = append(problems, checkValue(variable, vars[name])...)
+	}
+
+	for _, name := range variables.Names() {
+		if _, ok := vars[name]; !ok && variables[name].Required {
+			problems = append(problems, fmt.Sprintf("missing required variable %q (declared at %s)", name, variables[name].Range))
+		}
+	}
+	return problems
+}
+
+// checkValue converts a Go value to the variable's declared type the way
+// Terraform would, reporting unsupported object attributes separately because
+// Terraform drops them without an error.
+func checkValue(variable *Variable, value interface{}) []string {
+	val, err := goToCty(value)
+	if err != nil {
+		return []string{fmt.Sprintf("variable %q: cannot encode value: %s", variable.Name, err)}
+	}
+
+	var problems []string
+	for _, path := range unknownAttributes(variable.Type, val, variable.Name) {
+		problems = append(problems, fmt.Sprintf("unknown attribute %q", path))
+	}
+
+	// Conversion errors already describe nested paths the way Terraform
+	// reports them, e.g. `attribute "zones": element 1: string required`.
+	if _, err := convert.Convert(val, variable.Type); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid value for %s: %s", variable.Name, err))
+	}
+	return problems
+}
+
+// goToCty converts a Go value built from maps, slices and scalars to cty
+// through JSON, matching how Terraform sees a -var value before conversion.
+func goToCty(value interface{}) (cty.Value, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ty, err := ctyjson.ImpliedType(encoded)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(encoded, ty)
+}
+
+// unknownAttributes walks an object-typed constraint alongside a value and
+// returns the paths of attributes the constraint does not declare.
+func unknownAttributes(ty cty.Type, val cty.Value, path string) []string {
+	if val.IsNull() || !val.IsKnown() {
+		return nil
+	}
+
+	valType := val.Type()
+	var paths []string
+
+	switch {
+	case ty.IsObjectType() && (valType.IsObjectType() || valType.IsMapType()):
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			name := key.AsString()
+			if !ty.HasAttribute(name) {
+				paths = append(paths, path+"."+name)
+				continue
+			}
+			paths = append(paths, unknownAttributes(ty.AttributeType(name), elem, path+"."+name)...)
+		}
+	case (ty.IsListType() || ty.IsSetType()) && (valType.IsTupleType() || valType.IsListType()):
+		index := 0
+		for it := val.ElementIterator(); it.Next(); index++ {
+			_, elem := it.Element()
+			paths = append(paths, unknownAttributes(ty.ElementType(), elem, fmt.Sprintf("%s[%d]", path, index))...)
+		}
+	case ty.IsMapType() && (valType.IsObjectType() || valType.IsMapType()):
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			paths = append(paths, unknownAttributes(ty.ElementType(), elem, fmt.Sprintf("%s[%q]", path, key.AsString()))...)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// ContractError reports every problem found between a fixture's vars and the
+// variables its module declares.
+type ContractError struct {
+	Module   string
+	Problems []string
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("module %s: %d variable contract problem(s):\n  - %s",
+		e.Module, len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// CheckE checks the fixture's vars against the variables declared by its
+// module, returning a *ContractError when they do not match.
+func (f *Fixture) CheckE() error {
+	dir, err := ModuleDirE(f.Module)
+	if err != nil {
+		return err
+	}
+	variables, err := LoadVariablesE(dir)
+	if err != nil {
+		return err
+	}
+	if problems := CheckVars(variables, f.Vars); len(problems) > 0 {
+		return &ContractError{Module: f.Module, Problems: problems}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of vars in a stable order for reporting.
+func sortedKeys(vars Vars) []string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
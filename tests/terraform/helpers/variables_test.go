@@ -0,0 +1,149 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - VARIABLE CONTRACT TESTS
+// =============================================================================
+//
+// Tests for the variables.tf parser and the fixture contract checker. These
+// run without Terraform or Azure credentials.
+//
+// Run with: go test -v -run 'TestCheck|TestLoadVariables|TestFixturesMatch' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// TestLoadVariables tests reading types and defaults from variables.tf
+func TestLoadVariables(t *testing.T) {
+	t.Parallel()
+
+	variables := loadTestVariables(t)
+
+	assert.Equal(t, []string{"anything", "name", "pool", "pools", "replicas"}, variables.Names())
+	assert.True(t, variables["name"].Required)
+	assert.False(t, variables["replicas"].Required)
+	assert.False(t, variables["pool"].Required, "a null default is still a default")
+	assert.Equal(t, cty.String, variables["name"].Type)
+	assert.Equal(t, cty.DynamicPseudoType, variables["anything"].Type)
+	assert.True(t, variables["pool"].Type.IsObjectType())
+	assert.True(t, variables["pool"].Type.AttributeOptional("labels"))
+	assert.Equal(t, "variables.tf", filepath.Base(variables["name"].Range.Filename))
+}
+
+// TestCheckVars tests the problems reported for mismatched vars
+func TestCheckVars(t *testing.T) {
+	t.Parallel()
+
+	variables := loadTestVariables(t)
+
+	testCases := []struct {
+		name string
+		vars Vars
+		want []string
+	}{
+		{
+			name: "valid",
+			vars: Vars{
+				"name":     "terratest",
+				"replicas": "3",
+				"pool": map[string]interface{}{
+					"vm_size": "Standard_D2s_v5",
+					"zones":   []string{"1"},
+				},
+				"pools":    map[string]interface{}{"gpu": map[string]interface{}{"vm_size": "Standard_NC6s_v3"}},
+				"anything": []int{1, 2},
+			},
+		},
+		{
+			name: "unknown_variable",
+			vars: Vars{"name": "terratest", "sizing_profile": "small"},
+			want: []string{`unknown variable "sizing_profile"`},
+		},
+		{
+			name: "missing_required",
+			vars: Vars{"replicas": 2},
+			want: []string{`missing required variable "name" (declared at testdata/variables/variables.tf:3,1-16)`},
+		},
+		{
+			name: "type_mismatch",
+			vars: Vars{"name": "terratest", "replicas": "three"},
+			want: []string{"invalid value for replicas: a number is required"},
+		},
+		{
+			name: "nested_attributes",
+			vars: Vars{
+				"name": "terratest",
+				"pool": map[string]interface{}{
+					"vm_size":    "Standard_D2s_v5",
+					"node_count": 3,
+				},
+				"pools": map[string]interface{}{
+					"gpu": map[string]interface{}{"vm_size": "Standard_NC6s_v3", "taints": []string{}},
+				},
+			},
+			want: []string{
+				`unknown attribute "pool.node_count"`,
+				`invalid value for pool: attribute "zones" is required`,
+				`unknown attribute "pools[\"gpu\"].taints"`,
+			},
+		},
+		{
+			name: "nested_type_mismatch",
+			vars: Vars{
+				"name": "terratest",
+				"pool": map[string]interface{}{
+					"vm_size": "Standard_D2s_v5",
+					"zones":   []interface{}{"1", []string{"2"}},
+				},
+			},
+			want: []string{`invalid value for pool: attribute "zones": element 1: string required`},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, CheckVars(variables, tc.vars))
+		})
+	}
+}
+
+// TestCheckEReportsModule tests that contract errors name the module
+func TestCheckEReportsModule(t *testing.T) {
+	t.Parallel()
+
+	err := AKSCluster().With("system_node_pool", map[string]interface{}{}).Without("customer_name").CheckE()
+	require.Error(t, err)
+
+	var contractErr *ContractError
+	require.ErrorAs(t, err, &contractErr)
+	assert.Equal(t, "aks-cluster", contractErr.Module)
+	assert.Contains(t, err.Error(), "module aks-cluster: 2 variable contract problem(s)")
+	assert.Contains(t, err.Error(), `unknown variable "system_node_pool"`)
+	assert.Contains(t, err.Error(), `missing required variable "customer_name"`)
+}
+
+// TestFixturesMatchModuleVariables tests every baseline fixture against its
+// module's variables.tf
+func TestFixturesMatchModuleVariables(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range Fixtures() {
+		assert.NoError(t, fixture.CheckE())
+	}
+}
+
+// loadTestVariables parses the test module in testdata.
+func loadTestVariables(t *testing.T) Variables {
+	variables, err := LoadVariablesE(filepath.Join("testdata", "variables"))
+	require.NoError(t, err)
+	return variables
+}
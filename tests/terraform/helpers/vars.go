@@ -0,0 +1,51 @@
+package helpers
+
+// Vars holds the input variables passed to a Terraform module. It has the
+// same shape as terraform.Options.Vars.
+type Vars map[string]interface{}
+
+// Clone returns a deep copy of the vars so a test can change nested values
+// without affecting other tests built from the same fixture.
+func (v Vars) Clone() Vars {
+	if v == nil {
+		return nil
+	}
+	return cloneValue(map[string]interface{}(v)).(map[string]interface{})
+}
+
+// cloneValue deep copies the maps and slices that appear in fixture values.
+// Scalars are returned unchanged.
+func cloneValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case Vars:
+		return cloneValue(map[string]interface{}(typed))
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			out[key] = cloneValue(item)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(typed))
+		for key, item := range typed {
+			out[key] = item
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+		for i, item := range typed {
+			out[i] = cloneValue(item)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(typed))
+		for i, item := range typed {
+			out[i] = cloneValue(item).(map[string]interface{})
+		}
+		return out
+	case []string:
+		return append([]string(nil), typed...)
+	default:
+		return value
+	}
+}
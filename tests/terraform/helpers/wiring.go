@@ -0,0 +1,594 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// Configuration is the parsed top level of a Terraform configuration: its
+// variable defaults, locals, outputs, moved blocks and the local modules it
+// calls. It is enough to tell which module calls a set of variables enables
+// and which references would then fail, without providers or Terraform.
+type Configuration struct {
+	Dir string
+
+	// Calls are the module blocks with a local source, keyed by name.
+	Calls map[string]*ModuleCall
+
+	// Moves are the moved blocks, in file order.
+	Moves []*Move
+
+	variables map[string]*hclsyntax.Attribute
+	locals    map[string]*hclsyntax.Attribute
+
+	// evaluated are the blocks Terraform evaluates whatever the variables:
+	// outputs, providers, locals and resources without count.
+	evaluated []*hclsyntax.Block
+	localAttr []*hclsyntax.Attribute
+}
+
+// ModuleCall is a module block that calls a module in the same repository.
+type ModuleCall struct {
+	Name   string
+	Source string
+
+	// Dir is the absolute path of the called module.
+	Dir string
+
+	// Count is the count argument, or nil when the module is always called.
+	Count hcl.Expression
+
+	// Arguments are the input variables the block sets.
+	Arguments hclsyntax.Attributes
+
+	block *hclsyntax.Block
+}
+
+// Move is a moved block. From and To are addresses relative to the module
+// that declares it, such as azurerm_redis_cache.main or module.old[0].
+type Move struct {
+	From  string
+	To    string
+	Range hcl.Range
+}
+
+// moduleMetaArguments are module block arguments that are not input
+// variables.
+var moduleMetaArguments = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"depends_on": true,
+	"providers":  true,
+}
+
+// LoadConfiguration parses the named module, usually RootModule. This will
+// fail the test if the module cannot be parsed.
+func LoadConfiguration(t testing.TestingT, module string) *Configuration {
+	config, err := LoadConfigurationE(ModuleDir(t, module))
+	require.NoError(t, err)
+	return config
+}
+
+// LoadConfigurationE parses the .tf files in dir. Module calls to registry or
+// git sources are ignored.
+func LoadConfigurationE(dir string) (*Configuration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .tf files in %s", dir)
+	}
+
+	config := &Configuration{
+		Dir:       dir,
+		Calls:     map[string]*ModuleCall{},
+		variables: map[string]*hclsyntax.Attribute{},
+		locals:    map[string]*hclsyntax.Attribute{},
+	}
+	var diags hcl.Diagnostics
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, parseDiags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		diags = append(diags, parseDiags...)
+		if parseDiags.HasErrors() {
+			continue
+		}
+
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			switch block.Type {
+			case "variable":
+				if attr, ok := block.Body.Attributes["default"]; ok {
+					config.variables[block.Labels[0]] = attr
+				} else {
+					config.variables[block.Labels[0]] = nil
+				}
+			case "locals":
+				for name, attr := range block.Body.Attributes {
+					config.locals[name] = attr
+					config.localAttr = append(config.localAttr, attr)
+				}
+			case "module":
+				call, err := newModuleCall(dir, block)
+				if err != nil {
+					return nil, err
+				}
+				if call != nil {
+					config.Calls[call.Name] = call
+				}
+			case "resource", "data":
+				if _, ok := block.Body.Attributes["count"]; !ok {
+					config.evaluated = append(config.evaluated, block)
+				}
+			case "output", "provider":
+				config.evaluated = append(config.evaluated, block)
+			case "moved":
+				move, moveDiags := newMove(block)
+				diags = append(diags, moveDiags...)
+				if move != nil {
+					config.Moves = append(config.Moves, move)
+				}
+			}
+		}
+	}
+
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parsing %s: %s", dir, diags.Error())
+	}
+	return config, nil
+}
+
+// newMove reads the from and to addresses of a moved block.
+func newMove(block *hclsyntax.Block) (*Move, hcl.Diagnostics) {
+	move := &Move{Range: block.DefRange()}
+	var diags hcl.Diagnostics
+	for _, field := range []struct {
+		name    string
+		address *string
+	}{{"from", &move.From}, {"to", &move.To}} {
+		name, address := field.name, field.address
+		attr, ok := block.Body.Attributes[name]
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("moved block has no %s", name),
+				Subject:  block.DefRange().Ptr(),
+			})
+			continue
+		}
+		traversal, travDiags := hcl.AbsTraversalForExpr(attr.Expr)
+		diags = append(diags, travDiags...)
+		*address = traversalText(traversal)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return move, nil
+}
+
+// newModuleCall reads a module block, returning nil when its source is not a
+// local path.
+func newModuleCall(dir string, block *hclsyntax.Block) (*ModuleCall, error) {
+	sourceAttr, ok := block.Body.Attributes["source"]
+	if !ok {
+		return nil, fmt.Errorf("%s: module %q has no source", block.DefRange(), block.Labels[0])
+	}
+	sourceVal, diags := sourceAttr.Expr.Value(nil)
+	if diags.HasErrors() || sourceVal.Type() != cty.String {
+		return nil, fmt.Errorf("%s: module %q source must be a string literal", sourceAttr.SrcRange, block.Labels[0])
+	}
+	source := sourceVal.AsString()
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return nil, nil
+	}
+
+	call := &ModuleCall{
+		Name:      block.Labels[0],
+		Source:    source,
+		Dir:       filepath.Join(dir, source),
+		Arguments: hclsyntax.Attributes{},
+		block:     block,
+	}
+	if count, ok := block.Body.Attributes["count"]; ok {
+		call.Count = count.Expr
+	}
+	for name, attr := range block.Body.Attributes {
+		if !moduleMetaArguments[name] {
+			call.Arguments[name] = attr
+		}
+	}
+	return call, nil
+}
+
+// CheckModuleCallsE checks every module call against the module it calls and
+// returns one message per problem: arguments the module does not declare,
+// required variables left unset, references to outputs the module does not
+// declare and references to a counted module without an index. None of these
+// depend on the variables, and each fails `terraform validate`.
+func (c *Configuration) CheckModuleCallsE() ([]string, error) {
+	var problems []string
+
+	outputs := map[string]map[string]bool{}
+	for _, name := range sortedStrings(c.Calls) {
+		call := c.Calls[name]
+		variables, err := LoadVariablesE(call.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range sortedStrings(call.Arguments) {
+			if _, ok := variables[arg]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: module.%s sets %s, which %s does not declare",
+					call.Arguments[arg].SrcRange, name, arg, call.Source))
+			}
+		}
+		for _, variable := range variables.Names() {
+			if _, ok := call.Arguments[variable]; !ok && variables[variable].Required {
+				problems = append(problems, fmt.Sprintf("%s: module.%s does not set required variable %s of %s",
+					call.block.DefRange(), name, variable, call.Source))
+			}
+		}
+
+		if outputs[name], err = moduleOutputsE(call.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ref := range c.moduleReferences(nil) {
+		call := c.Calls[ref.module]
+		if call == nil {
+			continue
+		}
+		if call.Count != nil && ref.attr != "" && !ref.indexed {
+			problems = append(problems, fmt.Sprintf("%s: module.%s has count, so %s needs an index",
+				ref.rng, ref.module, ref.text))
+			continue
+		}
+		if ref.attr != "" && !outputs[ref.module][ref.attr] {
+			problems = append(problems, fmt.Sprintf("%s: %s has no output %q (referenced as %s)",
+				ref.rng, call.Source, ref.attr, ref.text))
+		}
+	}
+	return dedupe(problems), nil
+}
+
+// EnabledCallsE returns which module calls are made with vars, which override
+// the variable defaults. A call whose count cannot be evaluated without
+// providers counts as enabled.
+func (c *Configuration) EnabledCallsE(vars Vars) (map[string]bool, error) {
+	ctx, err := c.evalContext(vars)
+	if err != nil {
+		return nil, err
+	}
+	return c.enabledCalls(ctx), nil
+}
+
+// enabledCalls evaluates the count of every module call in ctx.
+func (c *Configuration) enabledCalls(ctx *hcl.EvalContext) map[string]bool {
+	enabled := map[string]bool{}
+	for name, call := range c.Calls {
+		enabled[name] = true
+		if call.Count == nil {
+			continue
+		}
+		count, diags := call.Count.Value(ctx)
+		if diags.HasErrors() || !count.IsKnown() || count.IsNull() || count.Type() != cty.Number {
+			continue
+		}
+		enabled[name] = !count.Equals(cty.Zero).True()
+	}
+	return enabled
+}
+
+// CheckDisabledReferencesE returns one message per reference, in a block
+// Terraform evaluates with vars, to an instance of a module call that vars
+// disables, e.g. module.observability[0] when enable_observability is false.
+// References in the branch of a conditional that vars does not take, in
+// disabled module calls and splat expressions such as module.x[*].id are
+// safe and not reported.
+func (c *Configuration) CheckDisabledReferencesE(vars Vars) ([]string, error) {
+	ctx, err := c.evalContext(vars)
+	if err != nil {
+		return nil, err
+	}
+	enabled := c.enabledCalls(ctx)
+
+	var problems []string
+	for _, ref := range c.moduleReferences(ctx) {
+		if c.Calls[ref.module] == nil || enabled[ref.module] || !ref.indexed {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s references %s, but module.%s is disabled",
+			ref.rng, ref.from, ref.text, ref.module))
+	}
+	return dedupe(problems), nil
+}
+
+// moduleReference is a traversal that starts with module.<name>.
+type moduleReference struct {
+	module  string
+	attr    string
+	indexed bool
+	text    string
+	from    string
+	rng     hcl.Range
+}
+
+// moduleReferences returns the references to module calls in the blocks
+// Terraform evaluates. With a nil ctx every module call and both branches of
+// every conditional are included; otherwise disabled module calls and
+// untaken branches are skipped.
+func (c *Configuration) moduleReferences(ctx *hcl.EvalContext) []moduleReference {
+	var enabled map[string]bool
+	if ctx != nil {
+		enabled = c.enabledCalls(ctx)
+	}
+
+	var refs []moduleReference
+	collect := func(from string, node hclsyntax.Node) {
+		refs = append(refs, referencesIn(ctx, from, node)...)
+	}
+
+	for _, attr := range c.localAttr {
+		collect("local."+attr.Name, attr.Expr)
+	}
+	for _, block := range c.evaluated {
+		collect(blockAddress(block), block.Body)
+	}
+	for _, name := range sortedStrings(c.Calls) {
+		if enabled != nil && !enabled[name] {
+			continue
+		}
+		call := c.Calls[name]
+		for _, arg := range sortedStrings(call.Arguments) {
+			collect("module."+name, call.Arguments[arg].Expr)
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].rng.Filename != refs[j].rng.Filename {
+			return refs[i].rng.Filename < refs[j].rng.Filename
+		}
+		return refs[i].rng.Start.Byte < refs[j].rng.Start.Byte
+	})
+	return refs
+}
+
+// referencesIn returns the module references under node. When ctx is set,
+// conditionals whose condition it can evaluate only contribute the branch
+// they take.
+func referencesIn(ctx *hcl.EvalContext, from string, node hclsyntax.Node) []moduleReference {
+	var untaken []hcl.Range
+	var refs []moduleReference
+
+	hclsyntax.VisitAll(node, func(n hclsyntax.Node) hcl.Diagnostics {
+		switch expr := n.(type) {
+		case *hclsyntax.ConditionalExpr:
+			if ctx == nil {
+				return nil
+			}
+			cond, diags := expr.Condition.Value(ctx)
+			if diags.HasErrors() || !cond.IsKnown() || cond.IsNull() || cond.Type() != cty.Bool {
+				return nil
+			}
+			if cond.True() {
+				untaken = append(untaken, expr.FalseResult.Range())
+			} else {
+				untaken = append(untaken, expr.TrueResult.Range())
+			}
+		case *hclsyntax.ScopeTraversalExpr:
+			if ref, ok := newModuleReference(from, expr); ok {
+				refs = append(refs, ref)
+			}
+		}
+		return nil
+	})
+
+	kept := refs[:0]
+	for _, ref := range refs {
+		skip := false
+		for _, rng := range untaken {
+			if rng.Filename == ref.rng.Filename && rng.ContainsOffset(ref.rng.Start.Byte) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			kept = append(kept, ref)
+		}
+	}
+	return kept
+}
+
+// newModuleReference reads module.<name>[<index>].<output> from a traversal.
+func newModuleReference(from string, expr *hclsyntax.ScopeTraversalExpr) (moduleReference, bool) {
+	traversal := expr.Traversal
+	if len(traversal) < 2 || traversal.RootName() != "module" {
+		return moduleReference{}, false
+	}
+	name, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return moduleReference{}, false
+	}
+
+	ref := moduleReference{module: name.Name, from: from, rng: expr.SrcRange}
+	rest := traversal[2:]
+	if len(rest) > 0 {
+		if _, ok := rest[0].(hcl.TraverseIndex); ok {
+			ref.indexed = true
+			rest = rest[1:]
+		}
+	}
+	if len(rest) > 0 {
+		if attr, ok := rest[0].(hcl.TraverseAttr); ok {
+			ref.attr = attr.Name
+		}
+	}
+	ref.text = traversalText(traversal)
+	return ref, true
+}
+
+// traversalText formats a traversal the way it is written, e.g.
+// module.observability[0].log_analytics_workspace_id.
+func traversalText(traversal hcl.Traversal) string {
+	var b strings.Builder
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(step.Name)
+		case hcl.TraverseAttr:
+			b.WriteString("." + step.Name)
+		case hcl.TraverseIndex:
+			if step.Key.Type() == cty.Number {
+				b.WriteString("[" + step.Key.AsBigFloat().String() + "]")
+			} else if step.Key.Type() == cty.String {
+				fmt.Fprintf(&b, "[%q]", step.Key.AsString())
+			} else {
+				b.WriteString("[...]")
+			}
+		default:
+			b.WriteString("[...]")
+		}
+	}
+	return b.String()
+}
+
+// blockAddress names a block in messages, e.g. output.grafana_url.
+func blockAddress(block *hclsyntax.Block) string {
+	switch block.Type {
+	case "resource":
+		return strings.Join(block.Labels, ".")
+	case "data":
+		return "data." + strings.Join(block.Labels, ".")
+	}
+	return block.Type + "." + strings.Join(block.Labels, ".")
+}
+
+// evalFunctions are the Terraform functions available to counts, conditions
+// and locals in EnabledCallsE. Expressions that need any other function are
+// treated as unknown.
+var evalFunctions = map[string]function.Function{
+	"coalesce": stdlib.CoalesceFunc,
+	"concat":   stdlib.ConcatFunc,
+	"contains": stdlib.ContainsFunc,
+	"keys":     stdlib.KeysFunc,
+	"length":   stdlib.LengthFunc,
+	"lookup":   stdlib.LookupFunc,
+	"lower":    stdlib.LowerFunc,
+	"merge":    stdlib.MergeFunc,
+	"upper":    stdlib.UpperFunc,
+	"values":   stdlib.ValuesFunc,
+}
+
+// evalContext returns var and local values for vars: vars override the
+// variable defaults, and locals are evaluated as far as they only depend on
+// variables and other locals. Everything else is unknown.
+func (c *Configuration) evalContext(vars Vars) (*hcl.EvalContext, error) {
+	variables := map[string]cty.Value{}
+	for name, attr := range c.variables {
+		variables[name] = cty.DynamicVal
+		if attr != nil {
+			if value, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+				variables[name] = value
+			}
+		}
+	}
+	for name, value := range vars {
+		if _, ok := c.variables[name]; !ok {
+			return nil, fmt.Errorf("%s declares no variable %q", c.Dir, name)
+		}
+		converted, err := goToCty(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %w", name, err)
+		}
+		variables[name] = converted
+	}
+
+	locals := map[string]cty.Value{}
+	for name := range c.locals {
+		locals[name] = cty.DynamicVal
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(variables), "local": cty.ObjectVal(locals)},
+		Functions: evalFunctions,
+	}
+
+	// Each pass evaluates the locals whose dependencies the previous passes
+	// resolved, so chains of locals settle within len(locals) passes.
+	resolved := map[string]bool{}
+	for pass := 0; pass < len(c.locals); pass++ {
+		progress := false
+		for name, attr := range c.locals {
+			if resolved[name] {
+				continue
+			}
+			value, diags := attr.Expr.Value(ctx)
+			if diags.HasErrors() || !value.IsWhollyKnown() {
+				continue
+			}
+			locals[name] = value
+			resolved[name] = true
+			progress = true
+		}
+		if !progress {
+			break
+		}
+		ctx.Variables["local"] = cty.ObjectVal(locals)
+	}
+	return ctx, nil
+}
+
+// moduleOutputsE returns the names of the outputs a module declares.
+func moduleOutputsE(dir string) (map[string]bool, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]bool{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			if block.Type == "output" {
+				outputs[block.Labels[0]] = true
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// dedupe removes repeated messages, keeping the first of each.
+func dedupe(messages []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, message := range messages {
+		if !seen[message] {
+			seen[message] = true
+			out = append(out, message)
+		}
+	}
+	return out
+}
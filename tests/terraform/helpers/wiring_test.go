@@ -0,0 +1,134 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - MODULE WIRING TESTS
+// =============================================================================
+//
+// Tests for the static checks of module calls: arguments and outputs that
+// the called module does not declare, and references to module instances a
+// set of variables disables. testdata/wiring is a small root configuration
+// with one of each mistake; the real root module is loaded to check the
+// feature-flag factors.
+//
+// Run with: go test -v -run 'Wiring|RootFeature' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWiringLoadConfiguration tests reading module calls from a configuration
+func TestWiringLoadConfiguration(t *testing.T) {
+	t.Parallel()
+
+	config := loadTestConfiguration(t)
+	assert.Equal(t, []string{"ai", "monitoring"}, sortedStrings(config.Calls), "registry modules are ignored")
+	assert.Equal(t, "./modules/ai", config.Calls["ai"].Source)
+	assert.Equal(t, filepath.Join("testdata", "wiring", "modules", "ai"), config.Calls["ai"].Dir)
+	assert.Equal(t, []string{"name", "sku", "workspace_id", "workspace_name"}, sortedStrings(config.Calls["ai"].Arguments))
+	assert.NotNil(t, config.Calls["monitoring"].Count)
+}
+
+// TestWiringCheckModuleCalls tests the checks that do not depend on variables
+func TestWiringCheckModuleCalls(t *testing.T) {
+	t.Parallel()
+
+	problems, err := loadTestConfiguration(t).CheckModuleCallsE()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"testdata/wiring/main.tf:49,3-24: module.ai sets sku, which ./modules/ai does not declare",
+		"testdata/wiring/main.tf:42,1-12: module.ai does not set required variable region of ./modules/ai",
+		"testdata/wiring/main.tf:68,11-41: module.monitoring has count, so module.monitoring.workspace_id needs an index",
+		`testdata/wiring/main.tf:72,11-45: ./modules/monitoring has no output "workspace_key" (referenced as module.monitoring[0].workspace_key)`,
+	}, problems)
+}
+
+// TestWiringEnabledCalls tests evaluating counts from variables and locals
+func TestWiringEnabledCalls(t *testing.T) {
+	t.Parallel()
+
+	config := loadTestConfiguration(t)
+
+	testCases := []struct {
+		vars Vars
+		want map[string]bool
+	}{
+		{Vars{}, map[string]bool{"ai": false, "monitoring": true}},
+		{Vars{"enable_ai": true}, map[string]bool{"ai": false, "monitoring": true}},
+		{Vars{"enable_ai": true, "mode": "large"}, map[string]bool{"ai": true, "monitoring": true}},
+		{Vars{"enable_monitoring": false}, map[string]bool{"ai": false, "monitoring": false}},
+	}
+
+	for _, tc := range testCases {
+		enabled, err := config.EnabledCallsE(tc.vars)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, enabled, "%v", tc.vars)
+	}
+
+	_, err := config.EnabledCallsE(Vars{"enable_purview": true})
+	assert.ErrorContains(t, err, `declares no variable "enable_purview"`)
+}
+
+// TestWiringDisabledReferences tests finding references to disabled module instances
+func TestWiringDisabledReferences(t *testing.T) {
+	t.Parallel()
+
+	config := loadTestConfiguration(t)
+
+	testCases := []struct {
+		name string
+		vars Vars
+		want []string
+	}{
+		{"defaults", Vars{}, nil},
+		{
+			"ai without monitoring",
+			Vars{"enable_ai": true, "mode": "large", "enable_monitoring": false},
+			[]string{
+				"testdata/wiring/main.tf:47,20-53: module.ai references module.monitoring[0].workspace_id, but module.monitoring is disabled",
+				"testdata/wiring/main.tf:72,11-45: output.workspace_key references module.monitoring[0].workspace_key, but module.monitoring is disabled",
+			},
+		},
+		{
+			"ai flag in small mode",
+			Vars{"enable_ai": true},
+			[]string{"testdata/wiring/main.tf:60,27-48: output.ai_endpoint references module.ai[0].endpoint, but module.ai is disabled"},
+		},
+	}
+
+	for _, tc := range testCases {
+		problems, err := config.CheckDisabledReferencesE(tc.vars)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.want, problems, tc.name)
+	}
+}
+
+// TestRootFeatureFactors tests that every feature flag of the root module is a factor
+func TestRootFeatureFactors(t *testing.T) {
+	t.Parallel()
+
+	factors := RootFeatureFactors(t)
+	require.NotEmpty(t, factors)
+	assert.Equal(t, Factor{Name: "deployment_mode", Values: DeploymentModes}, factors[0])
+
+	var names []string
+	for _, factor := range factors[1:] {
+		assert.Equal(t, []interface{}{false, true}, factor.Values, factor.Name)
+		names = append(names, factor.Name)
+	}
+	assert.Subset(t, names, []string{"enable_ai_foundry", "enable_databases", "enable_defender", "enable_disaster_recovery", "enable_observability", "enable_purview"})
+
+	assert.NoError(t, Root().CheckE())
+}
+
+// loadTestConfiguration returns the configuration in testdata/wiring.
+func loadTestConfiguration(t *testing.T) *Configuration {
+	config, err := LoadConfigurationE(filepath.Join("testdata", "wiring"))
+	require.NoError(t, err)
+	return config
+}
@@ -0,0 +1,151 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// PluginCacheEnv is Terraform's own plugin cache variable. When it is set the
+// harness shares that directory; otherwise it uses a cache under the user's
+// cache directory.
+const PluginCacheEnv = "TF_PLUGIN_CACHE_DIR"
+
+// pluginCacheLockFile serializes terraform init between test processes, since
+// Terraform does not support concurrent writes to a plugin cache.
+const pluginCacheLockFile = ".terratest.lock"
+
+// pluginCacheMu serializes terraform init between tests in one process.
+var pluginCacheMu sync.Mutex
+
+// WorkspaceE copies the Terraform configuration in dir to a new temporary
+// directory, without any .terraform directory, lock file or state, and returns
+// the path of the copy. Tests that share a module each get their own copy, so
+// parallel init, plan and apply never touch the same files.
+func WorkspaceE(dir string) (string, error) {
+	return files.CopyTerraformFolderToTemp(dir, "terratest-"+filepath.Base(dir)+"-")
+}
+
+// Workspace copies the named module to a temporary directory that is removed
+// when the test finishes. This will fail the test if the copy fails.
+func Workspace(t testing.TestingT, module string) string {
+	dir, err := WorkspaceE(ModuleDir(t, module))
+	require.NoError(t, err)
+	removeOnCleanup(t, filepath.Dir(dir))
+	return dir
+}
+
+// PluginCacheDirE returns the shared provider plugin cache, creating it if
+// needed.
+func PluginCacheDirE() (string, error) {
+	dir := os.Getenv(PluginCacheEnv)
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cache, "agentic-devops-platform", "terraform-plugins")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// pluginCacheEnvVars points Terraform at the shared plugin cache. Workspaces
+// start without a dependency lock file, so Terraform is allowed to fill one
+// from the cache instead of downloading every provider again to verify it.
+func pluginCacheEnvVars(t testing.TestingT) map[string]string {
+	dir, err := PluginCacheDirE()
+	require.NoError(t, err)
+	return map[string]string{
+		PluginCacheEnv: dir,
+		"TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE": "true",
+	}
+}
+
+// Init runs terraform init while holding the plugin cache lock. This will fail
+// the test if init fails.
+func Init(t testing.TestingT, options *terraform.Options) string {
+	out, err := InitE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// InitE runs terraform init while holding the plugin cache lock, so parallel
+// tests fill the shared cache one at a time. Once a provider is cached, init
+// only links it into the workspace and the lock is held briefly.
+func InitE(t testing.TestingT, options *terraform.Options) (string, error) {
+	unlock, err := lockPluginCache()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	return terraform.InitE(t, options)
+}
+
+// Validate runs terraform validate on an initialized workspace. This will fail
+// the test if the configuration is invalid.
+func Validate(t testing.TestingT, options *terraform.Options) string {
+	out, err := ValidateE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// ValidateE runs terraform validate without the -var and -var-file flags that
+// terraform.Validate adds, which validate rejects. Validation does not read
+// variable values, so the options' vars are only checked by Options.
+func ValidateE(t testing.TestingT, options *terraform.Options) (string, error) {
+	args := []string{"validate"}
+	if options.NoColor {
+		args = append(args, "-no-color")
+	}
+	return terraform.RunTerraformCommandE(t, options, args...)
+}
+
+// lockPluginCache takes the in-process and cross-process plugin cache locks
+// and returns a function that releases both.
+func lockPluginCache() (func(), error) {
+	pluginCacheMu.Lock()
+
+	dir, err := PluginCacheDirE()
+	if err != nil {
+		pluginCacheMu.Unlock()
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, pluginCacheLockFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		pluginCacheMu.Unlock()
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		pluginCacheMu.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+		pluginCacheMu.Unlock()
+	}, nil
+}
+
+// cleaner is implemented by *testing.T.
+type cleaner interface {
+	Cleanup(func())
+}
+
+// removeOnCleanup deletes dir when the test finishes, if t supports cleanup
+// functions. Otherwise the directory is left in the system temp directory.
+func removeOnCleanup(t testing.TestingT, dir string) {
+	if c, ok := t.(cleaner); ok {
+		c.Cleanup(func() { os.RemoveAll(dir) })
+	}
+}
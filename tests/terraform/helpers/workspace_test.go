@@ -0,0 +1,91 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - WORKSPACE TESTS
+// =============================================================================
+//
+// Tests for the per-test module copies and the shared plugin cache lock.
+// These run without Terraform or Azure credentials.
+//
+// Run with: go test -v -run 'TestWorkspace|TestPluginCache' ./helpers/
+//
+// =============================================================================
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkspaceIsolation tests that each Options call gets its own copy
+func TestWorkspaceIsolation(t *testing.T) {
+	t.Setenv(PluginCacheEnv, t.TempDir())
+
+	var dirs []string
+	t.Run("copies", func(t *testing.T) {
+		first := Networking().Options(t)
+		second := Networking().Options(t)
+		dirs = []string{first.TerraformDir, second.TerraformDir}
+
+		assert.NotEqual(t, first.TerraformDir, second.TerraformDir)
+		assert.NotEqual(t, ModuleDir(t, "networking"), first.TerraformDir)
+		assert.FileExists(t, filepath.Join(first.TerraformDir, "variables.tf"))
+		assert.Equal(t, os.Getenv(PluginCacheEnv), first.EnvVars[PluginCacheEnv])
+	})
+
+	for _, dir := range dirs {
+		assert.NoDirExists(t, dir, "workspaces are removed when the test finishes")
+	}
+}
+
+// TestWorkspaceSkipsLocalState tests that local Terraform files are not copied
+func TestWorkspaceSkipsLocalState(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	for _, name := range []string{"main.tf", "terraform.tfstate", ".terraform/providers/marker"} {
+		path := filepath.Join(src, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("# test\n"), 0o644))
+	}
+
+	dir, err := WorkspaceE(src)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Dir(dir))
+
+	assert.FileExists(t, filepath.Join(dir, "main.tf"))
+	assert.NoFileExists(t, filepath.Join(dir, "terraform.tfstate"))
+	assert.NoDirExists(t, filepath.Join(dir, ".terraform"))
+}
+
+// TestPluginCacheLock tests that init holders never overlap
+func TestPluginCacheLock(t *testing.T) {
+	t.Setenv(PluginCacheEnv, filepath.Join(t.TempDir(), "plugins"))
+
+	var holders, overlaps int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := lockPluginCache()
+			if !assert.NoError(t, err) {
+				return
+			}
+			if atomic.AddInt32(&holders, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			atomic.AddInt32(&holders, -1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, overlaps)
+	assert.FileExists(t, filepath.Join(os.Getenv(PluginCacheEnv), pluginCacheLockFile))
+}
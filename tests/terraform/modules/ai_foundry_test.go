@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform AI Foundry module.
 //
-// Run with: go test -v -run TestAIFoundry ./modules/
+// Run with: go test -v -tags=integration -run TestAIFoundry ./modules/
 //
 // =============================================================================
 
@@ -13,227 +13,116 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestAIFoundryModuleBasic tests basic AI Foundry configuration
 func TestAIFoundryModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/ai-foundry",
-		Vars: map[string]interface{}{
-			"customer_name":               "testai",
-			"environment":                 "dev",
-			"location":                    "eastus",
-			"resource_group_name":         "rg-test-ai",
-			"subnet_id":                   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":                "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"log_analytics_workspace_id":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"private_dns_zone_ids": map[string]interface{}{
-				"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-				"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-				"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
+	terraformOptions := aiFoundry("testai").
+		With("log_analytics_workspace_id", helpers.LogAnalyticsWorkspaceID("law")).
+		WithAttr("openai_config", "models", []map[string]interface{}{
+			{
+				"name":          "gpt-4o",
+				"model_name":    "gpt-4o",
+				"model_version": "2024-05-13",
+				"capacity":      30,
+				"rai_policy":    "DefaultRaiPolicy",
 			},
-			"openai_config": map[string]interface{}{
-				"enabled":  true,
-				"sku_name": "S0",
-				"models": []map[string]interface{}{
-					{
-						"name":          "gpt-4o",
-						"model_name":    "gpt-4o",
-						"model_version": "2024-05-13",
-						"capacity":      30,
-						"rai_policy":    "DefaultRaiPolicy",
-					},
-				},
-			},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
+		}).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
 
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Azure OpenAI is planned
-	assert.Contains(t, planOutput, "azurerm_cognitive_account.openai")
+	helpers.AssertCreated(t, plan, "azurerm_cognitive_account.openai[0]")
+	helpers.AssertAttribute(t, plan, "azurerm_cognitive_account.openai[0]", "kind", "OpenAI")
+	helpers.AssertAttribute(t, plan, `azurerm_cognitive_deployment.models["gpt-4o"]`, "scale.0.capacity", 30)
+
+	// Verify the region offers the planned models
+	helpers.AssertRegionAvailability(t, helpers.RegionAvailability(t), plan)
 }
 
 // TestAIFoundryModuleOpenAI tests Azure OpenAI configuration
 func TestAIFoundryModuleOpenAI(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/ai-foundry",
-		Vars: map[string]interface{}{
-			"customer_name":              "oaitest",
-			"environment":                "dev",
-			"location":                   "eastus",
-			"resource_group_name":        "rg-test-oai",
-			"subnet_id":                  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"log_analytics_workspace_id": "",
-			"private_dns_zone_ids": map[string]interface{}{
-				"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-				"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-				"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
-			},
-			"openai_config": map[string]interface{}{
-				"enabled":  true,
-				"sku_name": "S0",
-				"models":   []map[string]interface{}{},
-			},
-			"ai_search_config": map[string]interface{}{
-				"enabled": false,
-			},
-			"content_safety_config": map[string]interface{}{
-				"enabled": false,
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := aiFoundry("oaitest").
+		WithAttr("openai_config", "models", []map[string]interface{}{}).
+		WithAttr("ai_search_config", "enabled", false).
+		WithAttr("content_safety_config", "enabled", false).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify OpenAI naming convention
-	assert.Contains(t, planOutput, "oai-")
+	helpers.AssertAttribute(t, plan, "azurerm_cognitive_account.openai[0]", "name", "oai-oaitest-dev")
+	helpers.AssertAttribute(t, plan, "azurerm_cognitive_account.openai[0]", "custom_subdomain_name", "oai-oaitestdev")
+	helpers.AssertNotPlanned(t, plan, "azurerm_cognitive_deployment.models")
 }
 
 // TestAIFoundryModuleAISearch tests AI Search configuration
 func TestAIFoundryModuleAISearch(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/ai-foundry",
-		Vars: map[string]interface{}{
-			"customer_name":              "srchtest",
-			"environment":                "dev",
-			"location":                   "eastus",
-			"resource_group_name":        "rg-test-search",
-			"subnet_id":                  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"log_analytics_workspace_id": "",
-			"private_dns_zone_ids": map[string]interface{}{
-				"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-				"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-				"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
-			},
-			"openai_config": map[string]interface{}{
-				"enabled": false,
-			},
-			"ai_search_config": map[string]interface{}{
-				"enabled":                       true,
-				"sku_name":                      "standard",
-				"replica_count":                 1,
-				"partition_count":               1,
-				"public_network_access_enabled": false,
-				"semantic_search_sku":           "standard",
-			},
-			"content_safety_config": map[string]interface{}{
-				"enabled": false,
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := aiFoundry("srchtest").
+		WithAttr("openai_config", "enabled", false).
+		WithAttr("content_safety_config", "enabled", false).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify AI Search is planned
-	assert.Contains(t, planOutput, "azurerm_search_service.main")
+	helpers.AssertCreated(t, plan, "azurerm_search_service.main[0]")
+	helpers.AssertNotPlanned(t, plan, "azurerm_cognitive_account.openai")
 }
 
 // TestAIFoundryModuleContentSafety tests Content Safety configuration
 func TestAIFoundryModuleContentSafety(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/ai-foundry",
-		Vars: map[string]interface{}{
-			"customer_name":              "cstest",
-			"environment":                "dev",
-			"location":                   "eastus",
-			"resource_group_name":        "rg-test-cs",
-			"subnet_id":                  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"log_analytics_workspace_id": "",
-			"private_dns_zone_ids": map[string]interface{}{
-				"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-				"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-				"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
-			},
-			"openai_config": map[string]interface{}{
-				"enabled": false,
-			},
-			"ai_search_config": map[string]interface{}{
-				"enabled": false,
-			},
-			"content_safety_config": map[string]interface{}{
-				"enabled":  true,
-				"sku_name": "S0",
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := aiFoundry("cstest").
+		WithAttr("openai_config", "enabled", false).
+		WithAttr("ai_search_config", "enabled", false).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Content Safety is planned
-	assert.Contains(t, planOutput, "azurerm_cognitive_account.content_safety")
+	helpers.AssertCreated(t, plan, "azurerm_cognitive_account.content_safety[0]")
+	helpers.AssertNotPlanned(t, plan, "azurerm_search_service.main")
 }
 
 // TestAIFoundryModulePrivateEndpoints tests private endpoint creation
 func TestAIFoundryModulePrivateEndpoints(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/ai-foundry",
-		Vars: map[string]interface{}{
-			"customer_name":              "petest",
-			"environment":                "prod",
-			"location":                   "eastus",
-			"resource_group_name":        "rg-test-ai-pe",
-			"subnet_id":                  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"log_analytics_workspace_id": "",
-			"private_dns_zone_ids": map[string]interface{}{
-				"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-				"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-				"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
-			},
-			"openai_config": map[string]interface{}{
-				"enabled":  true,
-				"sku_name": "S0",
-				"models":   []map[string]interface{}{},
-			},
-			"ai_search_config": map[string]interface{}{
-				"enabled": false,
-			},
-			"content_safety_config": map[string]interface{}{
-				"enabled": false,
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := aiFoundry("petest").
+		With("environment", "prod").
+		WithAttr("openai_config", "models", []map[string]interface{}{}).
+		WithAttr("ai_search_config", "enabled", false).
+		WithAttr("content_safety_config", "enabled", false).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify private endpoints are planned
-	assert.Contains(t, planOutput, "azurerm_private_endpoint.openai")
+	helpers.AssertCreated(t, plan, "azurerm_private_endpoint.openai[0]")
+	helpers.AssertAttribute(t, plan, "azurerm_cognitive_account.openai[0]", "public_network_access_enabled", false)
 }
 
 // TestAIFoundryModuleEnvironments tests different environments
 func TestAIFoundryModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -243,38 +132,27 @@ func TestAIFoundryModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/ai-foundry",
-				Vars: map[string]interface{}{
-					"customer_name":              "aienv",
-					"environment":                env,
-					"location":                   "eastus",
-					"resource_group_name":        "rg-test-ai-" + env,
-					"subnet_id":                  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"log_analytics_workspace_id": "",
-					"private_dns_zone_ids": map[string]interface{}{
-						"openai":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/openai",
-						"cognitiveservices": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cognitive",
-						"search":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/search",
-					},
-					"openai_config": map[string]interface{}{
-						"enabled": false,
-					},
-					"ai_search_config": map[string]interface{}{
-						"enabled": false,
-					},
-					"content_safety_config": map[string]interface{}{
-						"enabled": false,
-					},
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := aiFoundry("aienv").
+				With("environment", env).
+				With("resource_group_name", "rg-test-ai-"+env).
+				WithAttr("openai_config", "enabled", false).
+				WithAttr("ai_search_config", "enabled", false).
+				WithAttr("content_safety_config", "enabled", false).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertNotPlanned(t, plan, "azurerm_cognitive_account.openai")
+			helpers.AssertNotPlanned(t, plan, "azurerm_search_service.main")
+			helpers.AssertNotPlanned(t, plan, "azurerm_cognitive_account.content_safety")
 		})
 	}
 }
+
+// aiFoundry returns the AI Foundry fixture used by these tests, deployed to
+// eastus.
+func aiFoundry(customerName string) *helpers.Fixture {
+	return helpers.AIFoundry().
+		With("customer_name", customerName).
+		With("location", "eastus")
+}
@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform AKS cluster module.
 //
-// Run with: go test -v -run TestAKS ./modules/
+// Run with: go test -v -tags=integration -run TestAKS ./modules/
 //
 // =============================================================================
 
@@ -14,58 +14,41 @@ import (
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestAKSClusterModuleBasic tests basic AKS cluster configuration
 func TestAKSClusterModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/aks-cluster",
-		Vars: map[string]interface{}{
-			"resource_group_name": "rg-test-aks",
-			"location":            "brazilsouth",
-			"customer_name":       "testaks",
-			"environment":         "dev",
-			"kubernetes_version":  "1.29",
-			"sku_tier":            "Standard",
-			"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-			"system_node_pool": map[string]interface{}{
-				"name":       "system",
-				"vm_size":    "Standard_D4s_v5",
-				"node_count": 3,
-				"zones":      []string{"1", "2", "3"},
-			},
-			"workload_identity": true,
-			"addons": map[string]interface{}{
-				"azure_policy":           true,
-				"azure_keyvault_secrets": true,
-				"oms_agent":              false,
-			},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.AKSCluster().
+		With("customer_name", "testaks").
+		With("vnet_subnet_id", helpers.SubnetID("snet-aks")).
+		With("enable_workload_identity", true).
+		With("enable_azure_policy", true).
+		Options(t)
 
 	// Initialize and validate
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
 
 	// Plan only (no actual resources created in unit test)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify plan contains expected resources
-	assert.Contains(t, planOutput, "azurerm_kubernetes_cluster.main")
-	assert.Contains(t, planOutput, "aks-testaks-dev")
+	helpers.AssertCreated(t, plan, "azurerm_kubernetes_cluster.main")
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "name", "aks-testaks-dev")
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "sku_tier", "Standard")
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oidc_issuer_enabled", true)
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.zones", []string{"1", "2", "3"})
 }
 
 // TestAKSClusterModuleKubernetesVersions tests different K8s versions
 func TestAKSClusterModuleKubernetesVersions(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	versions := []string{"1.28", "1.29", "1.30"}
@@ -75,35 +58,20 @@ func TestAKSClusterModuleKubernetesVersions(t *testing.T) {
 		t.Run("k8s_"+version, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "vertest",
-					"environment":         "dev",
-					"kubernetes_version":  version,
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-				},
-				NoColor: true,
-			}
+			terraformOptions := smallAKSCluster("vertest").
+				With("kubernetes_version", version).
+				Options(t)
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
-			assert.Contains(t, planOutput, version)
+			helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "kubernetes_version", version)
 		})
 	}
 }
 
 // TestAKSClusterModuleSKUTiers tests different SKU tiers
 func TestAKSClusterModuleSKUTiers(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -120,120 +88,79 @@ func TestAKSClusterModuleSKUTiers(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "skutest",
-					"environment":         "dev",
-					"sku_tier":            tc.skuTier,
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-				},
-				NoColor: true,
-			}
+			terraformOptions := smallAKSCluster("skutest").
+				With("sku_tier", tc.skuTier).
+				Options(t)
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
-			assert.Contains(t, planOutput, tc.skuTier)
+			helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "sku_tier", tc.skuTier)
 		})
 	}
 }
 
 // TestAKSClusterModuleNodePools tests node pool configurations
 func TestAKSClusterModuleNodePools(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/aks-cluster",
-		Vars: map[string]interface{}{
-			"resource_group_name": "rg-test-aks",
-			"location":            "brazilsouth",
-			"customer_name":       "nptest",
-			"environment":         "dev",
-			"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-			"system_node_pool": map[string]interface{}{
-				"name":       "system",
-				"vm_size":    "Standard_D4s_v5",
-				"node_count": 3,
-				"zones":      []string{"1", "2", "3"},
-			},
-			"user_node_pools": []map[string]interface{}{
-				{
-					"name":      "user1",
-					"vm_size":   "Standard_D8s_v5",
-					"min_count": 1,
-					"max_count": 10,
-					"zones":     []string{"1", "2", "3"},
-					"labels": map[string]string{
-						"workload": "general",
-					},
-					"taints": []string{},
+	terraformOptions := helpers.AKSCluster().
+		With("customer_name", "nptest").
+		With("vnet_subnet_id", helpers.SubnetID("snet-aks")).
+		With("additional_node_pools", map[string]interface{}{
+			"user1": map[string]interface{}{
+				"name":                "user1",
+				"node_count":          1,
+				"vm_size":             "Standard_D8s_v5",
+				"min_count":           1,
+				"max_count":           10,
+				"enable_auto_scaling": true,
+				"max_pods":            110,
+				"zones":               []string{"1", "2", "3"},
+				"node_labels": map[string]string{
+					"workload": "general",
 				},
-				{
-					"name":      "gpu",
-					"vm_size":   "Standard_NC6s_v3",
-					"min_count": 0,
-					"max_count": 5,
-					"zones":     []string{"1"},
-					"labels": map[string]string{
-						"workload":       "gpu",
-						"accelerator":   "nvidia",
-					},
-					"taints": []string{"gpu=true:NoSchedule"},
+				"node_taints": []string{},
+			},
+			"gpu": map[string]interface{}{
+				"name":                "gpu",
+				"node_count":          0,
+				"vm_size":             "Standard_NC6s_v3",
+				"min_count":           0,
+				"max_count":           5,
+				"enable_auto_scaling": true,
+				"max_pods":            30,
+				"zones":               []string{"1"},
+				"node_labels": map[string]string{
+					"workload":    "gpu",
+					"accelerator": "nvidia",
 				},
+				"node_taints": []string{"gpu=true:NoSchedule"},
 			},
-		},
-		NoColor: true,
-	})
+		}).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify node pools are planned
-	assert.Contains(t, planOutput, "azurerm_kubernetes_cluster.main")
-	assert.Contains(t, planOutput, "azurerm_kubernetes_cluster_node_pool.user")
+	helpers.AssertCreated(t, plan, "azurerm_kubernetes_cluster.main")
+	helpers.AssertCreated(t, plan, `azurerm_kubernetes_cluster_node_pool.user["user1"]`)
+	helpers.AssertCreated(t, plan, `azurerm_kubernetes_cluster_node_pool.user["gpu"]`)
 }
 
 // TestAKSClusterModuleAddons tests AKS addon configurations
 func TestAKSClusterModuleAddons(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
-		name   string
-		addons map[string]interface{}
+		name        string
+		azurePolicy bool
+		omsAgent    bool
 	}{
-		{
-			"all_addons_enabled",
-			map[string]interface{}{
-				"azure_policy":           true,
-				"azure_keyvault_secrets": true,
-				"oms_agent":              true,
-			},
-		},
-		{
-			"minimal_addons",
-			map[string]interface{}{
-				"azure_policy":           false,
-				"azure_keyvault_secrets": false,
-				"oms_agent":              false,
-			},
-		},
-		{
-			"security_addons_only",
-			map[string]interface{}{
-				"azure_policy":           true,
-				"azure_keyvault_secrets": true,
-				"oms_agent":              false,
-			},
-		},
+		{"all_addons_enabled", true, true},
+		{"minimal_addons", false, false},
+		{"security_addons_only", true, false},
 	}
 
 	for _, tc := range testCases {
@@ -241,33 +168,29 @@ func TestAKSClusterModuleAddons(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "addontest",
-					"environment":         "dev",
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-					"addons": tc.addons,
-				},
-				NoColor: true,
+			// Container Insights is enabled by passing a workspace ID
+			workspaceID := helpers.LogAnalyticsWorkspaceID("log-addontest")
+			fixture := smallAKSCluster("addontest").
+				With("enable_azure_policy", tc.azurePolicy)
+			if tc.omsAgent {
+				fixture = fixture.With("log_analytics_id", workspaceID)
 			}
 
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlanJSON(t, fixture.Options(t))
+
+			helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "azure_policy_enabled", tc.azurePolicy)
+			if tc.omsAgent {
+				helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oms_agent.0.log_analytics_workspace_id", workspaceID)
+			} else {
+				helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oms_agent", []interface{}{})
+			}
 		})
 	}
 }
 
 // TestAKSClusterModuleWorkloadIdentity tests workload identity configuration
 func TestAKSClusterModuleWorkloadIdentity(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -283,38 +206,21 @@ func TestAKSClusterModuleWorkloadIdentity(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks",
-					"location":            "brazilsouth",
-					"customer_name":       "witest",
-					"environment":         "dev",
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-					"workload_identity": tc.workloadIdentity,
-				},
-				NoColor: true,
-			}
+			terraformOptions := smallAKSCluster("witest").
+				With("enable_workload_identity", tc.workloadIdentity).
+				Options(t)
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
-			if tc.workloadIdentity {
-				assert.Contains(t, planOutput, "workload_identity_enabled")
-				assert.Contains(t, planOutput, "oidc_issuer_enabled")
-			}
+			helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "workload_identity_enabled", tc.workloadIdentity)
+			helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oidc_issuer_enabled", tc.workloadIdentity)
 		})
 	}
 }
 
 // TestAKSClusterModuleEnvironments tests different environment configurations
 func TestAKSClusterModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -324,66 +230,39 @@ func TestAKSClusterModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars: map[string]interface{}{
-					"resource_group_name": "rg-test-aks-" + env,
-					"location":            "brazilsouth",
-					"customer_name":       "envtest",
-					"environment":         env,
-					"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-					"system_node_pool": map[string]interface{}{
-						"name":       "system",
-						"vm_size":    "Standard_D2s_v5",
-						"node_count": 1,
-						"zones":      []string{"1"},
-					},
-				},
-				NoColor: true,
-			}
+			terraformOptions := smallAKSCluster("envtest").
+				With("environment", env).
+				With("resource_group_name", "rg-test-aks-"+env).
+				Options(t)
 
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 			// Verify environment is reflected in naming
-			assert.Contains(t, planOutput, "aks-envtest-"+env)
+			helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "name", "aks-envtest-"+env)
 		})
 	}
 }
 
 // TestAKSClusterModuleValidation tests input validation
 func TestAKSClusterModuleValidation(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
 		name        string
-		vars        map[string]interface{}
+		fixture     *helpers.Fixture
 		shouldError bool
 	}{
 		{
-			name: "valid_inputs",
-			vars: map[string]interface{}{
-				"resource_group_name": "rg-test-aks",
-				"location":            "brazilsouth",
-				"customer_name":       "valid",
-				"environment":         "dev",
-				"vnet_subnet_id":      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-aks",
-				"system_node_pool": map[string]interface{}{
-					"name":       "system",
-					"vm_size":    "Standard_D2s_v5",
-					"node_count": 1,
-					"zones":      []string{"1"},
-				},
-			},
+			name:        "valid_inputs",
+			fixture:     smallAKSCluster("valid"),
 			shouldError: false,
 		},
 		{
 			name: "missing_required_var",
-			vars: map[string]interface{}{
-				"location":      "brazilsouth",
-				"customer_name": "test",
-				"environment":   "dev",
-			},
+			fixture: helpers.AKSCluster().
+				With("customer_name", "test").
+				Without("resource_group_name"),
 			shouldError: true,
 		},
 	}
@@ -393,13 +272,9 @@ func TestAKSClusterModuleValidation(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/aks-cluster",
-				Vars:         tc.vars,
-				NoColor:      true,
-			}
+			terraformOptions := tc.fixture.UncheckedOptions(t)
 
-			terraform.Init(t, terraformOptions)
+			helpers.Init(t, terraformOptions)
 
 			if tc.shouldError {
 				_, err := terraform.PlanE(t, terraformOptions)
@@ -410,3 +285,16 @@ func TestAKSClusterModuleValidation(t *testing.T) {
 		})
 	}
 }
+
+// smallAKSCluster returns the AKS fixture with the single-node system pool
+// used by the table tests.
+func smallAKSCluster(customerName string) *helpers.Fixture {
+	return helpers.AKSCluster().
+		With("customer_name", customerName).
+		With("vnet_subnet_id", helpers.SubnetID("snet-aks")).
+		WithAttr("default_node_pool", "vm_size", "Standard_D2s_v5").
+		WithAttr("default_node_pool", "node_count", 1).
+		WithAttr("default_node_pool", "min_count", 1).
+		WithAttr("default_node_pool", "max_count", 3).
+		WithAttr("default_node_pool", "zones", []string{"1"})
+}
@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform ArgoCD module.
 //
-// Run with: go test -v -run TestArgoCD ./modules/
+// Run with: go test -v -tags=integration -run TestArgoCD ./modules/
 //
 // =============================================================================
 
@@ -13,45 +13,35 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestArgoCDModuleBasic tests basic ArgoCD configuration
 func TestArgoCDModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/argocd",
-		Vars: map[string]interface{}{
-			"customer_name": "testargocd",
-			"environment":   "dev",
-			"github_org":    "test-org",
-			"github_repo":   "test-repo",
-			"argocd_config": map[string]interface{}{
-				"namespace":   "argocd",
-				"ha_enabled":  false,
-				"server_host": "argocd.example.com",
-			},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ArgoCD().
+		With("customer_name", "testargocd").
+		With("github_org", "test-org").
+		With("domain_name", "example.com").
+		With("ha_enabled", false).
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify ArgoCD resources are planned
-	assert.Contains(t, planOutput, "helm_release.argocd")
+	helpers.AssertCreated(t, plan, "kubernetes_namespace.argocd")
+	helpers.AssertCreated(t, plan, "helm_release.argocd")
+	helpers.AssertAttribute(t, plan, "helm_release.argocd", "chart", "argo-cd")
 }
 
 // TestArgoCDModuleHAConfiguration tests high availability setup
 func TestArgoCDModuleHAConfiguration(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -67,58 +57,41 @@ func TestArgoCDModuleHAConfiguration(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/argocd",
-				Vars: map[string]interface{}{
-					"customer_name": "hatest",
-					"environment":   "prod",
-					"github_org":    "test-org",
-					"github_repo":   "test-repo",
-					"argocd_config": map[string]interface{}{
-						"namespace":   "argocd",
-						"ha_enabled":  tc.haEnabled,
-						"server_host": "argocd.example.com",
-					},
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.ArgoCD().
+				With("customer_name", "hatest").
+				With("environment", "prod").
+				With("github_org", "test-org").
+				With("domain_name", "example.com").
+				With("ha_enabled", tc.haEnabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestArgoCDModuleApplicationSets tests ApplicationSet configuration
 func TestArgoCDModuleApplicationSets(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/argocd",
-		Vars: map[string]interface{}{
-			"customer_name": "appsettest",
-			"environment":   "dev",
-			"github_org":    "test-org",
-			"github_repo":   "test-repo",
-			"argocd_config": map[string]interface{}{
-				"namespace":             "argocd",
-				"ha_enabled":            false,
-				"server_host":           "argocd.example.com",
-				"enable_applicationset": true,
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
-
-	// Verify ApplicationSet controller is planned
-	assert.Contains(t, planOutput, "argocd")
+	terraformOptions := helpers.ArgoCD().
+		With("customer_name", "appsettest").
+		With("github_org", "test-org").
+		With("domain_name", "example.com").
+		With("ha_enabled", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+	// The ApplicationSet controller ships with the chart and is always enabled
+	helpers.AssertCreated(t, plan, "helm_release.argocd")
+	helpers.AssertCreated(t, plan, "kubectl_manifest.platform_project")
 }
 
 // TestArgoCDModuleEnvironments tests different environments
 func TestArgoCDModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -128,26 +101,17 @@ func TestArgoCDModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/argocd",
-				Vars: map[string]interface{}{
-					"customer_name": "envtest",
-					"environment":   env,
-					"github_org":    "test-org",
-					"github_repo":   "test-repo",
-					"argocd_config": map[string]interface{}{
-						"namespace":   "argocd",
-						"ha_enabled":  env == "prod",
-						"server_host": "argocd-" + env + ".example.com",
-					},
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.ArgoCD().
+				With("customer_name", "envtest").
+				With("environment", env).
+				With("github_org", "test-org").
+				With("domain_name", env+".example.com").
+				With("ha_enabled", env == "prod").
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "kubernetes_namespace.argocd", "metadata.0.labels.agentic-devops-platform/environment", env)
 		})
 	}
 }
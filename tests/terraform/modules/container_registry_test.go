@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform container registry module.
 //
-// Run with: go test -v -run TestContainerRegistry ./modules/
+// Run with: go test -v -tags=integration -run TestContainerRegistry ./modules/
 //
 // =============================================================================
 
@@ -13,44 +13,31 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestContainerRegistryModuleBasic tests basic ACR configuration
 func TestContainerRegistryModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                   "testacr",
-			"environment":                     "dev",
-			"location":                        "brazilsouth",
-			"resource_group_name":             "rg-test-acr",
-			"sku":                             "Premium",
-			"subnet_id":                       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":             "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id":  "00000000-0000-0000-0000-000000000001",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ContainerRegistry().
+		With("customer_name", "testacr").
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify ACR is planned
-	assert.Contains(t, planOutput, "azurerm_container_registry.main")
+	helpers.AssertCreated(t, plan, "azurerm_container_registry.main")
+	helpers.AssertAttribute(t, plan, "azurerm_container_registry.main", "admin_enabled", false)
 }
 
 // TestContainerRegistryModuleSKUs tests different SKU configurations
 func TestContainerRegistryModuleSKUs(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -67,138 +54,91 @@ func TestContainerRegistryModuleSKUs(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/container-registry",
-				Vars: map[string]interface{}{
-					"customer_name":                  "skutest",
-					"environment":                    "dev",
-					"location":                       "brazilsouth",
-					"resource_group_name":            "rg-test-sku-" + tc.name,
-					"sku":                            tc.sku,
-					"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-					"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, "azurerm_container_registry.main")
+			terraformOptions := helpers.ContainerRegistry().
+				With("customer_name", "skutest").
+				With("resource_group_name", "rg-test-sku-"+tc.name).
+				With("sku", tc.sku).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertCreated(t, plan, "azurerm_container_registry.main")
+			helpers.AssertAttribute(t, plan, "azurerm_container_registry.main", "sku", tc.sku)
 		})
 	}
 }
 
 // TestContainerRegistryModuleNaming tests ACR naming convention
 func TestContainerRegistryModuleNaming(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "nametest",
-			"environment":                    "dev",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-naming",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ContainerRegistry().
+		With("customer_name", "nametest").
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// ACR names must be alphanumeric only (no hyphens)
-	assert.Contains(t, planOutput, "crnametestdev")
+	helpers.AssertAttribute(t, plan, "azurerm_container_registry.main", "name", "crnametestdev")
 }
 
 // TestContainerRegistryModuleGeoReplication tests geo-replication
 func TestContainerRegistryModuleGeoReplication(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "georep",
-			"environment":                    "prod",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-georep",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-			"geo_replication_locations":      []string{"eastus", "westeurope"},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ContainerRegistry().
+		With("customer_name", "georep").
+		With("environment", "prod").
+		With("geo_replication_locations", []string{"eastus", "westeurope"}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify geo-replication is planned for Premium SKU
-	assert.Contains(t, planOutput, "azurerm_container_registry_replication")
+	helpers.AssertCreated(t, plan, `azurerm_container_registry_replication.replicas["eastus"]`)
+	helpers.AssertCreated(t, plan, `azurerm_container_registry_replication.replicas["westeurope"]`)
+	helpers.AssertAttribute(t, plan, "azurerm_container_registry_replication.replicas", "zone_redundancy_enabled", true)
 }
 
 // TestContainerRegistryModulePrivateEndpoint tests private endpoint
 func TestContainerRegistryModulePrivateEndpoint(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "petest",
-			"environment":                    "prod",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-pe",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ContainerRegistry().
+		With("customer_name", "petest").
+		With("environment", "prod").
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify private endpoint is planned
-	assert.Contains(t, planOutput, "azurerm_private_endpoint.acr")
+	helpers.AssertCreated(t, plan, "azurerm_private_endpoint.acr")
+	helpers.AssertAttribute(t, plan, "azurerm_private_endpoint.acr", "private_service_connection.0.subresource_names", []string{"registry"})
 }
 
 // TestContainerRegistryModuleRBAC tests RBAC role assignments
 func TestContainerRegistryModuleRBAC(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/container-registry",
-		Vars: map[string]interface{}{
-			"customer_name":                  "rbactest",
-			"environment":                    "dev",
-			"location":                       "brazilsouth",
-			"resource_group_name":            "rg-test-rbac",
-			"sku":                            "Premium",
-			"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-			"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-			"github_actions_identity_ids":    []string{"00000000-0000-0000-0000-000000000002"},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ContainerRegistry().
+		With("customer_name", "rbactest").
+		With("github_actions_identity_ids", []string{"00000000-0000-0000-0000-000000000002"}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify role assignments are planned
-	assert.Contains(t, planOutput, "azurerm_role_assignment.aks_acr_pull")
-	assert.Contains(t, planOutput, "azurerm_role_assignment.github_actions_push")
+	helpers.AssertAttribute(t, plan, "azurerm_role_assignment.aks_acr_pull", "role_definition_name", "AcrPull")
+	helpers.AssertAttribute(t, plan, `azurerm_role_assignment.github_actions_push["00000000-0000-0000-0000-000000000002"]`, "role_definition_name", "AcrPush")
 }
 
 // TestContainerRegistryModuleWebhook tests optional webhook configuration
 func TestContainerRegistryModuleWebhook(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -216,30 +156,19 @@ func TestContainerRegistryModuleWebhook(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/container-registry",
-				Vars: map[string]interface{}{
-					"customer_name":                  "whtest",
-					"environment":                    "dev",
-					"location":                       "brazilsouth",
-					"resource_group_name":            "rg-test-wh",
-					"sku":                            "Premium",
-					"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-					"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-					"enable_webhook":                 tc.enableWebhook,
-					"webhook_service_uri":            tc.webhookURI,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.ContainerRegistry().
+				With("customer_name", "whtest").
+				With("enable_webhook", tc.enableWebhook).
+				With("webhook_service_uri", tc.webhookURI).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 			if tc.expectWebhook {
-				assert.Contains(t, planOutput, "azurerm_container_registry_webhook")
+				helpers.AssertCreated(t, plan, "azurerm_container_registry_webhook.image_push[0]")
+				helpers.AssertAttribute(t, plan, "azurerm_container_registry_webhook.image_push[0]", "service_uri", tc.webhookURI)
 			} else {
-				assert.NotContains(t, planOutput, "azurerm_container_registry_webhook.image_push[0]")
+				helpers.AssertNotPlanned(t, plan, "azurerm_container_registry_webhook.image_push")
 			}
 		})
 	}
@@ -247,6 +176,7 @@ func TestContainerRegistryModuleWebhook(t *testing.T) {
 
 // TestContainerRegistryModuleEnvironments tests different environments
 func TestContainerRegistryModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -256,25 +186,15 @@ func TestContainerRegistryModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/container-registry",
-				Vars: map[string]interface{}{
-					"customer_name":                  "envtest",
-					"environment":                    env,
-					"location":                       "brazilsouth",
-					"resource_group_name":            "rg-test-acr-" + env,
-					"sku":                            "Premium",
-					"subnet_id":                      "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_id":            "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/acr",
-					"aks_kubelet_identity_object_id": "00000000-0000-0000-0000-000000000001",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.ContainerRegistry().
+				With("customer_name", "envtest").
+				With("environment", env).
+				With("resource_group_name", "rg-test-acr-"+env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_container_registry.main", "name", "crenvtest"+env)
 		})
 	}
 }
@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform Cost Management module.
 //
-// Run with: go test -v -run TestCostManagement ./modules/
+// Run with: go test -v -tags=integration -run TestCostManagement ./modules/
 //
 // =============================================================================
 
@@ -13,42 +13,31 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestCostManagementModuleBasic tests basic cost management configuration
 func TestCostManagementModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/cost-management",
-		Vars: map[string]interface{}{
-			"customer_name":         "testcost",
-			"environment":           "dev",
-			"location":              "brazilsouth",
-			"resource_group_name":   "rg-test-cost",
-			"monthly_budget":        5000,
-			"alert_email_addresses": []string{"ops@example.com"},
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := costManagement("testcost").
+		With("monthly_budget", 5000).
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify budget is planned
-	assert.Contains(t, planOutput, "azurerm_consumption_budget")
+	helpers.AssertCreated(t, plan, "azurerm_consumption_budget_resource_group.main")
+	helpers.AssertAttribute(t, plan, "azurerm_consumption_budget_resource_group.main", "amount", 5000)
 }
 
 // TestCostManagementModuleBudgetThresholds tests different budget levels
 func TestCostManagementModuleBudgetThresholds(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	budgets := []int{1000, 5000, 10000, 50000}
@@ -58,56 +47,44 @@ func TestCostManagementModuleBudgetThresholds(t *testing.T) {
 		t.Run(string(rune(budget)), func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":         "budgettest",
-					"environment":           "prod",
-					"location":              "brazilsouth",
-					"resource_group_name":   "rg-test-cost-budget",
-					"monthly_budget":        budget,
-					"alert_email_addresses": []string{"ops@example.com", "finance@example.com"},
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := costManagement("budgettest").
+				With("environment", "prod").
+				With("resource_group_name", "rg-test-cost-budget").
+				With("monthly_budget", budget).
+				With("alert_email_addresses", []string{"ops@example.com", "finance@example.com"}).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestCostManagementModuleMultipleAlertRecipients tests multiple email alerts
 func TestCostManagementModuleMultipleAlertRecipients(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/cost-management",
-		Vars: map[string]interface{}{
-			"customer_name":       "alerttest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-cost-alerts",
-			"monthly_budget":      10000,
-			"alert_email_addresses": []string{
-				"ops@example.com",
-				"finance@example.com",
-				"manager@example.com",
-				"director@example.com",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := costManagement("alerttest").
+		With("environment", "prod").
+		With("resource_group_name", "rg-test-cost-alerts").
+		With("alert_email_addresses", []string{
+			"ops@example.com",
+			"finance@example.com",
+			"manager@example.com",
+			"director@example.com",
+		}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify action group is planned
-	assert.Contains(t, planOutput, "azurerm_monitor_action_group")
+	helpers.AssertCreated(t, plan, "azurerm_monitor_action_group.cost_alerts[0]")
+	helpers.AssertAttribute(t, plan, "azurerm_monitor_action_group.cost_alerts[0]", "email_receiver.3.email_address", "director@example.com")
 }
 
 // TestCostManagementModuleCostExport tests cost export configuration
 func TestCostManagementModuleCostExport(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	recurrences := []string{"Daily", "Weekly", "Monthly"}
@@ -117,29 +94,21 @@ func TestCostManagementModuleCostExport(t *testing.T) {
 		t.Run(recurrence, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":         "exporttest",
-					"environment":           "prod",
-					"location":              "brazilsouth",
-					"resource_group_name":   "rg-test-cost-export",
-					"monthly_budget":        10000,
-					"alert_email_addresses": []string{"ops@example.com"},
-					"enable_cost_export":    true,
-					"export_recurrence":     recurrence,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := costManagement("exporttest").
+				With("environment", "prod").
+				With("resource_group_name", "rg-test-cost-export").
+				With("enable_cost_export", true).
+				With("export_recurrence", recurrence).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestCostManagementModuleSubscriptionBudget tests subscription-level budget
 func TestCostManagementModuleSubscriptionBudget(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -155,57 +124,42 @@ func TestCostManagementModuleSubscriptionBudget(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":              "subtest",
-					"environment":                "prod",
-					"location":                   "brazilsouth",
-					"resource_group_name":        "rg-test-cost-sub",
-					"monthly_budget":             10000,
-					"alert_email_addresses":      []string{"ops@example.com"},
-					"create_subscription_budget": tc.enabled,
-					"subscription_monthly_budget": 50000,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := costManagement("subtest").
+				With("environment", "prod").
+				With("resource_group_name", "rg-test-cost-sub").
+				With("create_subscription_budget", tc.enabled).
+				With("subscription_monthly_budget", 50000).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestCostManagementModuleWebhooks tests webhook notification configuration
 func TestCostManagementModuleWebhooks(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/cost-management",
-		Vars: map[string]interface{}{
-			"customer_name":         "webhooktest",
-			"environment":           "prod",
-			"location":              "brazilsouth",
-			"resource_group_name":   "rg-test-cost-webhook",
-			"monthly_budget":        10000,
-			"alert_email_addresses": []string{"ops@example.com"},
-			"webhook_urls": []string{
-				"https://hooks.slack.com/services/xxx/yyy/zzz",
-				"https://teams.webhook.office.com/xxx",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := costManagement("webhooktest").
+		With("environment", "prod").
+		With("resource_group_name", "rg-test-cost-webhook").
+		With("webhook_urls", []string{
+			"https://hooks.slack.com/services/xxx/yyy/zzz",
+			"https://teams.webhook.office.com/xxx",
+		}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify webhook configuration
-	assert.Contains(t, planOutput, "cost")
+	helpers.AssertAttribute(t, plan, "azurerm_monitor_action_group.cost_alerts[0]", "webhook_receiver.0.service_uri", "https://hooks.slack.com/services/xxx/yyy/zzz")
+	helpers.AssertAttribute(t, plan, "azurerm_monitor_action_group.cost_alerts[0]", "webhook_receiver.1.service_uri", "https://teams.webhook.office.com/xxx")
 }
 
 // TestCostManagementModuleCustomAlerts tests custom cost alert rules
 func TestCostManagementModuleCustomAlerts(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -221,28 +175,20 @@ func TestCostManagementModuleCustomAlerts(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":              "customtest",
-					"environment":                "prod",
-					"location":                   "brazilsouth",
-					"resource_group_name":        "rg-test-cost-custom",
-					"monthly_budget":             10000,
-					"alert_email_addresses":      []string{"ops@example.com"},
-					"enable_custom_cost_alerts":  tc.enabled,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := costManagement("customtest").
+				With("environment", "prod").
+				With("resource_group_name", "rg-test-cost-custom").
+				With("enable_custom_cost_alerts", tc.enabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestCostManagementModuleEnvironments tests different environments
 func TestCostManagementModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -252,23 +198,24 @@ func TestCostManagementModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/cost-management",
-				Vars: map[string]interface{}{
-					"customer_name":         "envtest",
-					"environment":           env,
-					"location":              "brazilsouth",
-					"resource_group_name":   "rg-test-cost-" + env,
-					"monthly_budget":        5000,
-					"alert_email_addresses": []string{"ops@example.com"},
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := costManagement("envtest").
+				With("environment", env).
+				With("resource_group_name", "rg-test-cost-"+env).
+				With("monthly_budget", 5000).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_consumption_budget_resource_group.main", "name", "budget-envtest-"+env+"-rg")
 		})
 	}
 }
+
+// costManagement returns the cost management fixture used by these tests: a
+// 10000 monthly budget alerting a single ops mailbox.
+func costManagement(customerName string) *helpers.Fixture {
+	return helpers.CostManagement().
+		With("customer_name", customerName).
+		With("monthly_budget", 10000).
+		With("alert_email_addresses", []string{"ops@example.com"})
+}
@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform databases module.
 //
-// Run with: go test -v -run TestDatabases ./modules/
+// Run with: go test -v -tags=integration -run TestDatabases ./modules/
 //
 // =============================================================================
 
@@ -13,88 +13,59 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestDatabasesModuleBasic tests basic databases module configuration
 func TestDatabasesModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "testdb",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-databases",
-			"subnet_id":           "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.Network/virtualNetworks/vnet-test/subnets/snet-db",
-			"key_vault_id":        "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-test/providers/Microsoft.KeyVault/vaults/kv-test",
-			"private_dns_zone_ids": map[string]interface{}{
-				"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/privatelink.postgres.database.azure.com",
-				"redis":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/privatelink.redis.cache.windows.net",
-			},
-			"enable_postgresql": true,
-			"enable_redis":      true,
-			"enable_cosmosdb":   false,
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Databases().
+		With("customer_name", "testdb").
+		WithAttr("postgresql_config", "enabled", true).
+		WithAttr("redis_config", "enabled", true).
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify PostgreSQL and Redis are planned
-	assert.Contains(t, planOutput, "azurerm_postgresql_flexible_server")
-	assert.Contains(t, planOutput, "azurerm_redis_cache")
+	helpers.AssertCreated(t, plan, "azurerm_postgresql_flexible_server.main[0]")
+	helpers.AssertCreated(t, plan, "azurerm_redis_cache.main[0]")
+	helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main[0]", "public_network_access_enabled", false)
 }
 
 // TestDatabasesModulePostgreSQLConfig tests PostgreSQL configuration
 func TestDatabasesModulePostgreSQLConfig(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "psqltest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-psql",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"private_dns_zone_ids": map[string]interface{}{
-				"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/postgres",
-			},
-			"enable_postgresql": true,
-			"enable_redis":      false,
-			"postgresql_config": map[string]interface{}{
-				"sku_name":         "GP_Standard_D2s_v3",
-				"version":          "15",
-				"storage_mb":       32768,
-				"backup_retention": 7,
-				"geo_redundant":    false,
-				"high_availability": false,
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Databases().
+		With("customer_name", "psqltest").
+		WithAttr("redis_config", "enabled", false).
+		WithAttr("postgresql_config", "sku_name", "GP_Standard_D2s_v3").
+		WithAttr("postgresql_config", "version", "15").
+		WithAttr("postgresql_config", "storage_mb", 32768).
+		WithAttr("postgresql_config", "backup_retention_days", 7).
+		WithAttr("postgresql_config", "geo_redundant_backup", false).
+		WithAttr("postgresql_config", "high_availability", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify PostgreSQL configuration
-	assert.Contains(t, planOutput, "azurerm_postgresql_flexible_server")
-	assert.Contains(t, planOutput, "psql-")
+	helpers.AssertCreated(t, plan, "azurerm_postgresql_flexible_server.main[0]")
+	helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "name", "psql-psqltest-dev")
+	helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "sku_name", "GP_Standard_D2s_v3")
+	helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "version", "15")
 }
 
 // TestDatabasesModuleRedisConfig tests Redis configuration
 func TestDatabasesModuleRedisConfig(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -113,103 +84,69 @@ func TestDatabasesModuleRedisConfig(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/databases",
-				Vars: map[string]interface{}{
-					"customer_name":       "redistest",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-redis",
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"private_dns_zone_ids": map[string]interface{}{
-						"redis": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/redis",
-					},
-					"enable_postgresql": false,
-					"enable_redis":      true,
-					"redis_config": map[string]interface{}{
-						"sku_name": tc.sku,
-						"family":   tc.family,
-						"capacity": tc.capacity,
-					},
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, "azurerm_redis_cache")
+			terraformOptions := helpers.Databases().
+				With("customer_name", "redistest").
+				WithAttr("postgresql_config", "enabled", false).
+				WithAttr("redis_config", "enabled", true).
+				WithAttr("redis_config", "sku_name", tc.sku).
+				WithAttr("redis_config", "family", tc.family).
+				WithAttr("redis_config", "capacity", tc.capacity).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertCreated(t, plan, "azurerm_redis_cache.main[0]")
+			helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main[0]", "sku_name", tc.sku)
+			helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main[0]", "family", tc.family)
+			helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main[0]", "capacity", tc.capacity)
 		})
 	}
 }
 
-// TestDatabasesModuleCosmosDB tests Cosmos DB configuration
+// TestDatabasesModuleCosmosDB tests that Cosmos DB is not provisioned by this
+// module; the naming module reserves the name, but no module manages the account
 func TestDatabasesModuleCosmosDB(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "cosmostest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-cosmos",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"private_dns_zone_ids": map[string]interface{}{
-				"cosmosdb": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/cosmos",
-			},
-			"enable_postgresql": false,
-			"enable_redis":      false,
-			"enable_cosmosdb":   true,
-			"cosmosdb_config": map[string]interface{}{
-				"offer_type":  "Standard",
-				"kind":        "MongoDB",
-				"consistency": "Session",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
-
-	// Verify Cosmos DB is planned when enabled
-	assert.Contains(t, planOutput, "azurerm_cosmosdb_account")
+	terraformOptions := helpers.Databases().
+		With("customer_name", "cosmostest").
+		WithAttr("postgresql_config", "enabled", false).
+		WithAttr("redis_config", "enabled", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+	// With both engines disabled the module plans no database servers
+	helpers.AssertActionCount(t, plan, "azurerm_cosmosdb_account", helpers.ActionCreate, 0)
+	helpers.AssertNotPlanned(t, plan, "azurerm_postgresql_flexible_server.main")
+	helpers.AssertNotPlanned(t, plan, "azurerm_redis_cache.main")
 }
 
 // TestDatabasesModulePrivateEndpoints tests private endpoint creation
 func TestDatabasesModulePrivateEndpoints(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/databases",
-		Vars: map[string]interface{}{
-			"customer_name":       "petest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-pe",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"private_dns_zone_ids": map[string]interface{}{
-				"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/postgres",
-			},
-			"enable_postgresql": true,
-			"enable_redis":      false,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
-
-	// Verify private endpoints are planned
-	assert.Contains(t, planOutput, "azurerm_private_endpoint")
+	terraformOptions := helpers.Databases().
+		With("customer_name", "petest").
+		With("environment", "prod").
+		WithAttr("postgresql_config", "enabled", true).
+		WithAttr("redis_config", "enabled", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+	// Verify private access is planned: PostgreSQL is injected into the
+	// delegated subnet, Redis is reached through a private endpoint
+	helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "delegated_subnet_id", helpers.SubnetID("snet-private-endpoints"))
+	helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "private_dns_zone_id", helpers.PrivateDNSZoneID(helpers.PrivateDNSZoneNames["postgres"]))
+	helpers.AssertActionCount(t, plan, "azurerm_private_endpoint", helpers.ActionCreate, 1)
 }
 
 // TestDatabasesModuleEnvironments tests different environment configurations
 func TestDatabasesModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -219,28 +156,17 @@ func TestDatabasesModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/databases",
-				Vars: map[string]interface{}{
-					"customer_name":       "dbenv",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-db-" + env,
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"private_dns_zone_ids": map[string]interface{}{
-						"postgres": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/postgres",
-					},
-					"enable_postgresql": true,
-					"enable_redis":      false,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.Databases().
+				With("customer_name", "dbenv").
+				With("environment", env).
+				With("resource_group_name", "rg-test-db-"+env).
+				WithAttr("postgresql_config", "enabled", true).
+				WithAttr("redis_config", "enabled", false).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "name", "psql-dbenv-"+env)
 		})
 	}
 }
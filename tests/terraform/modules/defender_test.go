@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform Microsoft Defender module.
 //
-// Run with: go test -v -run TestDefender ./modules/
+// Run with: go test -v -tags=integration -run TestDefender ./modules/
 //
 // =============================================================================
 
@@ -13,41 +13,31 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestDefenderModuleBasic tests basic Defender configuration
 func TestDefenderModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":            "00000000-0000-0000-0000-000000000000",
-			"customer_name":              "testdef",
-			"environment":                "dev",
-			"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":     "security@example.com",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Defender().
+		With("customer_name", "testdef").
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Defender for Cloud is planned
-	assert.Contains(t, planOutput, "azurerm_security_center")
+	helpers.AssertCreated(t, plan, "azurerm_security_center_contact.main")
+	helpers.AssertAttribute(t, plan, "azurerm_security_center_subscription_pricing.containers", "tier", "Standard")
 }
 
 // TestDefenderModuleSizingProfiles tests different sizing profiles
 func TestDefenderModuleSizingProfiles(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	profiles := []string{"small", "medium", "large", "xlarge"}
@@ -57,78 +47,61 @@ func TestDefenderModuleSizingProfiles(t *testing.T) {
 		t.Run(profile, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/defender",
-				Vars: map[string]interface{}{
-					"subscription_id":            "00000000-0000-0000-0000-000000000000",
-					"customer_name":              "sizetest",
-					"environment":                "prod",
-					"sizing_profile":             profile,
-					"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-					"security_contact_email":     "security@example.com",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Defender().
+				With("customer_name", "sizetest").
+				With("environment", "prod").
+				With("sizing_profile", profile).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestDefenderModuleComplianceStandards tests regulatory compliance configuration
 func TestDefenderModuleComplianceStandards(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":                  "00000000-0000-0000-0000-000000000000",
-			"customer_name":                    "comptest",
-			"environment":                      "prod",
-			"log_analytics_workspace_id":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":           "security@example.com",
-			"regulatory_compliance_standards":  []string{"Azure-CIS-1.4.0", "SOC-2", "ISO-27001"},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Defender().
+		With("customer_name", "comptest").
+		With("environment", "prod").
+		With("regulatory_compliance_standards", []string{"Azure-CIS-1.4.0", "SOC-2", "ISO-27001"}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify compliance standards are configured
-	assert.Contains(t, planOutput, "security")
+	helpers.AssertCreated(t, plan, `azapi_resource.regulatory_compliance["Azure-CIS-1.4.0"]`)
+	helpers.AssertCreated(t, plan, `azapi_resource.regulatory_compliance["SOC-2"]`)
+	helpers.AssertCreated(t, plan, `azapi_resource.regulatory_compliance["ISO-27001"]`)
+	helpers.AssertActionCount(t, plan, "azapi_resource", helpers.ActionCreate, 3)
 }
 
 // TestDefenderModuleAKSIntegration tests Defender for Containers with AKS
 func TestDefenderModuleAKSIntegration(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":            "00000000-0000-0000-0000-000000000000",
-			"customer_name":              "aksdeftest",
-			"environment":                "prod",
-			"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":     "security@example.com",
-			"aks_cluster_ids": []string{
-				"/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks1",
-				"/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks2",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Defender().
+		With("customer_name", "aksdeftest").
+		With("environment", "prod").
+		With("aks_cluster_ids", []string{
+			helpers.KubernetesClusterID("aks1"),
+			helpers.KubernetesClusterID("aks2"),
+		}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify AKS integration is planned
-	assert.Contains(t, planOutput, "defender")
+	helpers.AssertActionCount(t, plan, "azapi_update_resource", helpers.ActionCreate, 2)
+	helpers.AssertAttribute(t, plan, "azapi_update_resource.defender_for_aks", "type", "Microsoft.ContainerService/managedClusters@2023-08-01")
 }
 
 // TestDefenderModuleJITAccess tests Just-In-Time access configuration
 func TestDefenderModuleJITAccess(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -144,55 +117,41 @@ func TestDefenderModuleJITAccess(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/defender",
-				Vars: map[string]interface{}{
-					"subscription_id":            "00000000-0000-0000-0000-000000000000",
-					"customer_name":              "jittest",
-					"environment":                "prod",
-					"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-					"security_contact_email":     "security@example.com",
-					"enable_jit_access":          tc.jitEnabled,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Defender().
+				With("customer_name", "jittest").
+				With("environment", "prod").
+				With("enable_jit_access", tc.jitEnabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestDefenderModuleAutoProvisioning tests auto-provisioning settings
 func TestDefenderModuleAutoProvisioning(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/defender",
-		Vars: map[string]interface{}{
-			"subscription_id":            "00000000-0000-0000-0000-000000000000",
-			"customer_name":              "autoprovtest",
-			"environment":                "prod",
-			"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-			"security_contact_email":     "security@example.com",
-			"auto_provisioning_settings": map[string]interface{}{
-				"log_analytics_agent":      true,
-				"vulnerability_assessment": true,
-				"defender_for_containers":  true,
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Defender().
+		With("customer_name", "autoprovtest").
+		With("environment", "prod").
+		With("auto_provisioning_settings", map[string]interface{}{
+			"log_analytics_agent":      true,
+			"vulnerability_assessment": true,
+			"defender_for_containers":  true,
+		}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify auto-provisioning is configured
-	assert.Contains(t, planOutput, "security")
+	helpers.AssertAttribute(t, plan, "azurerm_security_center_auto_provisioning.log_analytics", "auto_provision", "On")
 }
 
 // TestDefenderModuleEnvironments tests different environments
 func TestDefenderModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -202,22 +161,14 @@ func TestDefenderModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/defender",
-				Vars: map[string]interface{}{
-					"subscription_id":            "00000000-0000-0000-0000-000000000000",
-					"customer_name":              "envtest",
-					"environment":                env,
-					"log_analytics_workspace_id": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law",
-					"security_contact_email":     "security@example.com",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.Defender().
+				With("customer_name", "envtest").
+				With("environment", env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_security_center_automation.export_to_log_analytics", "resource_group_name", "rg-envtest-"+env+"-security")
 		})
 	}
 }
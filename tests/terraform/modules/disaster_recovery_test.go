@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform Disaster Recovery module.
 //
-// Run with: go test -v -run TestDisasterRecovery ./modules/
+// Run with: go test -v -tags=integration -run TestDisasterRecovery ./modules/
 //
 // =============================================================================
 
@@ -13,41 +13,33 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestDisasterRecoveryModuleBasic tests basic DR configuration
 func TestDisasterRecoveryModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/disaster-recovery",
-		Vars: map[string]interface{}{
-			"customer_name":               "testdr",
-			"environment":                 "dev",
-			"primary_location":            "brazilsouth",
-			"primary_region_short":        "brz",
-			"primary_resource_group_name": "rg-test-dr-primary",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.DisasterRecovery().
+		With("customer_name", "testdr").
+		With("primary_resource_group_name", "rg-test-dr-primary").
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Recovery Services Vault is planned
-	assert.Contains(t, planOutput, "azurerm_recovery_services_vault")
+	helpers.AssertCreated(t, plan, "azurerm_recovery_services_vault.main")
+	helpers.AssertAttribute(t, plan, "azurerm_recovery_services_vault.main", "name", "rsv-testdr-dev-brs")
+	helpers.AssertAttribute(t, plan, "azurerm_recovery_services_vault.main", "soft_delete_enabled", true)
 }
 
 // TestDisasterRecoveryModuleRPORTO tests RPO/RTO configuration
 func TestDisasterRecoveryModuleRPORTO(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -65,56 +57,49 @@ func TestDisasterRecoveryModuleRPORTO(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "rpotest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-rpo",
-					"recovery_point_objective":    tc.rpo,
-					"recovery_time_objective":     tc.rto,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.DisasterRecovery().
+				With("customer_name", "rpotest").
+				With("environment", "prod").
+				With("primary_resource_group_name", "rg-test-dr-rpo").
+				With("recovery_point_objective", tc.rpo).
+				With("recovery_time_objective", tc.rto).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestDisasterRecoveryModuleRetention tests backup retention configuration
 func TestDisasterRecoveryModuleRetention(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/disaster-recovery",
-		Vars: map[string]interface{}{
-			"customer_name":               "rettest",
-			"environment":                 "prod",
-			"primary_location":            "brazilsouth",
-			"primary_region_short":        "brz",
-			"primary_resource_group_name": "rg-test-dr-retention",
-			"retention_daily_count":       14,
-			"retention_weekly_count":      8,
-			"retention_monthly_count":     24,
-			"retention_yearly_count":      5,
-			"instant_restore_days":        3,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.DisasterRecovery().
+		With("customer_name", "rettest").
+		With("environment", "prod").
+		With("primary_resource_group_name", "rg-test-dr-retention").
+		With("retention_daily_count", 14).
+		With("retention_weekly_count", 8).
+		With("retention_monthly_count", 24).
+		With("retention_yearly_count", 5).
+		With("instant_restore_days", 3).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify backup policy is planned
-	assert.Contains(t, planOutput, "azurerm_backup_policy")
+	helpers.AssertCreated(t, plan, "azurerm_backup_policy_vm.daily")
+	helpers.AssertAttribute(t, plan, "azurerm_backup_policy_vm.daily", "retention_daily.0.count", 14)
+	helpers.AssertAttribute(t, plan, "azurerm_backup_policy_vm.daily", "retention_weekly.0.count", 8)
+	helpers.AssertAttribute(t, plan, "azurerm_backup_policy_vm.daily", "retention_monthly.0.count", 24)
+	helpers.AssertAttribute(t, plan, "azurerm_backup_policy_vm.daily", "retention_yearly.0.count", 5)
+	helpers.AssertAttribute(t, plan, "azurerm_backup_policy_vm.daily", "instant_restore_retention_days", 3)
 }
 
 // TestDisasterRecoveryModuleStorageRedundancy tests storage redundancy options
 func TestDisasterRecoveryModuleStorageRedundancy(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	redundancyOptions := []string{"GeoRedundant", "LocallyRedundant", "ZoneRedundant"}
@@ -124,27 +109,21 @@ func TestDisasterRecoveryModuleStorageRedundancy(t *testing.T) {
 		t.Run(redundancy, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "redtest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-redundancy",
-					"storage_redundancy":          redundancy,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.DisasterRecovery().
+				With("customer_name", "redtest").
+				With("environment", "prod").
+				With("primary_resource_group_name", "rg-test-dr-redundancy").
+				With("storage_redundancy", redundancy).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestDisasterRecoveryModuleSiteRecovery tests Azure Site Recovery configuration
 func TestDisasterRecoveryModuleSiteRecovery(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -160,56 +139,45 @@ func TestDisasterRecoveryModuleSiteRecovery(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "asrtest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-asr",
-					"enable_site_recovery":        tc.enabled,
-					"dr_location":                 "eastus2",
-					"dr_region_short":             "eu2",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.DisasterRecovery().
+				With("customer_name", "asrtest").
+				With("environment", "prod").
+				With("primary_resource_group_name", "rg-test-dr-asr").
+				With("enable_site_recovery", tc.enabled).
+				With("dr_location", "eastus2").
+				With("dr_region_short", "eu2").
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestDisasterRecoveryModuleCrossRegion tests cross-region restore
 func TestDisasterRecoveryModuleCrossRegion(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/disaster-recovery",
-		Vars: map[string]interface{}{
-			"customer_name":               "crosstest",
-			"environment":                 "prod",
-			"primary_location":            "brazilsouth",
-			"primary_region_short":        "brz",
-			"primary_resource_group_name": "rg-test-dr-cross",
-			"dr_location":                 "eastus2",
-			"dr_region_short":             "eu2",
-			"enable_cross_region_restore": true,
-			"storage_redundancy":          "GeoRedundant",
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.DisasterRecovery().
+		With("customer_name", "crosstest").
+		With("environment", "prod").
+		With("primary_resource_group_name", "rg-test-dr-cross").
+		With("dr_location", "eastus2").
+		With("dr_region_short", "eu2").
+		With("enable_cross_region_restore", true).
+		With("storage_redundancy", "GeoRedundant").
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify cross-region configuration
-	assert.Contains(t, planOutput, "recovery")
+	helpers.AssertAttribute(t, plan, "azurerm_recovery_services_vault.main", "cross_region_restore_enabled", true)
+	helpers.AssertAttribute(t, plan, "azurerm_recovery_services_vault.main", "storage_mode_type", "GeoRedundant")
 }
 
 // TestDisasterRecoveryModuleImmutability tests immutability configuration
 func TestDisasterRecoveryModuleImmutability(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -225,27 +193,21 @@ func TestDisasterRecoveryModuleImmutability(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "immtest",
-					"environment":                 "prod",
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-immutable",
-					"enable_immutability":         tc.enabled,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.DisasterRecovery().
+				With("customer_name", "immtest").
+				With("environment", "prod").
+				With("primary_resource_group_name", "rg-test-dr-immutable").
+				With("enable_immutability", tc.enabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestDisasterRecoveryModuleEnvironments tests different environments
 func TestDisasterRecoveryModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -255,22 +217,15 @@ func TestDisasterRecoveryModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/disaster-recovery",
-				Vars: map[string]interface{}{
-					"customer_name":               "envtest",
-					"environment":                 env,
-					"primary_location":            "brazilsouth",
-					"primary_region_short":        "brz",
-					"primary_resource_group_name": "rg-test-dr-" + env,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.DisasterRecovery().
+				With("customer_name", "envtest").
+				With("environment", env).
+				With("primary_resource_group_name", "rg-test-dr-"+env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_recovery_services_vault.main", "name", "rsv-envtest-"+env+"-brs")
 		})
 	}
 }
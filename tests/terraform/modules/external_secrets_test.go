@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform External Secrets Operator module.
 //
-// Run with: go test -v -run TestExternalSecrets ./modules/
+// Run with: go test -v -tags=integration -run TestExternalSecrets ./modules/
 //
 // =============================================================================
 
@@ -13,48 +13,37 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestExternalSecretsModuleBasic tests basic ESO configuration
 func TestExternalSecretsModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/external-secrets",
-		Vars: map[string]interface{}{
-			"customer_name":       "testeso",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-eso",
-			"aks_cluster_name":    "aks-test-eso",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"key_vault_uri":       "https://kv-test.vault.azure.net/",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.ExternalSecrets().
+		With("customer_name", "testeso").
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
 
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify ESO Helm release is planned
-	assert.Contains(t, planOutput, "helm_release")
+	helpers.AssertCreated(t, plan, "helm_release.external_secrets")
+	helpers.AssertAttribute(t, plan, "helm_release.external_secrets", "chart", "external-secrets")
+	helpers.AssertCreated(t, plan, "azurerm_federated_identity_credential.eso")
 }
 
 // TestExternalSecretsModuleRBAC tests RBAC vs Access Policy configuration
 func TestExternalSecretsModuleRBAC(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
-		name     string
-		useRBAC  bool
+		name    string
+		useRBAC bool
 	}{
 		{"rbac_enabled", true},
 		{"access_policy", false},
@@ -65,29 +54,20 @@ func TestExternalSecretsModuleRBAC(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":       "rbactest",
-					"environment":         "prod",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-eso-rbac",
-					"aks_cluster_name":    "aks-test-eso-rbac",
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":       "https://kv-test.vault.azure.net/",
-					"use_key_vault_rbac":  tc.useRBAC,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.ExternalSecrets().
+				With("customer_name", "rbactest").
+				With("environment", "prod").
+				With("use_key_vault_rbac", tc.useRBAC).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestExternalSecretsModuleMetrics tests Prometheus metrics configuration
 func TestExternalSecretsModuleMetrics(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -103,29 +83,20 @@ func TestExternalSecretsModuleMetrics(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":              "metricstest",
-					"environment":                "prod",
-					"location":                   "brazilsouth",
-					"resource_group_name":        "rg-test-eso-metrics",
-					"aks_cluster_name":           "aks-test-eso-metrics",
-					"key_vault_id":               "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":              "https://kv-test.vault.azure.net/",
-					"enable_prometheus_metrics":  tc.enabled,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.ExternalSecrets().
+				With("customer_name", "metricstest").
+				With("environment", "prod").
+				With("enable_prometheus_metrics", tc.enabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestExternalSecretsModulePushSecrets tests PushSecret configuration
 func TestExternalSecretsModulePushSecrets(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -141,91 +112,63 @@ func TestExternalSecretsModulePushSecrets(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":        "pushtest",
-					"environment":          "prod",
-					"location":             "brazilsouth",
-					"resource_group_name":  "rg-test-eso-push",
-					"aks_cluster_name":     "aks-test-eso-push",
-					"key_vault_id":         "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":        "https://kv-test.vault.azure.net/",
-					"enable_push_secrets":  tc.enabled,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.ExternalSecrets().
+				With("customer_name", "pushtest").
+				With("environment", "prod").
+				With("enable_push_secrets", tc.enabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestExternalSecretsModuleExampleSecret tests example secret creation
 func TestExternalSecretsModuleExampleSecret(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/external-secrets",
-		Vars: map[string]interface{}{
-			"customer_name":         "exampletest",
-			"environment":           "dev",
-			"location":              "brazilsouth",
-			"resource_group_name":   "rg-test-eso-example",
-			"aks_cluster_name":      "aks-test-eso-example",
-			"key_vault_id":          "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"key_vault_uri":         "https://kv-test.vault.azure.net/",
-			"create_example_secret": true,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.ExternalSecrets().
+		With("customer_name", "exampletest").
+		With("create_example_secret", true).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify example secret is planned
-	assert.Contains(t, planOutput, "external")
+	helpers.AssertCreated(t, plan, "kubernetes_manifest.example_external_secret[0]")
 }
 
 // TestExternalSecretsModuleNodeSelector tests node selector configuration
 func TestExternalSecretsModuleNodeSelector(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/external-secrets",
-		Vars: map[string]interface{}{
-			"customer_name":       "nodetest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-eso-node",
-			"aks_cluster_name":    "aks-test-eso-node",
-			"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-			"key_vault_uri":       "https://kv-test.vault.azure.net/",
-			"node_selector": map[string]interface{}{
-				"workload-type": "platform",
-			},
-			"tolerations": []map[string]interface{}{
-				{
-					"key":      "platform",
-					"operator": "Equal",
-					"value":    "true",
-					"effect":   "NoSchedule",
-				},
+	terraformOptions := helpers.ExternalSecrets().
+		With("customer_name", "nodetest").
+		With("environment", "prod").
+		With("node_selector", map[string]interface{}{
+			"workload-type": "platform",
+		}).
+		With("tolerations", []map[string]interface{}{
+			{
+				"key":      "platform",
+				"operator": "Equal",
+				"value":    "true",
+				"effect":   "NoSchedule",
 			},
-		},
-		NoColor: true,
-	})
+		}).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify node configuration
-	assert.Contains(t, planOutput, "external")
+	helpers.AssertCreated(t, plan, "helm_release.external_secrets")
 }
 
 // TestExternalSecretsModuleEnvironments tests different environments
 func TestExternalSecretsModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -235,24 +178,16 @@ func TestExternalSecretsModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/external-secrets",
-				Vars: map[string]interface{}{
-					"customer_name":       "envtest",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-eso-" + env,
-					"aks_cluster_name":    "aks-test-eso-" + env,
-					"key_vault_id":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv",
-					"key_vault_uri":       "https://kv-test.vault.azure.net/",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.ExternalSecrets().
+				With("customer_name", "envtest").
+				With("environment", env).
+				With("resource_group_name", "rg-test-eso-"+env).
+				With("aks_cluster_name", "aks-test-eso-"+env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_user_assigned_identity.eso", "name", "id-envtest-"+env+"-eso")
 		})
 	}
 }
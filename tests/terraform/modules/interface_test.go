@@ -0,0 +1,37 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - MODULE INTERFACE TESTS
+// =============================================================================
+//
+// Compares the variables and outputs of every module in the working tree with
+// TERRATEST_UPGRADE_REF (default HEAD) and fails on changes that break
+// callers: a removed variable or output, a new required variable, a narrowed
+// type or an output that became sensitive. The log lists the suggested
+// version bump of every module; cmd/module-interface prints the same report.
+// Only git is needed.
+//
+// Run with: TERRATEST_UPGRADE_REF=origin/main go test -v -run TestModuleInterfaces ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+// TestModuleInterfaces tests that no module interface changed incompatibly since the upgrade ref
+func TestModuleInterfaces(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	ref := helpers.UpgradeRef()
+	reports, err := helpers.CompareRefsE(ref, "")
+	require.NoError(t, err)
+
+	t.Logf("suggested module versions since %s:\n%s", ref, helpers.FormatModuleReports(reports))
+	helpers.AssertCompatibleInterfaces(t, reports, ref)
+}
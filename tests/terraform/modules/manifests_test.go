@@ -0,0 +1,34 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - KUBERNETES MANIFEST POLICY TESTS
+// =============================================================================
+//
+// Evaluates the Gatekeeper constraints in policies/kubernetes against every
+// Kubernetes manifest in the repository, including golden-path skeletons with
+// their placeholders rendered, the way the admission webhook would on create.
+// Needs no cluster, no Terraform and no credentials.
+//
+// Run with: go test -v -run TestKubernetesManifests ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+// TestKubernetesManifestsComply tests every manifest in the repo against the Gatekeeper constraints
+func TestKubernetesManifestsComply(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierValidate)
+	t.Parallel()
+
+	manifests, err := helpers.FindManifestsE(helpers.RepoRoot(t))
+	require.NoError(t, err)
+	require.NotEmpty(t, manifests, "no Kubernetes manifests found")
+
+	helpers.AssertManifestsComply(t, helpers.KubernetesPolicies(t), manifests)
+}
@@ -0,0 +1,247 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - NAMING MODULE FUZZ TESTS
+// =============================================================================
+//
+// Property-based tests for the naming module. The fuzz target evaluates the
+// module in process for generated project_name, environment, location,
+// instance and org_code values and checks every output against the Azure
+// naming rules. Inputs the module accepts must produce legal, deterministic
+// names; inputs its validation blocks reject must produce an illegal name,
+// unless they break one of the conventions below. Without
+// -fuzz only the seed corpus runs. TestNamingModuleFuzzSeeds plans the seed
+// corpus with terraform, so the evaluator is checked against the real thing.
+//
+// Run with: go test -v -run 'FuzzNamingModule|TestNamingModuleFuzzSeeds' ./modules/
+// Fuzz with: go test -run '^$' -fuzz FuzzNamingModule -fuzztime 5m ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+// namingSeeds are the seed corpus: project_name, environment, location,
+// instance and org_code.
+var namingSeeds = [][5]string{
+	{"contoso", "dev", "brazilsouth", "001", ""},
+	{"abcdefghij", "prd", "southcentralus", "999", "abcd"},
+	{"ab", "sbx", "a", "000", "ms"},
+	{"x1", "tst", "eastus2", "042", "12"},
+	{"1contoso", "dev", "eastus", "001", ""},
+	{"contoso", "qa", "eastus", "001", ""},
+	{"contoso", "dev", "East US", "001", ""},
+	{"contoso", "dev", "eastus", "1", ""},
+	{"contoso", "dev", "eastus", "001", "a_b"},
+	{"contoso", "dev", "", "001", ""},
+}
+
+// namingEnvironments are the environment short names of the platform.
+var namingEnvironments = map[string]bool{"dev": true, "stg": true, "prd": true, "sbx": true, "tst": true}
+
+// instancePattern is the format of the instance variable.
+var instancePattern = regexp.MustCompile(`^[0-9]{3}$`)
+
+// namingConventions are validations that enforce a platform convention
+// rather than legal names: a value that breaks one may still produce legal
+// names, so its rejection is not checked against the outputs. A rejected
+// value that follows every convention must produce an illegal name.
+var namingConventions = []struct {
+	variable string
+	reason   string
+	breaks   func(value string) bool
+}{
+	{"environment", "it is one of the platform's environments",
+		func(value string) bool { return !namingEnvironments[value] }},
+	{"instance", "it is a zero-padded three-digit number, so instances sort by name",
+		func(value string) bool { return !instancePattern.MatchString(value) }},
+	{"project_name", "it is 2-10 characters, so length-limited names keep the rest of the prefix",
+		func(value string) bool { return len(value) < 2 || len(value) > 10 }},
+	{"org_code", "it is 2-4 characters", func(value string) bool { return len(value) == 1 || len(value) > 4 }},
+	{"location", "it starts with a letter, as Azure region names do",
+		func(value string) bool { return value != "" && (value[0] < 'a' || value[0] > 'z') }},
+}
+
+// breaksNamingConvention reports whether the value of a variable breaks one
+// of namingConventions.
+func breaksNamingConvention(variable, value string) bool {
+	for _, convention := range namingConventions {
+		if convention.variable == variable && convention.breaks(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// namingFixture returns the naming fixture for the fuzzed inputs.
+func namingFixture(projectName, environment, location, instance, orgCode string) *helpers.Fixture {
+	return helpers.Naming().
+		With("project_name", projectName).
+		With("environment", environment).
+		With("location", location).
+		With("instance", instance).
+		With("org_code", orgCode)
+}
+
+// FuzzNamingModule tests naming output invariants for generated naming module inputs
+func FuzzNamingModule(f *testing.F) {
+	helpers.RequireTier(f, helpers.TierValidate)
+
+	for _, seed := range namingSeeds {
+		f.Add(seed[0], seed[1], seed[2], seed[3], seed[4])
+	}
+
+	f.Fuzz(func(t *testing.T, projectName, environment, location, instance, orgCode string) {
+		for _, s := range []string{projectName, environment, location, instance, orgCode} {
+			if !utf8.ValidString(s) || strings.ContainsRune(s, 0) {
+				t.Skipf("inputs that cannot be passed to Terraform as an argument: %q", s)
+			}
+		}
+		fixture := namingFixture(projectName, environment, location, instance, orgCode)
+
+		evaluation, err := fixture.EvaluateE()
+		if err != nil {
+			require.Contains(t, err.Error(), "invalid value for variable", "the evaluation failed for a reason other than input validation")
+			assertNamingRejection(t, fixture)
+			return
+		}
+
+		outputs := evaluation.OutputValues()
+		assert.Empty(t, namingViolations(fixture.Vars, outputs), "the module accepted %v, which produces illegal names", fixture.Vars)
+
+		again, err := fixture.EvaluateE()
+		require.NoError(t, err)
+		assert.Equal(t, outputs, again.OutputValues(), "outputs differ between two evaluations of %v", fixture.Vars)
+	})
+}
+
+// TestNamingModuleFuzzSeeds tests that terraform and the evaluator agree on the fuzz seed corpus
+func TestNamingModuleFuzzSeeds(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	options := helpers.Naming().UncheckedOptions(t)
+	options.Logger = logger.Discard
+	helpers.Init(t, options)
+
+	for _, seed := range namingSeeds {
+		fixture := namingFixture(seed[0], seed[1], seed[2], seed[3], seed[4])
+
+		planned, planErr := planNamingOutputs(t, options, fixture.Vars)
+		evaluation, err := fixture.EvaluateE()
+		if planErr != nil {
+			assert.Contains(t, planErr.Error(), "Invalid value for variable", "%v", fixture.Vars)
+			assert.Error(t, err, "terraform rejects %v, but the evaluator accepts it", fixture.Vars)
+			continue
+		}
+		if assert.NoError(t, err, "terraform accepts %v, but the evaluator rejects it", fixture.Vars) {
+			assert.Equal(t, planned, evaluation.OutputValues(), "%v", fixture.Vars)
+		}
+	}
+}
+
+// assertNamingRejection checks that inputs the module's validation rejects
+// would produce an illegal name, unless every rejected value breaks one of
+// namingConventions.
+func assertNamingRejection(t *testing.T, fixture *helpers.Fixture) {
+	evaluation, err := fixture.EvaluateUncheckedE()
+	if err != nil {
+		// No names can be built from the inputs at all
+		return
+	}
+	if len(namingViolations(fixture.Vars, evaluation.OutputValues())) > 0 {
+		return
+	}
+	var rejected []string
+	for name := range evaluation.Invalid {
+		rejected = append(rejected, name)
+	}
+	sort.Strings(rejected)
+	for _, name := range rejected {
+		if value, _ := fixture.Vars[name].(string); !breaksNamingConvention(name, value) {
+			assert.Failf(t, "validation is stricter than the naming rules",
+				"the module rejects %s = %q (%s), but every name built from it is legal",
+				name, fixture.Vars[name], strings.Join(evaluation.Invalid[name], "; "))
+		}
+	}
+}
+
+// planNamingOutputs plans the initialized naming workspace with vars and
+// returns its outputs.
+func planNamingOutputs(t *testing.T, options *terraform.Options, vars helpers.Vars) (map[string]interface{}, error) {
+	planOptions, err := options.Clone()
+	require.NoError(t, err)
+	planOptions.Vars = vars
+
+	plan, err := helpers.PlanJSONE(t, planOptions)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Outputs(), nil
+}
+
+// Patterns some naming outputs must match beyond helpers.NameRules.
+var (
+	storageAccountPattern = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	keyVaultPattern       = regexp.MustCompile(`^[a-zA-Z]`)
+)
+
+// namingViolations lists the naming module outputs for one input that are
+// illegal: those breaking the Azure naming rules, names with an empty
+// hyphen-separated part and a name_prefix whose parts are not one per input.
+func namingViolations(vars helpers.Vars, outputs map[string]interface{}) []string {
+	violations := helpers.NamingOutputViolations(outputs)
+
+	// name_prefix is [org_code-]project_name-environment-region_code
+	parts := 3
+	if orgCode, _ := vars["org_code"].(string); orgCode != "" {
+		parts++
+	}
+	if s, _ := outputs["name_prefix"].(string); len(strings.Split(s, "-")) != parts {
+		violations = append(violations, fmt.Sprintf("name_prefix: %s does not have %d parts", s, parts))
+	}
+
+	for _, name := range []string{"storage_account", "storage_account_diag"} {
+		if s, _ := outputs[name].(string); !storageAccountPattern.MatchString(s) {
+			violations = append(violations, name+": "+s+" is not 3-24 lowercase letters and digits")
+		}
+	}
+	if s, _ := outputs["key_vault"].(string); !keyVaultPattern.MatchString(s) {
+		violations = append(violations, "key_vault: "+s+" does not start with a letter")
+	}
+	for _, name := range []string{"aks_cluster", "aks_node_pool", "aks_node_pool_system", "aks_node_pool_user"} {
+		if s, _ := outputs[name].(string); strings.Contains(s, "_") {
+			violations = append(violations, name+": "+s+" contains an underscore")
+		}
+	}
+
+	// Every name is built from hyphen-separated parts, none of them empty
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s, ok := outputs[name].(string)
+		if !ok {
+			continue
+		}
+		if s == "" || strings.Contains(s, "--") || strings.HasPrefix(s, "-") || strings.HasSuffix(s, "-") {
+			violations = append(violations, name+": "+s+" has an empty part")
+		}
+	}
+	return violations
+}
@@ -4,83 +4,80 @@
 //
 // Unit and integration tests for the Terraform naming module.
 //
-// Run with: go test -v -run TestNaming ./modules/
+// Run with: go test -v -tags=integration -run TestNaming ./modules/
 //
 // =============================================================================
 
 package modules
 
 import (
-	"fmt"
-	"strings"
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestNamingModuleBasic tests basic naming conventions
 func TestNamingModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierApply)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/naming",
-		Vars: map[string]interface{}{
-			"customer_name": "contoso",
-			"environment":   "dev",
-			"region":        "brazilsouth",
-			"project":       "platform",
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.Naming().
+		With("project_name", "contoso").
+		With("location", "brazilsouth").
+		With("org_code", "plat").
+		Options(t)
 
 	// Initialize and plan only (no resources created)
-	terraform.Init(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
 	terraform.Plan(t, terraformOptions)
 
-	// Apply to get outputs
-	terraform.Apply(t, terraformOptions)
+	// Apply to get outputs; a second plan must be empty
+	helpers.Apply(t, terraformOptions)
 	defer terraform.Destroy(t, terraformOptions)
 
 	// Test resource group naming
-	rgName := terraform.Output(t, terraformOptions, "resource_group_name")
+	rgName := terraform.Output(t, terraformOptions, "resource_group")
 	assert.Contains(t, rgName, "contoso")
 	assert.Contains(t, rgName, "dev")
 	assert.Contains(t, rgName, "rg")
 
 	// Test AKS cluster naming
-	aksName := terraform.Output(t, terraformOptions, "aks_cluster_name")
+	aksName := terraform.Output(t, terraformOptions, "aks_cluster")
 	assert.Contains(t, aksName, "aks")
 	assert.NotContains(t, aksName, "_") // AKS names cannot contain underscores
 
 	// Test Storage Account naming (no hyphens, max 24 chars)
-	storageName := terraform.Output(t, terraformOptions, "storage_account_name")
+	storageName := terraform.Output(t, terraformOptions, "storage_account")
 	assert.NotContains(t, storageName, "-")
 	assert.LessOrEqual(t, len(storageName), 24)
 
 	// Test ACR naming (no hyphens)
-	acrName := terraform.Output(t, terraformOptions, "container_registry_name")
+	acrName := terraform.Output(t, terraformOptions, "container_registry")
 	assert.NotContains(t, acrName, "-")
 
 	// Test Key Vault naming (max 24 chars)
-	kvName := terraform.Output(t, terraformOptions, "key_vault_name")
+	kvName := terraform.Output(t, terraformOptions, "key_vault")
 	assert.LessOrEqual(t, len(kvName), 24)
 }
 
 // TestNamingModuleRegionCodes tests region short codes
 func TestNamingModuleRegionCodes(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierApply)
 	t.Parallel()
 
 	testCases := []struct {
 		region       string
 		expectedCode string
 	}{
-		{"brazilsouth", "brz"},
+		{"brazilsouth", "brs"},
 		{"eastus", "eus"},
-		{"eastus2", "eu2"},
+		{"eastus2", "eus2"},
 		{"westus", "wus"},
-		{"westus2", "wu2"},
+		{"westus2", "wus2"},
 		{"westeurope", "weu"},
 		{"northeurope", "neu"},
 	}
@@ -90,22 +87,16 @@ func TestNamingModuleRegionCodes(t *testing.T) {
 		t.Run(tc.region, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/naming",
-				Vars: map[string]interface{}{
-					"customer_name": "test",
-					"environment":   "dev",
-					"region":        tc.region,
-					"project":       "test",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Apply(t, terraformOptions)
+			terraformOptions := helpers.Naming().
+				With("project_name", "test").
+				With("location", tc.region).
+				Options(t)
+
+			helpers.Init(t, terraformOptions)
+			helpers.Apply(t, terraformOptions)
 			defer terraform.Destroy(t, terraformOptions)
 
-			regionCode := terraform.Output(t, terraformOptions, "region_short")
+			regionCode := terraform.Output(t, terraformOptions, "region_code")
 			assert.Equal(t, tc.expectedCode, regionCode)
 		})
 	}
@@ -113,31 +104,27 @@ func TestNamingModuleRegionCodes(t *testing.T) {
 
 // TestNamingModuleEnvironments tests different environment configurations
 func TestNamingModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierApply)
 	t.Parallel()
 
-	environments := []string{"dev", "staging", "prod"}
+	environments := []string{"dev", "stg", "prd"}
 
 	for _, env := range environments {
 		env := env
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/naming",
-				Vars: map[string]interface{}{
-					"customer_name": "test",
-					"environment":   env,
-					"region":        "brazilsouth",
-					"project":       "test",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Apply(t, terraformOptions)
+			terraformOptions := helpers.Naming().
+				With("project_name", "test").
+				With("environment", env).
+				With("location", "brazilsouth").
+				Options(t)
+
+			helpers.Init(t, terraformOptions)
+			helpers.Apply(t, terraformOptions)
 			defer terraform.Destroy(t, terraformOptions)
 
-			rgName := terraform.Output(t, terraformOptions, "resource_group_name")
+			rgName := terraform.Output(t, terraformOptions, "resource_group")
 			assert.Contains(t, rgName, env)
 		})
 	}
@@ -145,57 +132,17 @@ func TestNamingModuleEnvironments(t *testing.T) {
 
 // TestNamingModuleValidation tests input validation
 func TestNamingModuleValidation(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	testCases := []struct {
-		name        string
-		vars        map[string]interface{}
-		shouldError bool
-	}{
-		{
-			name: "valid_inputs",
-			vars: map[string]interface{}{
-				"customer_name": "contoso",
-				"environment":   "dev",
-				"region":        "brazilsouth",
-				"project":       "platform",
-			},
-			shouldError: false,
-		},
-		{
-			name: "invalid_environment",
-			vars: map[string]interface{}{
-				"customer_name": "contoso",
-				"environment":   "invalid",
-				"region":        "brazilsouth",
-				"project":       "platform",
-			},
-			shouldError: true,
-		},
-		{
-			name: "customer_name_too_long",
-			vars: map[string]interface{}{
-				"customer_name": "this-is-a-very-long-customer-name-that-exceeds-limits",
-				"environment":   "dev",
-				"region":        "brazilsouth",
-				"project":       "platform",
-			},
-			shouldError: true,
-		},
-	}
-
-	for _, tc := range testCases {
+	for _, tc := range namingValidationCases() {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/naming",
-				Vars:         tc.vars,
-				NoColor:      true,
-			}
+			terraformOptions := tc.fixture.UncheckedOptions(t)
 
-			terraform.Init(t, terraformOptions)
+			helpers.Init(t, terraformOptions)
 
 			if tc.shouldError {
 				_, err := terraform.PlanE(t, terraformOptions)
@@ -209,36 +156,26 @@ func TestNamingModuleValidation(t *testing.T) {
 
 // TestNamingModuleOutputConsistency tests that outputs are consistent across runs
 func TestNamingModuleOutputConsistency(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierApply)
 	t.Parallel()
 
-	vars := map[string]interface{}{
-		"customer_name": "consistency",
-		"environment":   "dev",
-		"region":        "brazilsouth",
-		"project":       "test",
-	}
+	fixture := helpers.Naming().
+		With("project_name", "consistent").
+		With("location", "brazilsouth")
 
 	// First run
-	terraformOptions1 := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/naming",
-		Vars:         vars,
-		NoColor:      true,
-	})
-
-	terraform.Init(t, terraformOptions1)
-	terraform.Apply(t, terraformOptions1)
+	terraformOptions1 := fixture.Options(t)
+
+	helpers.Init(t, terraformOptions1)
+	helpers.Apply(t, terraformOptions1)
 	outputs1 := terraform.OutputAll(t, terraformOptions1)
 	terraform.Destroy(t, terraformOptions1)
 
 	// Second run with same inputs
-	terraformOptions2 := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/naming",
-		Vars:         vars,
-		NoColor:      true,
-	})
-
-	terraform.Init(t, terraformOptions2)
-	terraform.Apply(t, terraformOptions2)
+	terraformOptions2 := fixture.Options(t)
+
+	helpers.Init(t, terraformOptions2)
+	helpers.Apply(t, terraformOptions2)
 	outputs2 := terraform.OutputAll(t, terraformOptions2)
 	defer terraform.Destroy(t, terraformOptions2)
 
@@ -250,42 +187,129 @@ func TestNamingModuleOutputConsistency(t *testing.T) {
 	}
 }
 
-// TestNamingModuleAzureCompliance tests Azure naming rules compliance
+// TestNamingModuleAzureCompliance tests naming outputs against the Azure naming rules of their resource types
 func TestNamingModuleAzureCompliance(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierApply)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/naming",
-		Vars: map[string]interface{}{
-			"customer_name": "azure",
-			"environment":   "prod",
-			"region":        "brazilsouth",
-			"project":       "compliance",
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.Naming().
+		With("project_name", "compliance").
+		With("environment", "prd").
+		With("location", "brazilsouth").
+		With("org_code", "azr").
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	terraform.Apply(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
+	helpers.Apply(t, terraformOptions)
 	defer terraform.Destroy(t, terraformOptions)
 
-	// Test various Azure naming constraints
+	// Each output must pass the Azure naming rule of the resource type it names
 	outputs := terraform.OutputAll(t, terraformOptions)
+	helpers.AssertNamingOutputs(t, outputs)
+}
+
+// TestNamingModuleLocals tests region codes and name composition without terraform
+func TestNamingModuleLocals(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierValidate)
+	t.Parallel()
+
+	testCases := []struct {
+		region       string
+		expectedCode string
+	}{
+		{"brazilsouth", "brs"},
+		{"eastus", "eus"},
+		{"eastus2", "eus2"},
+		{"westus", "wus"},
+		{"westus2", "wus2"},
+		{"westeurope", "weu"},
+		{"northeurope", "neu"},
+		{"southafricanorth", "sout"}, // unlisted regions use their first four characters
+	}
 
-	// Storage account: lowercase alphanumeric, 3-24 chars
-	storageName := fmt.Sprintf("%v", outputs["storage_account_name"])
-	assert.Regexp(t, "^[a-z0-9]{3,24}$", storageName)
+	for _, tc := range testCases {
+		evaluation := helpers.Naming().
+			With("project_name", "contoso").
+			With("location", tc.region).
+			With("org_code", "plat").
+			Evaluate(t)
+
+		assert.Empty(t, evaluation.Skipped, tc.region)
+		assert.Equal(t, tc.expectedCode, evaluation.Output(t, "region_code"), tc.region)
+		assert.Equal(t, "plat-contoso-dev-"+tc.expectedCode, evaluation.Output(t, "name_prefix"), tc.region)
+		assert.Equal(t, "rg-plat-contoso-dev-"+tc.expectedCode, evaluation.Output(t, "resource_group"), tc.region)
+		helpers.AssertNamingOutputs(t, evaluation.OutputValues())
+	}
+}
 
-	// Container registry: alphanumeric, 5-50 chars
-	acrName := fmt.Sprintf("%v", outputs["container_registry_name"])
-	assert.Regexp(t, "^[a-zA-Z0-9]{5,50}$", acrName)
+// TestNamingModuleInputValidation tests input validation without terraform
+func TestNamingModuleInputValidation(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierValidate)
+	t.Parallel()
 
-	// Key vault: alphanumeric and hyphens, 3-24 chars, start with letter
-	kvName := fmt.Sprintf("%v", outputs["key_vault_name"])
-	assert.Regexp(t, "^[a-zA-Z][a-zA-Z0-9-]{2,23}$", kvName)
+	for _, tc := range namingValidationCases() {
+		_, err := tc.fixture.EvaluateE()
+		if tc.shouldError {
+			assert.ErrorContains(t, err, "invalid value for variable", tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
 
-	// Resource group: alphanumeric, periods, underscores, hyphens, parentheses
-	rgName := fmt.Sprintf("%v", outputs["resource_group_name"])
-	assert.LessOrEqual(t, len(rgName), 90)
-	assert.False(t, strings.HasSuffix(rgName, "."))
+// namingValidationCase is a naming module input and whether validation
+// rejects it.
+type namingValidationCase struct {
+	name        string
+	fixture     *helpers.Fixture
+	shouldError bool
+}
+
+// namingValidationCases are shared by the terraform and in-process
+// validation tests.
+func namingValidationCases() []namingValidationCase {
+	base := func() *helpers.Fixture {
+		return helpers.Naming().
+			With("project_name", "contoso").
+			With("location", "brazilsouth").
+			With("org_code", "plat")
+	}
+
+	return []namingValidationCase{
+		{
+			name:        "valid_inputs",
+			fixture:     base(),
+			shouldError: false,
+		},
+		{
+			name:        "invalid_environment",
+			fixture:     base().With("environment", "invalid"),
+			shouldError: true,
+		},
+		{
+			name:        "customer_name_too_long",
+			fixture:     base().With("project_name", "this-is-a-very-long-customer-name-that-exceeds-limits"),
+			shouldError: true,
+		},
+		{
+			name:        "project_name_starts_with_digit",
+			fixture:     base().With("project_name", "1contoso"),
+			shouldError: true,
+		},
+		{
+			name:        "location_display_name",
+			fixture:     base().With("location", "Brazil South"),
+			shouldError: true,
+		},
+		{
+			name:        "instance_not_three_digits",
+			fixture:     base().With("instance", "1"),
+			shouldError: true,
+		},
+		{
+			name:        "org_code_with_underscore",
+			fixture:     base().With("org_code", "a_b"),
+			shouldError: true,
+		},
+	}
 }
@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform networking module.
 //
-// Run with: go test -v -run TestNetworking ./modules/
+// Run with: go test -v -tags=integration -run TestNetworking ./modules/
 //
 // =============================================================================
 
@@ -14,59 +14,42 @@ import (
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestNetworkingModuleBasic tests basic networking configuration
 func TestNetworkingModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "testcustomer",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-networking",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"subnet_config": map[string]interface{}{
-				"aks_nodes_cidr":         "10.0.0.0/22",
-				"aks_pods_cidr":          "10.0.16.0/20",
-				"private_endpoints_cidr": "10.0.4.0/24",
-				"bastion_cidr":           "10.0.5.0/26",
-				"app_gateway_cidr":       "10.0.6.0/24",
-			},
-			"enable_bastion":     false,
-			"enable_app_gateway": false,
-			"create_dns_zone":    false,
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.Networking().
+		With("customer_name", "testcustomer").
+		With("create_dns_zone", false).
+		Options(t)
 
 	// Initialize and validate
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
 
 	// Plan only (no actual resources created in unit test)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify plan contains expected resources
-	assert.Contains(t, planOutput, "azurerm_virtual_network.main")
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_nodes")
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_pods")
-	assert.Contains(t, planOutput, "azurerm_subnet.private_endpoints")
-	assert.Contains(t, planOutput, "azurerm_network_security_group.aks_nodes")
-	assert.Contains(t, planOutput, "azurerm_network_security_group.private_endpoints")
+	helpers.AssertCreated(t, plan, "azurerm_virtual_network.main")
+	helpers.AssertAttribute(t, plan, "azurerm_virtual_network.main", "address_space", []string{"10.0.0.0/16"})
+	helpers.AssertCreated(t, plan, "azurerm_subnet.aks_nodes")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.aks_pods")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.private_endpoints")
+	helpers.AssertCreated(t, plan, "azurerm_network_security_group.aks_nodes")
+	helpers.AssertCreated(t, plan, "azurerm_network_security_group.private_endpoints")
+	helpers.AssertNotPlanned(t, plan, "azurerm_dns_zone.public")
 }
 
 // TestNetworkingModuleVNetCIDRValidation tests VNet CIDR validation
 func TestNetworkingModuleVNetCIDRValidation(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -84,21 +67,13 @@ func TestNetworkingModuleVNetCIDRValidation(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := &terraform.Options{
-				TerraformDir: "../../../terraform/modules/networking",
-				Vars: map[string]interface{}{
-					"customer_name":       "test",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test",
-					"vnet_cidr":           tc.vnetCIDR,
-					"dns_zone_name":       "test.example.com",
-					"create_dns_zone":     false,
-				},
-				NoColor: true,
-			}
+			terraformOptions := helpers.Networking().
+				With("customer_name", "test").
+				With("vnet_cidr", tc.vnetCIDR).
+				With("create_dns_zone", false).
+				Options(t)
 
-			terraform.Init(t, terraformOptions)
+			helpers.Init(t, terraformOptions)
 
 			if tc.shouldErr {
 				_, err := terraform.PlanE(t, terraformOptions)
@@ -112,96 +87,71 @@ func TestNetworkingModuleVNetCIDRValidation(t *testing.T) {
 
 // TestNetworkingModuleSubnetConfiguration tests subnet configurations
 func TestNetworkingModuleSubnetConfiguration(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "subnet",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-subnet",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"subnet_config": map[string]interface{}{
-				"aks_nodes_cidr":         "10.0.0.0/22",
-				"aks_pods_cidr":          "10.0.16.0/20",
-				"private_endpoints_cidr": "10.0.4.0/24",
-				"bastion_cidr":           "10.0.5.0/26",
-				"app_gateway_cidr":       "10.0.6.0/24",
-			},
-			"enable_bastion":     true,
-			"enable_app_gateway": true,
-			"create_dns_zone":    false,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Networking().
+		With("customer_name", "subnet").
+		With("enable_bastion", true).
+		With("enable_app_gateway", true).
+		With("create_dns_zone", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify all subnets are planned when enabled
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_nodes")
-	assert.Contains(t, planOutput, "azurerm_subnet.aks_pods")
-	assert.Contains(t, planOutput, "azurerm_subnet.private_endpoints")
-	assert.Contains(t, planOutput, "azurerm_subnet.bastion")
-	assert.Contains(t, planOutput, "azurerm_subnet.app_gateway")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.aks_nodes")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.aks_pods")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.private_endpoints")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.bastion[0]")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.app_gateway[0]")
+	helpers.AssertActionCount(t, plan, "azurerm_subnet", helpers.ActionCreate, 5)
 }
 
 // TestNetworkingModulePrivateDNSZones tests private DNS zone creation
 func TestNetworkingModulePrivateDNSZones(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "dns",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-dns",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"create_dns_zone":     false,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Networking().
+		With("customer_name", "dns").
+		With("create_dns_zone", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify private DNS zones are created
-	assert.Contains(t, planOutput, "azurerm_private_dns_zone.zones")
-	assert.Contains(t, planOutput, "azurerm_private_dns_zone_virtual_network_link.links")
+	helpers.AssertCreated(t, plan, "azurerm_private_dns_zone.zones")
+	helpers.AssertCreated(t, plan, "azurerm_private_dns_zone_virtual_network_link.links")
+	helpers.AssertAttribute(t, plan, `azurerm_private_dns_zone.zones["postgres"]`, "name", helpers.PrivateDNSZoneNames["postgres"])
+	helpers.AssertAttribute(t, plan, `azurerm_private_dns_zone.zones["keyvault"]`, "name", helpers.PrivateDNSZoneNames["keyvault"])
 }
 
 // TestNetworkingModuleNSGRules tests NSG rule configurations
 func TestNetworkingModuleNSGRules(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/networking",
-		Vars: map[string]interface{}{
-			"customer_name":       "nsg",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-nsg",
-			"vnet_cidr":           "10.0.0.0/16",
-			"dns_zone_name":       "test.example.com",
-			"create_dns_zone":     false,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Networking().
+		With("customer_name", "nsg").
+		With("environment", "prod").
+		With("create_dns_zone", false).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify NSGs are created with proper naming
-	assert.Contains(t, planOutput, "nsg-aks-nodes")
-	assert.Contains(t, planOutput, "nsg-private-endpoints")
+	helpers.AssertAttribute(t, plan, "azurerm_network_security_group.aks_nodes", "name", "nsg-aks-nodes-nsg-prod")
+	helpers.AssertAttribute(t, plan, "azurerm_network_security_group.private_endpoints", "name", "nsg-private-endpoints-nsg-prod")
+
+	// No rule opens management ports to the Internet, allows any-any or
+	// hides a deny
+	helpers.AssertNSGRules(t, plan)
 }
 
 // TestNetworkingModuleBastionConfiguration tests Azure Bastion configuration
 func TestNetworkingModuleBastionConfiguration(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -218,30 +168,21 @@ func TestNetworkingModuleBastionConfiguration(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/networking",
-				Vars: map[string]interface{}{
-					"customer_name":       "bastion",
-					"environment":         "dev",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-bastion",
-					"vnet_cidr":           "10.0.0.0/16",
-					"dns_zone_name":       "test.example.com",
-					"enable_bastion":      tc.enableBastion,
-					"create_dns_zone":     false,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Networking().
+				With("customer_name", "bastion").
+				With("enable_bastion", tc.enableBastion).
+				With("create_dns_zone", false).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 			if tc.expectBastion {
-				assert.Contains(t, planOutput, "azurerm_bastion_host.main")
-				assert.Contains(t, planOutput, "azurerm_public_ip.bastion")
-				assert.Contains(t, planOutput, "AzureBastionSubnet")
+				helpers.AssertCreated(t, plan, "azurerm_bastion_host.main[0]")
+				helpers.AssertCreated(t, plan, "azurerm_public_ip.bastion[0]")
+				helpers.AssertAttribute(t, plan, "azurerm_subnet.bastion[0]", "name", "AzureBastionSubnet")
 			} else {
-				assert.NotContains(t, planOutput, "azurerm_bastion_host.main")
+				helpers.AssertNotPlanned(t, plan, "azurerm_bastion_host.main")
+				helpers.AssertNotPlanned(t, plan, "azurerm_subnet.bastion")
 			}
 		})
 	}
@@ -249,6 +190,7 @@ func TestNetworkingModuleBastionConfiguration(t *testing.T) {
 
 // TestNetworkingModuleEnvironments tests different environment configurations
 func TestNetworkingModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -258,25 +200,17 @@ func TestNetworkingModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/networking",
-				Vars: map[string]interface{}{
-					"customer_name":       "envtest",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-" + env,
-					"vnet_cidr":           "10.0.0.0/16",
-					"dns_zone_name":       "test.example.com",
-					"create_dns_zone":     false,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Networking().
+				With("customer_name", "envtest").
+				With("environment", env).
+				With("resource_group_name", "rg-test-"+env).
+				With("create_dns_zone", false).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 			// Verify environment is reflected in naming
-			assert.Contains(t, planOutput, env)
+			helpers.AssertAttribute(t, plan, "azurerm_virtual_network.main", "name", "vnet-envtest-"+env)
 		})
 	}
 }
@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform observability module.
 //
-// Run with: go test -v -run TestObservability ./modules/
+// Run with: go test -v -tags=integration -run TestObservability ./modules/
 //
 // =============================================================================
 
@@ -13,124 +13,84 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestObservabilityModuleBasic tests basic observability configuration
 func TestObservabilityModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "testobs",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-obs",
-			"enable_prometheus":   true,
-			"enable_grafana":      true,
-			"enable_loki":         true,
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Observability().
+		With("customer_name", "testobs").
+		With("enable_container_insights", true).
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify observability stack is planned
-	assert.Contains(t, planOutput, "azurerm_log_analytics_workspace")
+	helpers.AssertCreated(t, plan, "azurerm_log_analytics_workspace.main[0]")
+	helpers.AssertCreated(t, plan, "azurerm_monitor_workspace.prometheus")
+	helpers.AssertCreated(t, plan, "azurerm_dashboard_grafana.main")
 }
 
 // TestObservabilityModuleLogAnalytics tests Log Analytics workspace
 func TestObservabilityModuleLogAnalytics(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "latest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-la",
-			"log_analytics_config": map[string]interface{}{
-				"sku":               "PerGB2018",
-				"retention_in_days": 30,
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Observability().
+		With("customer_name", "latest").
+		With("retention_days", 30).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Log Analytics workspace naming
-	assert.Contains(t, planOutput, "log-")
+	helpers.AssertAttribute(t, plan, "azurerm_log_analytics_workspace.main[0]", "name", "law-latest-dev")
+	helpers.AssertAttribute(t, plan, "azurerm_log_analytics_workspace.main[0]", "sku", "PerGB2018")
+	helpers.AssertAttribute(t, plan, "azurerm_log_analytics_workspace.main[0]", "retention_in_days", 30)
 }
 
 // TestObservabilityModuleGrafana tests Azure Managed Grafana
 func TestObservabilityModuleGrafana(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "graftest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-grafana",
-			"enable_grafana":      true,
-			"grafana_config": map[string]interface{}{
-				"sku":                    "Standard",
-				"api_key_enabled":        true,
-				"public_network_enabled": false,
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Observability().
+		With("customer_name", "graftest").
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Grafana is planned
-	assert.Contains(t, planOutput, "azurerm_dashboard_grafana")
+	helpers.AssertCreated(t, plan, "azurerm_dashboard_grafana.main")
+	helpers.AssertAttribute(t, plan, "azurerm_dashboard_grafana.main", "api_key_enabled", true)
 }
 
 // TestObservabilityModuleAlerts tests alert configuration
 func TestObservabilityModuleAlerts(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/observability",
-		Vars: map[string]interface{}{
-			"customer_name":       "alerttest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-alerts",
-			"enable_alerts":       true,
-			"alert_config": map[string]interface{}{
-				"action_group_email": "ops@example.com",
-				"severity_levels":    []int{0, 1, 2},
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Observability().
+		With("customer_name", "alerttest").
+		With("environment", "prod").
+		With("alert_email_receivers", []string{"ops@example.com"}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify action group is planned
-	assert.Contains(t, planOutput, "azurerm_monitor_action_group")
+	helpers.AssertCreated(t, plan, "azurerm_monitor_action_group.alerts[0]")
 }
 
 // TestObservabilityModuleEnvironments tests different environments
 func TestObservabilityModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -140,21 +100,16 @@ func TestObservabilityModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/observability",
-				Vars: map[string]interface{}{
-					"customer_name":       "obsenv",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-obs-" + env,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.Observability().
+				With("customer_name", "obsenv").
+				With("environment", env).
+				With("resource_group_name", "rg-test-obs-"+env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_dashboard_grafana.main", "name", "grafana-obsenv-"+env)
+			helpers.AssertAttribute(t, plan, "azurerm_dashboard_grafana.main", "zone_redundancy_enabled", env == "prod")
 		})
 	}
 }
@@ -0,0 +1,583 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - OFFLINE PLAN TESTS
+// =============================================================================
+//
+// Plan-only tests that run against mocked providers in a temporary copy of
+// each module. They need no Azure credentials, no ARM_* variables and, with
+// TERRATEST_PLUGIN_DIR pointing at pre-installed providers, no network.
+// Provider-computed values, including provider defaults, are unknown in
+// these plans, so assert on values the module sets.
+//
+// Every test in this file is named TestOffline*; live tests never are.
+// TestOfflineGoldenPlans compares each baseline fixture's plan with its
+// snapshot in testdata/golden and fails for a fixture that has none; pass
+// -update to write or rewrite the snapshots after an intended change.
+// TestOfflineSizingProfiles plans every profile in
+// config/sizing-profiles.yaml and checks the planned sizes against it.
+// TestOfflineRegionAvailability checks planned models and DR regions against
+// config/region-availability.yaml.
+// TestOfflineRootFeatureCombinations plans the root module for every
+// pairwise combination of deployment_mode and feature flags, leaving out
+// the pairs in helpers.RootFeatureExclusions;
+// TestOfflineRootFeatureExclusions checks that each of those pairs fails.
+// TestOfflineNetworkAddressSpace checks the planned subnets against the AKS
+// node pools placed in them. TestOfflineNetworkNSGRules checks the planned
+// network security rules for Internet-facing management ports, any-any
+// allows and unreachable denies. TestOfflinePrivateEndpointDNS checks that
+// every module's private endpoints point at the privatelink zone their
+// service needs, and that the networking module creates it.
+// TestOfflineHorizonCompositions plans the module calls of each horizon
+// together with the calls they depend on, wired as in terraform/main.tf.
+// TestOfflineModuleUpgrade deploys each module as of TERRATEST_UPGRADE_REF
+// (default HEAD) with mocked providers and checks that the working tree
+// keeps every deployed resource; see upgrade_test.go for replacements.
+//
+// Run with: go test -v -run TestOffline ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+// TestOfflineNamingModule tests naming outputs from an offline plan
+func TestOfflineNamingModule(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	terraformOptions := helpers.Naming().
+		With("project_name", "contoso").
+		With("location", "brazilsouth").
+		OfflineOptions(t)
+
+	plan := helpers.OfflinePlanJSON(t, terraformOptions)
+
+	rgName, ok := plan.Output("resource_group")
+	assert.True(t, ok)
+	assert.Equal(t, "rg-contoso-dev-brs", rgName)
+
+	storageName, ok := plan.Output("storage_account")
+	assert.True(t, ok)
+	assert.NotContains(t, storageName, "-")
+
+	helpers.AssertNamingOutputs(t, plan.Outputs())
+
+	// Evaluating the module in process must give the outputs terraform plans
+	evaluation := helpers.Naming().
+		With("project_name", "contoso").
+		With("location", "brazilsouth").
+		Evaluate(t)
+	assert.Equal(t, plan.Outputs(), evaluation.OutputValues())
+}
+
+// TestOfflineNetworkingModuleBasic tests the networking plan without Azure
+func TestOfflineNetworkingModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	terraformOptions := helpers.Networking().
+		With("customer_name", "testcustomer").
+		With("create_dns_zone", false).
+		OfflineOptions(t)
+
+	plan := helpers.OfflinePlanJSON(t, terraformOptions)
+
+	helpers.AssertCreated(t, plan, "azurerm_virtual_network.main")
+	helpers.AssertAttribute(t, plan, "azurerm_virtual_network.main", "address_space", []string{"10.0.0.0/16"})
+	helpers.AssertCreated(t, plan, "azurerm_subnet.aks_nodes")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.aks_pods")
+	helpers.AssertCreated(t, plan, "azurerm_subnet.private_endpoints")
+	helpers.AssertNotPlanned(t, plan, "azurerm_subnet.bastion")
+	helpers.AssertNotPlanned(t, plan, "azurerm_dns_zone.public")
+}
+
+// TestOfflineAKSClusterModuleBasic tests the AKS plan without Azure
+func TestOfflineAKSClusterModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	terraformOptions := helpers.AKSCluster().
+		With("customer_name", "testaks").
+		With("enable_workload_identity", true).
+		OfflineOptions(t)
+
+	plan := helpers.OfflinePlanJSON(t, terraformOptions)
+
+	helpers.AssertCreated(t, plan, "azurerm_kubernetes_cluster.main")
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "name", "aks-testaks-dev")
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "oidc_issuer_enabled", true)
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.zones", []string{"1", "2", "3"})
+}
+
+// TestOfflineDatabasesModuleBasic tests the databases plan without Azure
+func TestOfflineDatabasesModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	terraformOptions := helpers.Databases().
+		With("customer_name", "testdb").
+		WithAttr("redis_config", "enabled", false).
+		OfflineOptions(t)
+
+	plan := helpers.OfflinePlanJSON(t, terraformOptions)
+
+	helpers.AssertCreated(t, plan, "azurerm_postgresql_flexible_server.main[0]")
+	helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main[0]", "delegated_subnet_id", helpers.SubnetID("snet-private-endpoints"))
+	helpers.AssertNotPlanned(t, plan, "azurerm_redis_cache.main")
+}
+
+// TestOfflineGoldenPlans tests each module's baseline plan against its snapshot
+func TestOfflineGoldenPlans(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	for _, fixture := range helpers.Fixtures() {
+		fixture := fixture
+		t.Run(fixture.Module, func(t *testing.T) {
+			t.Parallel()
+
+			plan := helpers.OfflinePlanJSON(t, fixture.OfflineOptions(t))
+			helpers.AssertGoldenPlan(t, plan)
+		})
+	}
+}
+
+// TestOfflineModuleUpgrade tests that each module keeps the resources deployed by its version at the upgrade ref
+func TestOfflineModuleUpgrade(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	ref := helpers.UpgradeRef()
+	for _, fixture := range helpers.Fixtures() {
+		fixture := fixture
+		t.Run(fixture.Module, func(t *testing.T) {
+			t.Parallel()
+
+			plan, err := fixture.OfflineUpgradePlanE(t, ref)
+			if errors.Is(err, helpers.ErrNotAtRef) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			helpers.AssertUpgrade(t, plan, ref)
+			helpers.AssertNoProtectedDestroy(t, plan)
+		})
+	}
+}
+
+// TestOfflineSizingProfiles tests that each sizing profile plans the VM sizes, node counts, SKUs and storage it defines
+func TestOfflineSizingProfiles(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	profiles := helpers.SizingProfiles(t)
+	regions := helpers.RegionAvailability(t)
+	for _, name := range profiles.Names() {
+		name := name
+		profile := profiles.Profiles[name]
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, cluster := range profile.Clusters() {
+				cluster := cluster
+				t.Run("aks-cluster/"+cluster.Role, func(t *testing.T) {
+					t.Parallel()
+
+					region := cluster.Location
+					if region == "" {
+						region = helpers.DefaultLocation
+					}
+					helpers.AssertSizingAvailable(t, regions, region, name)
+
+					fixture, err := profile.AKSClusterFixtureE(cluster)
+					require.NoError(t, err)
+					plan := helpers.OfflinePlanJSON(t, fixture.OfflineOptions(t))
+					assertSizedAKS(t, plan, cluster.AKS)
+				})
+			}
+
+			t.Run("container-registry", func(t *testing.T) {
+				t.Parallel()
+
+				fixture, err := profile.ContainerRegistryFixtureE()
+				require.NoError(t, err)
+				plan := helpers.OfflinePlanJSON(t, fixture.OfflineOptions(t))
+				assertSizedACR(t, plan, profile.Infrastructure.ACR)
+			})
+
+			t.Run("databases", func(t *testing.T) {
+				t.Parallel()
+
+				fixture, err := profile.DatabasesFixtureE()
+				require.NoError(t, err)
+				plan := helpers.OfflinePlanJSON(t, fixture.OfflineOptions(t))
+				assertSizedDatabases(t, plan, profile)
+			})
+
+			t.Run("networking", func(t *testing.T) {
+				t.Parallel()
+
+				fixture, err := profile.NetworkingFixtureE()
+				require.NoError(t, err)
+				plan := helpers.OfflinePlanJSON(t, fixture.OfflineOptions(t))
+				assertSizedNetworking(t, plan, profile.Infrastructure.Networking)
+			})
+		})
+	}
+}
+
+// assertSizedAKS checks the planned node pools against the cluster's sizing.
+func assertSizedAKS(t *testing.T, plan *helpers.Plan, aks *helpers.AKSSizing) {
+	pools := aks.Pools()
+	system := pools[0]
+
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.name", system.Name)
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.vm_size", system.VMSize)
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.node_count", system.NodeCount)
+	helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.enable_auto_scaling", system.Autoscaled())
+	if system.Autoscaled() {
+		helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.min_count", system.AutoScaling.MinNodes)
+		helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.max_count", system.AutoScaling.MaxNodes)
+	}
+	if system.OSDiskSizeGB > 0 {
+		helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.os_disk_size_gb", system.OSDiskSizeGB)
+	}
+	if system.MaxPods > 0 {
+		helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "default_node_pool.0.max_pods", system.MaxPods)
+	}
+	if aks.KubernetesVersion != "" {
+		helpers.AssertAttribute(t, plan, "azurerm_kubernetes_cluster.main", "kubernetes_version", aks.KubernetesVersion)
+	}
+
+	helpers.AssertActionCount(t, plan, "azurerm_kubernetes_cluster_node_pool", helpers.ActionCreate, len(pools)-1)
+	for _, pool := range pools[1:] {
+		address := fmt.Sprintf("azurerm_kubernetes_cluster_node_pool.user[%q]", pool.Name)
+		helpers.AssertAttribute(t, plan, address, "vm_size", pool.VMSize)
+		helpers.AssertAttribute(t, plan, address, "enable_auto_scaling", pool.Autoscaled())
+		if pool.Autoscaled() {
+			helpers.AssertAttribute(t, plan, address, "min_count", pool.AutoScaling.MinNodes)
+			helpers.AssertAttribute(t, plan, address, "max_count", pool.AutoScaling.MaxNodes)
+		} else {
+			helpers.AssertAttribute(t, plan, address, "node_count", pool.NodeCount)
+		}
+		if len(pool.Taints) > 0 {
+			helpers.AssertAttribute(t, plan, address, "node_taints", pool.Taints)
+		}
+	}
+}
+
+// assertSizedACR checks the planned registry SKU and replicas.
+func assertSizedACR(t *testing.T, plan *helpers.Plan, acr *helpers.ACRSizing) {
+	if acr == nil {
+		helpers.AssertCreated(t, plan, "azurerm_container_registry.main")
+		return
+	}
+	helpers.AssertAttribute(t, plan, "azurerm_container_registry.main", "sku", acr.SKU)
+	if acr.RetentionDays > 0 {
+		helpers.AssertAttribute(t, plan, "azurerm_container_registry.main", "retention_policy.0.days", acr.RetentionDays)
+	}
+	helpers.AssertActionCount(t, plan, "azurerm_container_registry_replication", helpers.ActionCreate, len(acr.Replications))
+	for _, replication := range acr.Replications {
+		address := fmt.Sprintf("azurerm_container_registry_replication.replicas[%q]", replication.Location)
+		helpers.AssertAttribute(t, plan, address, "location", replication.Location)
+	}
+}
+
+// assertSizedDatabases checks the planned PostgreSQL and Redis SKUs and
+// storage.
+func assertSizedDatabases(t *testing.T, plan *helpers.Plan, profile *helpers.SizingProfile) {
+	if server := profile.PostgreSQLServer(); server != nil {
+		skuName, err := helpers.PostgreSQLSKUName(server.SKU)
+		require.NoError(t, err)
+		helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main", "sku_name", skuName)
+		helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main", "storage_mb", server.StorageGB*1024)
+		if server.BackupRetentionDays > 0 {
+			helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main", "backup_retention_days", server.BackupRetentionDays)
+		}
+		if server.Version != "" {
+			helpers.AssertAttribute(t, plan, "azurerm_postgresql_flexible_server.main", "version", server.Version)
+		}
+	}
+	if redis := profile.Databases.Redis; redis != nil {
+		helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main", "sku_name", redis.SKU)
+		helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main", "family", redis.Family)
+		helpers.AssertAttribute(t, plan, "azurerm_redis_cache.main", "capacity", redis.Capacity)
+	}
+}
+
+// assertSizedNetworking checks the planned VNet and the subnets the profile
+// gives.
+func assertSizedNetworking(t *testing.T, plan *helpers.Plan, networking *helpers.NetworkingSizing) {
+	if networking == nil {
+		helpers.AssertCreated(t, plan, "azurerm_virtual_network.main")
+		return
+	}
+	helpers.AssertAttribute(t, plan, "azurerm_virtual_network.main", "address_space", []string{networking.VNetCIDR})
+	if networking.AKSSubnet != "" {
+		helpers.AssertAttribute(t, plan, "azurerm_subnet.aks_nodes", "address_prefixes", []string{networking.AKSSubnet})
+	}
+	if networking.PrivateEndpointsSubnet != "" {
+		helpers.AssertAttribute(t, plan, "azurerm_subnet.private_endpoints", "address_prefixes", []string{networking.PrivateEndpointsSubnet})
+	}
+	if networking.ApplicationGateway != nil && networking.ApplicationGateway.Enabled {
+		helpers.AssertAttribute(t, plan, "azurerm_subnet.app_gateway", "address_prefixes", []string{networking.AppGatewaySubnet})
+	}
+}
+
+// TestOfflineRegionAvailability tests planned models and DR regions against the region matrix
+func TestOfflineRegionAvailability(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	regions := helpers.RegionAvailability(t)
+
+	t.Run("ai-foundry", func(t *testing.T) {
+		t.Parallel()
+
+		plan := helpers.OfflinePlanJSON(t, helpers.AIFoundry().OfflineOptions(t))
+		helpers.AssertRegionAvailability(t, regions, plan)
+	})
+
+	t.Run("ai-foundry-brazilsouth", func(t *testing.T) {
+		t.Parallel()
+
+		plan := helpers.OfflinePlanJSON(t, helpers.AIFoundry().With("location", "brazilsouth").OfflineOptions(t))
+		violations := regions.CheckPlan(plan)
+		if assert.Len(t, violations, 1) {
+			assert.Equal(t, "regions.brazilsouth.services.openai_gpt4o", violations[0].Entry)
+		}
+	})
+
+	t.Run("disaster-recovery", func(t *testing.T) {
+		t.Parallel()
+
+		// The us-based deployment pattern
+		terraformOptions := helpers.DisasterRecovery().
+			With("primary_location", "eastus2").
+			With("primary_region_short", "eu2").
+			With("dr_location", "southcentralus").
+			With("dr_region_short", "scu").
+			OfflineOptions(t)
+
+		plan := helpers.OfflinePlanJSON(t, terraformOptions)
+		helpers.AssertRegionAvailability(t, regions, plan)
+	})
+
+	t.Run("disaster-recovery-brazilsouth", func(t *testing.T) {
+		t.Parallel()
+
+		// The baseline keeps the module's default dr_location, eastus2
+		plan := helpers.OfflinePlanJSON(t, helpers.DisasterRecovery().OfflineOptions(t))
+		violations := regions.CheckPlan(plan)
+		if assert.Len(t, violations, 1) {
+			assert.Equal(t, "regions.brazilsouth.data_residency", violations[0].Entry)
+		}
+	})
+}
+
+// TestOfflineRootFeatureCombinations tests planning the root module with pairwise flag combinations
+func TestOfflineRootFeatureCombinations(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	config := helpers.LoadConfiguration(t, helpers.RootModule)
+
+	for i, combination := range helpers.Pairwise(t, helpers.RootFeatureFactors(t), helpers.RootFeatureExclusions...) {
+		combination := combination
+		t.Run(fmt.Sprintf("%02d-%s", i, combination["deployment_mode"]), func(t *testing.T) {
+			t.Parallel()
+			t.Logf("variables: %v", combination)
+
+			root := helpers.Root()
+			for name, value := range combination {
+				root.With(name, value)
+			}
+			plan := helpers.OfflinePlanJSON(t, root.OfflineOptions(t))
+
+			// Each module is planned exactly when its count says so. Modules
+			// without providers, such as naming, plan no resources.
+			enabled, err := config.EnabledCallsE(combination)
+			require.NoError(t, err)
+			planned := plannedModules(plan)
+			for name, want := range enabled {
+				providers, err := helpers.ModuleProvidersE(config.Calls[name].Dir)
+				require.NoError(t, err)
+				if len(providers) == 0 {
+					continue
+				}
+				assert.Equal(t, want, planned[name], "module.%s planned", name)
+			}
+		})
+	}
+}
+
+// TestOfflineRootFeatureExclusions tests that the root module rejects each excluded flag pair
+func TestOfflineRootFeatureExclusions(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	for _, excluded := range helpers.RootFeatureExclusions {
+		excluded := excluded
+		t.Run(fmt.Sprint(excluded), func(t *testing.T) {
+			t.Parallel()
+
+			root := helpers.Root()
+			for name, value := range excluded {
+				root.With(name, value)
+			}
+			_, err := helpers.OfflinePlanJSONE(t, root.OfflineOptions(t))
+			assert.ErrorContains(t, err, "Resource precondition failed")
+		})
+	}
+}
+
+// TestOfflineNetworkAddressSpace tests the networking subnets against the AKS node pools they hold
+func TestOfflineNetworkAddressSpace(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	network := helpers.OfflinePlanJSON(t, helpers.Networking().
+		With("enable_bastion", true).
+		With("enable_app_gateway", true).
+		OfflineOptions(t))
+	aks := helpers.OfflinePlanJSON(t, helpers.AKSCluster().
+		With("additional_node_pools", map[string]interface{}{
+			"workload": map[string]interface{}{
+				"name":                "workload",
+				"node_count":          3,
+				"vm_size":             "Standard_D4s_v5",
+				"min_count":           3,
+				"max_count":           20,
+				"enable_auto_scaling": true,
+				"max_pods":            110,
+				"node_labels":         map[string]string{"workload-type": "application"},
+				"node_taints":         []string{},
+				"zones":               []string{"1", "2", "3"},
+			},
+		}).
+		OfflineOptions(t))
+
+	// The fixture's subnet IDs name the networking subnets, so every pool is
+	// placed
+	helpers.AssertAddressSpace(t, network, aks)
+	report := helpers.AnalyzeAddressSpace(network, aks)
+	assert.Empty(t, report.Unchecked)
+	assert.Len(t, report.Subnets, 5)
+}
+
+// TestOfflineNetworkNSGRules tests the networking NSG rules for risky allows and shadowed denies
+func TestOfflineNetworkNSGRules(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	plan := helpers.OfflinePlanJSON(t, helpers.Networking().OfflineOptions(t))
+
+	helpers.AssertNSGRules(t, plan)
+
+	// Both groups end in a reachable DenyAllInbound
+	denies := 0
+	for _, rule := range helpers.PlannedNSGRules(plan) {
+		if rule.Name == "DenyAllInbound" {
+			assert.Equal(t, 4096, rule.Priority, rule.NSG)
+			denies++
+		}
+	}
+	assert.Equal(t, 2, denies)
+}
+
+// TestOfflinePrivateEndpointDNS tests each module's private endpoints against the networking private DNS zones
+func TestOfflinePrivateEndpointDNS(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	// The root module adds the Purview zones when Purview is enabled
+	network := helpers.OfflinePlanJSON(t, helpers.Networking().
+		With("additional_private_dns_zones", map[string]string{
+			"purview":        helpers.PrivateDNSZoneNames["purview"],
+			"purview_studio": helpers.PrivateDNSZoneNames["purview_studio"],
+			"queue":          helpers.PrivateDNSZoneNames["storage_queue"],
+			"servicebus":     helpers.PrivateDNSZoneNames["servicebus"],
+		}).
+		OfflineOptions(t))
+
+	testCases := []struct {
+		fixture   *helpers.Fixture
+		endpoints int
+	}{
+		{helpers.AIFoundry(), 3},
+		{helpers.Databases(), 1},
+		{helpers.Purview(), 2},
+		{helpers.Security(), 1},
+		{helpers.ContainerRegistry(), 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.fixture.Module, func(t *testing.T) {
+			t.Parallel()
+
+			plan := helpers.OfflinePlanJSON(t, tc.fixture.OfflineOptions(t))
+			config := helpers.LoadConfiguration(t, tc.fixture.Module)
+
+			helpers.AssertPrivateDNS(t, config, plan, network)
+			report := helpers.AnalyzePrivateDNS(config, plan, network)
+			assert.Empty(t, report.Unchecked)
+			assert.Len(t, report.Endpoints, tc.endpoints)
+		})
+	}
+}
+
+// TestOfflineHorizonCompositions tests planning each horizon's modules wired as in the root module
+func TestOfflineHorizonCompositions(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierOffline)
+	t.Parallel()
+
+	config := helpers.LoadConfiguration(t, helpers.RootModule)
+
+	for _, horizon := range helpers.Horizons {
+		horizon := horizon
+		t.Run(horizon.Name, func(t *testing.T) {
+			t.Parallel()
+
+			plan := helpers.OfflinePlanJSON(t, horizon.OfflineOptions(t))
+
+			// Every composed call is planned, so its inputs were accepted
+			calls, err := config.DependenciesE(horizon.Calls...)
+			require.NoError(t, err)
+			planned := plannedModules(plan)
+			for _, name := range calls {
+				assert.True(t, planned[name], "module.%s planned", name)
+			}
+
+			// Private endpoints resolve through the zones module.networking
+			// creates
+			helpers.AssertPrivateDNS(t, config, plan)
+			assert.Empty(t, helpers.AnalyzePrivateDNS(config, plan).Unchecked)
+		})
+	}
+}
+
+// plannedModules returns the names of the root module calls with at least one
+// resource change in the plan.
+func plannedModules(plan *helpers.Plan) map[string]bool {
+	planned := map[string]bool{}
+	for _, address := range plan.Addresses() {
+		rest, ok := strings.CutPrefix(address, "module.")
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(rest, ".")
+		name, _, _ = strings.Cut(name, "[")
+		planned[name] = true
+	}
+	return planned
+}
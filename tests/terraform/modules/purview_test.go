@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform Microsoft Purview module.
 //
-// Run with: go test -v -run TestPurview ./modules/
+// Run with: go test -v -tags=integration -run TestPurview ./modules/
 //
 // =============================================================================
 
@@ -13,50 +13,33 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestPurviewModuleBasic tests basic Purview configuration
 func TestPurviewModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/purview",
-		Vars: map[string]interface{}{
-			"customer_name":       "testpurv",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-purview",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_ids": map[string]interface{}{
-				"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-				"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-				"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-				"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-				"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-				"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-			},
-			"admin_group_id": "00000000-0000-0000-0000-000000000000",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.Purview().
+		With("customer_name", "testpurv").
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
 
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Purview account is planned
-	assert.Contains(t, planOutput, "azurerm_purview_account")
+	helpers.AssertCreated(t, plan, "azurerm_purview_account.main")
+	helpers.AssertAttribute(t, plan, "azurerm_purview_account.main", "public_network_enabled", false)
+	helpers.AssertCreated(t, plan, "azurerm_private_endpoint.purview_portal")
+	helpers.AssertCreated(t, plan, "azurerm_private_endpoint.purview_account")
 }
 
 // TestPurviewModuleSizingProfiles tests different sizing profiles
 func TestPurviewModuleSizingProfiles(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	profiles := []string{"small", "medium", "large", "xlarge"}
@@ -66,82 +49,54 @@ func TestPurviewModuleSizingProfiles(t *testing.T) {
 		t.Run(profile, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/purview",
-				Vars: map[string]interface{}{
-					"customer_name":       "sizetest",
-					"environment":         "prod",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-purview-" + profile,
-					"sizing_profile":      profile,
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_ids": map[string]interface{}{
-						"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-						"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-						"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-						"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-						"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-						"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-					},
-					"admin_group_id": "00000000-0000-0000-0000-000000000000",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Purview().
+				With("customer_name", "sizetest").
+				With("environment", "prod").
+				With("resource_group_name", "rg-test-purview-"+profile).
+				With("sizing_profile", profile).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestPurviewModuleDataSources tests data source registration
 func TestPurviewModuleDataSources(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/purview",
-		Vars: map[string]interface{}{
-			"customer_name":       "dstest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-purview-ds",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_ids": map[string]interface{}{
-				"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-				"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-				"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-				"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-				"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-				"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
+	terraformOptions := helpers.Purview().
+		With("customer_name", "dstest").
+		With("environment", "prod").
+		With("data_sources", []map[string]interface{}{
+			{
+				"name":           "storage-account-1",
+				"type":           "AzureBlobStorage",
+				"resource_id":    helpers.ResourceID("Microsoft.Storage", "storageAccounts", "st1"),
+				"scan_frequency": "Weekly",
 			},
-			"admin_group_id": "00000000-0000-0000-0000-000000000000",
-			"data_sources": []map[string]interface{}{
-				{
-					"name":           "storage-account-1",
-					"type":           "AzureBlobStorage",
-					"resource_id":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/st1",
-					"scan_frequency": "Weekly",
-				},
-				{
-					"name":           "sql-database-1",
-					"type":           "AzureSqlDatabase",
-					"resource_id":    "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Sql/servers/sql1/databases/db1",
-					"scan_frequency": "Daily",
-				},
+			{
+				"name":           "sql-database-1",
+				"type":           "AzureSqlDatabase",
+				"resource_id":    helpers.ResourceID("Microsoft.Sql", "servers", "sql1", "databases", "db1"),
+				"scan_frequency": "Daily",
 			},
-		},
-		NoColor: true,
-	})
+		}).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify data sources are configured
-	assert.Contains(t, planOutput, "purview")
+	helpers.AssertCreated(t, plan, `azapi_resource.data_sources["storage-account-1"]`)
+	helpers.AssertCreated(t, plan, `azapi_resource.data_sources["sql-database-1"]`)
+	helpers.AssertCreated(t, plan, `azurerm_role_assignment.purview_storage_reader["storage-account-1"]`)
+	helpers.AssertCreated(t, plan, `azurerm_role_assignment.purview_sql_reader["sql-database-1"]`)
 }
 
 // TestPurviewModuleLATAMClassifications tests LATAM-specific classifications
 func TestPurviewModuleLATAMClassifications(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	testCases := []struct {
@@ -157,75 +112,45 @@ func TestPurviewModuleLATAMClassifications(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/purview",
-				Vars: map[string]interface{}{
-					"customer_name":       "latamtest",
-					"environment":         "prod",
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-purview-latam",
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_ids": map[string]interface{}{
-						"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-						"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-						"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-						"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-						"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-						"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-					},
-					"admin_group_id":                "00000000-0000-0000-0000-000000000000",
-					"enable_latam_classifications": tc.enabled,
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Purview().
+				With("customer_name", "latamtest").
+				With("environment", "prod").
+				With("enable_latam_classifications", tc.enabled).
+				Options(t)
+
+			helpers.InitAndPlanJSON(t, terraformOptions)
 		})
 	}
 }
 
 // TestPurviewModuleCollectionHierarchy tests collection structure
 func TestPurviewModuleCollectionHierarchy(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/purview",
-		Vars: map[string]interface{}{
-			"customer_name":       "colltest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-purview-coll",
-			"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-			"private_dns_zone_ids": map[string]interface{}{
-				"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-				"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-				"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-				"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-				"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-				"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-			},
-			"admin_group_id": "00000000-0000-0000-0000-000000000000",
-			"collection_hierarchy": []map[string]interface{}{
-				{"name": "H1-Foundation", "parent": "", "description": "Foundation infrastructure assets"},
-				{"name": "H2-Enhancement", "parent": "", "description": "Enhanced platform assets"},
-				{"name": "H3-Innovation", "parent": "", "description": "AI/ML innovation assets"},
-				{"name": "Databases", "parent": "H1-Foundation", "description": "Database assets"},
-				{"name": "Storage", "parent": "H1-Foundation", "description": "Storage assets"},
-			},
-		},
-		NoColor: true,
-	})
+	terraformOptions := helpers.Purview().
+		With("customer_name", "colltest").
+		With("environment", "prod").
+		With("collection_hierarchy", []map[string]interface{}{
+			{"name": "H1-Foundation", "parent": "", "description": "Foundation infrastructure assets"},
+			{"name": "H2-Enhancement", "parent": "", "description": "Enhanced platform assets"},
+			{"name": "H3-Innovation", "parent": "", "description": "AI/ML innovation assets"},
+			{"name": "Databases", "parent": "H1-Foundation", "description": "Database assets"},
+			{"name": "Storage", "parent": "H1-Foundation", "description": "Storage assets"},
+		}).
+		Options(t)
 
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify collection hierarchy is planned
-	assert.Contains(t, planOutput, "purview")
+	helpers.AssertAttribute(t, plan, `azapi_resource.collections["H1-Foundation"]`, "name", "h1foundation")
+	helpers.AssertCreated(t, plan, `azapi_resource.collections["Databases"]`)
+	helpers.AssertCreated(t, plan, `azapi_resource.collections["Storage"]`)
 }
 
 // TestPurviewModuleEnvironments tests different environments
 func TestPurviewModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -235,31 +160,15 @@ func TestPurviewModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/purview",
-				Vars: map[string]interface{}{
-					"customer_name":       "envtest",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-purview-" + env,
-					"subnet_id":           "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/snet",
-					"private_dns_zone_ids": map[string]interface{}{
-						"purview":        "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purview",
-						"purview_studio": "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/purviewstudio",
-						"storage_blob":   "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/blob",
-						"storage_queue":  "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/queue",
-						"servicebus":     "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/servicebus",
-						"eventhub":       "/subscriptions/00000000/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/eventhub",
-					},
-					"admin_group_id": "00000000-0000-0000-0000-000000000000",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
-
-			assert.Contains(t, planOutput, env)
+			terraformOptions := helpers.Purview().
+				With("customer_name", "envtest").
+				With("environment", env).
+				With("resource_group_name", "rg-test-purview-"+env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
+
+			helpers.AssertAttribute(t, plan, "azurerm_purview_account.main", "name", "pvenvtest"+env)
 		})
 	}
 }
@@ -0,0 +1,56 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - ROOT MODULE TESTS
+// =============================================================================
+//
+// Tests for the root configuration in terraform/, which calls every module
+// behind a feature flag and a deployment_mode. The wiring test checks each
+// module call against the module it calls, then checks every pairwise
+// combination of mode and flags for references to modules the combination
+// disables, such as module.observability[0] with observability off. It needs
+// no Terraform. TestOfflineRootFeatureCombinations plans the same
+// combinations against mocked providers.
+//
+// Run with: go test -v -run 'TestRootModule|TestOfflineRoot' ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+// TestRootModuleValidate tests that the root module initializes and validates
+func TestRootModuleValidate(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierValidate)
+	t.Parallel()
+
+	terraformOptions := helpers.Root().Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+}
+
+// TestRootModuleWiring tests module calls and flag combinations of the root module
+func TestRootModuleWiring(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierValidate)
+	t.Parallel()
+
+	config := helpers.LoadConfiguration(t, helpers.RootModule)
+
+	problems, err := config.CheckModuleCallsE()
+	require.NoError(t, err)
+	assert.Empty(t, problems, "%d module call problem(s) in terraform/:\n  - %s", len(problems), strings.Join(problems, "\n  - "))
+
+	for _, combination := range helpers.Pairwise(t, helpers.RootFeatureFactors(t), helpers.RootFeatureExclusions...) {
+		problems, err := config.CheckDisabledReferencesE(combination)
+		require.NoError(t, err)
+		assert.Empty(t, problems, "%d reference(s) to disabled modules with %v:\n  - %s", len(problems), combination, strings.Join(problems, "\n  - "))
+	}
+}
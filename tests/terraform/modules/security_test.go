@@ -4,7 +4,7 @@
 //
 // Unit and integration tests for the Terraform security module.
 //
-// Run with: go test -v -run TestSecurity ./modules/
+// Run with: go test -v -tags=integration -run TestSecurity ./modules/
 //
 // =============================================================================
 
@@ -13,141 +13,103 @@ package modules
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/stretchr/testify/assert"
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
 )
 
 // TestSecurityModuleBasic tests basic security module configuration
 func TestSecurityModuleBasic(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "testsec",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-security",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-			"tags": map[string]interface{}{
-				"Environment": "test",
-				"ManagedBy":   "Terratest",
-			},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	terraform.Validate(t, terraformOptions)
-
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Security().
+		With("customer_name", "testsec").
+		Options(t)
+
+	helpers.Init(t, terraformOptions)
+	helpers.Validate(t, terraformOptions)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify Key Vault is planned
-	assert.Contains(t, planOutput, "azurerm_key_vault.main")
+	helpers.AssertCreated(t, plan, "azurerm_key_vault.main")
+	helpers.AssertCreated(t, plan, "azurerm_private_endpoint.key_vault")
+	helpers.AssertAttribute(t, plan, "azurerm_key_vault.main", "public_network_access_enabled", false)
 }
 
 // TestSecurityModuleKeyVaultNaming tests Key Vault naming convention
 func TestSecurityModuleKeyVaultNaming(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "kvtest",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-kv",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Security().
+		With("customer_name", "kvtest").
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Key Vault name must follow CAF naming convention
-	assert.Contains(t, planOutput, "kv-")
+	helpers.AssertAttribute(t, plan, "azurerm_key_vault.main", "name", "kv-kvtest-dev")
 }
 
 // TestSecurityModuleManagedIdentities tests managed identity creation
 func TestSecurityModuleManagedIdentities(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":        "idtest",
-			"environment":          "dev",
-			"location":             "brazilsouth",
-			"resource_group_name":  "rg-test-identity",
-			"tenant_id":            "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":       "00000000-0000-0000-0000-000000000001",
-			"create_aks_identity":  true,
-			"create_app_identities": []string{"app1", "app2"},
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Security().
+		With("customer_name", "idtest").
+		With("workload_identities", map[string]interface{}{
+			"app1": workloadIdentity("app1"),
+			"app2": workloadIdentity("app2"),
+		}).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify managed identities are planned
-	assert.Contains(t, planOutput, "azurerm_user_assigned_identity")
+	helpers.AssertCreated(t, plan, "azurerm_user_assigned_identity.external_secrets")
+	helpers.AssertCreated(t, plan, `azurerm_user_assigned_identity.workload["app1"]`)
+	helpers.AssertCreated(t, plan, `azurerm_user_assigned_identity.workload["app2"]`)
+	helpers.AssertActionCount(t, plan, "azurerm_user_assigned_identity", helpers.ActionCreate, 3)
 }
 
 // TestSecurityModuleRBACAssignments tests RBAC role assignments
 func TestSecurityModuleRBACAssignments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "rbactest",
-			"environment":         "prod",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-rbac",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Security().
+		With("customer_name", "rbactest").
+		With("environment", "prod").
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// Verify RBAC assignments are planned
-	assert.Contains(t, planOutput, "azurerm_role_assignment")
+	helpers.AssertAttribute(t, plan, "azurerm_role_assignment.kv_admin", "role_definition_name", "Key Vault Administrator")
+	helpers.AssertAttribute(t, plan, "azurerm_role_assignment.kv_admin", "principal_id", helpers.FakeObjectID)
+	helpers.AssertCreated(t, plan, "azurerm_role_assignment.kv_admin_deployer")
 }
 
 // TestSecurityModuleKeyVaultAccessPolicies tests Key Vault access policies
 func TestSecurityModuleKeyVaultAccessPolicies(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../../../terraform/modules/security",
-		Vars: map[string]interface{}{
-			"customer_name":       "kvaccess",
-			"environment":         "dev",
-			"location":            "brazilsouth",
-			"resource_group_name": "rg-test-kvaccess",
-			"tenant_id":           "00000000-0000-0000-0000-000000000000",
-			"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-			"enable_rbac_authorization": true,
-		},
-		NoColor: true,
-	})
-
-	terraform.Init(t, terraformOptions)
-	planOutput := terraform.Plan(t, terraformOptions)
+	terraformOptions := helpers.Security().
+		With("customer_name", "kvaccess").
+		WithAttr("key_vault_config", "enable_rbac_authorization", true).
+		Options(t)
+
+	plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 	// With RBAC, access policies should not be used
-	assert.Contains(t, planOutput, "enable_rbac_authorization")
+	helpers.AssertAttribute(t, plan, "azurerm_key_vault.main", "enable_rbac_authorization", true)
 }
 
 // TestSecurityModuleEnvironments tests different environment configurations
 func TestSecurityModuleEnvironments(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
 	t.Parallel()
 
 	environments := []string{"dev", "staging", "prod"}
@@ -157,24 +119,28 @@ func TestSecurityModuleEnvironments(t *testing.T) {
 		t.Run(env, func(t *testing.T) {
 			t.Parallel()
 
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../../terraform/modules/security",
-				Vars: map[string]interface{}{
-					"customer_name":       "secenv",
-					"environment":         env,
-					"location":            "brazilsouth",
-					"resource_group_name": "rg-test-sec-" + env,
-					"tenant_id":           "00000000-0000-0000-0000-000000000000",
-					"admin_group_id":      "00000000-0000-0000-0000-000000000001",
-				},
-				NoColor: true,
-			})
-
-			terraform.Init(t, terraformOptions)
-			planOutput := terraform.Plan(t, terraformOptions)
+			terraformOptions := helpers.Security().
+				With("customer_name", "secenv").
+				With("environment", env).
+				With("resource_group_name", "rg-test-sec-"+env).
+				Options(t)
+
+			plan := helpers.InitAndPlanJSON(t, terraformOptions)
 
 			// Verify environment is reflected
-			assert.Contains(t, planOutput, env)
+			helpers.AssertAttribute(t, plan, "azurerm_key_vault.main", "name", "kv-secenv-"+env)
+			helpers.AssertAttribute(t, plan, "azurerm_key_vault.main", "resource_group_name", "rg-test-sec-"+env)
 		})
 	}
 }
+
+// workloadIdentity returns a workload identity for a service account of the
+// same name, with read access to Key Vault secrets.
+func workloadIdentity(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace":                   name,
+		"service_account":             name,
+		"key_vault_role":              "Key Vault Secrets User",
+		"additional_role_assignments": []interface{}{},
+	}
+}
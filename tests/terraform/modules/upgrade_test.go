@@ -0,0 +1,56 @@
+// =============================================================================
+// AGENTIC DEVOPS PLATFORM - MODULE UPGRADE TESTS
+// =============================================================================
+//
+// Upgrade tests for the modules that hold data. Each module is deployed as of
+// TERRATEST_UPGRADE_REF (default HEAD) with mocked providers, then the
+// working tree is planned against that state with the real azurerm provider
+// and -refresh=false. The provider decides which attribute changes force a
+// replacement, so this needs the credentials of the plan tier.
+// TestOfflineModuleUpgrade covers every module for deletes and missing moved
+// blocks without credentials.
+//
+// Run with: TERRATEST_UPGRADE_REF=origin/main go test -v -tags=integration -run TestModuleUpgrade ./modules/
+//
+// =============================================================================
+
+package modules
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/${GITHUB_ORG}/${GITHUB_REPO}/tests/helpers"
+)
+
+// TestModuleUpgrade tests that stateful modules neither replace nor delete resources deployed at the upgrade ref
+func TestModuleUpgrade(t *testing.T) {
+	helpers.RequireTier(t, helpers.TierPlan)
+	t.Parallel()
+
+	ref := helpers.UpgradeRef()
+	fixtures := []*helpers.Fixture{
+		helpers.ContainerRegistry(),
+		helpers.Databases(),
+		helpers.DisasterRecovery(),
+		helpers.Purview(),
+		helpers.Security(),
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.Module, func(t *testing.T) {
+			t.Parallel()
+
+			plan, err := fixture.UpgradePlanE(t, ref)
+			if errors.Is(err, helpers.ErrNotAtRef) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			helpers.AssertUpgrade(t, plan, ref)
+			helpers.AssertNoProtectedDestroy(t, plan)
+		})
+	}
+}